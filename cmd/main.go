@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
@@ -10,22 +12,46 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 
+	"deer-flow-go/internal/reload"
 	"deer-flow-go/internal/workflow"
+	"deer-flow-go/pkg/auth"
 	"deer-flow-go/pkg/config"
 	"deer-flow-go/pkg/handlers"
+	"deer-flow-go/pkg/logging"
 	"deer-flow-go/pkg/mcp"
 	"deer-flow-go/pkg/queue"
+	"deer-flow-go/pkg/usage"
 )
 
 func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "配置文件路径（.yaml/.yml/.toml），环境变量会覆盖文件中的值")
+	serverBinary := flag.String("mcp-server-binary", "", "预编译的MCP服务器（cmd/server）路径，设置后以子进程运行工具服务器而不是在进程内运行")
+	flag.Parse()
+
+	// 子命令: config validate
+	if args := flag.Args(); len(args) > 0 {
+		os.Exit(runCommand(args, *configPath))
+	}
+
 	// 加载配置
-	cfg, err := config.LoadConfig()
+	cfg, err := config.LoadConfigFromFile(*configPath)
 	if err != nil {
 		fmt.Printf("Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
 
+	// MCP服务器子进程继承环境变量，从而读取同一份配置文件
+	if *configPath != "" {
+		os.Setenv("CONFIG_FILE", *configPath)
+	}
+	// 命令行参数优先于配置；写入环境变量使热加载读取到相同的值
+	if *serverBinary != "" {
+		cfg.MCP.ServerBinary = *serverBinary
+		os.Setenv("MCP_SERVER_BINARY", *serverBinary)
+	}
+
 	// 设置日志
 	logger := logrus.New()
 	logLevel, err := logrus.ParseLevel(cfg.LogLevel)
@@ -37,6 +63,12 @@ func main() {
 		FullTimestamp: true,
 	})
 
+	// 统一日志脱敏：密钥、URL中的appid、可选的查询个人信息
+	redactor := logging.NewRedactor(cfg.Secrets(), cfg.LogRedactPII)
+	redactionHook := logging.NewRedactionHook(redactor)
+	logger.AddHook(redactionHook)
+	logrus.AddHook(redactionHook)
+
 	logger.Info("Starting deer-flow-go agent dialogue system")
 
 	// 设置Gin模式
@@ -46,18 +78,21 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// 创建真正的MCP客户端
-	mcpClient := mcp.NewClient(logger)
+	// 创建MCP服务器管理器并启动配置中的MCP服务器（默认在进程内运行统一工具服务器）
+	mcpManager := mcp.NewManager(cfg, logger)
 
-	// 启动MCP服务器进程
-	ctx := context.Background()
-	if err := mcpClient.Start(ctx); err != nil {
-		logger.WithError(err).Fatal("Failed to start MCP server process")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mcpManager.Start(ctx, cfg.MCP.Servers); err != nil {
+		logger.WithError(err).Fatal("Failed to start MCP servers")
 	}
-	logger.Info("Real MCP server process started successfully")
+	logger.Info("MCP servers started successfully")
 
 	// 创建工作流（使用真正的MCP客户端）
-	agentWorkflow := workflow.NewAgentWorkflowWithMCP(cfg, mcpClient, logger)
+	agentWorkflow := workflow.NewAgentWorkflowWithMCP(cfg, mcpManager, logger)
+	if err := agentWorkflow.UpdateSystemPrompts(cfg.SystemPrompts); err != nil {
+		logger.WithError(err).Fatal("Failed to load system prompts")
+	}
 
 	// 验证工作流配置
 	if err := agentWorkflow.ValidateWorkflow(ctx); err != nil {
@@ -71,7 +106,20 @@ func main() {
 		RequestTimeout: time.Duration(cfg.Queue.RequestTimeout) * time.Second,
 		QueueTimeout:   time.Duration(cfg.Queue.QueueTimeout) * time.Second,
 	}
-	queueManager := queue.NewQueueManager(queueConfig, agentWorkflow, logger)
+	// 用量记账：队列处理完每个请求后按租户记账
+	var (
+		ledger    *usage.Ledger
+		processor queue.RequestProcessor = agentWorkflow
+	)
+	if cfg.Usage.Enabled {
+		ledger, err = usage.NewLedger(cfg.Usage, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to open usage ledger")
+		}
+		defer ledger.Close()
+		processor = usage.NewMeter(agentWorkflow, ledger, logger)
+	}
+	queueManager := queue.NewQueueManager(queueConfig, processor, logger)
 
 	// 启动队列管理器
 	if err := queueManager.Start(); err != nil {
@@ -82,8 +130,17 @@ func main() {
 	// 创建路由器
 	router := gin.Default()
 
+	// API密钥鉴权
+	keyStore := auth.NewKeyStore(cfg.Auth)
+	if !cfg.Auth.Enabled {
+		logger.WithFields(logrus.Fields{
+			"exposed_endpoints": []string{"/api/chat", "/v1/chat/completions", "/api/usage", "/api/workflow/status", "/api/queue/stats"},
+			"how_to_fix":        "set auth.enabled=true (AUTH_ENABLED=true) and configure auth.keys or auth.key_file",
+		}).Warn("!!! API KEY AUTHENTICATION IS DISABLED: every endpoint, including usage reports and status, is publicly accessible. Do not expose this server to untrusted networks !!!")
+	}
+
 	// 设置API处理器
-	apiHandler := handlers.NewAPIHandler(agentWorkflow, queueManager, logger)
+	apiHandler := handlers.NewAPIHandler(agentWorkflow, queueManager, keyStore, ledger, logger)
 	apiHandler.SetupRoutes(router)
 
 	// 启动服务器
@@ -97,6 +154,21 @@ func main() {
 		}
 	}()
 
+	// 配置热加载：监听配置文件变化或SIGHUP
+	reloader := reload.NewReloader(cfg, queueManager, agentWorkflow, mcpManager, keyStore, ledger, redactor, logger)
+	watcher := config.NewWatcher(*configPath, 5*time.Second, logger)
+	go watcher.Watch(ctx, func(newCfg *config.Config) {
+		reloader.Apply(ctx, newCfg)
+	})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			watcher.Trigger()
+		}
+	}()
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -109,9 +181,39 @@ func main() {
 	logger.Info("Queue manager stopped")
 
 	// 停止MCP客户端
-	if err := mcpClient.Stop(); err != nil {
+	if err := mcpManager.Stop(); err != nil {
 		logger.WithError(err).Error("Failed to stop MCP client")
 	} else {
 		logger.Info("MCP client stopped")
 	}
-}
\ No newline at end of file
+}
+
+// runCommand 执行命令行子命令，返回进程退出码
+func runCommand(args []string, configPath string) int {
+	if len(args) == 2 && args[0] == "config" && args[1] == "validate" {
+		cfg, err := config.LoadConfigFromFile(configPath)
+		if cfg != nil {
+			data, marshalErr := yaml.Marshal(cfg.Redacted())
+			if marshalErr != nil {
+				fmt.Fprintf(os.Stderr, "Failed to render configuration: %v\n", marshalErr)
+				return 1
+			}
+			fmt.Printf("# effective configuration\n%s", data)
+		}
+
+		var validationErrs config.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			fmt.Fprintf(os.Stderr, "\n%v\n", err)
+			return 1
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+			return 1
+		}
+
+		fmt.Println("# configuration is valid")
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command: %v\nusage: %s [--config path] [config validate]\n", args, os.Args[0])
+	return 2
+}
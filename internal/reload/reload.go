@@ -0,0 +1,261 @@
+package reload
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/internal/workflow"
+	"deer-flow-go/pkg/auth"
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/logging"
+	"deer-flow-go/pkg/mcp"
+	"deer-flow-go/pkg/queue"
+	"deer-flow-go/pkg/usage"
+)
+
+// restartRequiredFields 无法在运行时修改的字段，变化时只报告并保留旧值
+var restartRequiredFields = map[string]bool{
+	"port":              true,
+	"queue.queue_size":  true,
+	"mcp.enabled":       true,
+	"mcp.server_binary": true,
+	"usage.enabled":     true,
+	"usage.ledger_file": true,
+}
+
+// Result 一次热加载的结果
+type Result struct {
+	Applied         []string `json:"applied"`          // 已生效的字段
+	RestartRequired []string `json:"restart_required"` // 需要重启才能生效的字段（保持旧值）
+	Failed          []string `json:"failed"`           // 应用失败的字段（保持旧值）
+	Ignored         []string `json:"ignored"`          // 热加载不处理的字段，新值已记录但不会影响运行中的组件
+}
+
+// Reloader 将通过校验的新配置应用到运行中的组件
+type Reloader struct {
+	mu           sync.Mutex
+	current      *config.Config
+	queueManager *queue.QueueManager
+	workflow     *workflow.AgentWorkflow
+	mcpManager   *mcp.Manager
+	keyStore     *auth.KeyStore
+	ledger       *usage.Ledger
+	redactor     *logging.Redactor
+	logger       *logrus.Logger
+}
+
+// NewReloader 创建配置热加载器
+// ledger 为空（未启用用量记账）时 usage.* 的变化只记录不应用。
+func NewReloader(cfg *config.Config, queueManager *queue.QueueManager, agentWorkflow *workflow.AgentWorkflow, mcpManager *mcp.Manager, keyStore *auth.KeyStore, ledger *usage.Ledger, redactor *logging.Redactor, logger *logrus.Logger) *Reloader {
+	return &Reloader{
+		current:      cfg,
+		queueManager: queueManager,
+		workflow:     agentWorkflow,
+		mcpManager:   mcpManager,
+		keyStore:     keyStore,
+		ledger:       ledger,
+		redactor:     redactor,
+		logger:       logger,
+	}
+}
+
+// Current 返回当前生效的配置
+func (r *Reloader) Current() *config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Apply 对比新旧配置并应用可热更新的变化
+// ctx 用于启动新的MCP服务器子进程，应与服务生命周期一致而不是某个请求的上下文。
+func (r *Reloader) Apply(ctx context.Context, newConfig *config.Config) *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldConfig := r.current
+	effective := *newConfig
+	result := &Result{}
+
+	changes := config.Changes(oldConfig, newConfig)
+	if len(changes) == 0 {
+		r.logger.Info("Config reloaded, no changes detected")
+		return result
+	}
+
+	// 需要重启的字段保留旧值，使后续的变更检测仍能报告它们；
+	// 在应用MCP服务器变化之前设置，新启动的服务器不会使用尚未生效的 mcp.server_binary
+	effective.Port = oldConfig.Port
+	effective.Queue.QueueSize = oldConfig.Queue.QueueSize
+	effective.MCP.Enabled = oldConfig.MCP.Enabled
+	effective.MCP.ServerBinary = oldConfig.MCP.ServerBinary
+	effective.Usage.Enabled = oldConfig.Usage.Enabled
+	effective.Usage.LedgerFile = oldConfig.Usage.LedgerFile
+
+	var (
+		updateLLM     bool
+		updateLogging bool
+		updateQueue   bool
+		updateAuth    bool
+		updatePrompts bool
+		updateUsage   bool
+		restartMCP    bool
+	)
+
+	for _, field := range changes {
+		switch {
+		case restartRequiredFields[field]:
+			result.RestartRequired = append(result.RestartRequired, field)
+		case field == "log_level" || field == "log_redact_pii":
+			updateLogging = true
+		case strings.HasPrefix(field, "azure_openai."):
+			updateLLM = true
+		case strings.HasPrefix(field, "queue."):
+			updateQueue = true
+		case strings.HasPrefix(field, "auth."):
+			updateAuth = true
+		case strings.HasPrefix(field, "system_prompts."):
+			updatePrompts = true
+		case strings.HasPrefix(field, "usage."):
+			updateUsage = true
+		case field == "mcp.servers":
+			added, removed, restarted, err := r.mcpManager.Apply(ctx, &effective)
+			if err != nil {
+				r.logger.WithError(err).Error("Failed to apply MCP server changes")
+				effective.MCP.Servers = oldConfig.MCP.Servers
+				result.Failed = append(result.Failed, field)
+				continue
+			}
+			r.logger.WithFields(logrus.Fields{
+				"added":     added,
+				"removed":   removed,
+				"restarted": restarted,
+			}).Info("MCP servers updated")
+			result.Applied = append(result.Applied, field)
+		case strings.HasPrefix(field, "tavily.") || strings.HasPrefix(field, "search.") || strings.HasPrefix(field, "weather.") || strings.HasPrefix(field, "fetch.") || field == "mcp.prompts_dir":
+			// 搜索、天气、网页抓取和提示词包配置由MCP服务器读取，需要重启MCP服务器
+			restartMCP = true
+		default:
+			result.Ignored = append(result.Ignored, field)
+		}
+	}
+
+	// 任何密钥（包括备用部署的密钥）变化都要让脱敏器知道新值，否则新密钥会以明文出现在日志中
+	if !slices.Equal(oldConfig.Secrets(), newConfig.Secrets()) {
+		updateLogging = true
+	}
+
+	if updateLogging {
+		if level, err := logrus.ParseLevel(newConfig.LogLevel); err == nil {
+			r.logger.SetLevel(level)
+		}
+		r.redactor.Update(newConfig.Secrets(), newConfig.LogRedactPII)
+		result.Applied = append(result.Applied, fieldsWithPrefix(changes, "log_")...)
+	}
+
+	if updateAuth {
+		r.keyStore.Update(newConfig.Auth)
+		if oldConfig.Auth.Enabled && !newConfig.Auth.Enabled {
+			r.logger.Warn("!!! API KEY AUTHENTICATION HAS BEEN DISABLED BY CONFIG RELOAD: every endpoint, including usage reports and status, is now publicly accessible !!!")
+		}
+		r.redactor.Update(newConfig.Secrets(), newConfig.LogRedactPII)
+		result.Applied = append(result.Applied, fieldsWithPrefix(changes, "auth.")...)
+	}
+
+	if updateLLM {
+		r.workflow.UpdateLLMConfig(newConfig.AzureOpenAI)
+		result.Applied = append(result.Applied, fieldsWithPrefix(changes, "azure_openai.")...)
+	}
+
+	if updatePrompts {
+		promptFields := fieldsWithPrefix(changes, "system_prompts.")
+		if err := r.workflow.UpdateSystemPrompts(newConfig.SystemPrompts); err != nil {
+			r.logger.WithError(err).Error("Failed to reload system prompts")
+			effective.SystemPrompts = oldConfig.SystemPrompts
+			result.Failed = append(result.Failed, promptFields...)
+		} else {
+			result.Applied = append(result.Applied, promptFields...)
+		}
+	}
+
+	if updateUsage {
+		if r.ledger != nil {
+			r.ledger.UpdateConfig(effective.Usage)
+		}
+		for _, field := range fieldsWithPrefix(changes, "usage.") {
+			if !restartRequiredFields[field] {
+				result.Applied = append(result.Applied, field)
+			}
+		}
+	}
+
+	if updateQueue {
+		queueFields := fieldsWithPrefix(changes, "queue.")
+		if err := r.queueManager.Resize(newConfig.Queue.MaxWorkers); err != nil {
+			r.logger.WithError(err).Error("Failed to resize worker pool")
+			effective.Queue = oldConfig.Queue
+			result.Failed = append(result.Failed, "queue.max_workers")
+		} else {
+			r.queueManager.UpdateTimeouts(
+				time.Duration(newConfig.Queue.RequestTimeout)*time.Second,
+				time.Duration(newConfig.Queue.QueueTimeout)*time.Second,
+			)
+			for _, field := range queueFields {
+				if !restartRequiredFields[field] {
+					result.Applied = append(result.Applied, field)
+				}
+			}
+		}
+	}
+
+	if restartMCP {
+		mcpFields := fieldsWithPrefix(changes, "tavily.")
+		mcpFields = append(mcpFields, fieldsWithPrefix(changes, "search.")...)
+		mcpFields = append(mcpFields, fieldsWithPrefix(changes, "weather.")...)
+		mcpFields = append(mcpFields, fieldsWithPrefix(changes, "fetch.")...)
+		mcpFields = append(mcpFields, fieldsWithPrefix(changes, "mcp.prompts_dir")...)
+		if err := r.mcpManager.Restart(ctx, &effective); err != nil {
+			r.logger.WithError(err).Error("Failed to restart MCP servers with new search/weather/fetch/prompt settings")
+			effective.Tavily = oldConfig.Tavily
+			effective.Search = oldConfig.Search
+			effective.Weather = oldConfig.Weather
+			effective.Fetch = oldConfig.Fetch
+			effective.MCP.PromptsDir = oldConfig.MCP.PromptsDir
+			result.Failed = append(result.Failed, mcpFields...)
+		} else {
+			result.Applied = append(result.Applied, mcpFields...)
+		}
+	}
+
+	r.current = &effective
+
+	r.logger.WithFields(logrus.Fields{
+		"applied":          result.Applied,
+		"restart_required": result.RestartRequired,
+		"failed":           result.Failed,
+		"ignored":          result.Ignored,
+	}).Info("Configuration reloaded")
+	if len(result.RestartRequired) > 0 {
+		r.logger.WithField("fields", result.RestartRequired).Warn("Some configuration changes require a restart and were not applied")
+	}
+	if len(result.Ignored) > 0 {
+		r.logger.WithField("fields", result.Ignored).Warn("Some configuration changes are not handled by hot reload and have no effect")
+	}
+
+	return result
+}
+
+// fieldsWithPrefix 筛选指定前缀的字段
+func fieldsWithPrefix(fields []string, prefix string) []string {
+	var matched []string
+	for _, field := range fields {
+		if strings.HasPrefix(field, prefix) {
+			matched = append(matched, field)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,152 @@
+package reload
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/internal/workflow"
+	"deer-flow-go/pkg/auth"
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/logging"
+	"deer-flow-go/pkg/mcp"
+	"deer-flow-go/pkg/queue"
+)
+
+// testConfig 热加载测试使用的基础配置
+func testConfig() *config.Config {
+	return &config.Config{
+		Port:     "8080",
+		LogLevel: "info",
+		AzureOpenAI: config.AzureOpenAIConfig{
+			APIKey:     "primary-secret-key",
+			Endpoint:   "https://example.openai.azure.com",
+			Deployment: "gpt-4o",
+			APIVersion: "2024-06-01",
+		},
+		Queue: config.QueueConfig{
+			MaxWorkers:     2,
+			QueueSize:      10,
+			RequestTimeout: 30,
+			QueueTimeout:   10,
+		},
+	}
+}
+
+// newTestReloader 创建热加载器，日志写入返回的缓冲区并经过脱敏
+func newTestReloader(t *testing.T, cfg *config.Config) (*Reloader, *queue.QueueConfig, *logrus.Logger, *bytes.Buffer) {
+	t.Helper()
+	var output bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&output)
+	logger.SetLevel(logrus.InfoLevel)
+	redactor := logging.NewRedactor(cfg.Secrets(), cfg.LogRedactPII)
+	logger.AddHook(logging.NewRedactionHook(redactor))
+
+	mcpManager := mcp.NewManager(cfg, logger)
+	agentWorkflow := workflow.NewAgentWorkflowWithMCP(cfg, mcpManager, logger)
+	queueConfig := &queue.QueueConfig{
+		MaxWorkers:     cfg.Queue.MaxWorkers,
+		QueueSize:      cfg.Queue.QueueSize,
+		RequestTimeout: time.Duration(cfg.Queue.RequestTimeout) * time.Second,
+		QueueTimeout:   time.Duration(cfg.Queue.QueueTimeout) * time.Second,
+	}
+	queueManager := queue.NewQueueManager(queueConfig, agentWorkflow, logger)
+
+	reloader := NewReloader(cfg, queueManager, agentWorkflow, mcpManager, auth.NewKeyStore(cfg.Auth), nil, redactor, logger)
+	return reloader, queueConfig, logger, &output
+}
+
+func TestReloader_Apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Config)
+		check  func(t *testing.T, result *Result, queueConfig *queue.QueueConfig, logger *logrus.Logger, output *bytes.Buffer)
+	}{
+		{
+			name:   "queue resize",
+			modify: func(cfg *config.Config) { cfg.Queue.MaxWorkers = 5 },
+			check: func(t *testing.T, result *Result, queueConfig *queue.QueueConfig, logger *logrus.Logger, output *bytes.Buffer) {
+				assert.Equal(t, []string{"queue.max_workers"}, result.Applied)
+				assert.Equal(t, 5, queueConfig.MaxWorkers)
+			},
+		},
+		{
+			name:   "queue size requires restart",
+			modify: func(cfg *config.Config) { cfg.Queue.QueueSize = 50 },
+			check: func(t *testing.T, result *Result, queueConfig *queue.QueueConfig, logger *logrus.Logger, output *bytes.Buffer) {
+				assert.Empty(t, result.Applied)
+				assert.Equal(t, []string{"queue.queue_size"}, result.RestartRequired)
+				assert.Equal(t, 10, queueConfig.QueueSize)
+			},
+		},
+		{
+			name:   "timeout update",
+			modify: func(cfg *config.Config) { cfg.Queue.RequestTimeout = 90 },
+			check: func(t *testing.T, result *Result, queueConfig *queue.QueueConfig, logger *logrus.Logger, output *bytes.Buffer) {
+				assert.Equal(t, []string{"queue.request_timeout"}, result.Applied)
+				assert.Equal(t, 90*time.Second, queueConfig.RequestTimeout)
+			},
+		},
+		{
+			name:   "log level change",
+			modify: func(cfg *config.Config) { cfg.LogLevel = "debug" },
+			check: func(t *testing.T, result *Result, queueConfig *queue.QueueConfig, logger *logrus.Logger, output *bytes.Buffer) {
+				assert.Equal(t, []string{"log_level"}, result.Applied)
+				assert.Equal(t, logrus.DebugLevel, logger.GetLevel())
+			},
+		},
+		{
+			name:   "secret redaction",
+			modify: func(cfg *config.Config) { cfg.Tavily.APIKey = "tavily-secret-key" },
+			check: func(t *testing.T, result *Result, queueConfig *queue.QueueConfig, logger *logrus.Logger, output *bytes.Buffer) {
+				logger.Info("calling tavily with tavily-secret-key")
+				assert.NotContains(t, output.String(), "tavily-secret-key")
+				assert.Contains(t, output.String(), logging.RedactedValue)
+			},
+		},
+		{
+			name: "fallback key redaction",
+			modify: func(cfg *config.Config) {
+				cfg.AzureOpenAI.Fallbacks = []config.LLMFallbackConfig{{
+					Name:       "backup",
+					Provider:   config.LLMProviderOpenAI,
+					APIKey:     "fallback-secret-key",
+					Deployment: "gpt-4o-mini",
+				}}
+			},
+			check: func(t *testing.T, result *Result, queueConfig *queue.QueueConfig, logger *logrus.Logger, output *bytes.Buffer) {
+				assert.Equal(t, []string{"azure_openai.fallbacks"}, result.Applied)
+				logger.Info("calling fallback with fallback-secret-key")
+				assert.NotContains(t, output.String(), "fallback-secret-key")
+				assert.Contains(t, output.String(), logging.RedactedValue)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader, queueConfig, logger, output := newTestReloader(t, testConfig())
+			newConfig := testConfig()
+			tt.modify(newConfig)
+
+			result := reloader.Apply(context.Background(), newConfig)
+			require.Empty(t, result.Failed)
+			tt.check(t, result, queueConfig, logger, output)
+		})
+	}
+}
+
+func TestReloader_ApplyNoChanges(t *testing.T) {
+	reloader, _, _, _ := newTestReloader(t, testConfig())
+	current := reloader.Current()
+
+	result := reloader.Apply(context.Background(), testConfig())
+	assert.Empty(t, result.Applied)
+	assert.Same(t, current, reloader.Current())
+}
@@ -2,7 +2,9 @@ package workflow
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -10,20 +12,32 @@ import (
 	"deer-flow-go/pkg/config"
 	"deer-flow-go/pkg/llm"
 	"deer-flow-go/pkg/models"
+	"deer-flow-go/pkg/prompts"
+	"deer-flow-go/pkg/tools"
 	"deer-flow-go/pkg/weather"
 )
 
 // MCPClientInterface MCP客户端接口
 type MCPClientInterface interface {
 	ProcessRequest(ctx context.Context, req *models.MCPRequest) (*models.MCPResponse, error)
+	ListResources(ctx context.Context) ([]models.Resource, error)
+	ReadResource(ctx context.Context, uri string) ([]models.ResourceContent, error)
+	ListPrompts(ctx context.Context) ([]models.PromptTemplate, error)
+	GetPrompt(ctx context.Context, name string, args map[string]string) ([]models.ChatMessage, error)
 	HealthCheck(ctx context.Context) error
 	GetCapabilities() map[string]interface{}
 }
 
+// toolSchemaProvider 能提供工具参数 JSON Schema 的MCP客户端，用于校验查询路由结果
+type toolSchemaProvider interface {
+	ToolSchemas() map[string]map[string]interface{}
+}
+
 // AgentWorkflow 智能体工作流
 type AgentWorkflow struct {
 	llmClient *llm.AzureOpenAIClient
 	mcpClient MCPClientInterface
+	resources *resourceCache
 	logger    *logrus.Logger
 }
 
@@ -33,12 +47,24 @@ type AgentWorkflow struct {
 func NewAgentWorkflowWithMCP(cfg *config.Config, mcpClient MCPClientInterface, logger *logrus.Logger) *AgentWorkflow {
 	// 创建LLM客户端
 	llmClient := llm.NewAzureOpenAIClient(&cfg.AzureOpenAI, logger)
+	llmClient.UpdatePrompts(prompts.Builtin(), cfg.SystemPrompts.Locale)
 	
-	return &AgentWorkflow{
+	w := &AgentWorkflow{
 		llmClient: llmClient,
 		mcpClient: mcpClient,
+		resources: newResourceCache(),
 		logger:    logger,
 	}
+	llmClient.SetToolCatalog(w.toolCatalog)
+	if provider, ok := mcpClient.(toolSchemaProvider); ok {
+		llmClient.SetToolSchemas(func() map[string]map[string]interface{} {
+			return routableToolSchemas(provider.ToolSchemas())
+		})
+	}
+	if subscriber, ok := mcpClient.(resourceSubscriber); ok {
+		subscriber.OnResourceUpdated(w.resources.invalidate)
+	}
+	return w
 }
 
 // ProcessRequest 实现RequestProcessor接口
@@ -47,20 +73,51 @@ func (w *AgentWorkflow) ProcessRequest(ctx context.Context, query string) (*mode
 }
 
 // ProcessQuery 处理用户查询的完整工作流
+// 响应的 Usage 为本次所有LLM调用的token用量合计，Metadata 记录使用的系统提示词版本。
 func (w *AgentWorkflow) ProcessQuery(ctx context.Context, query string) (*models.ChatResponse, error) {
+	ctx, metadata := llm.WithMetadata(ctx)
+	response, err := w.processQuery(ctx, query)
+	if response != nil {
+		response.Usage = metadata.Usage()
+		response.Metadata = metadata.Response()
+	}
+	return response, err
+}
+
+// processQuery 依次完成资源读取、查询解析、工具调用和结果整理
+func (w *AgentWorkflow) processQuery(ctx context.Context, query string) (*models.ChatResponse, error) {
 	startTime := time.Now()
 	
 	w.logger.WithFields(logrus.Fields{
-		"query": query,
+		"query_length": len(query),
 	}).Info("Starting agent workflow")
 	
+	// 读取请求附加的MCP资源，作为参考资料交给LLM
+	resources, err := w.loadResources(ctx)
+	if err != nil {
+		w.logger.WithError(err).Error("Failed to read attached resources")
+		return &models.ChatResponse{
+			Response:  "抱歉，无法读取附加的参考资料。",
+			Timestamp: time.Now(),
+			Success:   false,
+			Error:     err.Error(),
+		}, nil
+	}
+
 	// 步骤1: 使用LLM将用户查询解析为MCP请求
 	w.logger.Debug("Step 1: Parsing query to MCP request")
-	mcpRequest, err := w.llmClient.ParseQueryToMCP(ctx, query)
+	mcpRequest, err := w.llmClient.ParseQueryToMCP(ctx, query, resources...)
 	if err != nil {
 		w.logger.WithError(err).Error("Failed to parse query to MCP")
+		message := "抱歉，处理您的查询时出现错误。"
+		switch {
+		case errors.Is(err, llm.ErrLLMUnavailable):
+			message = "抱歉，语言模型服务暂时不可用，请稍后重试。"
+		case errors.Is(err, llm.ErrInvalidStructuredOutput):
+			message = "抱歉，无法理解您的请求，请换一种方式描述。"
+		}
 		return &models.ChatResponse{
-			Response:  "抱歉，处理您的查询时出现错误。",
+			Response:  message,
 			Timestamp: time.Now(),
 			Success:   false,
 			Error:     err.Error(),
@@ -74,6 +131,7 @@ func (w *AgentWorkflow) ProcessQuery(ctx context.Context, query string) (*models
 	// 步骤2: 使用MCP客户端处理请求
 	w.logger.Debug("Step 2: Processing MCP request")
 	mcpResponse, err := w.mcpClient.ProcessRequest(ctx, mcpRequest)
+	llm.RecordToolCall(ctx, mcpRequest.Method)
 	if err != nil {
 		w.logger.WithError(err).Error("Failed to process MCP request")
 		return &models.ChatResponse{
@@ -119,7 +177,8 @@ func (w *AgentWorkflow) ProcessQuery(ctx context.Context, query string) (*models
 		} else {
 			finalResponse = "处理完成"
 		}
-	} else if mcpRequest.Method == "get_weather" || mcpRequest.Method == "get_weather_forecast" {
+	} else if mcpRequest.Method == "get_weather" || mcpRequest.Method == "get_weather_forecast" ||
+		mcpRequest.Method == "get_air_quality" || mcpRequest.Method == "get_weather_alerts" {
 		// 天气响应，处理真正的MCP协议返回的格式
 		if resultMap, ok := mcpResponse.Result.(map[string]interface{}); ok {
 			if content, exists := resultMap["content"]; exists {
@@ -155,11 +214,18 @@ func (w *AgentWorkflow) ProcessQuery(ctx context.Context, query string) (*models
 		// 搜索结果处理，支持真正的MCP协议格式
 		if resultMap, ok := mcpResponse.Result.(map[string]interface{}); ok {
 			if content, exists := resultMap["content"]; exists {
-				// 真正的MCP协议返回格式化的文本内容
-				if contentStr, ok := content.(string); ok {
+				// 真正的MCP协议返回格式化的文本内容，交给LLM整理为最终回复
+				contentStr, ok := content.(string)
+				if !ok {
+					contentStr = fmt.Sprintf("%v", content)
+				}
+				finalResponse, err = w.llmClient.FormatToolOutput(ctx, query, mcpRequest.Method, contentStr)
+				if err != nil {
+					w.logger.WithError(err).Error("Failed to format tool output")
+					// 如果格式化失败，使用工具返回的原始内容
 					finalResponse = contentStr
 				} else {
-					finalResponse = fmt.Sprintf("%v", content)
+					w.saveReport(ctx, query, finalResponse)
 				}
 			} else {
 				// 兼容其他格式
@@ -182,6 +248,8 @@ func (w *AgentWorkflow) ProcessQuery(ctx context.Context, query string) (*models
 				} else {
 					finalResponse = "抱歉，无法格式化搜索结果。"
 				}
+			} else {
+				w.saveReport(ctx, query, finalResponse)
 			}
 		} else {
 			w.logger.WithField("result_type", fmt.Sprintf("%T", mcpResponse.Result)).Error("Invalid MCP response format")
@@ -207,6 +275,94 @@ func (w *AgentWorkflow) ProcessQuery(ctx context.Context, query string) (*models
 	}, nil
 }
 
+// CallTool 直接调用MCP工具，不经过LLM解析
+func (w *AgentWorkflow) CallTool(ctx context.Context, name string, params map[string]interface{}) (*models.MCPResponse, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return w.mcpClient.ProcessRequest(ctx, &models.MCPRequest{Method: name, Params: params})
+}
+
+// ListResources 列出MCP服务器当前提供的资源
+func (w *AgentWorkflow) ListResources(ctx context.Context) ([]models.Resource, error) {
+	return w.mcpClient.ListResources(ctx)
+}
+
+// ListPrompts 列出MCP服务器提供的提示词
+func (w *AgentWorkflow) ListPrompts(ctx context.Context) ([]models.PromptTemplate, error) {
+	return w.mcpClient.ListPrompts(ctx)
+}
+
+// RenderPrompt 通过MCP服务器渲染提示词，多条消息按顺序拼接为一段文本
+func (w *AgentWorkflow) RenderPrompt(ctx context.Context, name string, args map[string]string) (string, error) {
+	messages, err := w.mcpClient.GetPrompt(ctx, name, args)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		parts = append(parts, msg.Content)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// LLMStats 返回LLM调用统计
+func (w *AgentWorkflow) LLMStats() llm.Stats {
+	return w.llmClient.Stats()
+}
+
+// UpdateLLMConfig 热更新LLM配置
+func (w *AgentWorkflow) UpdateLLMConfig(cfg config.AzureOpenAIConfig) {
+	w.llmClient.UpdateConfig(cfg)
+}
+
+// UpdateSystemPrompts 加载系统提示词模板，目录为空时使用内置模板；加载失败时保留当前模板
+func (w *AgentWorkflow) UpdateSystemPrompts(cfg config.SystemPromptsConfig) error {
+	registry := prompts.Builtin()
+	if cfg.Dir != "" {
+		loaded, err := prompts.LoadRegistry(cfg.Dir)
+		if err != nil {
+			return fmt.Errorf("failed to load system prompts: %w", err)
+		}
+		registry = loaded
+	}
+	w.llmClient.UpdatePrompts(registry, cfg.Locale)
+
+	versions := make([]string, 0)
+	for _, tmpl := range registry.Templates() {
+		versions = append(versions, fmt.Sprintf("%s@%s(%d)", tmpl.Name, tmpl.Version, tmpl.Weight))
+	}
+	w.logger.WithFields(logrus.Fields{
+		"dir":       cfg.Dir,
+		"locale":    cfg.Locale,
+		"templates": versions,
+	}).Info("System prompts loaded")
+	return nil
+}
+
+// toolCatalog 返回MCP服务器当前提供的工具名称，作为系统提示词的工具列表，内部工具不列出
+func (w *AgentWorkflow) toolCatalog() []string {
+	all, _ := w.mcpClient.GetCapabilities()["tools"].([]string)
+	catalog := make([]string, 0, len(all))
+	for _, name := range all {
+		if !tools.IsInternal(name) {
+			catalog = append(catalog, name)
+		}
+	}
+	return catalog
+}
+
+// routableToolSchemas 去掉内部工具，使查询路由的 method 只能是交给LLM选择的工具
+func routableToolSchemas(schemas map[string]map[string]interface{}) map[string]map[string]interface{} {
+	routable := make(map[string]map[string]interface{}, len(schemas))
+	for name, schema := range schemas {
+		if !tools.IsInternal(name) {
+			routable[name] = schema
+		}
+	}
+	return routable
+}
+
 // GetWorkflowStatus 获取工作流状态
 func (w *AgentWorkflow) GetWorkflowStatus(ctx context.Context) (*models.WorkflowState, error) {
 	// 检查MCP客户端健康状态
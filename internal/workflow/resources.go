@@ -0,0 +1,204 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/models"
+	"deer-flow-go/pkg/tools"
+)
+
+// resourcesKey 上下文中附加资源URI的键
+type resourcesKey struct{}
+
+// WithResources 返回附加了MCP资源URI的上下文，工作流处理查询时读取这些资源作为参考资料
+func WithResources(ctx context.Context, uris []string) context.Context {
+	if len(uris) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, resourcesKey{}, uris)
+}
+
+// resourcesFromContext 返回上下文中附加的资源URI
+func resourcesFromContext(ctx context.Context) []string {
+	uris, _ := ctx.Value(resourcesKey{}).([]string)
+	return uris
+}
+
+// resourceSubscriber 支持资源订阅的MCP客户端
+type resourceSubscriber interface {
+	Subscribe(ctx context.Context, uri string) error
+	Unsubscribe(ctx context.Context, uri string) error
+	OnResourceUpdated(fn func(uri string))
+}
+
+// 资源缓存限制
+const (
+	resourceCacheTTL        = 5 * time.Minute // 即使没有收到更新通知，缓存超过该时间后也重新读取
+	resourceCacheMaxEntries = 256             // 最多缓存的资源数，超出时淘汰最早读取的资源
+)
+
+// cachedResource 一条缓存的资源内容
+type cachedResource struct {
+	contents []models.ResourceContent
+	cachedAt time.Time
+}
+
+// resourceKey 资源缓存的键
+// 搜索结果、研究报告等资源按所有者隔离，同一URI按读取它的所有者分别缓存，避免其他所有者经缓存读到。
+type resourceKey struct {
+	owner string
+	uri   string
+}
+
+// resourceKeyFor 返回调用方读取该URI时使用的缓存键
+func resourceKeyFor(ctx context.Context, uri string) resourceKey {
+	owner, _ := tools.OwnerFromContext(ctx)
+	return resourceKey{owner: owner, uri: uri}
+}
+
+// resourceCache 已读取的资源内容
+// 首次读取时订阅资源，收到更新通知或超过 resourceCacheTTL 后丢弃缓存，下次使用时重新读取。
+// 并非所有资源变化都会触发通知（例如服务器只在读取时刷新的快照），TTL 保证缓存不会无限期过时。
+// MCP客户端不支持订阅时不缓存。
+type resourceCache struct {
+	mu       sync.Mutex
+	contents map[resourceKey]cachedResource
+	now      func() time.Time
+}
+
+// newResourceCache 创建资源缓存
+func newResourceCache() *resourceCache {
+	return &resourceCache{
+		contents: make(map[resourceKey]cachedResource),
+		now:      time.Now,
+	}
+}
+
+// invalidate 丢弃所有所有者缓存的该资源内容
+func (c *resourceCache) invalidate(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.contents {
+		if key.uri == uri {
+			delete(c.contents, key)
+		}
+	}
+}
+
+// get 返回未过期的缓存资源内容
+func (c *resourceCache) get(key resourceKey) ([]models.ResourceContent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.contents[key]
+	if !ok {
+		return nil, false
+	}
+	if c.now().Sub(entry.cachedAt) > resourceCacheTTL {
+		delete(c.contents, key)
+		return nil, false
+	}
+	return entry.contents, true
+}
+
+// put 缓存资源内容，缓存已满时淘汰最早读取的资源
+// 返回不再被任何所有者缓存的URI，以便取消订阅。
+func (c *resourceCache) put(key resourceKey, contents []models.ResourceContent) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var evicted []string
+	if _, exists := c.contents[key]; !exists {
+		for len(c.contents) >= resourceCacheMaxEntries {
+			var oldestKey resourceKey
+			oldest, found := time.Time{}, false
+			for cachedKey, entry := range c.contents {
+				if !found || entry.cachedAt.Before(oldest) {
+					oldestKey, oldest, found = cachedKey, entry.cachedAt, true
+				}
+			}
+			delete(c.contents, oldestKey)
+			if oldestKey.uri != key.uri && !c.cachedLocked(oldestKey.uri) {
+				evicted = append(evicted, oldestKey.uri)
+			}
+		}
+	}
+	c.contents[key] = cachedResource{contents: contents, cachedAt: c.now()}
+	return evicted
+}
+
+// cachedLocked 判断是否还有所有者缓存了该URI，调用方需持有锁
+func (c *resourceCache) cachedLocked(uri string) bool {
+	for key := range c.contents {
+		if key.uri == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// loadResources 读取上下文中附加的资源
+func (w *AgentWorkflow) loadResources(ctx context.Context) ([]models.ResourceContent, error) {
+	uris := resourcesFromContext(ctx)
+	if len(uris) == 0 {
+		return nil, nil
+	}
+
+	subscriber, canSubscribe := w.mcpClient.(resourceSubscriber)
+	resources := make([]models.ResourceContent, 0, len(uris))
+	for _, uri := range uris {
+		key := resourceKeyFor(ctx, uri)
+		if contents, ok := w.resources.get(key); ok {
+			resources = append(resources, contents...)
+			continue
+		}
+
+		// 先订阅再读取，避免错过读取期间的更新
+		subscribed := false
+		if canSubscribe {
+			if err := subscriber.Subscribe(ctx, uri); err != nil {
+				w.logger.WithError(err).WithField("uri", uri).Debug("Resource subscription not available, not caching")
+			} else {
+				subscribed = true
+			}
+		}
+
+		contents, err := w.mcpClient.ReadResource(ctx, uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read resource %s: %w", uri, err)
+		}
+		resources = append(resources, contents...)
+		if subscribed {
+			// 淘汰的资源不再需要更新通知，取消订阅以免服务器端的订阅无限增长
+			for _, evicted := range w.resources.put(key, contents) {
+				if err := subscriber.Unsubscribe(ctx, evicted); err != nil {
+					w.logger.WithError(err).WithField("uri", evicted).Debug("Failed to unsubscribe evicted resource")
+				}
+			}
+		}
+	}
+
+	w.logger.WithFields(logrus.Fields{
+		"resources": len(uris),
+		"contents":  len(resources),
+	}).Debug("Resources attached to query")
+	return resources, nil
+}
+
+// saveReport 将根据搜索结果整理出的回复保存为研究报告资源，MCP服务器不支持时忽略
+func (w *AgentWorkflow) saveReport(ctx context.Context, query, report string) {
+	response, err := w.mcpClient.ProcessRequest(ctx, &models.MCPRequest{
+		Method: tools.ReportToolName,
+		Params: map[string]interface{}{"query": query, "report": report},
+	})
+	if err == nil && response.Error != nil {
+		err = fmt.Errorf("%s", response.Error.Message)
+	}
+	if err != nil {
+		w.logger.WithError(err).Debug("Research report not saved")
+	}
+}
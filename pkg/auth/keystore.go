@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"sync"
+
+	"deer-flow-go/pkg/config"
+)
+
+// APIKey 已认证的API密钥及其限流状态
+type APIKey struct {
+	Name        string
+	Tenant      string // 用量记账和配额所属的租户
+	Scopes      []string
+	hash        string
+	bucket      *TokenBucket
+	concurrency *ConcurrencyLimiter
+}
+
+// HasScope 检查密钥是否拥有指定权限
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == config.ScopeAll {
+			return true
+		}
+	}
+	return false
+}
+
+// Bucket 返回密钥的令牌桶
+func (k *APIKey) Bucket() *TokenBucket {
+	return k.bucket
+}
+
+// Concurrency 返回密钥的并发限制器
+func (k *APIKey) Concurrency() *ConcurrencyLimiter {
+	return k.concurrency
+}
+
+// KeyStore API密钥存储，只保存密钥的SHA-256摘要
+type KeyStore struct {
+	mu      sync.RWMutex
+	enabled bool
+	keys    map[string]*APIKey // 按摘要索引
+}
+
+// NewKeyStore 根据鉴权配置创建密钥存储
+func NewKeyStore(cfg config.AuthConfig) *KeyStore {
+	s := &KeyStore{keys: make(map[string]*APIKey)}
+	s.Update(cfg)
+	return s
+}
+
+// Update 替换密钥配置（配置热加载时使用）
+// 摘要和限额都未变化的密钥会保留原有的限流状态和并发计数。
+func (s *KeyStore) Update(cfg config.AuthConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make(map[string]*APIKey, len(cfg.Keys))
+	for _, keyConfig := range cfg.Keys {
+		rpm := keyConfig.RequestsPerMinute
+		if rpm == 0 {
+			rpm = cfg.DefaultRequestsPerMinute
+		}
+		maxConcurrent := keyConfig.MaxConcurrent
+		if maxConcurrent == 0 {
+			maxConcurrent = cfg.DefaultMaxConcurrent
+		}
+
+		hash := keyConfig.Hash()
+		tenant := keyConfig.Tenant
+		if tenant == "" {
+			tenant = keyConfig.Name
+		}
+		key := &APIKey{
+			Name:   keyConfig.Name,
+			Tenant: tenant,
+			Scopes: append([]string(nil), keyConfig.Scopes...),
+			hash:   hash,
+		}
+		if existing, ok := s.keys[hash]; ok && existing.bucket.Limit() == rpm && existing.concurrency.max == maxConcurrent {
+			key.bucket = existing.bucket
+			key.concurrency = existing.concurrency
+		} else {
+			key.bucket = NewTokenBucket(rpm)
+			key.concurrency = NewConcurrencyLimiter(maxConcurrent)
+		}
+		keys[hash] = key
+	}
+
+	s.enabled = cfg.Enabled
+	s.keys = keys
+}
+
+// Enabled 是否启用鉴权
+func (s *KeyStore) Enabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabled
+}
+
+// Authenticate 校验明文密钥，返回对应的密钥信息
+func (s *KeyStore) Authenticate(rawKey string) (*APIKey, bool) {
+	if rawKey == "" {
+		return nil, false
+	}
+	hash := config.HashAPIKey(rawKey)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[hash]
+	if !ok || subtle.ConstantTimeCompare([]byte(key.hash), []byte(hash)) != 1 {
+		return nil, false
+	}
+	return key, true
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket 令牌桶限流器
+// 容量为每分钟请求数，令牌按 容量/60 每秒的速率补充，允许短时突发。
+type TokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64 // 每秒补充的令牌数
+	last     time.Time
+}
+
+// NewTokenBucket 创建令牌桶，requestsPerMinute<=0 表示不限流
+func NewTokenBucket(requestsPerMinute int) *TokenBucket {
+	return &TokenBucket{
+		capacity: float64(requestsPerMinute),
+		tokens:   float64(requestsPerMinute),
+		rate:     float64(requestsPerMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// Allow 尝试获取一个令牌，失败时返回需要等待的时间
+func (b *TokenBucket) Allow(now time.Time) (bool, time.Duration) {
+	if b.capacity <= 0 {
+		return true, 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+// Limit 返回每分钟请求数上限
+func (b *TokenBucket) Limit() int {
+	return int(b.capacity)
+}
+
+// Remaining 返回当前剩余令牌数
+func (b *TokenBucket) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.tokens)
+}
+
+// ConcurrencyLimiter 并发请求数限制
+type ConcurrencyLimiter struct {
+	mu      sync.Mutex
+	max     int
+	current int
+}
+
+// NewConcurrencyLimiter 创建并发限制器，max<=0 表示不限制
+func NewConcurrencyLimiter(max int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{max: max}
+}
+
+// Acquire 尝试占用一个并发名额
+func (l *ConcurrencyLimiter) Acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && l.current >= l.max {
+		return false
+	}
+	l.current++
+	return true
+}
+
+// Release 释放一个并发名额
+func (l *ConcurrencyLimiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.current > 0 {
+		l.current--
+	}
+}
+
+// InFlight 返回当前并发请求数
+func (l *ConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// API权限范围
+const (
+	ScopeChat   = "chat"   // 调用对话接口
+	ScopeStatus = "status" // 查询工作流和队列状态
+	ScopeUsage  = "usage"  // 查询所有租户的用量报表
+	ScopeAll    = "*"      // 全部权限
+)
+
+// KnownScopes 可分配给API密钥的权限范围
+var KnownScopes = []string{ScopeChat, ScopeStatus, ScopeUsage, ScopeAll}
+
+// AuthConfig API密钥鉴权配置
+type AuthConfig struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled"`
+	KeyFile string `yaml:"key_file" toml:"key_file"` // 本地密钥文件（YAML列表），可以包含明文密钥
+
+	// 主配置文件中只允许保存密钥的SHA-256摘要
+	Keys []APIKeyConfig `yaml:"keys" toml:"keys"`
+
+	// 未单独配置时每个密钥的默认限额
+	DefaultRequestsPerMinute int `yaml:"default_requests_per_minute" toml:"default_requests_per_minute"`
+	DefaultMaxConcurrent     int `yaml:"default_max_concurrent" toml:"default_max_concurrent"`
+}
+
+// APIKeyConfig 单个API密钥配置
+type APIKeyConfig struct {
+	Name              string   `yaml:"name" toml:"name"`
+	Tenant            string   `yaml:"tenant,omitempty" toml:"tenant,omitempty"` // 用量记账和配额所属的租户，为空时使用密钥名称
+	Key               string   `yaml:"key,omitempty" toml:"key,omitempty"`       // 明文密钥，仅允许出现在 key_file 中
+	KeySHA256         string   `yaml:"key_sha256,omitempty" toml:"key_sha256,omitempty"`
+	Scopes            []string `yaml:"scopes" toml:"scopes"`
+	RequestsPerMinute int      `yaml:"requests_per_minute,omitempty" toml:"requests_per_minute,omitempty"`
+	MaxConcurrent     int      `yaml:"max_concurrent,omitempty" toml:"max_concurrent,omitempty"`
+}
+
+// Hash 返回密钥的SHA-256摘要（十六进制）
+func (k APIKeyConfig) Hash() string {
+	if k.KeySHA256 != "" {
+		return strings.ToLower(k.KeySHA256)
+	}
+	return HashAPIKey(k.Key)
+}
+
+// HashAPIKey 计算API密钥的SHA-256摘要
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// rejectFileAPIKeys 拒绝写在主配置文件中的明文API密钥
+func (l *envLoader) rejectFileAPIKeys(config *Config) {
+	for i := range config.Auth.Keys {
+		if config.Auth.Keys[i].Key != "" {
+			l.errs = append(l.errs, FieldError{
+				Field:   fmt.Sprintf("auth.keys[%d].key", i),
+				Message: "plaintext API keys must not be set in the config file; use key_sha256 or auth.key_file",
+			})
+			config.Auth.Keys[i].Key = ""
+		}
+	}
+}
+
+// loadAPIKeyFile 从本地密钥文件加载API密钥并追加到配置中
+func (l *envLoader) loadAPIKeyFile(config *Config) {
+	if config.Auth.KeyFile == "" {
+		return
+	}
+
+	data, err := os.ReadFile(config.Auth.KeyFile)
+	if err != nil {
+		l.errs = append(l.errs, FieldError{Field: "auth.key_file", Message: fmt.Sprintf("failed to read key file: %v", err)})
+		return
+	}
+
+	var keys []APIKeyConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&keys); err != nil {
+		l.errs = append(l.errs, FieldError{Field: "auth.key_file", Message: fmt.Sprintf("failed to parse key file: %v", err)})
+		return
+	}
+
+	config.Auth.Keys = append(config.Auth.Keys, keys...)
+}
+
+// validateAuth 校验API密钥配置
+func (v *validator) validateAuth(auth AuthConfig) {
+	if auth.Enabled && len(auth.Keys) == 0 {
+		v.addf("auth.keys", "at least one API key is required when auth is enabled")
+	}
+	if auth.DefaultRequestsPerMinute < 0 {
+		v.addf("auth.default_requests_per_minute", "must not be negative, got %d", auth.DefaultRequestsPerMinute)
+	}
+	if auth.DefaultMaxConcurrent < 0 {
+		v.addf("auth.default_max_concurrent", "must not be negative, got %d", auth.DefaultMaxConcurrent)
+	}
+
+	names := make(map[string]bool, len(auth.Keys))
+	hashes := make(map[string]bool, len(auth.Keys))
+	for i, key := range auth.Keys {
+		field := fmt.Sprintf("auth.keys[%d]", i)
+		v.required(field+".name", key.Name)
+		if names[key.Name] {
+			v.addf(field+".name", "duplicate key name %q", key.Name)
+		}
+		names[key.Name] = true
+
+		switch {
+		case key.Key == "" && key.KeySHA256 == "":
+			v.addf(field, "either key (key file only) or key_sha256 is required")
+		case key.Key != "" && key.KeySHA256 != "":
+			v.addf(field, "key and key_sha256 are mutually exclusive")
+		case key.KeySHA256 != "":
+			if decoded, err := hex.DecodeString(key.KeySHA256); err != nil || len(decoded) != sha256.Size {
+				v.addf(field+".key_sha256", "must be a hex-encoded SHA-256 digest")
+			}
+		}
+		if hashes[key.Hash()] {
+			v.addf(field, "duplicate API key")
+		}
+		hashes[key.Hash()] = true
+
+		if len(key.Scopes) == 0 {
+			v.addf(field+".scopes", "at least one scope is required")
+		}
+		for _, scope := range key.Scopes {
+			v.oneOf(field+".scopes", scope, KnownScopes...)
+		}
+		if key.RequestsPerMinute < 0 {
+			v.addf(field+".requests_per_minute", "must not be negative, got %d", key.RequestsPerMinute)
+		}
+		if key.MaxConcurrent < 0 {
+			v.addf(field+".max_concurrent", "must not be negative, got %d", key.MaxConcurrent)
+		}
+	}
+}
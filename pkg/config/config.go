@@ -1,155 +1,468 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 // Config 应用配置结构
 type Config struct {
 	// 服务器配置
-	Port string `yaml:"port"`
+	Port string `yaml:"port" toml:"port"`
 
 	// Azure OpenAI 配置
-	AzureOpenAI AzureOpenAIConfig `yaml:"azure_openai"`
+	AzureOpenAI AzureOpenAIConfig `yaml:"azure_openai" toml:"azure_openai"`
 
 	// Tavily 搜索配置
-	Tavily TavilyConfig `yaml:"tavily"`
+	Tavily TavilyConfig `yaml:"tavily" toml:"tavily"`
+
+	// 搜索提供方配置
+	Search SearchConfig `yaml:"search" toml:"search"`
+
+	// 网页抓取配置
+	Fetch FetchConfig `yaml:"fetch" toml:"fetch"`
 
 	// MCP 配置
-	MCP MCPConfig `yaml:"mcp"`
+	MCP MCPConfig `yaml:"mcp" toml:"mcp"`
+
+	// LLM系统提示词模板配置
+	SystemPrompts SystemPromptsConfig `yaml:"system_prompts" toml:"system_prompts"`
+
+	// 用量记账与配额配置
+	Usage UsageConfig `yaml:"usage" toml:"usage"`
 
 	// 天气服务配置
-	Weather WeatherConfig `yaml:"weather"`
+	Weather WeatherConfig `yaml:"weather" toml:"weather"`
 
 	// 队列管理配置
-	Queue QueueConfig `yaml:"queue"`
+	Queue QueueConfig `yaml:"queue" toml:"queue"`
+
+	// API鉴权与限流配置
+	Auth AuthConfig `yaml:"auth" toml:"auth"`
 
 	// 日志配置
-	LogLevel string `yaml:"log_level"`
+	LogLevel     string `yaml:"log_level" toml:"log_level"`
+	LogRedactPII bool   `yaml:"log_redact_pii" toml:"log_redact_pii"` // 日志中隐藏查询里的手机号、邮箱、身份证号
 }
 
 // AzureOpenAIConfig Azure OpenAI 配置
 type AzureOpenAIConfig struct {
-	Endpoint    string  `yaml:"endpoint"`
-	APIKey      string  `yaml:"api_key"`
-	Deployment  string  `yaml:"deployment"`
-	APIVersion  string  `yaml:"api_version"`
-	Temperature float32 `yaml:"temperature"`
-}
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	APIKey      string  `yaml:"api_key" toml:"api_key"`
+	Deployment  string  `yaml:"deployment" toml:"deployment"`
+	APIVersion  string  `yaml:"api_version" toml:"api_version"`
+	Temperature float32 `yaml:"temperature" toml:"temperature"`
 
-// TavilyConfig Tavily 搜索配置
-type TavilyConfig struct {
-	APIKey      string `yaml:"api_key"`
-	MaxResults  int    `yaml:"max_results"`
-	SearchDepth string `yaml:"search_depth"`
-}
+	ContextWindow       int `yaml:"context_window" toml:"context_window"`               // 部署模型的上下文窗口（token），为0时按部署名称推断
+	MaxCompletionTokens int `yaml:"max_completion_tokens" toml:"max_completion_tokens"` // 回复的token上限，为0时不限制（预算仍为回复预留1024个token）
+
+	Timeout        int                     `yaml:"timeout" toml:"timeout"` // 单次调用超时(秒)，流式调用只限制建立连接和收到响应头
+	Retry          LLMRetryConfig          `yaml:"retry" toml:"retry"`
+	CircuitBreaker LLMCircuitBreakerConfig `yaml:"circuit_breaker" toml:"circuit_breaker"`
+	Fallbacks      []LLMFallbackConfig     `yaml:"fallbacks" toml:"fallbacks"` // 按顺序尝试的备用部署
 
-// MCPConfig MCP 配置
-type MCPConfig struct {
-	Enabled bool `yaml:"enabled"`
-	Timeout int  `yaml:"timeout"`
+	Structured LLMStructuredConfig `yaml:"structured" toml:"structured"` // 结构化输出（查询路由等需要JSON结果的调用）
+
+	Cache    LLMCacheConfig    `yaml:"cache" toml:"cache"`
+	Fixtures LLMFixturesConfig `yaml:"fixtures" toml:"fixtures"`
 }
 
-// WeatherConfig 天气服务配置
-type WeatherConfig struct {
-	APIKey  string `yaml:"api_key"`
-	BaseURL string `yaml:"base_url"`
-	Timeout int    `yaml:"timeout"`
+// TavilyConfig Tavily 搜索配置
+type TavilyConfig struct {
+	APIKey      string `yaml:"api_key" toml:"api_key"`
+	BaseURL     string `yaml:"base_url" toml:"base_url"`
+	MaxResults  int    `yaml:"max_results" toml:"max_results"`
+	SearchDepth string `yaml:"search_depth" toml:"search_depth"`
 }
 
 // QueueConfig 队列管理配置
 type QueueConfig struct {
-	MaxWorkers     int `yaml:"max_workers"`     // 最大工作协程数
-	QueueSize      int `yaml:"queue_size"`      // 队列大小
-	RequestTimeout int `yaml:"request_timeout"` // 请求超时时间(秒)
-	QueueTimeout   int `yaml:"queue_timeout"`   // 队列等待超时时间(秒)
+	MaxWorkers     int `yaml:"max_workers" toml:"max_workers"`         // 最大工作协程数
+	QueueSize      int `yaml:"queue_size" toml:"queue_size"`           // 队列大小
+	RequestTimeout int `yaml:"request_timeout" toml:"request_timeout"` // 请求超时时间(秒)
+	QueueTimeout   int `yaml:"queue_timeout" toml:"queue_timeout"`     // 队列等待超时时间(秒)
 }
 
-// LoadConfig 加载配置
+// LoadConfig 加载配置（默认值 + 环境变量）
 func LoadConfig() (*Config, error) {
+	return LoadConfigFromFile("")
+}
+
+// LoadConfigFromFile 加载配置
+// 加载顺序：默认值 -> 配置文件(YAML/TOML，path为空时跳过) -> 环境变量覆盖，最后统一校验。
+// 密钥只能来自环境变量、<KEY>_FILE 指向的文件或 SECRETS_DIR 目录，写在配置文件中会被视为错误。
+// 校验失败时返回合并后的配置和 ValidationErrors，便于调用方打印全部问题。
+func LoadConfigFromFile(path string) (*Config, error) {
 	// 加载 .env 文件
 	if err := godotenv.Load(); err != nil {
 		logrus.Warn("No .env file found")
 	}
 
-	config := &Config{
-		Port:     getEnv("PORT", "8080"),
-		LogLevel: getEnv("LOG_LEVEL", "info"),
+	config := defaultConfig()
+
+	if path != "" {
+		if err := loadFile(path, config); err != nil {
+			return nil, err
+		}
+	}
+
+	env := &envLoader{secretsDir: os.Getenv("SECRETS_DIR")}
+	env.rejectFileSecrets(config)
+	env.rejectFileAPIKeys(config)
+	env.apply(config)
+	env.loadAPIKeyFile(config)
+
+	errs := env.errs
+	if err := config.Validate(); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+	if len(errs) > 0 {
+		return config, errs
+	}
+
+	return config, nil
+}
+
+// defaultConfig 返回内置默认配置
+// 密钥、Azure 端点和部署名没有默认值，必须由部署环境显式提供。
+func defaultConfig() *Config {
+	return &Config{
+		Port:     "8080",
+		LogLevel: "info",
 
 		AzureOpenAI: AzureOpenAIConfig{
-			Endpoint:    getEnv("AZURE_OPENAI_ENDPOINT", "https://dajia-it-openai-japaneast.openai.azure.com"),
-			APIKey:      getEnv("AZURE_OPENAI_API_KEY", "**********************"),
-			Deployment:  getEnv("AZURE_OPENAI_DEPLOYMENT", "dajia-it-openai-JapanEast-gpt-4"),
-			APIVersion:  getEnv("AZURE_OPENAI_API_VERSION", "2023-08-01-preview"),
-			Temperature: getEnvFloat32("AZURE_OPENAI_TEMPERATURE", 0.0),
+			APIVersion:  "2023-08-01-preview",
+			Temperature: 0.0,
+			Timeout:     30,
+			Retry: LLMRetryConfig{
+				MaxAttempts:      3,
+				InitialBackoffMs: 500,
+				MaxBackoffMs:     10000,
+			},
+			CircuitBreaker: LLMCircuitBreakerConfig{
+				Enabled:          true,
+				FailureThreshold: 5,
+				Cooldown:         30,
+			},
+			Structured: LLMStructuredConfig{
+				ResponseFormat: LLMResponseFormatAuto,
+				MaxRepairs:     1,
+			},
+			Cache: LLMCacheConfig{
+				TTL:        3600,
+				MaxEntries: 1000,
+			},
+			Fixtures: LLMFixturesConfig{
+				Mode: LLMFixturesOff,
+				Dir:  "testdata/llm_fixtures",
+			},
 		},
 
 		Tavily: TavilyConfig{
-			APIKey:      getEnv("TAVILY_API_KEY", "***************"),
-			MaxResults:  getEnvInt("TAVILY_MAX_RESULTS", 5),
-			SearchDepth: getEnv("TAVILY_SEARCH_DEPTH", "advanced"),
+			BaseURL:     "https://api.tavily.com",
+			MaxResults:  5,
+			SearchDepth: "advanced",
+		},
+
+		Search: SearchConfig{
+			Provider:   SearchProviderTavily,
+			MaxResults: 5,
+			Timeout:    30,
+			Brave: BraveConfig{
+				BaseURL: "https://api.search.brave.com/res/v1",
+			},
+			Cache: SearchCacheConfig{
+				Enabled:    true,
+				TTL:        600,
+				MaxEntries: 1000,
+			},
+			Clean: SearchCleanConfig{
+				Rerank:             SearchRerankBM25,
+				MaxPerDomain:       2,
+				MaxContentLength:   1000,
+				DuplicateThreshold: 0.85,
+			},
+		},
+
+		Fetch: FetchConfig{
+			UserAgent:     "deer-flow-go/1.0",
+			Timeout:       20,
+			MaxBytes:      2 << 20,
+			MaxTokens:     4000,
+			RespectRobots: true,
 		},
 
 		MCP: MCPConfig{
-			Enabled: getEnvBool("MCP_ENABLED", true),
-			Timeout: getEnvInt("MCP_TIMEOUT", 60),
+			Enabled: true,
+			Timeout: 60,
+			Servers: []MCPServerConfig{
+				{Name: "unified", Transport: MCPTransportInProcess},
+			},
+		},
+
+		SystemPrompts: SystemPromptsConfig{
+			Locale: "zh-CN",
+		},
+
+		Usage: UsageConfig{
+			Enabled:       true,
+			RetentionDays: 90,
+			Currency:      "USD",
 		},
 
 		Weather: WeatherConfig{
-			APIKey:  getEnv("WEATHER_API_KEY", "***********"),
-			BaseURL: getEnv("WEATHER_BASE_URL", "https://api.openweathermap.org/data/2.5"),
-			Timeout: getEnvInt("WEATHER_TIMEOUT", 10),
+			Provider:   WeatherProviderOpenWeatherMap,
+			BaseURL:    "https://api.openweathermap.org/data/2.5",
+			Timeout:    10,
+			OneCallURL: "https://api.openweathermap.org/data/3.0",
+			OpenMeteo: OpenMeteoConfig{
+				BaseURL:       "https://api.open-meteo.com/v1",
+				AirQualityURL: "https://air-quality-api.open-meteo.com/v1",
+			},
+			Geocoding: GeocodingConfig{
+				BaseURL:  "https://geocoding-api.open-meteo.com/v1",
+				Language: "zh",
+			},
 		},
 
 		Queue: QueueConfig{
-			MaxWorkers:     getEnvInt("QUEUE_MAX_WORKERS", 3),
-			QueueSize:      getEnvInt("QUEUE_SIZE", 100),
-			RequestTimeout: getEnvInt("QUEUE_REQUEST_TIMEOUT", 30),
-			QueueTimeout:   getEnvInt("QUEUE_TIMEOUT", 10),
+			MaxWorkers:     3,
+			QueueSize:      100,
+			RequestTimeout: 30,
+			QueueTimeout:   10,
+		},
+
+		Auth: AuthConfig{
+			Enabled:                  false,
+			DefaultRequestsPerMinute: 60,
+			DefaultMaxConcurrent:     5,
 		},
 	}
+}
 
-	return config, nil
+// loadFile 根据扩展名解析 YAML 或 TOML 配置文件，未知字段视为错误
+func loadFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse YAML config %s: %w", path, err)
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(config); err != nil {
+			return fmt.Errorf("failed to parse TOML config %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file format: %s (expected .yaml, .yml or .toml)", path)
+	}
+
+	return nil
+}
+
+// envLoader 环境变量覆盖层，无法解析的值会被记录为校验错误而不是被静默忽略
+type envLoader struct {
+	secretsDir string
+	errs       ValidationErrors
+}
+
+// secretField 密钥字段及其对应的环境变量名
+type secretField struct {
+	field  string
+	envKey string
+	target *string
+}
+
+// secretFields 返回配置中的所有密钥字段
+func secretFields(config *Config) []secretField {
+	fields := []secretField{
+		{"azure_openai.api_key", "AZURE_OPENAI_API_KEY", &config.AzureOpenAI.APIKey},
+		{"tavily.api_key", "TAVILY_API_KEY", &config.Tavily.APIKey},
+		{"weather.api_key", "WEATHER_API_KEY", &config.Weather.APIKey},
+		{"search.brave.api_key", "BRAVE_API_KEY", &config.Search.Brave.APIKey},
+	}
+	for i := range config.AzureOpenAI.Fallbacks {
+		fallback := &config.AzureOpenAI.Fallbacks[i]
+		fields = append(fields, secretField{fmt.Sprintf("azure_openai.fallbacks[%d].api_key", i), fallback.APIKeyEnv(), &fallback.APIKey})
+	}
+	return fields
+}
+
+// rejectFileSecrets 拒绝写在配置文件中的密钥，避免密钥随配置文件提交或分发
+func (l *envLoader) rejectFileSecrets(config *Config) {
+	for _, secret := range secretFields(config) {
+		if *secret.target != "" {
+			l.errs = append(l.errs, FieldError{
+				Field:   secret.field,
+				Message: fmt.Sprintf("secrets must not be set in the config file; use %s, %s_FILE or SECRETS_DIR", secret.envKey, secret.envKey),
+			})
+			*secret.target = ""
+		}
+	}
 }
 
-// getEnv 获取环境变量，如果不存在则返回默认值
-func getEnv(key, defaultValue string) string {
+// apply 将环境变量覆盖到配置上
+func (l *envLoader) apply(config *Config) {
+	l.setString("PORT", &config.Port)
+	l.setString("LOG_LEVEL", &config.LogLevel)
+	l.setBool("LOG_REDACT_PII", &config.LogRedactPII)
+
+	for _, secret := range secretFields(config) {
+		l.setSecret(secret.envKey, secret.target)
+	}
+
+	l.setString("AZURE_OPENAI_ENDPOINT", &config.AzureOpenAI.Endpoint)
+	l.setString("AZURE_OPENAI_DEPLOYMENT", &config.AzureOpenAI.Deployment)
+	l.setString("AZURE_OPENAI_API_VERSION", &config.AzureOpenAI.APIVersion)
+	l.setFloat32("AZURE_OPENAI_TEMPERATURE", &config.AzureOpenAI.Temperature)
+	l.setInt("AZURE_OPENAI_CONTEXT_WINDOW", &config.AzureOpenAI.ContextWindow)
+	l.setInt("AZURE_OPENAI_MAX_COMPLETION_TOKENS", &config.AzureOpenAI.MaxCompletionTokens)
+	l.setInt("AZURE_OPENAI_TIMEOUT", &config.AzureOpenAI.Timeout)
+	l.setInt("LLM_RETRY_MAX_ATTEMPTS", &config.AzureOpenAI.Retry.MaxAttempts)
+	l.setBool("LLM_CIRCUIT_BREAKER_ENABLED", &config.AzureOpenAI.CircuitBreaker.Enabled)
+	l.setString("LLM_RESPONSE_FORMAT", &config.AzureOpenAI.Structured.ResponseFormat)
+	l.setInt("LLM_STRUCTURED_MAX_REPAIRS", &config.AzureOpenAI.Structured.MaxRepairs)
+	l.setBool("LLM_CACHE_ENABLED", &config.AzureOpenAI.Cache.Enabled)
+	l.setString("LLM_CACHE_DIR", &config.AzureOpenAI.Cache.Dir)
+	l.setString("LLM_FIXTURES_MODE", &config.AzureOpenAI.Fixtures.Mode)
+	l.setString("LLM_FIXTURES_DIR", &config.AzureOpenAI.Fixtures.Dir)
+
+	l.setInt("TAVILY_MAX_RESULTS", &config.Tavily.MaxResults)
+	l.setString("TAVILY_SEARCH_DEPTH", &config.Tavily.SearchDepth)
+	l.setString("TAVILY_BASE_URL", &config.Tavily.BaseURL)
+
+	l.setString("SEARCH_PROVIDER", &config.Search.Provider)
+	l.setInt("SEARCH_MAX_RESULTS", &config.Search.MaxResults)
+	l.setInt("SEARCH_TIMEOUT", &config.Search.Timeout)
+	l.setString("SEARXNG_BASE_URL", &config.Search.SearXNG.BaseURL)
+	l.setString("BRAVE_BASE_URL", &config.Search.Brave.BaseURL)
+	l.setString("LOCAL_INDEX_PATH", &config.Search.Local.Path)
+	l.setBool("SEARCH_CACHE_ENABLED", &config.Search.Cache.Enabled)
+	l.setInt("SEARCH_CACHE_TTL", &config.Search.Cache.TTL)
+	l.setInt("SEARCH_CACHE_MAX_ENTRIES", &config.Search.Cache.MaxEntries)
+	l.setString("SEARCH_CACHE_DIR", &config.Search.Cache.Dir)
+	l.setString("SEARCH_RERANK", &config.Search.Clean.Rerank)
+	l.setInt("SEARCH_MAX_PER_DOMAIN", &config.Search.Clean.MaxPerDomain)
+	l.setInt("SEARCH_MAX_CONTENT_LENGTH", &config.Search.Clean.MaxContentLength)
+
+	l.setString("FETCH_USER_AGENT", &config.Fetch.UserAgent)
+	l.setInt("FETCH_TIMEOUT", &config.Fetch.Timeout)
+	l.setInt("FETCH_MAX_BYTES", &config.Fetch.MaxBytes)
+	l.setInt("FETCH_MAX_TOKENS", &config.Fetch.MaxTokens)
+	l.setBool("FETCH_RESPECT_ROBOTS", &config.Fetch.RespectRobots)
+	l.setBool("FETCH_ALLOW_PRIVATE_NETWORKS", &config.Fetch.AllowPrivateNetworks)
+
+	l.setBool("MCP_ENABLED", &config.MCP.Enabled)
+	l.setInt("MCP_TIMEOUT", &config.MCP.Timeout)
+	l.setString("MCP_SERVER_BINARY", &config.MCP.ServerBinary)
+	l.setString("MCP_PROMPTS_DIR", &config.MCP.PromptsDir)
+	l.setString("SYSTEM_PROMPTS_DIR", &config.SystemPrompts.Dir)
+	l.setString("SYSTEM_PROMPTS_LOCALE", &config.SystemPrompts.Locale)
+	l.setBool("USAGE_ENABLED", &config.Usage.Enabled)
+	l.setString("USAGE_LEDGER_FILE", &config.Usage.LedgerFile)
+
+	l.setString("WEATHER_PROVIDER", &config.Weather.Provider)
+	l.setString("WEATHER_BASE_URL", &config.Weather.BaseURL)
+	l.setInt("WEATHER_TIMEOUT", &config.Weather.Timeout)
+	l.setString("WEATHER_ONE_CALL_URL", &config.Weather.OneCallURL)
+	l.setString("OPEN_METEO_BASE_URL", &config.Weather.OpenMeteo.BaseURL)
+	l.setString("OPEN_METEO_AIR_QUALITY_URL", &config.Weather.OpenMeteo.AirQualityURL)
+	l.setString("GEOCODING_BASE_URL", &config.Weather.Geocoding.BaseURL)
+	l.setString("GEOCODING_LANGUAGE", &config.Weather.Geocoding.Language)
+
+	l.setInt("QUEUE_MAX_WORKERS", &config.Queue.MaxWorkers)
+	l.setInt("QUEUE_SIZE", &config.Queue.QueueSize)
+	l.setInt("QUEUE_REQUEST_TIMEOUT", &config.Queue.RequestTimeout)
+	l.setInt("QUEUE_TIMEOUT", &config.Queue.QueueTimeout)
+
+	l.setBool("AUTH_ENABLED", &config.Auth.Enabled)
+	l.setString("API_KEYS_FILE", &config.Auth.KeyFile)
+}
+
+// setString 读取字符串类型环境变量
+func (l *envLoader) setString(key string, target *string) {
 	if value := os.Getenv(key); value != "" {
-		return value
+		*target = value
+	}
+}
+
+// setSecret 读取密钥，优先级：环境变量 > <KEY>_FILE 指向的文件 > SECRETS_DIR/<key小写>
+func (l *envLoader) setSecret(key string, target *string) {
+	if value := os.Getenv(key); value != "" {
+		*target = value
+		return
+	}
+
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			l.errs = append(l.errs, FieldError{Field: key + "_FILE", Message: fmt.Sprintf("failed to read secret file: %v", err)})
+			return
+		}
+		*target = strings.TrimSpace(string(data))
+		return
+	}
+
+	if l.secretsDir != "" {
+		data, err := os.ReadFile(filepath.Join(l.secretsDir, strings.ToLower(key)))
+		if err == nil {
+			*target = strings.TrimSpace(string(data))
+		} else if !errors.Is(err, os.ErrNotExist) {
+			l.errs = append(l.errs, FieldError{Field: "SECRETS_DIR", Message: fmt.Sprintf("failed to read secret %s: %v", strings.ToLower(key), err)})
+		}
 	}
-	return defaultValue
 }
 
-// getEnvInt 获取整数类型环境变量
-func getEnvInt(key string, defaultValue int) int {
+// setInt 读取整数类型环境变量
+func (l *envLoader) setInt(key string, target *int) {
 	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
+			l.errs = append(l.errs, FieldError{Field: key, Message: fmt.Sprintf("invalid integer %q", value)})
+			return
 		}
+		*target = intValue
 	}
-	return defaultValue
 }
 
-// getEnvFloat32 获取浮点数类型环境变量
-func getEnvFloat32(key string, defaultValue float32) float32 {
+// setFloat32 读取浮点数类型环境变量
+func (l *envLoader) setFloat32(key string, target *float32) {
 	if value := os.Getenv(key); value != "" {
-		if floatValue, err := strconv.ParseFloat(value, 32); err == nil {
-			return float32(floatValue)
+		floatValue, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			l.errs = append(l.errs, FieldError{Field: key, Message: fmt.Sprintf("invalid number %q", value)})
+			return
 		}
+		*target = float32(floatValue)
 	}
-	return defaultValue
 }
 
-// getEnvBool 获取布尔类型环境变量
-func getEnvBool(key string, defaultValue bool) bool {
+// setBool 读取布尔类型环境变量
+func (l *envLoader) setBool(key string, target *bool) {
 	if value := os.Getenv(key); value != "" {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			l.errs = append(l.errs, FieldError{Field: key, Message: fmt.Sprintf("invalid boolean %q", value)})
+			return
 		}
+		*target = boolValue
 	}
-	return defaultValue
 }
@@ -0,0 +1,471 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigFile 在临时目录写入配置文件
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// setRequiredEnv 设置通过校验所需的最小环境变量
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("SECRETS_DIR", "")
+	t.Setenv("AUTH_ENABLED", "")
+	t.Setenv("API_KEYS_FILE", "")
+	t.Setenv("SEARCH_PROVIDER", "")
+	t.Setenv("BRAVE_API_KEY", "")
+	t.Setenv("WEATHER_PROVIDER", "")
+	t.Setenv("MCP_SERVER_BINARY", "")
+	t.Setenv("MCP_PROMPTS_DIR", "")
+	t.Setenv("SYSTEM_PROMPTS_DIR", "")
+	t.Setenv("SYSTEM_PROMPTS_LOCALE", "")
+	t.Setenv("USAGE_ENABLED", "")
+	t.Setenv("USAGE_LEDGER_FILE", "")
+	t.Setenv("LLM_CACHE_ENABLED", "")
+	t.Setenv("LLM_FIXTURES_MODE", "")
+	t.Setenv("AZURE_OPENAI_TIMEOUT", "")
+	t.Setenv("LLM_RETRY_MAX_ATTEMPTS", "")
+	t.Setenv("LLM_CIRCUIT_BREAKER_ENABLED", "")
+	t.Setenv("LLM_RESPONSE_FORMAT", "")
+	t.Setenv("LLM_STRUCTURED_MAX_REPAIRS", "")
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "https://example.openai.azure.com")
+	t.Setenv("AZURE_OPENAI_DEPLOYMENT", "gpt-4")
+	t.Setenv("AZURE_OPENAI_API_KEY", "azure-key")
+	t.Setenv("TAVILY_API_KEY", "tavily-key")
+	t.Setenv("WEATHER_API_KEY", "weather-key")
+}
+
+func TestLoadConfigFromFile_YAMLWithEnvOverride(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeConfigFile(t, "config.yaml", `
+port: "9090"
+tavily:
+  max_results: 8
+  search_depth: basic
+queue:
+  max_workers: 6
+`)
+	t.Setenv("QUEUE_MAX_WORKERS", "12")
+
+	cfg, err := LoadConfigFromFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "9090", cfg.Port)
+	assert.Equal(t, "tavily-key", cfg.Tavily.APIKey)
+	assert.Equal(t, 8, cfg.Tavily.MaxResults)
+	assert.Equal(t, "basic", cfg.Tavily.SearchDepth)
+	// 环境变量覆盖文件配置
+	assert.Equal(t, 12, cfg.Queue.MaxWorkers)
+	// 未设置的字段保留默认值
+	assert.Equal(t, 100, cfg.Queue.QueueSize)
+}
+
+func TestLoadConfigFromFile_TOML(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeConfigFile(t, "config.toml", `
+log_level = "debug"
+
+[weather]
+base_url = "http://localhost:8081/data/2.5"
+timeout = 3
+`)
+
+	cfg, err := LoadConfigFromFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, "http://localhost:8081/data/2.5", cfg.Weather.BaseURL)
+	assert.Equal(t, 3, cfg.Weather.Timeout)
+}
+
+func TestLoadConfigFromFile_UnknownField(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "queue:\n  max_wokers: 3\n")
+
+	_, err := LoadConfigFromFile(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "max_wokers")
+}
+
+func TestLoadConfigFromFile_UnsupportedFormat(t *testing.T) {
+	path := writeConfigFile(t, "config.json", "{}")
+
+	_, err := LoadConfigFromFile(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported config file format")
+}
+
+func TestLoadConfigFromFile_ReportsAllErrors(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeConfigFile(t, "config.yaml", `
+azure_openai:
+  endpoint: "not a url"
+queue:
+  max_workers: -1
+`)
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("TAVILY_API_KEY", " ")
+	t.Setenv("QUEUE_SIZE", "abc")
+
+	cfg, err := LoadConfigFromFile(path)
+	require.Error(t, err)
+	require.NotNil(t, cfg, "merged config should be returned alongside validation errors")
+
+	var validationErrs ValidationErrors
+	require.True(t, errors.As(err, &validationErrs))
+
+	fields := make([]string, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		fields = append(fields, fieldErr.Field)
+	}
+	assert.ElementsMatch(t, []string{
+		"QUEUE_SIZE",
+		"azure_openai.endpoint",
+		"tavily.api_key",
+		"queue.max_workers",
+	}, fields)
+}
+
+func TestLoadConfigFromFile_MissingSecrets(t *testing.T) {
+	for _, key := range []string{"AZURE_OPENAI_ENDPOINT", "AZURE_OPENAI_DEPLOYMENT", "AZURE_OPENAI_API_KEY", "TAVILY_API_KEY", "WEATHER_API_KEY", "SECRETS_DIR", "LLM_FIXTURES_MODE"} {
+		t.Setenv(key, "")
+	}
+
+	_, err := LoadConfig()
+	require.Error(t, err)
+	for _, field := range []string{"azure_openai.endpoint", "azure_openai.deployment", "azure_openai.api_key", "tavily.api_key", "weather.api_key"} {
+		assert.Contains(t, err.Error(), field)
+	}
+}
+
+func TestLoadConfigFromFile_RejectsSecretsInFile(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeConfigFile(t, "config.yaml", "tavily:\n  api_key: leaked-key\n")
+
+	_, err := LoadConfigFromFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tavily.api_key: secrets must not be set in the config file")
+	assert.NotContains(t, err.Error(), "leaked-key")
+}
+
+func TestLoadConfigFromFile_SecretSources(t *testing.T) {
+	setRequiredEnv(t)
+	dir := t.TempDir()
+
+	// <KEY>_FILE 指向的文件
+	keyFile := filepath.Join(dir, "tavily.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("tavily-from-file\n"), 0o600))
+	t.Setenv("TAVILY_API_KEY", "")
+	t.Setenv("TAVILY_API_KEY_FILE", keyFile)
+
+	// SECRETS_DIR 目录
+	secretsDir := filepath.Join(dir, "secrets")
+	require.NoError(t, os.Mkdir(secretsDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(secretsDir, "weather_api_key"), []byte("weather-from-dir"), 0o600))
+	t.Setenv("WEATHER_API_KEY", "")
+	t.Setenv("SECRETS_DIR", secretsDir)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "tavily-from-file", cfg.Tavily.APIKey)
+	assert.Equal(t, "weather-from-dir", cfg.Weather.APIKey)
+	// 环境变量优先
+	assert.Equal(t, "azure-key", cfg.AzureOpenAI.APIKey)
+}
+
+func TestLoadConfigFromFile_APIKeys(t *testing.T) {
+	setRequiredEnv(t)
+	keyFile := writeConfigFile(t, "keys.yaml", `
+- name: app
+  key: app-secret
+  scopes: [chat]
+  requests_per_minute: 10
+`)
+	path := writeConfigFile(t, "config.yaml", `
+auth:
+  enabled: true
+  key_file: `+keyFile+`
+  keys:
+    - name: monitor
+      key_sha256: `+HashAPIKey("monitor-secret")+`
+      scopes: [status]
+`)
+
+	cfg, err := LoadConfigFromFile(path)
+	require.NoError(t, err)
+	require.Len(t, cfg.Auth.Keys, 2)
+	assert.Equal(t, "monitor", cfg.Auth.Keys[0].Name)
+	assert.Equal(t, "app", cfg.Auth.Keys[1].Name)
+	assert.Equal(t, HashAPIKey("app-secret"), cfg.Auth.Keys[1].Hash())
+	assert.Contains(t, cfg.Secrets(), "app-secret")
+	assert.NotContains(t, cfg.Redacted().Auth.Keys[1].Key, "app-secret")
+}
+
+func TestLoadConfigFromFile_RejectsInvalidAPIKeys(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeConfigFile(t, "config.yaml", `
+auth:
+  enabled: true
+  keys:
+    - name: app
+      key: plaintext
+      scopes: [chat]
+    - name: other
+      key_sha256: not-a-digest
+      scopes: [admin]
+`)
+
+	_, err := LoadConfigFromFile(path)
+	var verrs ValidationErrors
+	require.True(t, errors.As(err, &verrs))
+
+	fields := make([]string, 0, len(verrs))
+	for _, e := range verrs {
+		fields = append(fields, e.Field)
+	}
+	assert.Contains(t, fields, "auth.keys[0].key")
+	assert.Contains(t, fields, "auth.keys[1].key_sha256")
+	assert.Contains(t, fields, "auth.keys[1].scopes")
+}
+
+func TestLoadConfigFromFile_SearchProvider(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("TAVILY_API_KEY", "")
+	path := writeConfigFile(t, "config.yaml", `
+search:
+  provider: searxng
+  searxng:
+    base_url: http://localhost:8888
+`)
+
+	// 默认提供方不是Tavily时不再要求Tavily密钥
+	cfg, err := LoadConfigFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, SearchProviderSearXNG, cfg.Search.Provider)
+
+	t.Setenv("SEARCH_PROVIDER", "brave")
+	_, err = LoadConfigFromFile(path)
+	var verrs ValidationErrors
+	require.True(t, errors.As(err, &verrs))
+	assert.Equal(t, "search.brave.api_key", verrs[0].Field)
+}
+
+func TestLoadConfigFromFile_WeatherProvider(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("WEATHER_API_KEY", "")
+	path := writeConfigFile(t, "config.yaml", `
+weather:
+  provider: openmeteo
+  geocoding:
+    language: en
+`)
+
+	// Open-Meteo 无需密钥
+	cfg, err := LoadConfigFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, WeatherProviderOpenMeteo, cfg.Weather.Provider)
+	assert.Equal(t, "en", cfg.Weather.Geocoding.Language)
+	assert.Equal(t, "https://api.open-meteo.com/v1", cfg.Weather.OpenMeteo.BaseURL)
+
+	t.Setenv("WEATHER_PROVIDER", "openweathermap")
+	_, err = LoadConfigFromFile(path)
+	var verrs ValidationErrors
+	require.True(t, errors.As(err, &verrs))
+	assert.Equal(t, "weather.api_key", verrs[0].Field)
+
+	t.Setenv("WEATHER_PROVIDER", "accuweather")
+	_, err = LoadConfigFromFile(path)
+	require.True(t, errors.As(err, &verrs))
+	assert.Equal(t, "weather.provider", verrs[0].Field)
+}
+
+func TestLoadConfigFromFile_Fetch(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeConfigFile(t, "config.yaml", `
+fetch:
+  max_tokens: 2000
+  respect_robots: false
+`)
+	t.Setenv("FETCH_MAX_BYTES", "1048576")
+
+	cfg, err := LoadConfigFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2000, cfg.Fetch.MaxTokens)
+	assert.Equal(t, 1048576, cfg.Fetch.MaxBytes)
+	assert.False(t, cfg.Fetch.RespectRobots)
+	// 默认不允许访问内网地址
+	assert.False(t, cfg.Fetch.AllowPrivateNetworks)
+	assert.Equal(t, "deer-flow-go/1.0", cfg.Fetch.UserAgent)
+}
+
+func TestLoadConfigFromFile_LLMFixtures(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+	path := writeConfigFile(t, "config.yaml", `
+azure_openai:
+  cache:
+    enabled: true
+    ttl: 60
+`)
+
+	// 回放模式不需要端点和密钥
+	t.Setenv("LLM_FIXTURES_MODE", "replay")
+	cfg, err := LoadConfigFromFile(path)
+	require.NoError(t, err)
+	assert.True(t, cfg.AzureOpenAI.Replay())
+	assert.Equal(t, "testdata/llm_fixtures", cfg.AzureOpenAI.Fixtures.Dir)
+	assert.Equal(t, 60, cfg.AzureOpenAI.Cache.TTL)
+	assert.Equal(t, 1000, cfg.AzureOpenAI.Cache.MaxEntries)
+
+	t.Setenv("LLM_FIXTURES_MODE", "record")
+	_, err = LoadConfigFromFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "azure_openai.api_key")
+
+	t.Setenv("LLM_FIXTURES_MODE", "playback")
+	_, err = LoadConfigFromFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "azure_openai.fixtures.mode")
+}
+
+func TestLoadConfigFromFile_LLMFallbacks(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeConfigFile(t, "config.yaml", `
+azure_openai:
+  retry:
+    max_attempts: 2
+  fallbacks:
+    - name: eastus
+      deployment: gpt-4o-eastus
+      endpoint: https://eastus.openai.azure.com
+    - name: openai
+      provider: openai
+      deployment: gpt-4o
+`)
+
+	// openai 备用部署需要自己的密钥
+	_, err := LoadConfigFromFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "azure_openai.fallbacks[1].api_key")
+
+	t.Setenv("LLM_FALLBACK_OPENAI_API_KEY", "sk-fallback-key")
+	cfg, err := LoadConfigFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.AzureOpenAI.Retry.MaxAttempts)
+	assert.Equal(t, 500, cfg.AzureOpenAI.Retry.InitialBackoffMs)
+	assert.True(t, cfg.AzureOpenAI.CircuitBreaker.Enabled)
+	require.Len(t, cfg.AzureOpenAI.Fallbacks, 2)
+	assert.Empty(t, cfg.AzureOpenAI.Fallbacks[0].APIKey, "azure fallbacks inherit the primary key")
+	assert.Equal(t, "sk-fallback-key", cfg.AzureOpenAI.Fallbacks[1].APIKey)
+	assert.Contains(t, cfg.Secrets(), "sk-fallback-key")
+	assert.Equal(t, "********-key", cfg.Redacted().AzureOpenAI.Fallbacks[1].APIKey)
+
+	dup := writeConfigFile(t, "dup.yaml", `
+azure_openai:
+  fallbacks:
+    - name: gpt-4
+      deployment: gpt-4
+      provider: anthropic
+`)
+	_, err = LoadConfigFromFile(dup)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `duplicate deployment name "gpt-4"`)
+	assert.Contains(t, err.Error(), "azure_openai.fallbacks[0].provider")
+}
+
+func TestLoadConfigFromFile_LLMStructured(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := LoadConfigFromFile("")
+	require.NoError(t, err)
+	assert.Equal(t, LLMResponseFormatAuto, cfg.AzureOpenAI.Structured.ResponseFormat)
+	assert.Equal(t, 1, cfg.AzureOpenAI.Structured.MaxRepairs)
+
+	t.Setenv("LLM_RESPONSE_FORMAT", "json_object")
+	t.Setenv("LLM_STRUCTURED_MAX_REPAIRS", "0")
+	cfg, err = LoadConfigFromFile("")
+	require.NoError(t, err)
+	assert.Equal(t, LLMResponseFormatJSONObject, cfg.AzureOpenAI.Structured.ResponseFormat)
+	assert.Zero(t, cfg.AzureOpenAI.Structured.MaxRepairs)
+
+	t.Setenv("LLM_RESPONSE_FORMAT", "xml")
+	t.Setenv("LLM_STRUCTURED_MAX_REPAIRS", "-1")
+	_, err = LoadConfigFromFile("")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "azure_openai.structured.response_format")
+	assert.Contains(t, err.Error(), "azure_openai.structured.max_repairs")
+}
+
+func TestLoadConfigFromFile_MCPServers(t *testing.T) {
+	setRequiredEnv(t)
+
+	// 默认在进程内运行统一工具服务器
+	cfg, err := LoadConfigFromFile("")
+	require.NoError(t, err)
+	require.Len(t, cfg.MCP.Servers, 1)
+	assert.Equal(t, MCPTransportInProcess, cfg.MCP.Servers[0].EffectiveTransport())
+	assert.Empty(t, cfg.MCP.ServerBinary)
+
+	path := writeConfigFile(t, "config.yaml", `
+mcp:
+  servers:
+    - name: unified
+    - name: legacy
+      command: ./legacy-server
+    - name: broken
+      transport: stdio
+    - name: unknown
+      transport: http
+`)
+	t.Setenv("MCP_SERVER_BINARY", "/usr/local/bin/deer-flow-server")
+	cfg, err = LoadConfigFromFile(path)
+	var verrs ValidationErrors
+	require.True(t, errors.As(err, &verrs))
+	assert.Equal(t, "/usr/local/bin/deer-flow-server", cfg.MCP.ServerBinary)
+	// 未指定 transport 时有 command 即为 stdio
+	assert.Equal(t, MCPTransportInProcess, cfg.MCP.Servers[0].EffectiveTransport())
+	assert.Equal(t, MCPTransportStdio, cfg.MCP.Servers[1].EffectiveTransport())
+
+	fields := make([]string, 0, len(verrs))
+	for _, e := range verrs {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"mcp.servers[2].command", "mcp.servers[3].transport"}, fields)
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Tavily.APIKey = "tvly-secret-1234"
+
+	redacted := cfg.Redacted()
+	assert.Equal(t, "********1234", redacted.Tavily.APIKey)
+	// 原配置不受影响
+	assert.Equal(t, "tvly-secret-1234", cfg.Tavily.APIKey)
+}
+
+func TestChanges(t *testing.T) {
+	oldConfig := defaultConfig()
+	newConfig := defaultConfig()
+	newConfig.Queue.MaxWorkers = 8
+	newConfig.AzureOpenAI.Temperature = 0.7
+	newConfig.MCP.Servers = append(newConfig.MCP.Servers, MCPServerConfig{Name: "extra", Command: "./extra-server"})
+
+	assert.Equal(t, []string{
+		"azure_openai.temperature",
+		"mcp.servers",
+		"queue.max_workers",
+	}, Changes(oldConfig, newConfig))
+	assert.Empty(t, Changes(oldConfig, defaultConfig()))
+}
@@ -0,0 +1,19 @@
+package config
+
+// FetchConfig 网页抓取工具(fetch_url)配置
+type FetchConfig struct {
+	UserAgent            string `yaml:"user_agent" toml:"user_agent"`                         // 请求头及 robots.txt 匹配使用的 User-Agent
+	Timeout              int    `yaml:"timeout" toml:"timeout"`                               // 单次请求超时时间(秒)
+	MaxBytes             int    `yaml:"max_bytes" toml:"max_bytes"`                           // 最多读取的响应体字节数，超出部分丢弃
+	MaxTokens            int    `yaml:"max_tokens" toml:"max_tokens"`                         // 返回正文的默认token预算
+	RespectRobots        bool   `yaml:"respect_robots" toml:"respect_robots"`                 // 是否遵守 robots.txt
+	AllowPrivateNetworks bool   `yaml:"allow_private_networks" toml:"allow_private_networks"` // 是否允许访问内网和本机地址
+}
+
+// validateFetch 校验网页抓取配置
+func (v *validator) validateFetch(fetch FetchConfig) {
+	v.required("fetch.user_agent", fetch.UserAgent)
+	v.positive("fetch.timeout", fetch.Timeout)
+	v.positive("fetch.max_bytes", fetch.MaxBytes)
+	v.positive("fetch.max_tokens", fetch.MaxTokens)
+}
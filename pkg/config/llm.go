@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// LLM调用录制回放模式
+const (
+	LLMFixturesOff    = "off"    // 直接调用 Azure OpenAI
+	LLMFixturesRecord = "record" // 调用 Azure OpenAI 并把每次请求和响应保存为夹具文件
+	LLMFixturesReplay = "replay" // 只从夹具文件返回响应，不访问网络，也不需要 API 密钥
+)
+
+// LLM提供方
+const (
+	LLMProviderAzure  = "azure"  // Azure OpenAI 部署
+	LLMProviderOpenAI = "openai" // OpenAI 兼容API
+)
+
+// 结构化输出请求的 response_format
+const (
+	LLMResponseFormatAuto       = "auto"        // 按 api_version 选择：2024-08-01 起用 json_schema，2023-12-01 起用 json_object，更早的版本不设置
+	LLMResponseFormatJSONSchema = "json_schema" // 随请求发送期望的 JSON Schema
+	LLMResponseFormatJSONObject = "json_object" // JSON模式，只保证输出是合法的JSON对象
+	LLMResponseFormatOff        = "off"         // 不设置 response_format，只依靠提示词，适用于不支持JSON模式的模型
+)
+
+// LLMStructuredConfig 结构化输出配置
+// 无论是否设置 response_format，结果都会按 JSON Schema 校验，不通过时把校验错误发回模型修正。
+type LLMStructuredConfig struct {
+	ResponseFormat string `yaml:"response_format" toml:"response_format"` // auto | json_schema | json_object | off
+	MaxRepairs     int    `yaml:"max_repairs" toml:"max_repairs"`         // 校验失败后最多修正几次，0 表示不修正
+}
+
+// LLMRetryConfig LLM调用重试配置，429、5xx、超时和网络错误会重试
+type LLMRetryConfig struct {
+	MaxAttempts      int `yaml:"max_attempts" toml:"max_attempts"`             // 每个部署最多尝试次数（含第一次）
+	InitialBackoffMs int `yaml:"initial_backoff_ms" toml:"initial_backoff_ms"` // 第一次重试前的基础等待时间，之后按指数增长并加入随机抖动
+	MaxBackoffMs     int `yaml:"max_backoff_ms" toml:"max_backoff_ms"`         // 单次等待上限，Retry-After 超过该值时直接切换到备用部署
+}
+
+// LLMCircuitBreakerConfig 按部署的熔断配置
+type LLMCircuitBreakerConfig struct {
+	Enabled          bool `yaml:"enabled" toml:"enabled"`
+	FailureThreshold int  `yaml:"failure_threshold" toml:"failure_threshold"` // 连续失败多少次后熔断
+	Cooldown         int  `yaml:"cooldown" toml:"cooldown"`                   // 熔断持续时间(秒)，之后放行一次试探请求
+}
+
+// LLMFallbackConfig 备用部署，主部署失败或熔断时按顺序使用
+type LLMFallbackConfig struct {
+	Name       string `yaml:"name" toml:"name"`
+	Provider   string `yaml:"provider" toml:"provider"`       // azure（默认）| openai
+	Endpoint   string `yaml:"endpoint" toml:"endpoint"`       // azure 为空时使用主部署的端点；openai 为空时使用 https://api.openai.com/v1
+	APIKey     string `yaml:"api_key" toml:"api_key"`         // 只能通过 LLM_FALLBACK_<NAME>_API_KEY 提供，azure 为空时使用主部署的密钥
+	Deployment string `yaml:"deployment" toml:"deployment"`   // azure 部署名或 openai 模型名
+	APIVersion string `yaml:"api_version" toml:"api_version"` // azure 为空时使用主部署的版本
+}
+
+// APIKeyEnv 备用部署密钥的环境变量名
+func (f LLMFallbackConfig) APIKeyEnv() string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, f.Name)
+	return "LLM_FALLBACK_" + name + "_API_KEY"
+}
+
+// LLMCacheConfig LLM响应缓存配置
+// 只缓存 temperature 为 0 的请求，缓存键由部署、消息、工具和全部请求参数计算得到。
+type LLMCacheConfig struct {
+	Enabled    bool   `yaml:"enabled" toml:"enabled"`
+	TTL        int    `yaml:"ttl" toml:"ttl"`                 // 缓存有效期(秒)
+	MaxEntries int    `yaml:"max_entries" toml:"max_entries"` // 内存LRU最大条目数
+	Dir        string `yaml:"dir" toml:"dir"`                 // 可选的磁盘缓存目录，为空时只使用内存
+}
+
+// LLMFixturesConfig LLM调用录制回放配置，用于离线复现问题和不依赖密钥的测试
+type LLMFixturesConfig struct {
+	Mode string `yaml:"mode" toml:"mode"` // off | record | replay
+	Dir  string `yaml:"dir" toml:"dir"`   // 夹具目录，每次LLM调用一个JSON文件
+}
+
+// Replay 是否处于回放模式
+func (c AzureOpenAIConfig) Replay() bool {
+	return c.Fixtures.Mode == LLMFixturesReplay
+}
+
+// validateLLM 校验 Azure OpenAI 配置，回放模式下不要求端点和密钥
+func (v *validator) validateLLM(c AzureOpenAIConfig) {
+	if !c.Replay() {
+		v.httpURL("azure_openai.endpoint", c.Endpoint)
+		v.secret("azure_openai.api_key", "AZURE_OPENAI_API_KEY", c.APIKey)
+	}
+	v.required("azure_openai.deployment", c.Deployment)
+	v.required("azure_openai.api_version", c.APIVersion)
+	if c.Temperature < 0 || c.Temperature > 2 {
+		v.addf("azure_openai.temperature", "must be between 0 and 2, got %v", c.Temperature)
+	}
+	if c.ContextWindow < 0 {
+		v.addf("azure_openai.context_window", "must not be negative, got %d", c.ContextWindow)
+	}
+	if c.MaxCompletionTokens < 0 {
+		v.addf("azure_openai.max_completion_tokens", "must not be negative, got %d", c.MaxCompletionTokens)
+	}
+	if c.ContextWindow > 0 && c.MaxCompletionTokens >= c.ContextWindow {
+		v.addf("azure_openai.max_completion_tokens", "must be less than context_window (%d), got %d", c.ContextWindow, c.MaxCompletionTokens)
+	}
+
+	if c.Cache.Enabled {
+		v.positive("azure_openai.cache.ttl", c.Cache.TTL)
+		v.positive("azure_openai.cache.max_entries", c.Cache.MaxEntries)
+	}
+	v.positive("azure_openai.timeout", c.Timeout)
+	v.positive("azure_openai.retry.max_attempts", c.Retry.MaxAttempts)
+	v.positive("azure_openai.retry.initial_backoff_ms", c.Retry.InitialBackoffMs)
+	if c.Retry.MaxBackoffMs < c.Retry.InitialBackoffMs {
+		v.addf("azure_openai.retry.max_backoff_ms", "must not be less than initial_backoff_ms (%d), got %d", c.Retry.InitialBackoffMs, c.Retry.MaxBackoffMs)
+	}
+	if c.CircuitBreaker.Enabled {
+		v.positive("azure_openai.circuit_breaker.failure_threshold", c.CircuitBreaker.FailureThreshold)
+		v.positive("azure_openai.circuit_breaker.cooldown", c.CircuitBreaker.Cooldown)
+	}
+	names := make(map[string]bool, len(c.Fallbacks)+1)
+	names[c.Deployment] = true
+	for i, fallback := range c.Fallbacks {
+		field := fmt.Sprintf("azure_openai.fallbacks[%d]", i)
+		v.required(field+".name", fallback.Name)
+		if names[fallback.Name] {
+			v.addf(field+".name", "duplicate deployment name %q", fallback.Name)
+		}
+		names[fallback.Name] = true
+		if fallback.Provider != "" {
+			v.oneOf(field+".provider", fallback.Provider, LLMProviderAzure, LLMProviderOpenAI)
+		}
+		v.required(field+".deployment", fallback.Deployment)
+		if fallback.Endpoint != "" {
+			v.httpURL(field+".endpoint", fallback.Endpoint)
+		}
+		if fallback.Provider == LLMProviderOpenAI && !c.Replay() {
+			v.secret(field+".api_key", fallback.APIKeyEnv(), fallback.APIKey)
+		}
+	}
+
+	v.oneOf("azure_openai.structured.response_format", c.Structured.ResponseFormat,
+		LLMResponseFormatAuto, LLMResponseFormatJSONSchema, LLMResponseFormatJSONObject, LLMResponseFormatOff)
+	if c.Structured.MaxRepairs < 0 {
+		v.addf("azure_openai.structured.max_repairs", "must not be negative, got %d", c.Structured.MaxRepairs)
+	}
+
+	v.oneOf("azure_openai.fixtures.mode", c.Fixtures.Mode, LLMFixturesOff, LLMFixturesRecord, LLMFixturesReplay)
+	if c.Fixtures.Mode != LLMFixturesOff {
+		v.required("azure_openai.fixtures.dir", c.Fixtures.Dir)
+	}
+}
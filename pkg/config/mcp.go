@@ -0,0 +1,62 @@
+package config
+
+import "fmt"
+
+// MCP服务器的连接方式
+const (
+	// MCPTransportInProcess 在API进程内运行统一工具服务器，不启动子进程
+	MCPTransportInProcess = "inprocess"
+	// MCPTransportStdio 启动子进程并通过标准输入输出通信
+	MCPTransportStdio = "stdio"
+)
+
+// MCPConfig MCP 配置
+type MCPConfig struct {
+	Enabled bool              `yaml:"enabled" toml:"enabled"`
+	Timeout int               `yaml:"timeout" toml:"timeout"`
+	Servers []MCPServerConfig `yaml:"servers" toml:"servers"` // MCP服务器列表，支持热加载增删
+	// ServerBinary 预编译的统一MCP服务器（cmd/server）路径
+	// 设置后 inprocess 服务器改为以子进程方式运行该程序，便于隔离工具故障。
+	ServerBinary string `yaml:"server_binary" toml:"server_binary"`
+	// PromptsDir 提示词包目录，其中的 YAML 文件通过 prompts/list、prompts/get 提供，为空时不加载
+	PromptsDir string `yaml:"prompts_dir" toml:"prompts_dir"`
+}
+
+// MCPServerConfig 单个MCP服务器配置
+type MCPServerConfig struct {
+	Name      string   `yaml:"name" toml:"name"`
+	Transport string   `yaml:"transport" toml:"transport"` // inprocess | stdio，为空时有 command 即为 stdio，否则为 inprocess
+	Command   string   `yaml:"command" toml:"command"`     // stdio 服务器的启动命令
+	Args      []string `yaml:"args" toml:"args"`
+}
+
+// EffectiveTransport 返回服务器实际使用的连接方式
+func (s MCPServerConfig) EffectiveTransport() string {
+	if s.Transport != "" {
+		return s.Transport
+	}
+	if s.Command != "" {
+		return MCPTransportStdio
+	}
+	return MCPTransportInProcess
+}
+
+// validateMCP 校验MCP配置
+func (v *validator) validateMCP(mcp MCPConfig) {
+	v.positive("mcp.timeout", mcp.Timeout)
+	serverNames := make(map[string]bool, len(mcp.Servers))
+	for i, server := range mcp.Servers {
+		field := fmt.Sprintf("mcp.servers[%d]", i)
+		v.required(field+".name", server.Name)
+		if serverNames[server.Name] {
+			v.addf(field+".name", "duplicate server name %q", server.Name)
+		}
+		serverNames[server.Name] = true
+
+		transport := server.EffectiveTransport()
+		v.oneOf(field+".transport", transport, MCPTransportInProcess, MCPTransportStdio)
+		if transport == MCPTransportStdio {
+			v.required(field+".command", server.Command)
+		}
+	}
+}
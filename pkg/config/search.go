@@ -0,0 +1,112 @@
+package config
+
+// 搜索服务提供方
+const (
+	SearchProviderTavily  = "tavily"
+	SearchProviderSearXNG = "searxng"
+	SearchProviderBrave   = "brave"
+	SearchProviderLocal   = "local"
+)
+
+// KnownSearchProviders 支持的搜索服务提供方
+var KnownSearchProviders = []string{SearchProviderTavily, SearchProviderSearXNG, SearchProviderBrave, SearchProviderLocal}
+
+// SearchConfig 搜索服务配置
+// Tavily 的配置保留在顶层 tavily 节点下，这里配置默认提供方和其他提供方。
+type SearchConfig struct {
+	Provider   string `yaml:"provider" toml:"provider"`       // 默认提供方，search 工具可通过 provider 参数覆盖
+	MaxResults int    `yaml:"max_results" toml:"max_results"` // 非Tavily提供方的默认结果数
+	Timeout    int    `yaml:"timeout" toml:"timeout"`         // HTTP请求超时时间(秒)
+
+	SearXNG SearXNGConfig    `yaml:"searxng" toml:"searxng"`
+	Brave   BraveConfig      `yaml:"brave" toml:"brave"`
+	Local   LocalIndexConfig `yaml:"local" toml:"local"`
+
+	Cache SearchCacheConfig `yaml:"cache" toml:"cache"`
+	Clean SearchCleanConfig `yaml:"clean" toml:"clean"`
+}
+
+// 搜索结果重排方式
+const (
+	SearchRerankBM25 = "bm25"
+	SearchRerankNone = "none"
+)
+
+// SearchCleanConfig 搜索结果后处理配置：去重、重排、按域名限流和摘要截断
+type SearchCleanConfig struct {
+	Rerank             string  `yaml:"rerank" toml:"rerank"`                           // 重排方式: bm25 | none
+	MaxPerDomain       int     `yaml:"max_per_domain" toml:"max_per_domain"`           // 每个域名最多保留的结果数，0 表示不限制
+	MaxContentLength   int     `yaml:"max_content_length" toml:"max_content_length"`   // 摘要最大字节数，超出时按句子或字符边界截断
+	MinScore           float64 `yaml:"min_score" toml:"min_score"`                     // 低于该评分的结果被丢弃，0 表示不过滤
+	DuplicateThreshold float64 `yaml:"duplicate_threshold" toml:"duplicate_threshold"` // 摘要相似度达到该值视为重复(0-1]
+}
+
+// SearchCacheConfig 搜索结果缓存配置
+type SearchCacheConfig struct {
+	Enabled    bool   `yaml:"enabled" toml:"enabled"`
+	TTL        int    `yaml:"ttl" toml:"ttl"`                 // 缓存有效期(秒)
+	MaxEntries int    `yaml:"max_entries" toml:"max_entries"` // 内存LRU最大条目数
+	Dir        string `yaml:"dir" toml:"dir"`                 // 可选的磁盘缓存目录，为空时只使用内存
+}
+
+// SearXNGConfig 自建 SearXNG 实例配置（需开启 JSON 输出格式）
+type SearXNGConfig struct {
+	BaseURL  string   `yaml:"base_url" toml:"base_url"`
+	Engines  []string `yaml:"engines" toml:"engines"`
+	Language string   `yaml:"language" toml:"language"`
+}
+
+// BraveConfig Brave Search API 配置
+type BraveConfig struct {
+	APIKey  string `yaml:"api_key" toml:"api_key"`
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+}
+
+// LocalIndexConfig 本地文档索引配置
+type LocalIndexConfig struct {
+	Path string `yaml:"path" toml:"path"` // 文档目录（.md/.txt/.jsonl）或单个 .jsonl 文件
+}
+
+// validateSearch 校验搜索配置，只有被选为默认提供方时才要求其必填项
+func (v *validator) validateSearch(c *Config) {
+	v.oneOf("search.provider", c.Search.Provider, KnownSearchProviders...)
+	v.positive("search.max_results", c.Search.MaxResults)
+	v.positive("search.timeout", c.Search.Timeout)
+
+	v.httpURL("tavily.base_url", c.Tavily.BaseURL)
+	v.positive("tavily.max_results", c.Tavily.MaxResults)
+	v.oneOf("tavily.search_depth", c.Tavily.SearchDepth, "basic", "advanced")
+	if c.Search.Provider == SearchProviderTavily {
+		v.secret("tavily.api_key", "TAVILY_API_KEY", c.Tavily.APIKey)
+	}
+
+	if c.Search.SearXNG.BaseURL != "" || c.Search.Provider == SearchProviderSearXNG {
+		v.httpURL("search.searxng.base_url", c.Search.SearXNG.BaseURL)
+	}
+
+	v.httpURL("search.brave.base_url", c.Search.Brave.BaseURL)
+	if c.Search.Provider == SearchProviderBrave {
+		v.secret("search.brave.api_key", "BRAVE_API_KEY", c.Search.Brave.APIKey)
+	}
+
+	if c.Search.Provider == SearchProviderLocal {
+		v.required("search.local.path", c.Search.Local.Path)
+	}
+
+	v.oneOf("search.clean.rerank", c.Search.Clean.Rerank, SearchRerankBM25, SearchRerankNone)
+	if c.Search.Clean.MaxPerDomain < 0 {
+		v.addf("search.clean.max_per_domain", "must not be negative, got %d", c.Search.Clean.MaxPerDomain)
+	}
+	v.positive("search.clean.max_content_length", c.Search.Clean.MaxContentLength)
+	if c.Search.Clean.MinScore < 0 {
+		v.addf("search.clean.min_score", "must not be negative, got %v", c.Search.Clean.MinScore)
+	}
+	if c.Search.Clean.DuplicateThreshold <= 0 || c.Search.Clean.DuplicateThreshold > 1 {
+		v.addf("search.clean.duplicate_threshold", "must be in (0, 1], got %v", c.Search.Clean.DuplicateThreshold)
+	}
+
+	if c.Search.Cache.Enabled {
+		v.positive("search.cache.ttl", c.Search.Cache.TTL)
+		v.positive("search.cache.max_entries", c.Search.Cache.MaxEntries)
+	}
+}
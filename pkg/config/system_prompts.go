@@ -0,0 +1,12 @@
+package config
+
+// SystemPromptsConfig LLM系统提示词模板配置（查询路由和结果整理）
+type SystemPromptsConfig struct {
+	Dir    string `yaml:"dir" toml:"dir"`       // 模板目录，其中的 .tmpl 文件替换同名的内置模板；为空时只使用内置模板
+	Locale string `yaml:"locale" toml:"locale"` // 模板变量 {{.Locale}} 的值
+}
+
+// validateSystemPrompts 校验系统提示词模板配置
+func (v *validator) validateSystemPrompts(prompts SystemPromptsConfig) {
+	v.required("system_prompts.locale", prompts.Locale)
+}
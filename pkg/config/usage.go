@@ -0,0 +1,67 @@
+package config
+
+import "fmt"
+
+// UsageConfig 用量记账与配额配置
+type UsageConfig struct {
+	Enabled       bool                   `yaml:"enabled" toml:"enabled"`
+	LedgerFile    string                 `yaml:"ledger_file" toml:"ledger_file"`       // 用量记录文件（JSON Lines，追加写入），为空时只保存在内存中
+	RetentionDays int                    `yaml:"retention_days" toml:"retention_days"` // 内存中保留的记录天数，用于报表查询
+	Currency      string                 `yaml:"currency" toml:"currency"`             // 价格和费用的货币单位，仅用于展示
+	Prices        PriceTable             `yaml:"prices" toml:"prices"`
+	DefaultQuota  QuotaConfig            `yaml:"default_quota" toml:"default_quota"` // 未单独配置的租户使用的配额
+	Quotas        map[string]QuotaConfig `yaml:"quotas" toml:"quotas"`               // 按租户配置的配额
+}
+
+// PriceTable 价格表
+type PriceTable struct {
+	PromptPer1KTokens     float64            `yaml:"prompt_per_1k_tokens" toml:"prompt_per_1k_tokens"`
+	CompletionPer1KTokens float64            `yaml:"completion_per_1k_tokens" toml:"completion_per_1k_tokens"`
+	ToolCalls             map[string]float64 `yaml:"tool_calls" toml:"tool_calls"` // 工具名称 -> 每次调用的价格
+}
+
+// QuotaConfig 租户配额，0 表示不限制；按UTC自然日、自然月统计
+type QuotaConfig struct {
+	DailyCost     float64 `yaml:"daily_cost" toml:"daily_cost" json:"daily_cost"`
+	MonthlyCost   float64 `yaml:"monthly_cost" toml:"monthly_cost" json:"monthly_cost"`
+	DailyTokens   int     `yaml:"daily_tokens" toml:"daily_tokens" json:"daily_tokens"`
+	MonthlyTokens int     `yaml:"monthly_tokens" toml:"monthly_tokens" json:"monthly_tokens"`
+}
+
+// QuotaFor 返回租户的配额
+func (u UsageConfig) QuotaFor(tenant string) QuotaConfig {
+	if quota, ok := u.Quotas[tenant]; ok {
+		return quota
+	}
+	return u.DefaultQuota
+}
+
+// validateUsage 校验用量记账配置
+func (v *validator) validateUsage(usage UsageConfig) {
+	if !usage.Enabled {
+		return
+	}
+	v.positive("usage.retention_days", usage.RetentionDays)
+	if usage.Prices.PromptPer1KTokens < 0 {
+		v.addf("usage.prices.prompt_per_1k_tokens", "must not be negative, got %v", usage.Prices.PromptPer1KTokens)
+	}
+	if usage.Prices.CompletionPer1KTokens < 0 {
+		v.addf("usage.prices.completion_per_1k_tokens", "must not be negative, got %v", usage.Prices.CompletionPer1KTokens)
+	}
+	for tool, price := range usage.Prices.ToolCalls {
+		if price < 0 {
+			v.addf("usage.prices.tool_calls."+tool, "must not be negative, got %v", price)
+		}
+	}
+	v.validateQuota("usage.default_quota", usage.DefaultQuota)
+	for tenant, quota := range usage.Quotas {
+		v.validateQuota(fmt.Sprintf("usage.quotas.%s", tenant), quota)
+	}
+}
+
+// validateQuota 校验配额
+func (v *validator) validateQuota(field string, quota QuotaConfig) {
+	if quota.DailyCost < 0 || quota.MonthlyCost < 0 || quota.DailyTokens < 0 || quota.MonthlyTokens < 0 {
+		v.addf(field, "limits must not be negative")
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FieldError 单个配置字段的校验错误
+type FieldError struct {
+	Field   string // 字段路径（如 azure_openai.api_key）或环境变量名
+	Message string
+}
+
+// Error 实现error接口
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors 配置校验错误集合，一次性报告所有无效字段
+type ValidationErrors []FieldError
+
+// Error 实现error接口
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fieldErr := range e {
+		msgs[i] = fieldErr.Error()
+	}
+	return fmt.Sprintf("invalid configuration (%d errors):\n  - %s", len(e), strings.Join(msgs, "\n  - "))
+}
+
+// validator 收集校验错误
+type validator struct {
+	errs ValidationErrors
+}
+
+// addf 记录一条校验错误
+func (v *validator) addf(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// required 检查必填字符串
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(field, "is required")
+	}
+}
+
+// secret 检查必填密钥，并提示可用的密钥来源
+func (v *validator) secret(field, envKey, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(field, "is required (set %s, %s_FILE or SECRETS_DIR)", envKey, envKey)
+	}
+}
+
+// positive 检查正整数
+func (v *validator) positive(field string, value int) {
+	if value <= 0 {
+		v.addf(field, "must be positive, got %d", value)
+	}
+}
+
+// httpURL 检查 http(s) URL 格式
+func (v *validator) httpURL(field, value string) {
+	if value == "" {
+		v.addf(field, "is required")
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(field, "malformed URL %q", value)
+	}
+}
+
+// oneOf 检查枚举值
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf(field, "must be one of [%s], got %q", strings.Join(allowed, ", "), value)
+}
+
+// Validate 校验配置，返回包含所有无效字段的 ValidationErrors
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port <= 0 || port > 65535 {
+		v.addf("port", "must be a number between 1 and 65535, got %q", c.Port)
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		v.addf("log_level", "unknown log level %q", c.LogLevel)
+	}
+
+	v.validateLLM(c.AzureOpenAI)
+
+	v.validateSearch(c)
+	v.validateFetch(c.Fetch)
+
+	v.validateMCP(c.MCP)
+
+	v.validateSystemPrompts(c.SystemPrompts)
+
+	v.validateUsage(c.Usage)
+
+	v.validateWeather(c.Weather)
+
+	v.positive("queue.max_workers", c.Queue.MaxWorkers)
+	v.positive("queue.queue_size", c.Queue.QueueSize)
+	v.positive("queue.request_timeout", c.Queue.RequestTimeout)
+	v.positive("queue.queue_timeout", c.Queue.QueueTimeout)
+
+	v.validateAuth(c.Auth)
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// Redacted 返回隐藏了密钥的配置副本，用于打印生效配置
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.AzureOpenAI.APIKey = maskSecret(c.AzureOpenAI.APIKey)
+	redacted.Tavily.APIKey = maskSecret(c.Tavily.APIKey)
+	redacted.Search.Brave.APIKey = maskSecret(c.Search.Brave.APIKey)
+	redacted.Weather.APIKey = maskSecret(c.Weather.APIKey)
+	redacted.AzureOpenAI.Fallbacks = make([]LLMFallbackConfig, len(c.AzureOpenAI.Fallbacks))
+	for i, fallback := range c.AzureOpenAI.Fallbacks {
+		fallback.APIKey = maskSecret(fallback.APIKey)
+		redacted.AzureOpenAI.Fallbacks[i] = fallback
+	}
+	redacted.Auth.Keys = make([]APIKeyConfig, len(c.Auth.Keys))
+	for i, key := range c.Auth.Keys {
+		key.Key = maskSecret(key.Key)
+		redacted.Auth.Keys[i] = key
+	}
+	return &redacted
+}
+
+// Secrets 返回当前配置中所有非空密钥，用于日志脱敏
+func (c *Config) Secrets() []string {
+	secrets := make([]string, 0, 4+len(c.Auth.Keys))
+	for _, secret := range []string{c.AzureOpenAI.APIKey, c.Tavily.APIKey, c.Weather.APIKey, c.Search.Brave.APIKey} {
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	for _, fallback := range c.AzureOpenAI.Fallbacks {
+		if fallback.APIKey != "" {
+			secrets = append(secrets, fallback.APIKey)
+		}
+	}
+	for _, key := range c.Auth.Keys {
+		if key.Key != "" {
+			secrets = append(secrets, key.Key)
+		}
+	}
+	return secrets
+}
+
+// maskSecret 仅保留密钥末尾4位
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", 8) + secret[len(secret)-4:]
+}
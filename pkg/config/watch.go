@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Changes 比较两份配置，返回发生变化的字段路径（使用yaml标签，如 queue.max_workers）
+func Changes(oldConfig, newConfig *Config) []string {
+	var changed []string
+	diffValues("", reflect.ValueOf(*oldConfig), reflect.ValueOf(*newConfig), &changed)
+	return changed
+}
+
+// diffValues 递归比较结构体字段，切片等非结构体字段整体比较
+func diffValues(prefix string, oldValue, newValue reflect.Value, changed *[]string) {
+	if oldValue.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+			*changed = append(*changed, prefix)
+		}
+		return
+	}
+
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		diffValues(name, oldValue.Field(i), newValue.Field(i), changed)
+	}
+}
+
+// Watcher 配置文件监听器
+// 定期检查配置文件的修改时间，也可以通过 Trigger 主动触发（例如收到SIGHUP时）。
+// 新配置通过校验后才会回调 onReload，校验失败时保留旧配置并记录错误。
+type Watcher struct {
+	path     string
+	interval time.Duration
+	logger   *logrus.Logger
+	trigger  chan struct{}
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+}
+
+// NewWatcher 创建配置监听器，path为空时只响应 Trigger
+func NewWatcher(path string, interval time.Duration, logger *logrus.Logger) *Watcher {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	w := &Watcher{
+		path:     path,
+		interval: interval,
+		logger:   logger,
+		trigger:  make(chan struct{}, 1),
+	}
+	w.fileChanged() // 记录初始状态
+	return w
+}
+
+// Trigger 请求立即重新加载配置
+func (w *Watcher) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Watch 阻塞监听配置变化直到ctx取消
+func (w *Watcher) Watch(ctx context.Context, onReload func(*Config)) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if w.path == "" || !w.fileChanged() {
+				continue
+			}
+			w.logger.WithField("path", w.path).Info("Config file changed, reloading")
+		case <-w.trigger:
+			w.fileChanged()
+			w.logger.WithField("path", w.path).Info("Config reload requested")
+		}
+
+		newConfig, err := LoadConfigFromFile(w.path)
+		if err != nil {
+			w.logger.WithError(err).Error("Config reload rejected, keeping current configuration")
+			continue
+		}
+		onReload(newConfig)
+	}
+}
+
+// fileChanged 检查配置文件的修改时间和大小是否变化
+func (w *Watcher) fileChanged() bool {
+	if w.path == "" {
+		return false
+	}
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false
+	}
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+	return true
+}
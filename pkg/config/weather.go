@@ -0,0 +1,52 @@
+package config
+
+// 天气服务提供方
+const (
+	WeatherProviderOpenWeatherMap = "openweathermap"
+	WeatherProviderOpenMeteo      = "openmeteo"
+)
+
+// KnownWeatherProviders 支持的天气服务提供方
+var KnownWeatherProviders = []string{WeatherProviderOpenWeatherMap, WeatherProviderOpenMeteo}
+
+// WeatherConfig 天气服务配置
+// OpenWeatherMap 的配置保留在顶层字段中，Open-Meteo 无需密钥。
+type WeatherConfig struct {
+	Provider string `yaml:"provider" toml:"provider"` // 默认提供方，天气工具可通过 provider 参数覆盖
+	APIKey   string `yaml:"api_key" toml:"api_key"`   // OpenWeatherMap 密钥
+	BaseURL  string `yaml:"base_url" toml:"base_url"` // OpenWeatherMap 接口地址
+	Timeout  int    `yaml:"timeout" toml:"timeout"`   // HTTP请求超时时间(秒)
+
+	// OneCallURL OpenWeatherMap One Call 3.0 接口地址，用于查询气象预警（需要单独订阅）
+	OneCallURL string `yaml:"one_call_url" toml:"one_call_url"`
+
+	OpenMeteo OpenMeteoConfig `yaml:"open_meteo" toml:"open_meteo"`
+	Geocoding GeocodingConfig `yaml:"geocoding" toml:"geocoding"`
+}
+
+// OpenMeteoConfig Open-Meteo 天气接口配置
+type OpenMeteoConfig struct {
+	BaseURL       string `yaml:"base_url" toml:"base_url"`
+	AirQualityURL string `yaml:"air_quality_url" toml:"air_quality_url"` // 空气质量接口地址
+}
+
+// GeocodingConfig 地名解析配置（Open-Meteo Geocoding API，支持任意语言的地名）
+type GeocodingConfig struct {
+	BaseURL  string `yaml:"base_url" toml:"base_url"`
+	Language string `yaml:"language" toml:"language"` // 返回地名使用的语言，如 zh、en
+}
+
+// validateWeather 校验天气配置，OpenWeatherMap 密钥仅在它是默认提供方时必填
+func (v *validator) validateWeather(weather WeatherConfig) {
+	v.oneOf("weather.provider", weather.Provider, KnownWeatherProviders...)
+	if weather.Provider == WeatherProviderOpenWeatherMap {
+		v.secret("weather.api_key", "WEATHER_API_KEY", weather.APIKey)
+	}
+	v.httpURL("weather.base_url", weather.BaseURL)
+	v.positive("weather.timeout", weather.Timeout)
+	v.httpURL("weather.one_call_url", weather.OneCallURL)
+	v.httpURL("weather.open_meteo.base_url", weather.OpenMeteo.BaseURL)
+	v.httpURL("weather.open_meteo.air_quality_url", weather.OpenMeteo.AirQualityURL)
+	v.httpURL("weather.geocoding.base_url", weather.Geocoding.BaseURL)
+	v.required("weather.geocoding.language", weather.Geocoding.Language)
+}
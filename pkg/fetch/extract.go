@@ -0,0 +1,459 @@
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skippedElements 不属于正文的元素，整体跳过
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+}
+
+// boilerplatePattern class/id 中常见的非正文区块名称
+var boilerplatePattern = regexp.MustCompile(`(?i)(^|[\s_-])(nav|navbar|menu|sidebar|footer|breadcrumbs?|cookie|banner|advert|ads|share|social|comments?|related|subscribe|newsletter|popup|modal)($|[\s_-])`)
+
+// Document 从HTML中提取出的可读内容
+type Document struct {
+	Title    string
+	Markdown string
+}
+
+// ExtractHTML 解析HTML，提取标题和正文并转换为markdown
+// 正文优先取 <article>、<main> 或 role="main" 元素，找不到时使用 <body>；相对链接按 base 解析为绝对地址。
+func ExtractHTML(r io.Reader, base *url.URL) (*Document, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	if href := findBaseHref(root); href != "" && base != nil {
+		if resolved, err := base.Parse(href); err == nil {
+			base = resolved
+		}
+	}
+
+	content := findMainContent(root)
+	if content == nil {
+		content = root
+	}
+
+	w := &markdownWriter{base: base}
+	w.renderChildren(content)
+
+	return &Document{
+		Title:    findTitle(root),
+		Markdown: normalizeMarkdown(w.String()),
+	}, nil
+}
+
+// findTitle 提取页面标题，优先 og:title，其次 <title>，最后第一个 <h1>
+func findTitle(root *html.Node) string {
+	if node := findFirst(root, func(n *html.Node) bool {
+		return n.DataAtom == atom.Meta && (attr(n, "property") == "og:title" || attr(n, "name") == "og:title")
+	}); node != nil {
+		if title := collapseSpaces(attr(node, "content")); title != "" {
+			return title
+		}
+	}
+	for _, a := range []atom.Atom{atom.Title, atom.H1} {
+		if node := findFirst(root, func(n *html.Node) bool { return n.DataAtom == a }); node != nil {
+			if title := collapseSpaces(textContent(node)); title != "" {
+				return title
+			}
+		}
+	}
+	return ""
+}
+
+// findBaseHref 返回 <base href> 的值
+func findBaseHref(root *html.Node) string {
+	if node := findFirst(root, func(n *html.Node) bool { return n.DataAtom == atom.Base }); node != nil {
+		return attr(node, "href")
+	}
+	return ""
+}
+
+// findMainContent 查找正文容器，存在多个 <article> 时选择文本最长的一个
+func findMainContent(root *html.Node) *html.Node {
+	var best *html.Node
+	bestLen := 0
+	walk(root, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && skippedElements[n.DataAtom] {
+			return false
+		}
+		if n.DataAtom == atom.Article {
+			if l := len(strings.TrimSpace(textContent(n))); l > bestLen {
+				best, bestLen = n, l
+			}
+			return false
+		}
+		return true
+	})
+	if best != nil {
+		return best
+	}
+
+	if node := findFirst(root, func(n *html.Node) bool {
+		return n.DataAtom == atom.Main || attr(n, "role") == "main"
+	}); node != nil {
+		return node
+	}
+	return findFirst(root, func(n *html.Node) bool { return n.DataAtom == atom.Body })
+}
+
+// markdownWriter 将HTML节点渲染为markdown
+type markdownWriter struct {
+	base      *url.URL
+	sb        strings.Builder
+	listDepth int
+}
+
+// String 返回渲染结果
+func (w *markdownWriter) String() string {
+	return w.sb.String()
+}
+
+// block 开始一个新的块级元素
+func (w *markdownWriter) block() {
+	w.sb.WriteString("\n\n")
+}
+
+// renderChildren 渲染所有子节点
+func (w *markdownWriter) renderChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.render(c)
+	}
+}
+
+// render 渲染单个节点
+func (w *markdownWriter) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.sb.WriteString(collapseInline(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		w.renderChildren(n)
+		return
+	}
+
+	if skippedElements[n.DataAtom] || isBoilerplate(n) || attr(n, "aria-hidden") == "true" {
+		return
+	}
+	if _, hidden := attrLookup(n, "hidden"); hidden {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := collapseSpaces(textContent(n))
+		if text == "" {
+			return
+		}
+		level := int(n.Data[1] - '0')
+		w.block()
+		w.sb.WriteString(strings.Repeat("#", level) + " " + text)
+		w.block()
+	case atom.P, atom.Div, atom.Section, atom.Figure, atom.Figcaption, atom.Dl, atom.Dd, atom.Dt:
+		w.block()
+		w.renderChildren(n)
+		w.block()
+	case atom.Br:
+		w.sb.WriteString("  \n")
+	case atom.Hr:
+		w.block()
+		w.sb.WriteString("---")
+		w.block()
+	case atom.Pre:
+		code := strings.Trim(textContent(n), "\n")
+		if strings.TrimSpace(code) == "" {
+			return
+		}
+		w.block()
+		w.sb.WriteString("```\n" + code + "\n```")
+		w.block()
+	case atom.Code:
+		if text := collapseSpaces(textContent(n)); text != "" {
+			w.sb.WriteString("`" + text + "`")
+		}
+	case atom.Strong, atom.B:
+		w.wrapInline(n, "**")
+	case atom.Em, atom.I:
+		w.wrapInline(n, "_")
+	case atom.A:
+		w.renderLink(n)
+	case atom.Img:
+		// 图片对文本阅读帮助有限，只保留有意义的替代文字
+		if alt := collapseSpaces(attr(n, "alt")); alt != "" {
+			w.sb.WriteString("[图片: " + alt + "]")
+		}
+	case atom.Ul, atom.Ol:
+		w.renderList(n)
+	case atom.Blockquote:
+		inner := &markdownWriter{base: w.base}
+		inner.renderChildren(n)
+		text := normalizeMarkdown(inner.String())
+		if text == "" {
+			return
+		}
+		w.block()
+		for _, line := range strings.Split(text, "\n") {
+			w.sb.WriteString("> " + line + "\n")
+		}
+		w.block()
+	case atom.Table:
+		w.renderTable(n)
+	default:
+		w.renderChildren(n)
+	}
+}
+
+// wrapInline 用标记包裹行内元素
+func (w *markdownWriter) wrapInline(n *html.Node, mark string) {
+	text := collapseSpaces(textContent(n))
+	if text == "" {
+		return
+	}
+	w.sb.WriteString(mark + text + mark)
+}
+
+// renderLink 渲染链接，锚点和 javascript: 链接只保留文字
+func (w *markdownWriter) renderLink(n *html.Node) {
+	inner := &markdownWriter{base: w.base}
+	inner.renderChildren(n)
+	text := collapseSpaces(inner.String())
+	if text == "" {
+		return
+	}
+
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		w.sb.WriteString(text)
+		return
+	}
+	if w.base != nil {
+		if resolved, err := w.base.Parse(href); err == nil {
+			href = resolved.String()
+		}
+	}
+	w.sb.WriteString("[" + text + "](" + href + ")")
+}
+
+// renderList 渲染有序或无序列表，嵌套列表按层级缩进
+func (w *markdownWriter) renderList(n *html.Node) {
+	ordered := n.DataAtom == atom.Ol
+	indent := strings.Repeat("  ", w.listDepth)
+	if w.listDepth == 0 {
+		w.block()
+	} else {
+		w.sb.WriteString("\n")
+	}
+
+	index := 1
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom != atom.Li {
+			continue
+		}
+		inner := &markdownWriter{base: w.base, listDepth: w.listDepth + 1}
+		inner.renderChildren(c)
+		text := strings.TrimSpace(inner.String())
+		if text == "" {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", index)
+		}
+		index++
+		w.sb.WriteString(indent + marker + text + "\n")
+	}
+
+	if w.listDepth == 0 {
+		w.block()
+	}
+}
+
+// renderTable 将表格渲染为markdown表格，第一行作为表头
+func (w *markdownWriter) renderTable(n *html.Node) {
+	var rows [][]string
+	walk(n, func(node *html.Node) bool {
+		if node.DataAtom != atom.Tr {
+			return node == n || node.DataAtom != atom.Table
+		}
+		var cells []string
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Td || c.DataAtom == atom.Th {
+				cell := strings.ReplaceAll(collapseSpaces(textContent(c)), "|", "\\|")
+				cells = append(cells, cell)
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return
+	}
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	w.block()
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		w.sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			w.sb.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	w.block()
+}
+
+// isBoilerplate 根据 class/id/role 判断是否为导航、广告、评论等非正文区块
+func isBoilerplate(n *html.Node) bool {
+	switch attr(n, "role") {
+	case "navigation", "banner", "contentinfo", "complementary", "search":
+		return true
+	}
+	// 正文容器本身不做判断，避免 class="article-header" 之类误伤整篇文章
+	if n.DataAtom == atom.Article || n.DataAtom == atom.Main || n.DataAtom == atom.Body {
+		return false
+	}
+	return boilerplatePattern.MatchString(attr(n, "class")) || boilerplatePattern.MatchString(attr(n, "id"))
+}
+
+var (
+	inlineSpacePattern = regexp.MustCompile(`[ \t\r\n\f]+`)
+	trailingSpaces     = regexp.MustCompile(`[ \t]+\n`)
+	blankLines         = regexp.MustCompile(`\n{3,}`)
+)
+
+// collapseInline 合并行内文本的连续空白，保留首尾的单个空格
+func collapseInline(text string) string {
+	return inlineSpacePattern.ReplaceAllString(text, " ")
+}
+
+// collapseSpaces 合并连续空白并去掉首尾空白
+func collapseSpaces(text string) string {
+	return strings.TrimSpace(collapseInline(text))
+}
+
+// normalizeMarkdown 清理多余的空行和行首行尾空白
+func normalizeMarkdown(text string) string {
+	lines := strings.Split(text, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			lines[i] = strings.TrimSpace(line)
+			continue
+		}
+		if !inCode && !strings.HasPrefix(strings.TrimLeft(line, " "), "- ") && !isOrderedItem(line) {
+			lines[i] = strings.TrimLeft(line, " ")
+		}
+	}
+	text = strings.Join(lines, "\n")
+	text = trailingSpaces.ReplaceAllStringFunc(text, func(s string) string {
+		// 保留 markdown 换行标记（两个空格 + 换行）
+		if strings.HasSuffix(s, "  \n") {
+			return "  \n"
+		}
+		return "\n"
+	})
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// isOrderedItem 判断是否为（可能缩进的）有序列表项
+func isOrderedItem(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	i := 0
+	for i < len(trimmed) && trimmed[i] >= '0' && trimmed[i] <= '9' {
+		i++
+	}
+	return i > 0 && strings.HasPrefix(trimmed[i:], ". ")
+}
+
+// walk 深度优先遍历节点，visit 返回 false 时不进入子节点
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+// findFirst 返回第一个满足条件的元素节点
+func findFirst(root *html.Node, match func(*html.Node) bool) *html.Node {
+	var found *html.Node
+	walk(root, func(n *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if n.Type == html.ElementNode && match(n) {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// textContent 返回节点内的全部文本（跳过脚本和样式）
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(node *html.Node) bool {
+		if node.Type == html.ElementNode && (node.DataAtom == atom.Script || node.DataAtom == atom.Style) {
+			return false
+		}
+		if node.Type == html.TextNode {
+			sb.WriteString(node.Data)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// attr 返回属性值
+func attr(n *html.Node, key string) string {
+	value, _ := attrLookup(n, key)
+	return value
+}
+
+// attrLookup 返回属性值及是否存在
+func attrLookup(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
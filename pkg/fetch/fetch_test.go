@@ -0,0 +1,223 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/llm"
+)
+
+const articleHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>示例站点 - 首页</title>
+  <meta property="og:title" content="Go 1.23 发布说明">
+  <script>var tracking = "ignore me";</script>
+</head>
+<body>
+  <nav><a href="/">首页</a> <a href="/about">关于</a></nav>
+  <div class="sidebar">热门文章</div>
+  <article>
+    <h1>Go 1.23 发布说明</h1>
+    <p>Go 1.23 引入了 <strong>range-over-func</strong> 迭代器，详见 <a href="/doc/go1.23">发布说明</a>。</p>
+    <ul>
+      <li>新增 iter 包</li>
+      <li>新增 unique 包
+        <ul><li>用于值的规范化</li></ul>
+      </li>
+    </ul>
+    <pre><code>for v := range seq {
+	fmt.Println(v)
+}</code></pre>
+    <table>
+      <tr><th>版本</th><th>日期</th></tr>
+      <tr><td>1.23</td><td>2024-08</td></tr>
+    </table>
+    <div class="share-buttons">分享到微博</div>
+  </article>
+  <footer>版权所有</footer>
+</body>
+</html>`
+
+func newTestFetcher(t *testing.T, mutate func(*config.FetchConfig)) *Fetcher {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	cfg := config.FetchConfig{
+		UserAgent:            "deer-flow-go/1.0",
+		Timeout:              5,
+		MaxBytes:             1 << 20,
+		MaxTokens:            4000,
+		RespectRobots:        true,
+		AllowPrivateNetworks: true,
+	}
+	if mutate != nil {
+		mutate(&cfg)
+	}
+	return NewFetcher(&cfg, logger)
+}
+
+func TestExtractHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+	doc, err := ExtractHTML(strings.NewReader(articleHTML), base)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Go 1.23 发布说明", doc.Title)
+	md := doc.Markdown
+	assert.True(t, strings.HasPrefix(md, "# Go 1.23 发布说明"), md)
+	assert.Contains(t, md, "**range-over-func**")
+	assert.Contains(t, md, "[发布说明](https://example.com/doc/go1.23)")
+	assert.Contains(t, md, "- 新增 iter 包")
+	assert.Contains(t, md, "  - 用于值的规范化")
+	assert.Contains(t, md, "```\nfor v := range seq {\n\tfmt.Println(v)\n}\n```")
+	assert.Contains(t, md, "| 版本 | 日期 |\n| --- | --- |\n| 1.23 | 2024-08 |")
+
+	// 导航、侧边栏、分享按钮、页脚和脚本不属于正文
+	for _, noise := range []string{"关于", "热门文章", "分享到微博", "版权所有", "tracking"} {
+		assert.NotContains(t, md, noise)
+	}
+}
+
+func TestFetch_HTMLPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.NotFound(w, r)
+		case "/old":
+			http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+		case "/article":
+			assert.Equal(t, "deer-flow-go/1.0", r.Header.Get("User-Agent"))
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(articleHTML))
+		}
+	}))
+	defer srv.Close()
+
+	page, err := newTestFetcher(t, nil).Fetch(context.Background(), srv.URL+"/old#section", 0)
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/article", page.URL)
+	assert.Equal(t, "Go 1.23 发布说明", page.Title)
+	assert.Equal(t, "text/html", page.ContentType)
+	assert.Contains(t, page.Markdown, "新增 iter 包")
+	assert.False(t, page.Truncated)
+	assert.Greater(t, page.Tokens, 0)
+}
+
+func TestFetch_Robots(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /\n\nUser-agent: deer-flow-go\nDisallow: /private\nAllow: /private/public\n"))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("hello " + r.URL.Path))
+		}
+	}))
+	defer srv.Close()
+
+	fetcher := newTestFetcher(t, nil)
+
+	_, err := fetcher.Fetch(context.Background(), srv.URL+"/private/doc", 0)
+	assert.ErrorIs(t, err, ErrRobotsDisallowed)
+
+	// 专属规则组优先于 "*"，最长匹配的 Allow 生效
+	page, err := fetcher.Fetch(context.Background(), srv.URL+"/private/public/doc", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "hello /private/public/doc", page.Markdown)
+
+	page, err = fetcher.Fetch(context.Background(), srv.URL+"/news", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "hello /news", page.Markdown)
+
+	// 关闭 robots.txt 检查后可以抓取
+	_, err = newTestFetcher(t, func(c *config.FetchConfig) { c.RespectRobots = false }).
+		Fetch(context.Background(), srv.URL+"/private/doc", 0)
+	assert.NoError(t, err)
+}
+
+func TestFetch_Limits(t *testing.T) {
+	long := strings.Repeat("第一段内容很长。", 200) + "\n\n" + strings.Repeat("第二段内容。", 200)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.NotFound(w, r)
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		case "/long.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(long))
+		}
+	}))
+	defer srv.Close()
+
+	fetcher := newTestFetcher(t, nil)
+
+	_, err := fetcher.Fetch(context.Background(), srv.URL+"/image.png", 0)
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
+
+	_, err = fetcher.Fetch(context.Background(), "ftp://example.com/file", 0)
+	assert.Error(t, err)
+
+	page, err := fetcher.Fetch(context.Background(), srv.URL+"/long.txt", 500)
+	require.NoError(t, err)
+	assert.True(t, page.Truncated)
+	assert.LessOrEqual(t, llm.EstimateTokens(page.Markdown), 500)
+	assert.True(t, strings.HasSuffix(page.Markdown, "。"), "should cut at a sentence boundary")
+
+	// 超过 max_bytes 的响应体只读取前一部分
+	small := newTestFetcher(t, func(c *config.FetchConfig) { c.MaxBytes = 1000 })
+	page, err = small.Fetch(context.Background(), srv.URL+"/long.txt", 0)
+	require.NoError(t, err)
+	assert.True(t, page.BodyTruncated)
+	assert.LessOrEqual(t, len(page.Markdown), 1000)
+}
+
+func TestFetch_DeniesPrivateNetworks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer srv.Close()
+
+	fetcher := newTestFetcher(t, func(c *config.FetchConfig) {
+		c.AllowPrivateNetworks = false
+		c.RespectRobots = false
+	})
+	_, err := fetcher.Fetch(context.Background(), srv.URL+"/admin", 0)
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	text, truncated := TruncateToTokens("short text", 100)
+	assert.False(t, truncated)
+	assert.Equal(t, "short text", text)
+
+	// 在段落边界截断
+	text, truncated = TruncateToTokens("第一段的内容比较长。\n\n"+strings.Repeat("很长的第二段", 50), 15)
+	assert.True(t, truncated)
+	assert.Equal(t, "第一段的内容比较长。", text)
+
+	// 没有合适的边界时按字符截断，不会破坏多字节字符
+	text, truncated = TruncateToTokens(strings.Repeat("字", 100), 10)
+	assert.True(t, truncated)
+	assert.Equal(t, strings.Repeat("字", 10), text)
+}
+
+func TestMatchRobotsPattern(t *testing.T) {
+	assert.True(t, matchRobotsPattern("/private", "/private/doc"))
+	assert.True(t, matchRobotsPattern("/*.pdf$", "/files/a.pdf"))
+	assert.False(t, matchRobotsPattern("/*.pdf$", "/files/a.pdf?x=1"))
+	assert.True(t, matchRobotsPattern("/search*q=", "/search?lang=en&q=go"))
+	assert.False(t, matchRobotsPattern("/admin", "/"))
+}
@@ -0,0 +1,247 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/html/charset"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/llm"
+)
+
+// ToolName 网页抓取的MCP工具名
+const ToolName = "fetch_url"
+
+// maxRedirects 最多跟随的重定向次数
+const maxRedirects = 5
+
+var (
+	// ErrRobotsDisallowed 目标页面被 robots.txt 禁止抓取
+	ErrRobotsDisallowed = errors.New("fetching this URL is disallowed by robots.txt")
+	// ErrUnsupportedContentType 响应不是HTML或纯文本
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	// ErrPrivateAddress 目标地址属于内网或本机
+	ErrPrivateAddress = errors.New("refusing to connect to private or loopback address")
+)
+
+// Page 抓取并提取后的网页
+type Page struct {
+	URL           string `json:"url"`            // 跟随重定向后的最终地址
+	Title         string `json:"title"`          // 页面标题
+	ContentType   string `json:"content_type"`   // 响应的媒体类型
+	Markdown      string `json:"markdown"`       // 正文（markdown）
+	Tokens        int    `json:"tokens"`         // 正文的估算token数
+	Truncated     bool   `json:"truncated"`      // 正文是否因token预算被截断
+	BodyTruncated bool   `json:"body_truncated"` // 响应体是否超过 max_bytes 只读取了前半部分
+}
+
+// Fetcher 网页抓取器：遵守 robots.txt，限制响应大小和类型，提取正文并按token预算截断
+type Fetcher struct {
+	cfg    config.FetchConfig
+	client *http.Client
+	robots *robotsCache
+	logger *logrus.Logger
+}
+
+// NewFetcher 创建网页抓取器
+func NewFetcher(cfg *config.FetchConfig, logger *logrus.Logger) *Fetcher {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !cfg.AllowPrivateNetworks {
+		// 在连接建立时检查解析后的IP，防止通过DNS解析或重定向访问内网服务；
+		// 此时不使用环境变量中的代理，否则检查的只是代理地址。
+		dialer := &net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   denyPrivateAddress,
+		}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+
+	f := &Fetcher{
+		cfg:    *cfg,
+		logger: logger,
+	}
+	f.client = &http.Client{
+		Timeout:       time.Duration(cfg.Timeout) * time.Second,
+		Transport:     transport,
+		CheckRedirect: f.checkRedirect,
+	}
+	f.robots = newRobotsCache(f.client, cfg.UserAgent)
+	return f
+}
+
+// DefaultMaxTokens 返回默认的token预算
+func (f *Fetcher) DefaultMaxTokens() int {
+	return f.cfg.MaxTokens
+}
+
+// Fetch 下载网页并提取标题和正文，maxTokens <= 0 时使用配置的默认预算
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string, maxTokens int) (*Page, error) {
+	target, err := parseTarget(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if maxTokens <= 0 {
+		maxTokens = f.cfg.MaxTokens
+	}
+
+	if f.cfg.RespectRobots && !f.robots.Allowed(ctx, target) {
+		return nil, ErrRobotsDisallowed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,text/markdown;q=0.9")
+
+	f.logger.WithField("url", target.String()).Debug("Fetching URL")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrPrivateAddress) || errors.Is(err, ErrRobotsDisallowed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, bodyTruncated, err := readLimited(resp.Body, f.cfg.MaxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+
+	// 统一转换为UTF-8，HTML还会参考 <meta charset>
+	reader, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	page := &Page{
+		URL:           resp.Request.URL.String(),
+		ContentType:   mediaType,
+		BodyTruncated: bodyTruncated,
+	}
+
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		doc, err := ExtractHTML(reader, resp.Request.URL)
+		if err != nil {
+			return nil, err
+		}
+		page.Title = doc.Title
+		page.Markdown = doc.Markdown
+	case "text/plain", "text/markdown", "text/x-markdown":
+		text, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response body: %w", err)
+		}
+		page.Markdown = strings.TrimSpace(string(text))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, mediaType)
+	}
+
+	if bodyTruncated {
+		// 按字节截断的响应体末尾可能是不完整的字符，解码后会变成替换字符
+		page.Markdown = strings.TrimRight(page.Markdown, "\uFFFD")
+	}
+	page.Markdown, page.Truncated = TruncateToTokens(page.Markdown, maxTokens)
+	page.Tokens = llm.EstimateTokens(page.Markdown)
+
+	f.logger.WithFields(logrus.Fields{
+		"url":            page.URL,
+		"content_type":   page.ContentType,
+		"bytes":          len(body),
+		"tokens":         page.Tokens,
+		"truncated":      page.Truncated,
+		"body_truncated": page.BodyTruncated,
+	}).Info("URL fetched")
+
+	return page, nil
+}
+
+// checkRedirect 限制重定向次数，重定向目标同样需要满足协议和 robots.txt 要求
+func (f *Fetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+	if f.cfg.RespectRobots && !f.robots.Allowed(req.Context(), req.URL) {
+		return ErrRobotsDisallowed
+	}
+	return nil
+}
+
+// parseTarget 解析并校验目标URL，只允许 http/https
+func parseTarget(rawURL string) (*url.URL, error) {
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("invalid URL %q: only http and https are supported", rawURL)
+	}
+	if target.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: missing host", rawURL)
+	}
+	target.Fragment = ""
+	return target, nil
+}
+
+// readLimited 最多读取 limit 字节，返回内容是否被截断
+func readLimited(r io.Reader, limit int) ([]byte, bool, error) {
+	body, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(body) > limit {
+		return body[:limit], true, nil
+	}
+	return body, false, nil
+}
+
+// denyPrivateAddress 拒绝连接回环、内网、链路本地等地址
+func denyPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast() {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, ip)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package fetch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatPage 将抓取的网页格式化为工具返回文本：标题、地址、正文和截断提示
+func FormatPage(page *Page) string {
+	title := page.Title
+	if title == "" {
+		title = page.URL
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📰 %s\n🔗 %s\n\n", title, page.URL))
+	if page.Markdown == "" {
+		sb.WriteString("（未能从页面中提取到正文）")
+	} else {
+		sb.WriteString(page.Markdown)
+	}
+	if page.Truncated || page.BodyTruncated {
+		sb.WriteString(fmt.Sprintf("\n\n✂️ 内容过长，已截断（约%d token）", page.Tokens))
+	}
+	return sb.String()
+}
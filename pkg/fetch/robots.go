@@ -0,0 +1,220 @@
+package fetch
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// robotsCacheTTL robots.txt 的缓存时间
+	robotsCacheTTL = time.Hour
+	// maxRobotsBytes robots.txt 最多读取的字节数（RFC 9309 要求至少解析 500KiB）
+	maxRobotsBytes = 512 << 10
+)
+
+// robotsRule 单条 Allow/Disallow 规则
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsRules 针对本爬虫生效的规则组
+type robotsRules struct {
+	rules     []robotsRule
+	expiresAt time.Time
+}
+
+var (
+	allowAll    = &robotsRules{}
+	disallowAll = &robotsRules{rules: []robotsRule{{allow: false, pattern: "/"}}}
+)
+
+// robotsCache 按站点缓存 robots.txt 规则
+type robotsCache struct {
+	client    *http.Client
+	userAgent string
+
+	mu    sync.Mutex
+	hosts map[string]*robotsRules
+}
+
+// newRobotsCache 创建 robots.txt 缓存
+func newRobotsCache(client *http.Client, userAgent string) *robotsCache {
+	return &robotsCache{
+		client:    client,
+		userAgent: userAgent,
+		hosts:     make(map[string]*robotsRules),
+	}
+}
+
+// Allowed 判断是否允许抓取指定URL
+func (c *robotsCache) Allowed(ctx context.Context, target *url.URL) bool {
+	origin := target.Scheme + "://" + target.Host
+
+	c.mu.Lock()
+	rules, ok := c.hosts[origin]
+	c.mu.Unlock()
+	if !ok || time.Now().After(rules.expiresAt) {
+		rules = c.load(ctx, origin)
+		c.mu.Lock()
+		c.hosts[origin] = rules
+		c.mu.Unlock()
+	}
+
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+	return rules.allowed(path)
+}
+
+// load 下载并解析站点的 robots.txt
+// 按 RFC 9309：4xx 视为没有限制，5xx 或网络错误视为全部禁止。
+func (c *robotsCache) load(ctx context.Context, origin string) *robotsRules {
+	expiresAt := time.Now().Add(robotsCacheTTL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return withExpiry(disallowAll, expiresAt)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// 网络错误不长期缓存，避免临时故障导致站点一小时内不可抓取
+		return withExpiry(disallowAll, time.Now().Add(time.Minute))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		rules := parseRobots(io.LimitReader(resp.Body, maxRobotsBytes), productToken(c.userAgent))
+		rules.expiresAt = expiresAt
+		return rules
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return withExpiry(allowAll, expiresAt)
+	default:
+		return withExpiry(disallowAll, time.Now().Add(time.Minute))
+	}
+}
+
+// withExpiry 复制规则并设置过期时间
+func withExpiry(rules *robotsRules, expiresAt time.Time) *robotsRules {
+	return &robotsRules{rules: rules.rules, expiresAt: expiresAt}
+}
+
+// productToken 从 User-Agent 中提取产品名，如 "deer-flow-go/1.0" -> "deer-flow-go"
+func productToken(userAgent string) string {
+	token := strings.Fields(userAgent)
+	if len(token) == 0 {
+		return ""
+	}
+	name, _, _ := strings.Cut(token[0], "/")
+	return strings.ToLower(name)
+}
+
+// parseRobots 解析 robots.txt，返回匹配本爬虫的规则组，没有专属规则组时使用 "*" 规则组
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	var (
+		specific, wildcard []robotsRule
+		hasSpecific        bool
+		groupAgents        []string
+		inRules            bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// 规则之后出现的 User-agent 开始新的规则组
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// 空的 Disallow 表示不限制
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			for _, name := range groupAgents {
+				switch {
+				case agent != "" && name == agent:
+					specific = append(specific, rule)
+					hasSpecific = true
+				case name == "*":
+					wildcard = append(wildcard, rule)
+				}
+			}
+		}
+	}
+
+	if hasSpecific {
+		return &robotsRules{rules: specific}
+	}
+	return &robotsRules{rules: wildcard}
+}
+
+// allowed 按最长匹配原则判断路径是否允许，长度相同时 Allow 优先
+func (r *robotsRules) allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	best := -1
+	allow := true
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best = n
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// matchRobotsPattern 匹配 robots.txt 路径规则，支持 "*" 通配符和 "$" 结尾锚定
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
@@ -0,0 +1,8 @@
+package fetch
+
+import "deer-flow-go/pkg/llm"
+
+// TruncateToTokens 将文本截断到 maxTokens 以内，返回是否发生截断，截断规则见 llm.TruncateToTokens
+func TruncateToTokens(text string, maxTokens int) (string, bool) {
+	return llm.TruncateToTokens(text, maxTokens)
+}
@@ -2,34 +2,52 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
 	"github.com/sirupsen/logrus"
 
 	"deer-flow-go/internal/workflow"
+	"deer-flow-go/pkg/auth"
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/mcp"
 	"deer-flow-go/pkg/models"
 	"deer-flow-go/pkg/queue"
+	"deer-flow-go/pkg/search"
+	"deer-flow-go/pkg/tools"
+	"deer-flow-go/pkg/usage"
 )
 
 // APIHandler API处理器
 type APIHandler struct {
 	agentWorkflow *workflow.AgentWorkflow
 	queueManager  *queue.QueueManager
+	keyStore      *auth.KeyStore
+	ledger        *usage.Ledger
 	logger        *logrus.Logger
 }
 
-// NewAPIHandler 创建新的API处理器
-func NewAPIHandler(agentWorkflow *workflow.AgentWorkflow, queueManager *queue.QueueManager, logger *logrus.Logger) *APIHandler {
+// NewAPIHandler 创建新的API处理器，keyStore 为空时不做鉴权，ledger 为空时不检查配额
+func NewAPIHandler(agentWorkflow *workflow.AgentWorkflow, queueManager *queue.QueueManager, keyStore *auth.KeyStore, ledger *usage.Ledger, logger *logrus.Logger) *APIHandler {
 	return &APIHandler{
 		agentWorkflow: agentWorkflow,
 		queueManager:  queueManager,
+		keyStore:      keyStore,
+		ledger:        ledger,
 		logger:        logger,
 	}
 }
 
+// requireScope 返回指定权限范围的鉴权中间件
+func (h *APIHandler) requireScope(scope string) gin.HandlerFunc {
+	return AuthMiddleware(h.keyStore, scope, h.logger)
+}
+
 // SetupRoutes 设置API路由
 func (h *APIHandler) SetupRoutes(router *gin.Engine) {
 	// 健康检查
@@ -39,14 +57,36 @@ func (h *APIHandler) SetupRoutes(router *gin.Engine) {
 	api := router.Group("/api")
 	{
 		// 聊天相关
-		api.POST("/chat", h.Chat)
+		api.POST("/chat", h.requireScope(config.ScopeChat), h.Chat)
+
+		// 可作为参考资料附加到聊天请求的MCP资源
+		api.GET("/resources", h.requireScope(config.ScopeChat), h.ListResources)
+
+		// 可通过 prompt 字段在聊天请求中使用的MCP提示词
+		api.GET("/prompts", h.requireScope(config.ScopeChat), h.ListPrompts)
 		
 		// 工作流状态
-		api.GET("/workflow/status", h.WorkflowStatus)
+		api.GET("/workflow/status", h.requireScope(config.ScopeStatus), h.WorkflowStatus)
 		
 		// 队列状态
-		api.GET("/queue/status", h.QueueStatus)
-		api.GET("/queue/stats", h.QueueStats)
+		api.GET("/queue/status", h.requireScope(config.ScopeStatus), h.QueueStatus)
+		api.GET("/queue/stats", h.requireScope(config.ScopeStatus), h.QueueStats)
+
+		// 搜索缓存统计
+		api.GET("/search/cache/stats", h.requireScope(config.ScopeStatus), h.SearchCacheStats)
+
+		// LLM调用统计
+		api.GET("/llm/stats", h.requireScope(config.ScopeStatus), h.LLMStats)
+
+		// 用量报表
+		api.GET("/usage", h.requireScope(config.ScopeUsage), h.Usage)
+	}
+
+	// OpenAI兼容接口
+	v1 := router.Group("/v1")
+	{
+		v1.POST("/chat/completions", h.requireScope(config.ScopeChat), h.ChatCompletions)
+		v1.GET("/models", h.requireScope(config.ScopeChat), h.ListModels)
 	}
 }
 
@@ -69,63 +109,156 @@ func (h *APIHandler) Chat(c *gin.Context) {
 	}
 	
 	h.logger.WithFields(logrus.Fields{
-		"query":         req.Query,
+		"query_length":   len(req.Query),
 		"messages_count": len(req.Messages),
+		"resources":      len(req.Resources),
+		"prompt":         req.Prompt,
 	}).Info("Received chat request")
 	
-	// 创建上下文
+	// 创建上下文，附加的资源随上下文传给工作流
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
 	defer cancel()
+	ctx = workflow.WithResources(ctx, req.Resources)
+	ctx = tools.WithOwner(ctx, resourceOwner(c))
+
+	// 排队之前检查租户配额
+	ctx, quotaErr := h.checkQuota(ctx, c)
+	if quotaErr != nil {
+		respondQuotaExceeded(c, quotaErr)
+		return
+	}
+
+	// 使用提示词时，渲染结果作为问题，用户输入附加在其后
+	query := req.Query
+	if req.Prompt != "" {
+		rendered, err := h.agentWorkflow.RenderPrompt(ctx, req.Prompt, req.PromptArguments)
+		if err != nil {
+			h.respondPromptError(c, req.Prompt, err)
+			return
+		}
+		if query != "" {
+			rendered += "\n\n" + query
+		}
+		query = rendered
+	}
 	
 	// 使用队列管理器处理请求
-	resp, err := h.queueManager.SubmitRequest(ctx, req.Query)
+	resp, err := h.queueManager.SubmitRequest(ctx, query)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to process query through queue")
 		
-		// 根据错误类型返回不同的HTTP状态码
-		errorMsg := err.Error()
-		if strings.Contains(errorMsg, "request queue is full") || strings.Contains(errorMsg, "timeout after") {
-			// 队列超时 - 服务暂时不可用
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error": "Service temporarily unavailable, please try again later",
-				"code":  "QUEUE_TIMEOUT",
-				"details": errorMsg,
-			})
-		} else if strings.Contains(errorMsg, "request timeout") {
-			// 请求超时
-			c.JSON(http.StatusRequestTimeout, gin.H{
-				"error": "Request timeout, please try again",
-				"code":  "REQUEST_TIMEOUT",
-				"details": errorMsg,
-			})
-		} else if strings.Contains(errorMsg, "context canceled") || strings.Contains(errorMsg, "context deadline exceeded") {
-			// 上下文取消或超时
-			c.JSON(http.StatusRequestTimeout, gin.H{
-				"error": "Request was cancelled or timed out",
-				"code":  "CONTEXT_TIMEOUT",
-				"details": errorMsg,
-			})
-		} else if strings.Contains(errorMsg, "queue manager is not running") {
-			// 队列管理器未运行
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error": "Service is currently unavailable",
-				"code":  "SERVICE_UNAVAILABLE",
-				"details": errorMsg,
-			})
-		} else {
-			// 其他内部错误
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Internal server error",
-				"code":  "INTERNAL_ERROR",
-				"details": errorMsg,
-			})
-		}
+		status, code, message := classifyQueueError(err)
+		c.JSON(status, gin.H{
+			"error":   message,
+			"code":    code,
+			"details": err.Error(),
+		})
 		return
 	}
 	
 	c.JSON(http.StatusOK, resp)
 }
 
+// resourceOwner 返回请求所属的租户，搜索结果、研究报告等资源按租户隔离
+func resourceOwner(c *gin.Context) string {
+	return requestIdentity(c).Tenant
+}
+
+// ListResources 列出MCP服务器当前提供的资源，搜索结果和研究报告只包含调用方租户的
+func (h *APIHandler) ListResources(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(tools.WithOwner(c.Request.Context(), resourceOwner(c)), 5*time.Second)
+	defer cancel()
+
+	resources, err := h.agentWorkflow.ListResources(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list MCP resources")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "MCP server is unavailable",
+			"code":  "SERVICE_UNAVAILABLE",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resources": resources,
+		"timestamp": time.Now(),
+	})
+}
+
+// ListPrompts 列出MCP服务器提供的提示词
+func (h *APIHandler) ListPrompts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	prompts, err := h.agentWorkflow.ListPrompts(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list MCP prompts")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "MCP server is unavailable",
+			"code":  "SERVICE_UNAVAILABLE",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prompts":   prompts,
+		"timestamp": time.Now(),
+	})
+}
+
+// respondPromptError 返回提示词渲染失败的响应：不存在返回404，参数错误返回400，
+// MCP服务器内部错误返回500，其他RPC或传输错误返回503
+func (h *APIHandler) respondPromptError(c *gin.Context, name string, err error) {
+	var rpcErr *mcp.RPCError
+	switch {
+	case errors.Is(err, mcp.ErrPromptNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Prompt not found",
+			"code":  "PROMPT_NOT_FOUND",
+		})
+	case errors.As(err, &rpcErr) && rpcErr.Code == mcpgo.INVALID_PARAMS:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to render prompt",
+			"code":    "INVALID_PROMPT",
+			"details": rpcErr.Message,
+		})
+	case errors.As(err, &rpcErr) && rpcErr.Code == mcpgo.INTERNAL_ERROR:
+		h.logger.WithError(err).WithField("prompt", name).Error("MCP server failed to render prompt")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to render prompt",
+			"code":  "INTERNAL_ERROR",
+		})
+	default:
+		h.logger.WithError(err).WithField("prompt", name).Error("Failed to render prompt")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "MCP server is unavailable",
+			"code":  "SERVICE_UNAVAILABLE",
+		})
+	}
+}
+
+// classifyQueueError 根据队列返回的错误类型确定HTTP状态码、错误码和提示信息
+func classifyQueueError(err error) (int, string, string) {
+	errorMsg := err.Error()
+	switch {
+	case strings.Contains(errorMsg, "request queue is full") || strings.Contains(errorMsg, "timeout after"):
+		// 队列超时 - 服务暂时不可用
+		return http.StatusServiceUnavailable, "QUEUE_TIMEOUT", "Service temporarily unavailable, please try again later"
+	case strings.Contains(errorMsg, "request timeout"):
+		// 请求超时
+		return http.StatusRequestTimeout, "REQUEST_TIMEOUT", "Request timeout, please try again"
+	case strings.Contains(errorMsg, "context canceled") || strings.Contains(errorMsg, "context deadline exceeded"):
+		// 上下文取消或超时
+		return http.StatusRequestTimeout, "CONTEXT_TIMEOUT", "Request was cancelled or timed out"
+	case strings.Contains(errorMsg, "queue manager is not running"):
+		// 队列管理器未运行
+		return http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "Service is currently unavailable"
+	default:
+		// 其他内部错误
+		return http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error"
+	}
+}
+
 // WorkflowStatus 工作流状态处理器
 func (h *APIHandler) WorkflowStatus(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
@@ -159,4 +292,57 @@ func (h *APIHandler) QueueStats(c *gin.Context) {
 	stats["timestamp"] = time.Now()
 	
 	c.JSON(http.StatusOK, stats)
-}
\ No newline at end of file
+}
+
+// LLMStats LLM调用统计处理器，包括各部署的重试、熔断、备用部署切换和响应缓存统计
+func (h *APIHandler) LLMStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"llm":       h.agentWorkflow.LLMStats(),
+		"timestamp": time.Now(),
+	})
+}
+
+// SearchCacheStats 搜索缓存统计处理器，统计信息由MCP服务器的 search_cache_stats 工具提供
+func (h *APIHandler) SearchCacheStats(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	resp, err := h.agentWorkflow.CallTool(ctx, search.CacheStatsToolName, nil)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get search cache stats")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "MCP server is unavailable",
+			"code":  "SERVICE_UNAVAILABLE",
+		})
+		return
+	}
+	if resp.Error != nil {
+		// 工具未注册说明缓存未启用
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Search cache is not enabled",
+			"code":  "NOT_FOUND",
+		})
+		return
+	}
+
+	var (
+		stats   search.CacheStats
+		content string
+	)
+	if result, ok := resp.Result.(map[string]interface{}); ok {
+		content, _ = result["content"].(string)
+	}
+	if err := json.Unmarshal([]byte(content), &stats); err != nil {
+		h.logger.WithError(err).Error("Invalid search cache stats response")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Invalid search cache stats response",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cache":     stats,
+		"timestamp": time.Now(),
+	})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"deer-flow-go/pkg/mcp"
+)
+
+func TestRespondPromptError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	h := NewAPIHandler(nil, nil, nil, nil, logger)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", fmt.Errorf("%w: missing", mcp.ErrPromptNotFound), http.StatusNotFound, "PROMPT_NOT_FOUND"},
+		{"invalid params", &mcp.RPCError{Code: mcpgo.INVALID_PARAMS, Message: "missing argument"}, http.StatusBadRequest, "INVALID_PROMPT"},
+		{"internal error", &mcp.RPCError{Code: mcpgo.INTERNAL_ERROR, Message: "template failed"}, http.StatusInternalServerError, "INTERNAL_ERROR"},
+		{"other rpc error", &mcp.RPCError{Code: mcpgo.METHOD_NOT_FOUND, Message: "no such method"}, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE"},
+		{"transport error", errors.New("broken pipe"), http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			h.respondPromptError(c, "summary", tt.err)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), tt.code)
+		})
+	}
+}
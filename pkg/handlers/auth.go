@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/auth"
+)
+
+// APIKeyContextKey gin上下文中保存已认证密钥的键
+const APIKeyContextKey = "api_key"
+
+// AuthMiddleware API密钥鉴权与限流中间件
+// 密钥通过 "Authorization: Bearer <key>" 或 "X-API-Key" 请求头传递。
+// 未提供或无效返回401，权限不足返回403，超出每分钟请求数或并发数返回429并附带Retry-After。
+func AuthMiddleware(keyStore *auth.KeyStore, scope string, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if keyStore == nil || !keyStore.Enabled() {
+			c.Next()
+			return
+		}
+
+		rawKey := extractAPIKey(c.Request)
+		if rawKey == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="deer-flow-go"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Missing API key",
+				"code":  "UNAUTHORIZED",
+			})
+			return
+		}
+
+		key, ok := keyStore.Authenticate(rawKey)
+		if !ok {
+			logger.WithField("client_ip", c.ClientIP()).Warn("Rejected request with invalid API key")
+			c.Header("WWW-Authenticate", `Bearer realm="deer-flow-go", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid API key",
+				"code":  "UNAUTHORIZED",
+			})
+			return
+		}
+
+		if !key.HasScope(scope) {
+			logger.WithFields(logrus.Fields{
+				"key_name": key.Name,
+				"scope":    scope,
+			}).Warn("API key lacks required scope")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "API key is not allowed to access this endpoint",
+				"code":  "FORBIDDEN",
+				"scope": scope,
+			})
+			return
+		}
+
+		// 先检查并发数，因并发超限被拒绝的请求不消耗每分钟请求配额
+		if !key.Concurrency().Acquire() {
+			c.Header("Retry-After", "1")
+			logger.WithField("key_name", key.Name).Warn("API key concurrency limit exceeded")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many concurrent requests, please retry later",
+				"code":        "CONCURRENCY_LIMITED",
+				"retry_after": 1,
+			})
+			return
+		}
+		defer key.Concurrency().Release()
+
+		allowed, wait := key.Bucket().Allow(time.Now())
+		if limit := key.Bucket().Limit(); limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(key.Bucket().Remaining()))
+		}
+		if !allowed {
+			retryAfter := retryAfterSeconds(wait)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			logger.WithField("key_name", key.Name).Warn("API key rate limit exceeded")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Rate limit exceeded, please retry later",
+				"code":        "RATE_LIMITED",
+				"retry_after": retryAfter,
+			})
+			return
+		}
+
+		c.Set(APIKeyContextKey, key)
+		c.Next()
+	}
+}
+
+// extractAPIKey 从请求头中提取API密钥
+func extractAPIKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "Bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
+
+// retryAfterSeconds 将等待时间向上取整为秒，至少为1秒
+func retryAfterSeconds(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
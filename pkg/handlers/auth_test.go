@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/auth"
+	"deer-flow-go/pkg/config"
+)
+
+func newAuthTestRouter(t *testing.T, cfg config.AuthConfig, handler gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	store := auth.NewKeyStore(cfg)
+	router := gin.New()
+	router.GET("/chat", AuthMiddleware(store, config.ScopeChat, logger), handler)
+	return router
+}
+
+func doAuthRequest(router *gin.Engine, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/chat", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func okHandler(c *gin.Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+func TestAuthMiddleware_Disabled(t *testing.T) {
+	router := newAuthTestRouter(t, config.AuthConfig{Enabled: false}, okHandler)
+
+	w := doAuthRequest(router, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthMiddleware_Unauthorized(t *testing.T) {
+	router := newAuthTestRouter(t, config.AuthConfig{
+		Enabled: true,
+		Keys:    []config.APIKeyConfig{{Name: "app", Key: "secret-key", Scopes: []string{config.ScopeChat}}},
+	}, okHandler)
+
+	w := doAuthRequest(router, "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	w = doAuthRequest(router, "X-API-Key", "wrong-key")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doAuthRequest(router, "Authorization", "Bearer secret-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doAuthRequest(router, "X-API-Key", "secret-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthMiddleware_Forbidden(t *testing.T) {
+	router := newAuthTestRouter(t, config.AuthConfig{
+		Enabled: true,
+		Keys: []config.APIKeyConfig{{
+			Name:      "monitor",
+			KeySHA256: config.HashAPIKey("monitor-key"),
+			Scopes:    []string{config.ScopeStatus},
+		}},
+	}, okHandler)
+
+	w := doAuthRequest(router, "X-API-Key", "monitor-key")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "FORBIDDEN")
+}
+
+func TestAuthMiddleware_RateLimited(t *testing.T) {
+	router := newAuthTestRouter(t, config.AuthConfig{
+		Enabled: true,
+		Keys: []config.APIKeyConfig{{
+			Name:              "app",
+			Key:               "secret-key",
+			Scopes:            []string{config.ScopeAll},
+			RequestsPerMinute: 2,
+		}},
+	}, okHandler)
+
+	for i := 0; i < 2; i++ {
+		w := doAuthRequest(router, "X-API-Key", "secret-key")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	w := doAuthRequest(router, "X-API-Key", "secret-key")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "RATE_LIMITED")
+	// 每分钟2次，补充一个令牌约需30秒
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+}
+
+func TestAuthMiddleware_ConcurrencyLimited(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router := newAuthTestRouter(t, config.AuthConfig{
+		Enabled: true,
+		Keys: []config.APIKeyConfig{{
+			Name:          "app",
+			Key:           "secret-key",
+			Scopes:        []string{config.ScopeChat},
+			MaxConcurrent: 1,
+		}},
+	}, func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.String(http.StatusOK, "ok")
+	})
+
+	done := make(chan int)
+	go func() {
+		done <- doAuthRequest(router, "X-API-Key", "secret-key").Code
+	}()
+	<-entered
+
+	w := doAuthRequest(router, "X-API-Key", "secret-key")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "CONCURRENCY_LIMITED")
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+
+	// 名额释放后可以再次请求
+	go func() { <-entered }()
+	w = doAuthRequest(router, "X-API-Key", "secret-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthMiddleware_ConcurrencyRejectionKeepsRateLimitTokens(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router := newAuthTestRouter(t, config.AuthConfig{
+		Enabled: true,
+		Keys: []config.APIKeyConfig{{
+			Name:              "app",
+			Key:               "secret-key",
+			Scopes:            []string{config.ScopeChat},
+			RequestsPerMinute: 2,
+			MaxConcurrent:     1,
+		}},
+	}, func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.String(http.StatusOK, "ok")
+	})
+
+	done := make(chan int)
+	go func() {
+		done <- doAuthRequest(router, "X-API-Key", "secret-key").Code
+	}()
+	<-entered
+
+	// 并发超限被拒绝的请求不消耗令牌
+	for i := 0; i < 3; i++ {
+		w := doAuthRequest(router, "X-API-Key", "secret-key")
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Body.String(), "CONCURRENCY_LIMITED")
+	}
+
+	close(release)
+	require.Equal(t, http.StatusOK, <-done)
+
+	go func() { <-entered }()
+	w := doAuthRequest(router, "X-API-Key", "secret-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+}
@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/llm"
+	"deer-flow-go/pkg/models"
+	"deer-flow-go/pkg/tools"
+)
+
+const (
+	// AgentModelID OpenAI兼容接口对外暴露的模型名
+	AgentModelID = "deer-flow-go"
+
+	// streamChunkRunes 流式输出时每个数据块包含的字符数
+	streamChunkRunes = 20
+	// streamKeepAliveInterval 等待智能体结果期间发送SSE注释保活的间隔
+	streamKeepAliveInterval = 15 * time.Second
+	// maxHistoryMessages 拼接到查询中的历史消息条数上限
+	maxHistoryMessages = 6
+)
+
+// modelCreated 模型列表中的创建时间
+var modelCreated = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+// ListModels 列出可用模型（OpenAI兼容）
+func (h *APIHandler) ListModels(c *gin.Context) {
+	c.JSON(http.StatusOK, models.OpenAIModelList{
+		Object: "list",
+		Data: []models.OpenAIModel{{
+			ID:      AgentModelID,
+			Object:  "model",
+			Created: modelCreated,
+			OwnedBy: "deer-flow-go",
+		}},
+	})
+}
+
+// ChatCompletions 对话补全（OpenAI兼容），请求由智能体工作流及其工具处理
+func (h *APIHandler) ChatCompletions(c *gin.Context) {
+	var req models.OpenAIChatCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		openAIError(c, http.StatusBadRequest, "invalid_request_error", "", "", fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if req.Model != "" && req.Model != AgentModelID {
+		openAIError(c, http.StatusNotFound, "invalid_request_error", "model", "model_not_found",
+			fmt.Sprintf("The model '%s' does not exist", req.Model))
+		return
+	}
+	history, query, err := buildAgentQuery(req.Messages)
+	if err != nil {
+		openAIError(c, http.StatusBadRequest, "invalid_request_error", "messages", "", err.Error())
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"messages_count": len(req.Messages),
+		"query_length":   len(query),
+		"stream":         req.Stream,
+	}).Info("Received chat completion request")
+
+	ctx, quotaErr := h.checkQuota(c.Request.Context(), c)
+	if quotaErr != nil {
+		openAIError(c, http.StatusTooManyRequests, "insufficient_quota", "", "quota_exceeded", quotaErr.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.queueManager.RequestTimeout())
+	defer cancel()
+	ctx = llm.WithHistory(ctx, history)
+	ctx = tools.WithOwner(ctx, resourceOwner(c))
+
+	completion := &chatCompletion{
+		id:      newCompletionID(),
+		created: time.Now().Unix(),
+		prompt:  req.Messages,
+	}
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		h.streamChatCompletion(ctx, c, completion, query, includeUsage)
+		return
+	}
+
+	resp, err := h.queueManager.SubmitRequest(ctx, query)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to process chat completion through queue")
+		status, code, message := classifyQueueError(err)
+		openAIError(c, status, openAIErrorType(status), "", strings.ToLower(code), message)
+		return
+	}
+	if !resp.Success {
+		openAIError(c, http.StatusBadGateway, "server_error", "", "agent_error", agentErrorMessage(resp))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.OpenAIChatCompletionResponse{
+		ID:      completion.id,
+		Object:  "chat.completion",
+		Created: completion.created,
+		Model:   AgentModelID,
+		Choices: []models.OpenAIChoice{{
+			Index:        0,
+			Message:      models.OpenAIResponseMessage{Role: "assistant", Content: resp.Response},
+			FinishReason: "stop",
+		}},
+		Usage: completion.usage(resp),
+	})
+}
+
+// streamChatCompletion 以SSE格式返回对话补全
+// 由LLM生成的最终回复（整理搜索结果）通过 llm.ChatCompletionStream 边生成边输出；
+// 工具直接返回的回复在智能体处理完成后按块输出。等待期间定期发送注释行保活，最后发送 [DONE]。
+func (h *APIHandler) streamChatCompletion(ctx context.Context, c *gin.Context, completion *chatCompletion, query string, includeUsage bool) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	writeEvent := func(payload interface{}) bool {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to encode stream chunk")
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		w.Flush()
+		return true
+	}
+
+	if !writeEvent(completion.chunk(models.OpenAIDelta{Role: "assistant"}, nil)) {
+		return
+	}
+
+	// LLM生成的文本增量由工作协程写入，这里转发给客户端
+	deltas := make(chan string, 64)
+	ctx = llm.WithStreamOutput(ctx, func(delta string) {
+		select {
+		case deltas <- delta:
+		case <-ctx.Done():
+		}
+	})
+
+	type result struct {
+		resp *models.ChatResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := h.queueManager.SubmitRequest(ctx, query)
+		done <- result{resp: resp, err: err}
+	}()
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	var (
+		res      result
+		streamed strings.Builder
+	)
+	forward := func(delta string) bool {
+		streamed.WriteString(delta)
+		return writeEvent(completion.chunk(models.OpenAIDelta{Content: delta}, nil))
+	}
+waitLoop:
+	for {
+		select {
+		case res = <-done:
+			break waitLoop
+		case delta := <-deltas:
+			if !forward(delta) {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		case <-c.Request.Context().Done():
+			h.logger.Debug("Client disconnected during streaming chat completion")
+			return
+		}
+	}
+
+	// 处理完成前写入的增量可能还在缓冲区中
+	for pending := true; pending; {
+		select {
+		case delta := <-deltas:
+			if !forward(delta) {
+				return
+			}
+		default:
+			pending = false
+		}
+	}
+
+	if res.err != nil || !res.resp.Success {
+		message := ""
+		if res.err != nil {
+			h.logger.WithError(res.err).Error("Failed to process streaming chat completion through queue")
+			_, _, message = classifyQueueError(res.err)
+		} else {
+			message = agentErrorMessage(res.resp)
+		}
+		writeEvent(models.OpenAIErrorResponse{Error: models.OpenAIErrorDetail{Message: message, Type: "server_error"}})
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		w.Flush()
+		return
+	}
+
+	// 已流式输出的部分不再重复；流中途失败后工作流改用了其他回复时，另起一段输出完整回复
+	remaining := res.resp.Response
+	if streamed.Len() > 0 {
+		if strings.HasPrefix(remaining, streamed.String()) {
+			remaining = remaining[streamed.Len():]
+		} else {
+			remaining = "\n\n" + remaining
+		}
+	}
+	for _, piece := range splitRunes(remaining, streamChunkRunes) {
+		if !writeEvent(completion.chunk(models.OpenAIDelta{Content: piece}, nil)) {
+			return
+		}
+	}
+
+	stop := "stop"
+	writeEvent(completion.chunk(models.OpenAIDelta{}, &stop))
+	if includeUsage {
+		usage := completion.usage(res.resp)
+		writeEvent(models.OpenAIChatCompletionChunk{
+			ID:      completion.id,
+			Object:  "chat.completion.chunk",
+			Created: completion.created,
+			Model:   AgentModelID,
+			Choices: []models.OpenAIChunkChoice{},
+			Usage:   &usage,
+		})
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	w.Flush()
+}
+
+// chatCompletion 一次对话补全的公共信息
+type chatCompletion struct {
+	id      string
+	created int64
+	prompt  []models.OpenAIMessage
+}
+
+// chunk 构造流式数据块
+func (cc *chatCompletion) chunk(delta models.OpenAIDelta, finishReason *string) models.OpenAIChatCompletionChunk {
+	return models.OpenAIChatCompletionChunk{
+		ID:      cc.id,
+		Object:  "chat.completion.chunk",
+		Created: cc.created,
+		Model:   AgentModelID,
+		Choices: []models.OpenAIChunkChoice{{
+			Index:        0,
+			Delta:        delta,
+			FinishReason: finishReason,
+		}},
+	}
+}
+
+// usage 返回本次补全的token用量，智能体返回了实际用量时使用实际用量，否则按消息估算
+func (cc *chatCompletion) usage(resp *models.ChatResponse) models.OpenAIUsage {
+	if resp.Usage != nil {
+		return models.OpenAIUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		}
+	}
+
+	contents := make([]string, 0, len(cc.prompt))
+	for _, msg := range cc.prompt {
+		contents = append(contents, string(msg.Content))
+	}
+	promptTokens := llm.EstimateMessagesTokens(contents...)
+	completionTokens := llm.EstimateTokens(resp.Response)
+	return models.OpenAIUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
+
+// buildAgentQuery 将对话消息转换为智能体查询
+// 最后一条用户消息作为当前问题，之前的若干轮对话作为历史返回，便于解析“明天呢”之类的追问。
+func buildAgentQuery(messages []models.OpenAIMessage) ([]models.ChatMessage, string, error) {
+	if len(messages) == 0 {
+		return nil, "", fmt.Errorf("messages must not be empty")
+	}
+
+	last := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			last = i
+			break
+		}
+	}
+	if last < 0 || strings.TrimSpace(string(messages[last].Content)) == "" {
+		return nil, "", fmt.Errorf("messages must contain a non-empty user message")
+	}
+
+	question := strings.TrimSpace(string(messages[last].Content))
+
+	var history []models.ChatMessage
+	for i := last - 1; i >= 0 && len(history) < maxHistoryMessages; i-- {
+		msg := messages[i]
+		content := strings.TrimSpace(string(msg.Content))
+		if content == "" || (msg.Role != "user" && msg.Role != "assistant" && msg.Role != "system") {
+			continue
+		}
+		history = append([]models.ChatMessage{{Role: msg.Role, Content: content}}, history...)
+	}
+
+	// 历史通过上下文交给LLM客户端，上下文窗口不足时从最早的历史开始省略
+	return history, question, nil
+}
+
+// splitRunes 按字符数切分文本，不会截断多字节字符
+func splitRunes(text string, size int) []string {
+	runes := []rune(text)
+	pieces := make([]string, 0, len(runes)/size+1)
+	for start := 0; start < len(runes); start += size {
+		end := start + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		pieces = append(pieces, string(runes[start:end]))
+	}
+	return pieces
+}
+
+// agentErrorMessage 返回智能体处理失败时的错误信息
+func agentErrorMessage(resp *models.ChatResponse) string {
+	if resp.Error != "" {
+		return resp.Error
+	}
+	return resp.Response
+}
+
+// openAIErrorType 根据HTTP状态码确定OpenAI错误类型
+func openAIErrorType(status int) string {
+	if status >= http.StatusInternalServerError {
+		return "server_error"
+	}
+	return "invalid_request_error"
+}
+
+// openAIError 返回OpenAI格式的错误响应
+func openAIError(c *gin.Context, status int, errType, param, code, message string) {
+	detail := models.OpenAIErrorDetail{Message: message, Type: errType}
+	if param != "" {
+		detail.Param = &param
+	}
+	if code != "" {
+		detail.Code = &code
+	}
+	c.AbortWithStatusJSON(status, models.OpenAIErrorResponse{Error: detail})
+}
+
+// newCompletionID 生成对话补全ID
+func newCompletionID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+	}
+	return "chatcmpl-" + hex.EncodeToString(buf)
+}
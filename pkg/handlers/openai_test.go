@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/internal/workflow"
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/llm"
+	"deer-flow-go/pkg/mcp"
+	"deer-flow-go/pkg/models"
+	"deer-flow-go/pkg/queue"
+	"deer-flow-go/pkg/search"
+	"deer-flow-go/pkg/tools"
+	"deer-flow-go/pkg/usage"
+)
+
+// echoProcessor 回显查询的请求处理器，记录收到的问题和对话历史
+type echoProcessor struct {
+	queries   chan string
+	histories chan []models.ChatMessage
+}
+
+func (p *echoProcessor) ProcessRequest(ctx context.Context, query string) (*models.ChatResponse, error) {
+	p.queries <- query
+	p.histories <- llm.HistoryFromContext(ctx)
+	return &models.ChatResponse{Response: "回复：" + query, Success: true, Timestamp: time.Now()}, nil
+}
+
+func newOpenAITestRouter(t *testing.T) (*gin.Engine, *echoProcessor) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	processor := &echoProcessor{queries: make(chan string, 10), histories: make(chan []models.ChatMessage, 10)}
+	queueManager := queue.NewQueueManager(&queue.QueueConfig{
+		MaxWorkers:     1,
+		QueueSize:      10,
+		RequestTimeout: 5 * time.Second,
+		QueueTimeout:   5 * time.Second,
+	}, processor, logger)
+	require.NoError(t, queueManager.Start())
+	t.Cleanup(queueManager.Stop)
+
+	router := gin.New()
+	NewAPIHandler(nil, queueManager, nil, nil, logger).SetupRoutes(router)
+	return router, processor
+}
+
+func postJSON(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestListModels(t *testing.T) {
+	router, _ := newOpenAITestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var list models.OpenAIModelList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, AgentModelID, list.Data[0].ID)
+}
+
+func TestChatCompletions(t *testing.T) {
+	router, processor := newOpenAITestRouter(t)
+
+	w := postJSON(router, "/v1/chat/completions", `{
+		"model": "deer-flow-go",
+		"messages": [
+			{"role": "system", "content": "You are helpful"},
+			{"role": "user", "content": [{"type": "text", "text": "北京天气"}]}
+		]
+	}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp models.OpenAIChatCompletionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "chat.completion", resp.Object)
+	assert.True(t, strings.HasPrefix(resp.ID, "chatcmpl-"))
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "assistant", resp.Choices[0].Message.Role)
+	assert.Contains(t, resp.Choices[0].Message.Content, "北京天气")
+	assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+	assert.Greater(t, resp.Usage.PromptTokens, 0)
+	assert.Equal(t, resp.Usage.PromptTokens+resp.Usage.CompletionTokens, resp.Usage.TotalTokens)
+
+	assert.Equal(t, "北京天气", <-processor.queries)
+	assert.Equal(t, []models.ChatMessage{{Role: "system", Content: "You are helpful"}}, <-processor.histories)
+}
+
+func TestChatCompletions_InvalidRequests(t *testing.T) {
+	router, _ := newOpenAITestRouter(t)
+
+	w := postJSON(router, "/v1/chat/completions", `{"model": "gpt-4", "messages": [{"role": "user", "content": "hi"}]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "model_not_found")
+
+	w = postJSON(router, "/v1/chat/completions", `{"messages": [{"role": "assistant", "content": "hi"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_request_error")
+}
+
+func TestChatCompletions_Stream(t *testing.T) {
+	router, _ := newOpenAITestRouter(t)
+
+	w := postJSON(router, "/v1/chat/completions", `{
+		"messages": [{"role": "user", "content": "上海明天会下雨吗，需要带伞吗"}],
+		"stream": true,
+		"stream_options": {"include_usage": true}
+	}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	var (
+		content  strings.Builder
+		finished bool
+		usage    *models.OpenAIUsage
+		done     bool
+	)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		data := strings.TrimPrefix(line, "data: ")
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+
+		var chunk models.OpenAIChatCompletionChunk
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		assert.Equal(t, "chat.completion.chunk", chunk.Object)
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+			if choice.FinishReason != nil {
+				finished = true
+			}
+		}
+	}
+
+	assert.Equal(t, "回复：上海明天会下雨吗，需要带伞吗", content.String())
+	assert.True(t, finished)
+	assert.True(t, done)
+	require.NotNil(t, usage)
+	assert.Greater(t, usage.CompletionTokens, 0)
+}
+
+// streamingProcessor 通过上下文中的流式输出回调逐段输出回复
+type streamingProcessor struct {
+	pieces []string
+	final  string
+}
+
+func (p *streamingProcessor) ProcessRequest(ctx context.Context, query string) (*models.ChatResponse, error) {
+	onDelta := llm.StreamOutputFromContext(ctx)
+	if onDelta == nil {
+		return nil, fmt.Errorf("stream output missing from context")
+	}
+	for _, piece := range p.pieces {
+		onDelta(piece)
+	}
+	return &models.ChatResponse{Response: p.final, Success: true, Timestamp: time.Now()}, nil
+}
+
+// readStreamContent 读取SSE响应并拼接所有文本增量
+func readStreamContent(t *testing.T, body string) string {
+	t.Helper()
+	var content strings.Builder
+	for _, line := range strings.Split(body, "\n") {
+		data := strings.TrimPrefix(line, "data: ")
+		if data == line || data == "[DONE]" {
+			continue
+		}
+		var chunk models.OpenAIChatCompletionChunk
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+		}
+	}
+	return content.String()
+}
+
+func TestChatCompletions_StreamForwardsLLMDeltas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	tests := []struct {
+		name      string
+		processor *streamingProcessor
+		want      string
+	}{
+		{"streamed reply", &streamingProcessor{pieces: []string{"北京", "今天", "晴"}, final: "北京今天晴"}, "北京今天晴"},
+		{"fallback after partial stream", &streamingProcessor{pieces: []string{"北京"}, final: "搜索摘要"}, "北京\n\n搜索摘要"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queueManager := queue.NewQueueManager(&queue.QueueConfig{
+				MaxWorkers:     1,
+				QueueSize:      1,
+				RequestTimeout: 5 * time.Second,
+				QueueTimeout:   5 * time.Second,
+			}, tt.processor, logger)
+			require.NoError(t, queueManager.Start())
+			defer queueManager.Stop()
+
+			router := gin.New()
+			NewAPIHandler(nil, queueManager, nil, nil, logger).SetupRoutes(router)
+
+			w := postJSON(router, "/v1/chat/completions", `{"messages": [{"role": "user", "content": "北京天气"}], "stream": true}`)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, readStreamContent(t, w.Body.String()))
+		})
+	}
+}
+
+// newRoutingLLMServer 模拟 Azure OpenAI：路由请求返回调用 search 工具的JSON，流式请求按给定的增量返回最终回复
+func newRoutingLLMServer(t *testing.T, pieces []string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Stream bool `json:"stream"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if !req.Stream {
+			route, _ := json.Marshal(`{"method": "search", "params": {"query": "goroutine"}}`)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":%s},"finish_reason":"stop"}]}`, route)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, piece := range pieces {
+			delta, _ := json.Marshal(piece)
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%s}}]}\n\n", delta)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestChatCompletions_StreamThroughMCPSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	// 真正的MCP客户端连接进程内工具服务器，搜索工具使用本地索引
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.md"), []byte("# Go语言\n\ngoroutine 非常轻量。"), 0o600))
+	cfg := &config.Config{
+		Search: config.SearchConfig{
+			Provider:   config.SearchProviderLocal,
+			MaxResults: 5,
+			Timeout:    10,
+			Local:      config.LocalIndexConfig{Path: dir},
+		},
+	}
+	providers, err := search.NewRegistry(cfg, logger)
+	require.NoError(t, err)
+	registry := tools.NewRegistry(logger)
+	registry.Register(tools.SearchTools(providers, logger)...)
+	mcpClient := mcp.NewInProcessClient("tools", registry.NewMCPServer("test-server", "1.0.0"), logger)
+	require.NoError(t, mcpClient.Start(context.Background()))
+	t.Cleanup(func() { mcpClient.Stop() })
+
+	pieces := []string{"goroutine ", "是Go语言", "的轻量线程。"}
+	cfg.AzureOpenAI = config.AzureOpenAIConfig{
+		Endpoint:   newRoutingLLMServer(t, pieces).URL,
+		APIKey:     "test-key",
+		Deployment: "gpt-4o",
+		APIVersion: "2024-10-21",
+		Fixtures:   config.LLMFixturesConfig{Mode: config.LLMFixturesOff},
+	}
+	agentWorkflow := workflow.NewAgentWorkflowWithMCP(cfg, mcpClient, logger)
+
+	queueManager := queue.NewQueueManager(&queue.QueueConfig{
+		MaxWorkers:     1,
+		QueueSize:      1,
+		RequestTimeout: 5 * time.Second,
+		QueueTimeout:   5 * time.Second,
+	}, agentWorkflow, logger)
+	require.NoError(t, queueManager.Start())
+	defer queueManager.Stop()
+
+	router := gin.New()
+	NewAPIHandler(agentWorkflow, queueManager, nil, nil, logger).SetupRoutes(router)
+
+	w := postJSON(router, "/v1/chat/completions", `{"messages": [{"role": "user", "content": "goroutine 是什么"}], "stream": true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// 每个LLM增量作为单独的数据块转发，而不是生成完后一次性发送
+	var deltas []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		data := strings.TrimPrefix(line, "data: ")
+		if data == line || data == "[DONE]" {
+			continue
+		}
+		var chunk models.OpenAIChatCompletionChunk
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				deltas = append(deltas, choice.Delta.Content)
+			}
+		}
+	}
+	assert.Equal(t, pieces, deltas)
+}
+
+func TestChatCompletions_QuotaExceeded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	ledger, err := usage.NewLedger(config.UsageConfig{
+		Enabled:      true,
+		DefaultQuota: config.QuotaConfig{DailyTokens: 100},
+	}, logger)
+	require.NoError(t, err)
+	ledger.Record(usage.Identity{Tenant: usage.DefaultTenant}, &models.ChatResponse{
+		Success: true,
+		Usage:   &models.TokenUsage{PromptTokens: 90, CompletionTokens: 10},
+	})
+
+	router := gin.New()
+	NewAPIHandler(nil, nil, nil, ledger, logger).SetupRoutes(router)
+
+	w := postJSON(router, "/v1/chat/completions", `{"messages": [{"role": "user", "content": "北京天气"}]}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient_quota")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/usage?tenant=default", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Report usage.Report      `json:"report"`
+		Quota  usage.QuotaStatus `json:"quota"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 100, body.Report.Total.TotalTokens)
+	assert.Equal(t, 100, body.Quota.Daily.Tokens)
+}
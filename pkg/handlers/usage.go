@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/auth"
+	"deer-flow-go/pkg/usage"
+)
+
+// requestIdentity 返回请求的计费身份，未启用鉴权时为默认租户
+func requestIdentity(c *gin.Context) usage.Identity {
+	if value, ok := c.Get(APIKeyContextKey); ok {
+		if key, ok := value.(*auth.APIKey); ok {
+			return usage.Identity{APIKey: key.Name, Tenant: key.Tenant}
+		}
+	}
+	return usage.Identity{Tenant: usage.DefaultTenant}
+}
+
+// checkQuota 在请求排队之前检查租户配额，返回附加了计费身份的上下文
+// 配额用尽时设置到配额重置时间的 Retry-After 并返回 *usage.QuotaError，由调用方按接口格式返回429。
+func (h *APIHandler) checkQuota(ctx context.Context, c *gin.Context) (context.Context, *usage.QuotaError) {
+	identity := requestIdentity(c)
+	if h.ledger == nil {
+		return ctx, nil
+	}
+
+	if err := h.ledger.Check(identity.Tenant); err != nil {
+		var quotaErr *usage.QuotaError
+		if !errors.As(err, &quotaErr) {
+			h.logger.WithError(err).Error("Failed to check usage quota")
+			return usage.WithIdentity(ctx, identity), nil
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(time.Until(quotaErr.ResetAt))))
+		h.logger.WithFields(logrus.Fields{
+			"tenant":  identity.Tenant,
+			"api_key": identity.APIKey,
+			"period":  quotaErr.Period,
+			"metric":  quotaErr.Metric,
+		}).Warn("Usage quota exceeded")
+		return ctx, quotaErr
+	}
+	return usage.WithIdentity(ctx, identity), nil
+}
+
+// respondQuotaExceeded 返回配额用尽的429响应
+func respondQuotaExceeded(c *gin.Context, err *usage.QuotaError) {
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":    err.Error(),
+		"code":     "QUOTA_EXCEEDED",
+		"period":   err.Period,
+		"metric":   err.Metric,
+		"limit":    err.Limit,
+		"used":     err.Used,
+		"reset_at": err.ResetAt,
+	})
+}
+
+// Usage 用量报表处理器
+// 查询参数：from、to（RFC3339或2006-01-02，默认本月初到现在）、tenant、api_key、group_by（tenant | api_key | day）。
+// 指定 tenant 时同时返回该租户的配额和本日、本月已用量。
+func (h *APIHandler) Usage(c *gin.Context) {
+	if h.ledger == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Usage accounting is not enabled",
+			"code":  "NOT_FOUND",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	query := usage.ReportQuery{
+		From:    time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		Tenant:  c.Query("tenant"),
+		APIKey:  c.Query("api_key"),
+		GroupBy: c.Query("group_by"),
+	}
+	var err error
+	if from := c.Query("from"); from != "" {
+		if query.From, err = parseReportTime(from); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error(), "code": "INVALID_REQUEST"})
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if query.To, err = parseReportTime(to); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error(), "code": "INVALID_REQUEST"})
+			return
+		}
+	}
+
+	report, err := h.ledger.Report(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	response := gin.H{"report": report}
+	if query.Tenant != "" {
+		response["quota"] = h.ledger.QuotaStatus(query.Tenant)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// parseReportTime 解析报表时间参数，支持RFC3339和日期（UTC零点）
+func parseReportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -2,39 +2,266 @@ package llm
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sashabaranov/go-openai"
 	"github.com/sirupsen/logrus"
 
 	"deer-flow-go/pkg/config"
 	"deer-flow-go/pkg/models"
+	"deer-flow-go/pkg/prompts"
 )
 
 // AzureOpenAIClient Azure OpenAI 客户端
 type AzureOpenAIClient struct {
-	client *openai.Client
-	config *config.AzureOpenAIConfig
-	logger *logrus.Logger
+	mu        sync.RWMutex
+	client    completer           // Azure OpenAI，按配置套上响应缓存和录制回放
+	resilient *resilientCompleter // 带重试和备用部署的底层调用，回放模式下为空
+	streamer  *resilientCompleter // 流式调用使用的客户端，录制回放时为空
+	cache     *ResponseCache      // 未启用缓存时为空
+	health    *healthRegistry     // 各部署的熔断状态和统计，热更新后保留
+	config    *config.AzureOpenAIConfig
+	logger    *logrus.Logger
+
+	prompts     *prompts.Registry                        // 系统提示词模板
+	locale      string                                   // 模板变量 Locale
+	toolCatalog func() []string                          // 模板变量 Tools，未设置时为空
+	toolSchemas func() map[string]map[string]interface{} // 工具参数的 JSON Schema，用于校验查询路由结果，未设置时不校验参数
 }
 
 // NewAzureOpenAIClient 创建新的 Azure OpenAI 客户端
 func NewAzureOpenAIClient(cfg *config.AzureOpenAIConfig, logger *logrus.Logger) *AzureOpenAIClient {
-	clientConfig := openai.DefaultAzureConfig(cfg.APIKey, cfg.Endpoint)
-	clientConfig.APIVersion = cfg.APIVersion
+	var cache *ResponseCache
+	if cfg.Cache.Enabled {
+		cache = NewResponseCache(cfg.Cache, logger)
+	}
+	health := newHealthRegistry()
+	client, resilient := newCompleter(cfg, cache, health, logger)
+	return &AzureOpenAIClient{
+		client:    client,
+		resilient: resilient,
+		streamer:  streamer(cfg, resilient),
+		cache:     cache,
+		health:    health,
+		config:    cfg,
+		logger:    logger,
+		prompts:   prompts.Builtin(),
+		locale:    "zh-CN",
+	}
+}
 
-	client := openai.NewClientWithConfig(clientConfig)
+// newCompleter 按配置组装调用链：响应缓存 -> 录制回放 -> 重试和备用部署（回放模式不创建）
+// 同时返回底层的重试调用层，用于流式调用和统计。
+func newCompleter(cfg *config.AzureOpenAIConfig, cache *ResponseCache, health *healthRegistry, logger *logrus.Logger) (completer, *resilientCompleter) {
+	var resilient *resilientCompleter
+	var next completer
+	if !cfg.Replay() {
+		resilient = newResilientCompleter(cfg, health, logger)
+		next = resilient
+	}
+	if cfg.Fixtures.Mode == config.LLMFixturesRecord || cfg.Fixtures.Mode == config.LLMFixturesReplay {
+		next = newFixtureCompleter(cfg.Fixtures, next, logger)
+	}
+	if cache != nil {
+		next = cache.wrap(next)
+	}
+	return next, resilient
+}
 
-	return &AzureOpenAIClient{
-		client: client,
-		config: cfg,
-		logger: logger,
+// streamer 返回流式调用使用的调用层，录制回放时为空，流式调用改走非流式调用链以便录制和回放
+func streamer(cfg *config.AzureOpenAIConfig, resilient *resilientCompleter) *resilientCompleter {
+	if cfg.Fixtures.Mode == config.LLMFixturesRecord || cfg.Fixtures.Mode == config.LLMFixturesReplay {
+		return nil
+	}
+	return resilient
+}
+
+// UpdateConfig 热更新LLM配置（端点、密钥、部署、温度、缓存、录制回放等），正在进行的调用继续使用旧配置
+// 缓存配置不变时保留已缓存的响应。
+func (c *AzureOpenAIClient) UpdateConfig(cfg config.AzureOpenAIConfig) {
+	c.mu.Lock()
+	cache := c.cache
+	if cache == nil || cache.cfg != cfg.Cache {
+		cache = nil
+		if cfg.Cache.Enabled {
+			cache = NewResponseCache(cfg.Cache, c.logger)
+		}
+	}
+	c.client, c.resilient = newCompleter(&cfg, cache, c.health, c.logger)
+	c.streamer = streamer(&cfg, c.resilient)
+	c.cache = cache
+	c.config = &cfg
+	c.mu.Unlock()
+
+	c.logger.WithFields(logrus.Fields{
+		"deployment":  cfg.Deployment,
+		"temperature": cfg.Temperature,
+		"cache":       cfg.Cache.Enabled,
+		"fixtures":    cfg.Fixtures.Mode,
+		"fallbacks":   len(cfg.Fallbacks),
+	}).Info("LLM configuration updated")
+}
+
+// Stats LLM调用统计
+type Stats struct {
+	Deployments []DeploymentStats `json:"deployments"`     // 按调用顺序排列，回放模式下为空
+	Cache       *CacheStats       `json:"cache,omitempty"` // 未启用响应缓存时为空
+}
+
+// Stats 返回各部署的调用统计、熔断状态和响应缓存统计
+func (c *AzureOpenAIClient) Stats() Stats {
+	c.mu.RLock()
+	resilient, cache := c.resilient, c.cache
+	c.mu.RUnlock()
+
+	stats := Stats{Deployments: []DeploymentStats{}}
+	if resilient != nil {
+		stats.Deployments = resilient.stats()
+	}
+	if cache != nil {
+		cacheStats := cache.Stats()
+		stats.Cache = &cacheStats
+	}
+	return stats
+}
+
+// UpdatePrompts 替换系统提示词模板和语言区域，之后开始的调用使用新模板
+func (c *AzureOpenAIClient) UpdatePrompts(registry *prompts.Registry, locale string) {
+	c.mu.Lock()
+	c.prompts = registry
+	c.locale = locale
+	c.mu.Unlock()
+}
+
+// SetToolCatalog 设置渲染模板时获取可用工具列表的函数
+func (c *AzureOpenAIClient) SetToolCatalog(fn func() []string) {
+	c.mu.Lock()
+	c.toolCatalog = fn
+	c.mu.Unlock()
+}
+
+// SetToolSchemas 设置获取工具参数 JSON Schema 的函数，键为工具名称
+func (c *AzureOpenAIClient) SetToolSchemas(fn func() map[string]map[string]interface{}) {
+	c.mu.Lock()
+	c.toolSchemas = fn
+	c.mu.Unlock()
+}
+
+// systemPrompt 选择并渲染系统提示词模板，key 决定A/B分流，使用的版本记录到上下文的 Metadata
+func (c *AzureOpenAIClient) systemPrompt(ctx context.Context, name, key string) (string, error) {
+	c.mu.RLock()
+	registry, locale, toolCatalog := c.prompts, c.locale, c.toolCatalog
+	c.mu.RUnlock()
+
+	data := prompts.Data{
+		Date:   time.Now().Format("2006-01-02"),
+		Locale: locale,
+	}
+	if toolCatalog != nil {
+		data.Tools = toolCatalog()
+	}
+
+	rendered, err := registry.Render(name, key, data)
+	if err != nil {
+		return "", err
 	}
+	recordPrompt(ctx, rendered.Name, rendered.Version)
+
+	c.logger.WithFields(logrus.Fields{
+		"prompt":  rendered.Name,
+		"version": rendered.Version,
+	}).Debug("System prompt rendered")
+	return rendered.Text, nil
+}
+
+// snapshot 获取当前客户端和配置
+func (c *AzureOpenAIClient) snapshot() (completer, config.AzureOpenAIConfig) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client, *c.config
+}
+
+// fit 按当前部署的上下文窗口裁剪提示词
+func (c *AzureOpenAIClient) fit(prompt Prompt) (Prompt, error) {
+	_, cfg := c.snapshot()
+	fitted, report, err := NewBudget(cfg).Fit(prompt)
+	if err != nil {
+		return prompt, err
+	}
+	if report.Trimmed() {
+		c.logger.WithFields(logrus.Fields{
+			"context_window":    report.Window,
+			"reserved_tokens":   report.Reserved,
+			"safety_margin":     report.Margin,
+			"prompt_tokens":     report.PromptTokens,
+			"dropped_history":   report.DroppedHistory,
+			"truncated_context": report.TruncatedContext,
+			"dropped_context":   report.DroppedContext,
+		}).Info("Prompt trimmed to fit context window")
+	}
+	return fitted, nil
 }
 
 // ChatCompletion 调用聊天完成API
+// 为回复预留token，消息超出上下文窗口时返回 ErrContextWindowExceeded 而不发送请求。
 func (c *AzureOpenAIClient) ChatCompletion(ctx context.Context, messages []models.ChatMessage, systemPrompt string) (string, error) {
+	client, cfg := c.snapshot()
+
+	req, promptTokens, err := buildRequest(cfg, messages, systemPrompt)
+	if err != nil {
+		return "", err
+	}
+	return c.complete(ctx, client, cfg, req, promptTokens)
+}
+
+// complete 发送构建好的请求并返回第一个回复的内容，token用量记录到上下文的 Metadata
+func (c *AzureOpenAIClient) complete(ctx context.Context, client completer, cfg config.AzureOpenAIConfig, req openai.ChatCompletionRequest, promptTokens int) (string, error) {
+	c.logger.WithFields(logrus.Fields{
+		"deployment":       cfg.Deployment,
+		"messages":         len(req.Messages),
+		"estimated_tokens": promptTokens,
+	}).Debug("Calling Azure OpenAI API")
+
+	// 调用API
+	resp, err := client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to call Azure OpenAI API")
+		return "", fmt.Errorf("Azure OpenAI API call failed: %w", err)
+	}
+
+	recordUsage(ctx, resp.Usage)
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response choices returned from Azure OpenAI")
+	}
+
+	result := resp.Choices[0].Message.Content
+	c.logger.WithFields(logrus.Fields{
+		"response_length":   len(result),
+		"prompt_tokens":     resp.Usage.PromptTokens,
+		"completion_tokens": resp.Usage.CompletionTokens,
+		"usage_tokens":      resp.Usage.TotalTokens,
+	}).Debug("Azure OpenAI API response received")
+
+	return result, nil
+}
+
+// buildRequest 构建聊天完成请求，返回估算的提示词token数
+// 消息超出上下文窗口时返回 ErrContextWindowExceeded。
+func buildRequest(cfg config.AzureOpenAIConfig, messages []models.ChatMessage, systemPrompt string) (openai.ChatCompletionRequest, int, error) {
+	budget := NewBudget(cfg)
+	promptTokens := budget.messageTokens(systemPrompt)
+	for _, msg := range messages {
+		promptTokens += budget.messageTokens(msg.Content)
+	}
+	if promptTokens > budget.Available() {
+		return openai.ChatCompletionRequest{}, promptTokens, fmt.Errorf("%w: %d tokens, %d available", ErrContextWindowExceeded, promptTokens, budget.Available())
+	}
+
 	// 构建OpenAI消息格式
 	openaiMessages := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
 
@@ -64,179 +291,207 @@ func (c *AzureOpenAIClient) ChatCompletion(ctx context.Context, messages []model
 		})
 	}
 
-	// 创建请求
 	req := openai.ChatCompletionRequest{
-		Model:       c.config.Deployment,
+		Model:       cfg.Deployment,
 		Messages:    openaiMessages,
-		Temperature: c.config.Temperature,
+		Temperature: cfg.Temperature,
 		Stream:      false,
 	}
-
-	c.logger.WithFields(logrus.Fields{
-		"deployment": c.config.Deployment,
-		"messages":   len(openaiMessages),
-	}).Debug("Calling Azure OpenAI API")
-
-	// 调用API
-	resp, err := c.client.CreateChatCompletion(ctx, req)
-	if err != nil {
-		c.logger.WithError(err).Error("Failed to call Azure OpenAI API")
-		return "", fmt.Errorf("Azure OpenAI API call failed: %w", err)
-	}
-
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned from Azure OpenAI")
+	// 只在配置了上限时限制回复长度，o 系列模型不接受 max_tokens
+	if cfg.MaxCompletionTokens > 0 {
+		if usesMaxCompletionTokens(cfg.Deployment) {
+			req.MaxCompletionTokens = cfg.MaxCompletionTokens
+		} else {
+			req.MaxTokens = cfg.MaxCompletionTokens
+		}
 	}
-
-	result := resp.Choices[0].Message.Content
-	c.logger.WithFields(logrus.Fields{
-		"response_length": len(result),
-		"usage_tokens":    resp.Usage.TotalTokens,
-	}).Debug("Azure OpenAI API response received")
-
-	return result, nil
+	return req, promptTokens, nil
 }
 
-// ParseQueryToMCP 将用户查询解析为MCP请求格式
-func (c *AzureOpenAIClient) ParseQueryToMCP(ctx context.Context, query string) (*models.MCPRequest, error) {
-	systemPrompt := `你是一个专门将用户查询转换为MCP协议格式的助手。
+// maxCompletionTokensModels 只接受 max_completion_tokens 的模型前缀
+var maxCompletionTokensModels = []string{"o1", "o3", "o4"}
 
-你的任务是：
-1. 分析用户的查询内容
-2. 判断查询类型并选择合适的处理方法
-3. 将查询转换为标准的MCP请求格式
+// usesMaxCompletionTokens 部署模型是否需要用 max_completion_tokens 代替 max_tokens，按部署名称前缀判断
+func usesMaxCompletionTokens(deployment string) bool {
+	deployment = strings.ToLower(deployment)
+	for _, prefix := range maxCompletionTokensModels {
+		if strings.HasPrefix(deployment, prefix) {
+			return true
+		}
+	}
+	return false
+}
 
-判断规则：
-- 如果查询涉及天气信息（如天气、气温、降雨、预报等），使用get_weather或get_weather_forecast方法
-- 如果查询涉及其他实时信息（如新闻、股价等），使用search方法
-- 如果查询是一般知识问题、问候语、数学计算等，使用direct_response方法
+// ParseQueryToMCP 将用户查询解析为MCP请求格式，resources 为附加的参考资料（MCP资源内容）
+func (c *AzureOpenAIClient) ParseQueryToMCP(ctx context.Context, query string, resources ...models.ResourceContent) (*models.MCPRequest, error) {
+	systemPrompt, err := c.systemPrompt(ctx, prompts.QueryParser, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query to MCP: %w", err)
+	}
 
-城市名处理规则：
-- 对于天气查询，必须将中文城市名转换为对应的英文城市名
-- 常见转换：北京→Beijing, 上海→Shanghai, 广州→Guangzhou, 深圳→Shenzhen, 杭州→Hangzhou, 南京→Nanjing, 成都→Chengdu, 西安→Xi'an, 重庆→Chongqing, 天津→Tianjin, 武汉→Wuhan, 苏州→Suzhou, 青岛→Qingdao, 大连→Dalian, 厦门→Xiamen, 长沙→Changsha, 哈尔滨→Harbin, 沈阳→Shenyang, 郑州→Zhengzhou, 济南→Jinan
-- 如果是其他中文城市名，请转换为对应的英文拼音形式
+	// 参考资料按附加顺序，靠后的先截断
+	history := HistoryFromContext(ctx)
+	items := make([]ContextItem, 0, len(resources))
+	for _, resource := range resources {
+		items = append(items, ContextItem{Source: resource.URI, Content: resource.Text})
+	}
+	prompt, err := c.fit(Prompt{System: systemPrompt, History: history, Context: items, Question: query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query to MCP: %w", err)
+	}
 
-请严格按照以下JSON格式返回：
-对于天气查询：
-{
-  "method": "get_weather",
-  "params": {
-    "city": "英文城市名称（如Beijing、Shanghai等）"
-  }
-}
+	messages := make([]models.ChatMessage, 0, len(prompt.History)+2)
+	if len(prompt.Context) > 0 {
+		messages = append(messages, models.ChatMessage{Role: "user", Content: formatResources(prompt.Context)})
+	}
+	messages = append(messages, prompt.History...)
+	messages = append(messages, models.ChatMessage{Role: "user", Content: prompt.Question})
 
-对于天气预报查询（包含"预报"、"未来"、"明天"等关键词）：
-注意：天气预报最多支持5天，如果用户询问超过5天的预报，请将days参数设为5，并在direct_response中提醒用户。
-{
-  "method": "get_weather_forecast",
-  "params": {
-    "city": "英文城市名称（如Beijing、Shanghai等）",
-    "days": 3
-  }
-}
+	// 路由结果按可用工具和所选工具的参数定义校验，不通过时由模型修正
+	var mcpRequest models.MCPRequest
+	if err := c.StructuredCompletion(ctx, messages, systemPrompt, c.routeOutput(), &mcpRequest); err != nil {
+		return nil, fmt.Errorf("failed to parse query to MCP: %w", err)
+	}
 
-如果用户询问超过5天的天气预报，请使用direct_response方法：
-{
-  "method": "direct_response",
-  "params": {
-    "response": "抱歉，天气预报最多只能查询5天。如果您需要查询[城市名]的天气预报，我可以为您提供最多5天的预报信息。"
-  }
-}
+	c.logger.WithFields(logrus.Fields{
+		"query_length": len(query),
+		"mcp_method":   mcpRequest.Method,
+	}).Debug("Query parsed to MCP request")
 
-对于需要搜索的查询：
-{
-  "method": "search",
-  "params": {
-    "query": "优化后的搜索关键词",
-    "max_results": 5,
-    "search_depth": "advanced"
-  }
+	return &mcpRequest, nil
 }
 
-对于不需要搜索的查询：
-{
-  "method": "direct_response",
-  "params": {
-    "response": "直接回复内容"
-  }
+// directResponseSchema direct_response 的参数
+var directResponseSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"response"},
+	"properties": map[string]interface{}{
+		"response": map[string]interface{}{"type": "string", "minLength": 1},
+	},
 }
 
-只返回JSON格式，不要添加任何其他文字说明。`
+// routeOutput 查询路由的输出格式：method 为可用工具或 direct_response，params 按所选工具的 inputSchema 校验
+func (c *AzureOpenAIClient) routeOutput() StructuredOutput {
+	c.mu.RLock()
+	toolSchemas := c.toolSchemas
+	c.mu.RUnlock()
 
-	messages := []models.ChatMessage{
-		{Role: "user", Content: query},
+	schemas := map[string]map[string]interface{}{}
+	if toolSchemas != nil {
+		schemas = toolSchemas()
 	}
-
-	response, err := c.ChatCompletion(ctx, messages, systemPrompt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse query to MCP: %w", err)
+	method := map[string]interface{}{"type": "string"}
+	if len(schemas) > 0 {
+		names := make([]string, 0, len(schemas))
+		for name := range schemas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		enum := []interface{}{"direct_response"}
+		for _, name := range names {
+			enum = append(enum, name)
+		}
+		method["enum"] = enum
 	}
-	fmt.Println("!!!!!!!!!!!!!!",response)
-	c.logger.WithFields(logrus.Fields{
-		"llm_response": response,
-	}).Debug("LLM response for MCP parsing")
 
-	// 解析JSON响应
-	var mcpRequest models.MCPRequest
-	err = json.Unmarshal([]byte(response), &mcpRequest)
-	if err != nil {
-		c.logger.WithError(err).Warn("Failed to parse LLM response as JSON, falling back to search")
-		// 如果解析失败，默认使用搜索
-		mcpRequest = models.MCPRequest{
-			Method: "search",
-			Params: map[string]interface{}{
-				"query":        query,
-				"max_results":  5,
-				"search_depth": "advanced",
+	return StructuredOutput{
+		Name: "mcp_request",
+		Schema: map[string]interface{}{
+			"type":                 "object",
+			"required":             []interface{}{"method", "params"},
+			"additionalProperties": false,
+			"properties": map[string]interface{}{
+				"method": method,
+				"params": map[string]interface{}{"type": "object"},
 			},
-		}
+		},
+		Validate: func(value interface{}) []string {
+			request := value.(map[string]interface{})
+			name, _ := request["method"].(string)
+			schema, ok := schemas[name]
+			if name == "direct_response" {
+				schema, ok = directResponseSchema, true
+			}
+			if !ok {
+				return nil
+			}
+			var errs []string
+			validateSchema(schema, request["params"], "$.params", &errs)
+			return errs
+		},
 	}
+}
 
-	c.logger.WithFields(logrus.Fields{
-		"original_query": query,
-		"mcp_method":     mcpRequest.Method,
-	}).Debug("Query parsed to MCP request")
-
-	return &mcpRequest, nil
+// formatResources 将资源内容拼接为参考资料消息，每段以资源URI开头
+func formatResources(resources []ContextItem) string {
+	var b strings.Builder
+	b.WriteString("参考资料:\n")
+	for _, resource := range resources {
+		fmt.Fprintf(&b, "\n[%s]\n%s\n", resource.Source, resource.Content)
+	}
+	return b.String()
 }
 
 // FormatSearchResults 格式化搜索结果
 func (c *AzureOpenAIClient) FormatSearchResults(ctx context.Context, query string, searchResults *models.SearchResponse) (string, error) {
-	systemPrompt := `你是一个专业的信息整理助手。你的任务是：
-
-1. 分析用户的原始问题
-2. 整理和总结搜索到的信息
-3. 提供准确、有用、结构化的回答
-4. 确保信息的时效性和准确性
-
-请遵循以下原则：
-- 直接回答用户的问题
-- 使用清晰的结构组织信息
-- 引用相关的数据和事实
-- 保持客观和中立
-- 如果信息不足，请明确说明
+	// 排名靠前的搜索结果优先保留
+	items := make([]ContextItem, 0, len(searchResults.Results))
+	for i, result := range searchResults.Results {
+		items = append(items, ContextItem{
+			Source:   result.URL,
+			Title:    result.Title,
+			Content:  result.Content,
+			Priority: len(searchResults.Results) - i,
+		})
+	}
+	return c.formatReply(ctx, query, items, func(fitted []ContextItem) string {
+		var b strings.Builder
+		for i, result := range fitted {
+			fmt.Fprintf(&b, "%d. 标题：%s\n   链接：%s\n   内容：%s\n\n", i+1, result.Title, result.Source, result.Content)
+		}
+		return b.String()
+	})
+}
 
-请用中文回答，格式要清晰易读。`
+// FormatToolOutput 根据工具返回的文本生成最终回复
+// MCP服务器的搜索等工具返回已经排版的文本，整体作为一段参考资料交给模型整理。
+func (c *AzureOpenAIClient) FormatToolOutput(ctx context.Context, query, tool, output string) (string, error) {
+	items := []ContextItem{{Source: tool, Content: output, Priority: 1}}
+	return c.formatReply(ctx, query, items, func(fitted []ContextItem) string {
+		var b strings.Builder
+		for _, item := range fitted {
+			b.WriteString(item.Content)
+			b.WriteString("\n")
+		}
+		return b.String()
+	})
+}
 
-	// 构建包含搜索结果的用户消息
-	userContent := fmt.Sprintf("原始问题：%s\n\n搜索结果：\n", query)
-	for i, result := range searchResults.Results {
-		userContent += fmt.Sprintf("%d. 标题：%s\n   链接：%s\n   内容：%s\n\n",
-			i+1, result.Title, result.URL, result.Content)
+// formatReply 将搜索结果按上下文窗口裁剪后交给模型生成最终回复
+// 上下文带有流式输出回调时回复边生成边转发，见 WithStreamOutput。
+func (c *AzureOpenAIClient) formatReply(ctx context.Context, query string, items []ContextItem, render func([]ContextItem) string) (string, error) {
+	systemPrompt, err := c.systemPrompt(ctx, prompts.ResultFormatter, query)
+	if err != nil {
+		return "", fmt.Errorf("failed to format search results: %w", err)
 	}
 
-	messages := []models.ChatMessage{
-		{Role: "user", Content: userContent},
+	prompt, err := c.fit(Prompt{System: systemPrompt, History: HistoryFromContext(ctx), Context: items, Question: query})
+	if err != nil {
+		return "", fmt.Errorf("failed to format search results: %w", err)
 	}
 
-	response, err := c.ChatCompletion(ctx, messages, systemPrompt)
+	// 构建包含搜索结果的用户消息，对话历史作为之前的消息发送
+	userContent := fmt.Sprintf("原始问题：%s\n\n搜索结果：\n%s", prompt.Question, render(prompt.Context))
+	messages := append(append([]models.ChatMessage(nil), prompt.History...), models.ChatMessage{Role: "user", Content: userContent})
+
+	response, err := c.completeReply(ctx, messages, systemPrompt)
 	if err != nil {
 		return "", fmt.Errorf("failed to format search results: %w", err)
 	}
 
 	c.logger.WithFields(logrus.Fields{
-		"original_query":  query,
-		"search_results":  len(searchResults.Results),
+		"query_length":    len(query),
+		"search_results":  len(items),
 		"response_length": len(response),
 	}).Debug("Search results formatted")
 
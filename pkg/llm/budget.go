@@ -0,0 +1,240 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+const (
+	// defaultContextWindow 无法识别部署模型时使用的上下文窗口
+	defaultContextWindow = 8192
+	// messageOverhead 每条消息的角色、分隔符等格式开销
+	messageOverhead = 4
+	// framingTokens 对话历史、参考资料等段落标题的开销
+	framingTokens = 32
+	// keepRecentHistory 截断参考资料之前先保留的最近历史消息条数（一问一答）
+	keepRecentHistory = 2
+	// minContextTokens 参考资料截断后少于该token数时直接丢弃
+	minContextTokens = 32
+	// defaultReservedTokens 未配置 max_completion_tokens 时为回复预留的token数
+	defaultReservedTokens = 1024
+	// safetyMarginPercent 近似分词器的误差余量，占上下文窗口的百分比
+	safetyMarginPercent = 10
+)
+
+// ErrContextWindowExceeded 系统提示词和当前问题本身已经超出上下文窗口
+var ErrContextWindowExceeded = errors.New("prompt exceeds the model context window")
+
+// modelContextWindows 常见模型的上下文窗口，按前缀匹配部署名称（最长前缀优先）
+var modelContextWindows = map[string]int{
+	"gpt-4.1":           1047576,
+	"gpt-4o":            128000,
+	"gpt-4-turbo":       128000,
+	"gpt-4-1106":        128000,
+	"gpt-4-0125":        128000,
+	"gpt-4-32k":         32768,
+	"gpt-4":             8192,
+	"gpt-35-turbo-16k":  16384,
+	"gpt-35-turbo":      4096,
+	"gpt-3.5-turbo-16k": 16384,
+	"gpt-3.5-turbo":     16385,
+	"o1":                200000,
+	"o3":                200000,
+	"o4-mini":           200000,
+}
+
+// ContextWindow 返回模型（或以模型名开头的部署名称）的上下文窗口，未知模型返回 8192
+func ContextWindow(model string) int {
+	model = strings.ToLower(model)
+	best, window := 0, defaultContextWindow
+	for prefix, size := range modelContextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > best {
+			best, window = len(prefix), size
+		}
+	}
+	return window
+}
+
+// ContextItem 参考资料或工具输出，预算不足时按优先级截断
+type ContextItem struct {
+	Source   string // 资源URI或网页链接
+	Title    string
+	Content  string
+	Priority int // 数值越大越重要，越晚被截断
+}
+
+// Prompt 一次LLM调用的输入，预算分配以此为单位
+// System 和 Question 不会被裁剪；History 从旧到新排列，Context 按 Priority 截断。
+type Prompt struct {
+	System   string
+	History  []models.ChatMessage
+	Context  []ContextItem
+	Question string
+}
+
+// FitReport 预算分配结果
+type FitReport struct {
+	Window           int // 上下文窗口
+	Reserved         int // 为回复预留的token数
+	Margin           int // 为token估算误差保留的余量
+	PromptTokens     int // 裁剪后的提示词token数（估算）
+	DroppedHistory   int // 被省略的历史消息条数
+	TruncatedContext int // 被截断的参考资料条数
+	DroppedContext   int // 被丢弃的参考资料条数
+}
+
+// Trimmed 是否进行了裁剪
+func (r FitReport) Trimmed() bool {
+	return r.DroppedHistory > 0 || r.TruncatedContext > 0 || r.DroppedContext > 0
+}
+
+// Budget 上下文窗口预算：提示词不超过 Window - Reserved - Margin
+type Budget struct {
+	Window    int
+	Reserved  int
+	Margin    int // 分词器是近似估算时，为估算误差保留的余量
+	Tokenizer Tokenizer
+}
+
+// NewBudget 根据LLM配置创建预算，未配置上下文窗口时按部署名称推断
+// 使用近似分词器，因此额外保留窗口的 10% 作为误差余量。
+func NewBudget(cfg config.AzureOpenAIConfig) Budget {
+	window := cfg.ContextWindow
+	if window <= 0 {
+		window = ContextWindow(cfg.Deployment)
+	}
+	reserved := cfg.MaxCompletionTokens
+	if reserved <= 0 {
+		reserved = defaultReservedTokens
+	}
+	return Budget{
+		Window:    window,
+		Reserved:  reserved,
+		Margin:    window * safetyMarginPercent / 100,
+		Tokenizer: EstimateTokenizer{},
+	}
+}
+
+// Available 提示词可用的token数
+func (b Budget) Available() int {
+	return b.Window - b.Reserved - b.Margin
+}
+
+// messageTokens 一条消息的token数
+func (b Budget) messageTokens(content string) int {
+	return b.Tokenizer.CountTokens(content) + messageOverhead
+}
+
+// contextTokens 一条参考资料的token数
+func (b Budget) contextTokens(item ContextItem) int {
+	return b.Tokenizer.CountTokens(item.Source) + b.Tokenizer.CountTokens(item.Title) + b.Tokenizer.CountTokens(item.Content) + messageOverhead
+}
+
+// Fit 裁剪提示词使其放进预算
+// 依次：省略较早的历史（保留最近一问一答）、按优先级从低到高截断或丢弃参考资料、省略剩余历史。
+// System 和 Question 本身超出预算时返回 ErrContextWindowExceeded。
+func (b Budget) Fit(prompt Prompt) (Prompt, FitReport, error) {
+	report := FitReport{Window: b.Window, Reserved: b.Reserved, Margin: b.Margin}
+	available := b.Available()
+
+	fixed := b.messageTokens(prompt.System) + b.messageTokens(prompt.Question) + framingTokens
+	if fixed > available {
+		report.PromptTokens = fixed
+		return prompt, report, fmt.Errorf("%w: %d tokens, %d available", ErrContextWindowExceeded, fixed, available)
+	}
+
+	history := append([]models.ChatMessage(nil), prompt.History...)
+	historyTokens := make([]int, len(history))
+	items := append([]ContextItem(nil), prompt.Context...)
+	total := fixed
+	for i, msg := range history {
+		historyTokens[i] = b.messageTokens(msg.Content)
+		total += historyTokens[i]
+	}
+	for _, item := range items {
+		total += b.contextTokens(item)
+	}
+
+	// 1. 省略较早的历史
+	for total > available && len(history) > keepRecentHistory {
+		total -= historyTokens[0]
+		history, historyTokens = history[1:], historyTokens[1:]
+		report.DroppedHistory++
+	}
+
+	// 2. 按优先级截断参考资料，同优先级时靠后的先截断
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(x, y int) bool {
+		if items[order[x]].Priority != items[order[y]].Priority {
+			return items[order[x]].Priority < items[order[y]].Priority
+		}
+		return order[x] > order[y]
+	})
+	dropped := make(map[int]bool)
+	for _, i := range order {
+		if total <= available {
+			break
+		}
+		item := items[i]
+		tokens := b.contextTokens(item)
+		keep := b.Tokenizer.CountTokens(item.Content) - (total - available)
+		if keep < minContextTokens {
+			dropped[i] = true
+			total -= tokens
+			report.DroppedContext++
+			continue
+		}
+		item.Content, _ = truncateToTokens(b.Tokenizer, item.Content, keep)
+		items[i] = item
+		total += b.contextTokens(item) - tokens
+		report.TruncatedContext++
+	}
+	if len(dropped) > 0 {
+		kept := items[:0]
+		for i, item := range items {
+			if !dropped[i] {
+				kept = append(kept, item)
+			}
+		}
+		items = kept
+	}
+
+	// 3. 省略剩余历史
+	for total > available && len(history) > 0 {
+		total -= historyTokens[0]
+		history, historyTokens = history[1:], historyTokens[1:]
+		report.DroppedHistory++
+	}
+
+	prompt.History = history
+	prompt.Context = items
+	report.PromptTokens = total
+	return prompt, report, nil
+}
+
+// historyKey 上下文中对话历史的键
+type historyKey struct{}
+
+// WithHistory 返回附加了对话历史的上下文，历史从旧到新排列
+// 使用该上下文的查询解析和结果整理会把历史作为独立的聊天消息发送，上下文窗口不足时从最早的历史开始省略。
+func WithHistory(ctx context.Context, history []models.ChatMessage) context.Context {
+	if len(history) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, historyKey{}, history)
+}
+
+// HistoryFromContext 返回上下文中附加的对话历史，没有历史时返回 nil
+func HistoryFromContext(ctx context.Context) []models.ChatMessage {
+	history, _ := ctx.Value(historyKey{}).([]models.ChatMessage)
+	return history
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+func TestContextWindow(t *testing.T) {
+	assert.Equal(t, 128000, ContextWindow("gpt-4o-mini"))
+	assert.Equal(t, 32768, ContextWindow("GPT-4-32k-0613"))
+	assert.Equal(t, 8192, ContextWindow("gpt-4"))
+	assert.Equal(t, 4096, ContextWindow("gpt-35-turbo"))
+	assert.Equal(t, 8192, ContextWindow("my-deployment"))
+
+	// 窗口减去回复预留和10%的估算余量
+	budget := NewBudget(config.AzureOpenAIConfig{Deployment: "gpt-4o", MaxCompletionTokens: 1000})
+	assert.Equal(t, 128000-1000-12800, budget.Available())
+	budget = NewBudget(config.AzureOpenAIConfig{Deployment: "gpt-4o", ContextWindow: 4000, MaxCompletionTokens: 1000})
+	assert.Equal(t, 2600, budget.Available())
+	// 未配置回复上限时仍为回复预留token
+	budget = NewBudget(config.AzureOpenAIConfig{Deployment: "gpt-4", MaxCompletionTokens: 0})
+	assert.Equal(t, defaultReservedTokens, budget.Reserved)
+}
+
+func TestBudget_Fit(t *testing.T) {
+	budget := Budget{Window: 500, Reserved: 100, Tokenizer: EstimateTokenizer{}}
+
+	history := []models.ChatMessage{
+		{Role: "user", Content: strings.Repeat("旧", 100)},
+		{Role: "assistant", Content: strings.Repeat("答", 100)},
+		{Role: "user", Content: "上海呢"},
+		{Role: "assistant", Content: "上海晴"},
+	}
+	prompt := Prompt{
+		System:   "系统提示词",
+		History:  history,
+		Question: "明天呢",
+		Context: []ContextItem{
+			{Source: "a", Content: strings.Repeat("重要内容。", 40), Priority: 2},
+			{Source: "b", Content: strings.Repeat("次要内容。", 40), Priority: 1},
+		},
+	}
+
+	fitted, report, err := budget.Fit(prompt)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, report.PromptTokens, budget.Available())
+	// 先省略较早的历史，保留最近一问一答
+	assert.Equal(t, 2, report.DroppedHistory)
+	assert.Equal(t, history[2:], fitted.History)
+	// 再截断低优先级的参考资料，高优先级的保持完整
+	require.Len(t, fitted.Context, 2)
+	assert.Equal(t, prompt.Context[0].Content, fitted.Context[0].Content)
+	assert.Less(t, len(fitted.Context[1].Content), len(prompt.Context[1].Content))
+	assert.Equal(t, 1, report.TruncatedContext)
+	assert.Equal(t, "明天呢", fitted.Question)
+
+	// 预算很小时丢弃参考资料和全部历史
+	budget.Window = 150
+	fitted, report, err = budget.Fit(prompt)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, report.PromptTokens, budget.Available())
+	assert.Empty(t, fitted.History)
+	assert.Equal(t, 2, report.DroppedContext)
+	assert.Empty(t, fitted.Context)
+
+	// 问题本身超出预算
+	budget.Window = 120
+	_, _, err = budget.Fit(Prompt{System: "系统", Question: strings.Repeat("长", 100)})
+	assert.ErrorIs(t, err, ErrContextWindowExceeded)
+
+	// 预算足够时不做任何裁剪
+	budget.Window = 100000
+	fitted, report, err = budget.Fit(prompt)
+	require.NoError(t, err)
+	assert.False(t, report.Trimmed())
+	assert.Equal(t, prompt, fitted)
+}
+
+func TestWithHistory(t *testing.T) {
+	history := []models.ChatMessage{
+		{Role: "user", Content: "北京天气"},
+		{Role: "assistant", Content: "北京晴\n\n当前问题: 气温25°C"},
+	}
+	ctx := WithHistory(context.Background(), history)
+	assert.Equal(t, history, HistoryFromContext(ctx))
+
+	assert.Nil(t, HistoryFromContext(context.Background()))
+	assert.Equal(t, context.Background(), WithHistory(context.Background(), nil))
+}
+
+func TestBuildRequest_CompletionLimit(t *testing.T) {
+	messages := []models.ChatMessage{{Role: "user", Content: "北京天气"}}
+
+	// 未配置上限时不限制回复长度
+	req, _, err := buildRequest(config.AzureOpenAIConfig{Deployment: "gpt-4o"}, messages, "")
+	require.NoError(t, err)
+	assert.Zero(t, req.MaxTokens)
+	assert.Zero(t, req.MaxCompletionTokens)
+
+	req, _, err = buildRequest(config.AzureOpenAIConfig{Deployment: "gpt-4o", MaxCompletionTokens: 500}, messages, "")
+	require.NoError(t, err)
+	assert.Equal(t, 500, req.MaxTokens)
+	assert.Zero(t, req.MaxCompletionTokens)
+
+	// o 系列模型使用 max_completion_tokens
+	req, _, err = buildRequest(config.AzureOpenAIConfig{Deployment: "o3-mini", MaxCompletionTokens: 500}, messages, "")
+	require.NoError(t, err)
+	assert.Zero(t, req.MaxTokens)
+	assert.Equal(t, 500, req.MaxCompletionTokens)
+}
@@ -0,0 +1,252 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+)
+
+// completer 发送聊天完成请求的底层接口，*openai.Client、缓存和夹具录制回放都实现它
+type completer interface {
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
+// RequestKey 计算请求的缓存键：部署、消息、工具和全部请求参数序列化后的SHA-256
+func RequestKey(req openai.ChatCompletionRequest) string {
+	data, err := json.Marshal(req)
+	if err != nil {
+		// 请求只包含可序列化的字段，出错时退化为按格式化结果计算
+		data = []byte(fmt.Sprintf("%+v", req))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CacheStats LLM响应缓存统计
+type CacheStats struct {
+	Hits      int64 `json:"hits"`      // 命中次数（内存或磁盘）
+	DiskHits  int64 `json:"disk_hits"` // 其中来自磁盘的命中次数
+	Misses    int64 `json:"misses"`    // 未命中并实际调用下游的次数
+	Evictions int64 `json:"evictions"` // LRU淘汰次数
+	Entries   int   `json:"entries"`   // 当前内存条目数
+}
+
+// responseEntry 缓存条目，同时是磁盘缓存文件的内容
+type responseEntry struct {
+	Key       string                        `json:"key"`
+	ExpiresAt time.Time                     `json:"expires_at"`
+	Response  openai.ChatCompletionResponse `json:"response"`
+}
+
+// ResponseCache LLM响应缓存：内存LRU + 可选磁盘存储
+// 只缓存 temperature 为 0 的成功响应；命中时不会调用 Azure OpenAI，返回的 usage 为0，不计入token用量。
+type ResponseCache struct {
+	cfg    config.LLMCacheConfig
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+
+	hits      int64
+	diskHits  int64
+	misses    int64
+	evictions int64
+}
+
+// NewResponseCache 创建LLM响应缓存，磁盘目录无法创建时只使用内存
+func NewResponseCache(cfg config.LLMCacheConfig, logger *logrus.Logger) *ResponseCache {
+	if cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+			logger.WithError(err).Warn("Failed to create LLM cache dir, using memory only")
+			cfg.Dir = ""
+		}
+	}
+	return &ResponseCache{
+		cfg:     cfg,
+		logger:  logger,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Stats 返回缓存统计
+func (c *ResponseCache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.lru.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		DiskHits:  atomic.LoadInt64(&c.diskHits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Evictions: atomic.LoadInt64(&c.evictions),
+		Entries:   entries,
+	}
+}
+
+// wrap 返回带缓存的 completer
+func (c *ResponseCache) wrap(next completer) completer {
+	return &cachedCompleter{next: next, cache: c}
+}
+
+// get 读取未过期的缓存响应，内存未命中时查找磁盘
+func (c *ResponseCache) get(key string) (openai.ChatCompletionResponse, bool) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*responseEntry)
+		if time.Now().Before(entry.ExpiresAt) {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			atomic.AddInt64(&c.hits, 1)
+			return entry.Response, true
+		}
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	entry, ok := c.loadDisk(key)
+	if !ok {
+		atomic.AddInt64(&c.misses, 1)
+		return openai.ChatCompletionResponse{}, false
+	}
+	atomic.AddInt64(&c.hits, 1)
+	atomic.AddInt64(&c.diskHits, 1)
+	c.mu.Lock()
+	c.putLocked(entry)
+	c.mu.Unlock()
+	return entry.Response, true
+}
+
+// put 写入缓存
+func (c *ResponseCache) put(key string, response openai.ChatCompletionResponse) {
+	entry := &responseEntry{
+		Key:       key,
+		ExpiresAt: time.Now().Add(time.Duration(c.cfg.TTL) * time.Second),
+		Response:  response,
+	}
+	c.mu.Lock()
+	c.putLocked(entry)
+	c.mu.Unlock()
+	c.storeDisk(entry)
+}
+
+// putLocked 写入内存条目并按LRU淘汰，调用方需持有锁
+func (c *ResponseCache) putLocked(entry *responseEntry) {
+	if elem, ok := c.entries[entry.Key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.Key] = c.lru.PushFront(entry)
+	for c.cfg.MaxEntries > 0 && c.lru.Len() > c.cfg.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseEntry).Key)
+		atomic.AddInt64(&c.evictions, 1)
+	}
+}
+
+// diskPath 返回缓存键对应的磁盘文件路径
+func (c *ResponseCache) diskPath(key string) string {
+	return filepath.Join(c.cfg.Dir, key+".json")
+}
+
+// loadDisk 从磁盘读取未过期的缓存，过期或损坏的文件会被删除
+func (c *ResponseCache) loadDisk(key string) (*responseEntry, bool) {
+	if c.cfg.Dir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.diskPath(key))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			c.logger.WithError(err).Warn("Failed to read LLM cache file")
+		}
+		return nil, false
+	}
+
+	var entry responseEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || time.Now().After(entry.ExpiresAt) {
+		os.Remove(c.diskPath(key))
+		return nil, false
+	}
+	return &entry, true
+}
+
+// storeDisk 将响应写入磁盘，先写临时文件再重命名以避免读到不完整的文件
+func (c *ResponseCache) storeDisk(entry *responseEntry) {
+	if c.cfg.Dir == "" {
+		return
+	}
+	if err := writeJSONFile(c.cfg.Dir, c.diskPath(entry.Key), entry); err != nil {
+		c.logger.WithError(err).Warn("Failed to write LLM cache file")
+	}
+}
+
+// writeJSONFile 原子地写入JSON文件
+func writeJSONFile(dir, path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// cachedCompleter 带缓存的 completer
+type cachedCompleter struct {
+	next  completer
+	cache *ResponseCache
+}
+
+// CreateChatCompletion 优先从缓存返回 temperature 为 0 的请求的响应
+func (c *cachedCompleter) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if req.Temperature != 0 || req.Stream {
+		return c.next.CreateChatCompletion(ctx, req)
+	}
+
+	key := RequestKey(req)
+	if response, ok := c.cache.get(key); ok {
+		c.cache.logger.WithField("key", key[:12]).Debug("LLM response served from cache")
+		// 命中缓存没有消耗token
+		response.Usage = openai.Usage{}
+		return response, nil
+	}
+
+	response, err := c.next.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return response, err
+	}
+	if len(response.Choices) > 0 {
+		c.cache.put(key, response)
+	}
+	return response, nil
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// stubCompleter 返回固定回复并记录调用次数
+type stubCompleter struct {
+	calls int
+}
+
+func (s *stubCompleter) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	s.calls++
+	last := req.Messages[len(req.Messages)-1].Content
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: "回复：" + last}}},
+		Usage:   openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return logger
+}
+
+func chatRequest(content string, temperature float32) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model:       "gpt-4o",
+		Temperature: temperature,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "系统提示词"},
+			{Role: openai.ChatMessageRoleUser, Content: content},
+		},
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	dir := t.TempDir()
+	stub := &stubCompleter{}
+	cache := NewResponseCache(config.LLMCacheConfig{Enabled: true, TTL: 60, MaxEntries: 1, Dir: dir}, newTestLogger())
+	cached := cache.wrap(stub)
+	ctx := context.Background()
+
+	resp, err := cached.CreateChatCompletion(ctx, chatRequest("北京天气怎么样", 0))
+	require.NoError(t, err)
+	assert.Equal(t, 15, resp.Usage.TotalTokens)
+
+	resp, err = cached.CreateChatCompletion(ctx, chatRequest("北京天气怎么样", 0))
+	require.NoError(t, err)
+	assert.Equal(t, "回复：北京天气怎么样", resp.Choices[0].Message.Content)
+	assert.Zero(t, resp.Usage.TotalTokens, "cache hits consume no tokens")
+	assert.Equal(t, 1, stub.calls)
+
+	// 参数不同使用不同的缓存键，temperature 不为0时不缓存
+	req := chatRequest("北京天气怎么样", 0)
+	req.MaxTokens = 100
+	_, err = cached.CreateChatCompletion(ctx, req)
+	require.NoError(t, err)
+	_, err = cached.CreateChatCompletion(ctx, chatRequest("北京天气怎么样", 0.7))
+	require.NoError(t, err)
+	_, err = cached.CreateChatCompletion(ctx, chatRequest("北京天气怎么样", 0.7))
+	require.NoError(t, err)
+	assert.Equal(t, 4, stub.calls)
+
+	// 淘汰出内存的条目从磁盘恢复，新缓存实例同样可以读取
+	reopened := NewResponseCache(config.LLMCacheConfig{Enabled: true, TTL: 60, MaxEntries: 10, Dir: dir}, newTestLogger())
+	resp, err = reopened.wrap(stub).CreateChatCompletion(ctx, chatRequest("北京天气怎么样", 0))
+	require.NoError(t, err)
+	assert.Equal(t, "回复：北京天气怎么样", resp.Choices[0].Message.Content)
+	assert.Equal(t, 4, stub.calls)
+	assert.Equal(t, int64(1), reopened.Stats().DiskHits)
+}
+
+func TestFixtures_RecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.AzureOpenAIConfig{
+		Deployment:          "gpt-4o",
+		MaxCompletionTokens: 100,
+		Fixtures:            config.LLMFixturesConfig{Mode: config.LLMFixturesReplay, Dir: dir},
+	}
+	ctx := context.Background()
+	messages := []models.ChatMessage{{Role: "user", Content: "北京天气怎么样"}}
+
+	// 录制：用桩代替 Azure OpenAI
+	recorder := NewAzureOpenAIClient(&cfg, newTestLogger())
+	stub := &stubCompleter{}
+	recorder.client = newFixtureCompleter(config.LLMFixturesConfig{Mode: config.LLMFixturesRecord, Dir: dir}, stub, newTestLogger())
+	_, err := recorder.ChatCompletion(ctx, messages, "今天是2025-03-10")
+	require.NoError(t, err)
+	require.Equal(t, 1, stub.calls)
+
+	// 回放：不访问网络，系统提示词变化（如日期）时按其余部分匹配
+	replayer := NewAzureOpenAIClient(&cfg, newTestLogger())
+	result, err := replayer.ChatCompletion(ctx, messages, "今天是2025-03-10")
+	require.NoError(t, err)
+	assert.Equal(t, "回复：北京天气怎么样", result)
+	result, err = replayer.ChatCompletion(ctx, messages, "今天是2025-03-11")
+	require.NoError(t, err)
+	assert.Equal(t, "回复：北京天气怎么样", result)
+
+	_, err = replayer.ChatCompletion(ctx, []models.ChatMessage{{Role: "user", Content: "上海天气怎么样"}}, "")
+	assert.ErrorIs(t, err, ErrFixtureNotFound)
+}
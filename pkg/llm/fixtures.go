@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+)
+
+// ErrFixtureNotFound 回放模式下没有与请求匹配的夹具
+var ErrFixtureNotFound = errors.New("no recorded LLM fixture matches the request")
+
+// Fixture 一次录制的LLM调用，保存为 <key>.json
+type Fixture struct {
+	Key        string                        `json:"key"`
+	LooseKey   string                        `json:"loose_key"` // 不含系统提示词的请求键
+	RecordedAt time.Time                     `json:"recorded_at"`
+	Request    openai.ChatCompletionRequest  `json:"request"`
+	Response   openai.ChatCompletionResponse `json:"response"`
+}
+
+// looseKey 计算不含系统消息的请求键
+// 系统提示词包含当天日期和按A/B分流选中的模板版本，回放时精确键找不到夹具再按此键匹配。
+func looseKey(req openai.ChatCompletionRequest) string {
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		if msg.Role != openai.ChatMessageRoleSystem {
+			messages = append(messages, msg)
+		}
+	}
+	req.Messages = messages
+	return RequestKey(req)
+}
+
+// fixtureCompleter 录制或回放LLM调用
+// 录制模式调用下游并把成功的请求和响应写入夹具目录；回放模式只读取夹具，从不访问网络。
+type fixtureCompleter struct {
+	mode   string
+	dir    string
+	next   completer // 回放模式为空
+	logger *logrus.Logger
+	loose  map[string]string // 回放模式：不含系统提示词的请求键 -> 夹具文件，创建后只读
+}
+
+// newFixtureCompleter 创建录制回放 completer，回放模式时索引目录中已有的夹具
+func newFixtureCompleter(cfg config.LLMFixturesConfig, next completer, logger *logrus.Logger) *fixtureCompleter {
+	f := &fixtureCompleter{
+		mode:   cfg.Mode,
+		dir:    cfg.Dir,
+		next:   next,
+		logger: logger,
+		loose:  make(map[string]string),
+	}
+
+	switch cfg.Mode {
+	case config.LLMFixturesRecord:
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			logger.WithError(err).Warn("Failed to create LLM fixtures dir")
+		}
+	case config.LLMFixturesReplay:
+		f.index()
+		logger.WithFields(logrus.Fields{
+			"dir":      cfg.Dir,
+			"fixtures": len(f.loose),
+		}).Info("Replaying LLM calls from fixtures")
+	}
+	return f
+}
+
+// index 读取夹具目录建立不含系统提示词的键索引
+func (f *fixtureCompleter) index() {
+	paths, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		fixture, err := readFixture(path)
+		if err != nil {
+			f.logger.WithError(err).WithField("file", path).Warn("Skipping invalid LLM fixture")
+			continue
+		}
+		f.loose[fixture.LooseKey] = path
+	}
+}
+
+// readFixture 读取夹具文件
+func readFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture: %w", err)
+	}
+	return &fixture, nil
+}
+
+// CreateChatCompletion 按模式录制或回放
+func (f *fixtureCompleter) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	key := RequestKey(req)
+	if f.mode == config.LLMFixturesReplay {
+		return f.replay(key, req)
+	}
+
+	response, err := f.next.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return response, err
+	}
+	fixture := &Fixture{
+		Key:        key,
+		LooseKey:   looseKey(req),
+		RecordedAt: time.Now().UTC(),
+		Request:    req,
+		Response:   response,
+	}
+	if err := writeJSONFile(f.dir, filepath.Join(f.dir, key+".json"), fixture); err != nil {
+		f.logger.WithError(err).Warn("Failed to record LLM fixture")
+	} else {
+		f.logger.WithField("key", key[:12]).Debug("LLM fixture recorded")
+	}
+	return response, nil
+}
+
+// replay 返回与请求匹配的夹具响应，先按精确键，再按不含系统提示词的键
+func (f *fixtureCompleter) replay(key string, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	fixture, err := readFixture(filepath.Join(f.dir, key+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		path, ok := f.loose[looseKey(req)]
+		if !ok {
+			return openai.ChatCompletionResponse{}, fmt.Errorf("%w (key %s, last message %q)", ErrFixtureNotFound, key, lastMessage(req))
+		}
+		f.logger.WithField("fixture", filepath.Base(path)).Debug("Replaying LLM fixture recorded with a different system prompt")
+		fixture, err = readFixture(path)
+	}
+	if err != nil {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("failed to read LLM fixture: %w", err)
+	}
+	return fixture.Response, nil
+}
+
+// lastMessage 返回请求最后一条消息的开头，用于错误提示
+func lastMessage(req openai.ChatCompletionRequest) string {
+	if len(req.Messages) == 0 {
+		return ""
+	}
+	content := strings.TrimSpace(req.Messages[len(req.Messages)-1].Content)
+	if runes := []rune(content); len(runes) > 80 {
+		return string(runes[:80]) + "..."
+	}
+	return content
+}
@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+
+	"deer-flow-go/pkg/models"
+)
+
+// metadataKey 上下文中调用信息记录器的键
+type metadataKey struct{}
+
+// Metadata 记录一次请求中LLM调用使用的系统提示词版本、token用量和调用的工具
+type Metadata struct {
+	mu             sync.Mutex
+	promptVersions map[string]string
+	usage          models.TokenUsage
+	toolCalls      []string
+}
+
+// WithMetadata 返回附加了调用信息记录器的上下文，使用该上下文的LLM调用会写入记录器
+func WithMetadata(ctx context.Context) (context.Context, *Metadata) {
+	metadata := &Metadata{promptVersions: make(map[string]string)}
+	return context.WithValue(ctx, metadataKey{}, metadata), metadata
+}
+
+// recordPrompt 记录使用的系统提示词版本，上下文没有记录器时忽略
+func recordPrompt(ctx context.Context, name, version string) {
+	metadata, ok := ctx.Value(metadataKey{}).(*Metadata)
+	if !ok {
+		return
+	}
+	metadata.mu.Lock()
+	metadata.promptVersions[name] = version
+	metadata.mu.Unlock()
+}
+
+// recordUsage 累加一次LLM调用的token用量，上下文没有记录器时忽略
+func recordUsage(ctx context.Context, usage openai.Usage) {
+	metadata, ok := ctx.Value(metadataKey{}).(*Metadata)
+	if !ok {
+		return
+	}
+	metadata.mu.Lock()
+	metadata.usage.PromptTokens += usage.PromptTokens
+	metadata.usage.CompletionTokens += usage.CompletionTokens
+	metadata.usage.TotalTokens += usage.TotalTokens
+	metadata.usage.Calls++
+	metadata.mu.Unlock()
+}
+
+// RecordToolCall 记录一次MCP工具调用，用于按次计费，上下文没有记录器时忽略
+func RecordToolCall(ctx context.Context, tool string) {
+	metadata, ok := ctx.Value(metadataKey{}).(*Metadata)
+	if !ok {
+		return
+	}
+	metadata.mu.Lock()
+	metadata.toolCalls = append(metadata.toolCalls, tool)
+	metadata.mu.Unlock()
+}
+
+// Usage 返回累计的token用量，没有LLM调用时返回 nil
+func (m *Metadata) Usage() *models.TokenUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.usage.Calls == 0 {
+		return nil
+	}
+	usage := m.usage
+	return &usage
+}
+
+// Response 返回响应中的元数据，没有任何记录时返回 nil
+func (m *Metadata) Response() *models.ResponseMetadata {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.promptVersions) == 0 && len(m.toolCalls) == 0 {
+		return nil
+	}
+	versions := make(map[string]string, len(m.promptVersions))
+	for name, version := range m.promptVersions {
+		versions[name] = version
+	}
+	return &models.ResponseMetadata{
+		PromptVersions: versions,
+		ToolCalls:      append([]string(nil), m.toolCalls...),
+	}
+}
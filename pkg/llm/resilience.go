@@ -0,0 +1,535 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+)
+
+// ErrLLMUnavailable 所有部署都调用失败或处于熔断状态
+var ErrLLMUnavailable = errors.New("all LLM deployments are unavailable")
+
+// 熔断器状态
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// DeploymentStats 一个部署的调用统计和熔断状态
+type DeploymentStats struct {
+	Name                string    `json:"name"`
+	State               string    `json:"state"`                // closed | open | half_open
+	Attempts            int64     `json:"attempts"`             // 实际发出的调用次数
+	Successes           int64     `json:"successes"`            // 成功次数
+	Failures            int64     `json:"failures"`             // 失败次数
+	Retries             int64     `json:"retries"`              // 失败后在同一部署上重试的次数
+	FallbackSuccesses   int64     `json:"fallback_successes"`   // 作为备用部署成功处理的次数
+	CircuitOpens        int64     `json:"circuit_opens"`        // 熔断次数
+	ConsecutiveFailures int       `json:"consecutive_failures"` // 当前连续失败次数
+	LastError           string    `json:"last_error,omitempty"`
+	LastErrorAt         time.Time `json:"last_error_at,omitempty"`
+	LastLatencyMs       int64     `json:"last_latency_ms"`
+}
+
+// deploymentHealth 部署的熔断状态和调用统计，配置热更新后按部署名称保留
+type deploymentHealth struct {
+	mu        sync.Mutex
+	stats     DeploymentStats
+	openUntil time.Time
+	probing   bool // 半开状态下已放行试探请求
+}
+
+// allow 判断是否可以调用该部署：熔断期间拒绝，熔断结束后只放行一个试探请求
+func (h *deploymentHealth) allow(now time.Time, breaker config.LLMCircuitBreakerConfig) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !breaker.Enabled {
+		return true
+	}
+	switch h.state(now) {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if h.probing {
+			return false
+		}
+		h.probing = true
+	}
+	return true
+}
+
+// state 当前熔断状态，调用方需持有锁
+func (h *deploymentHealth) state(now time.Time) string {
+	if h.openUntil.IsZero() {
+		return CircuitClosed
+	}
+	if now.Before(h.openUntil) {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
+
+// success 记录一次成功调用并关闭熔断
+func (h *deploymentHealth) success(latency time.Duration, fallback bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats.Attempts++
+	h.stats.Successes++
+	if fallback {
+		h.stats.FallbackSuccesses++
+	}
+	h.stats.ConsecutiveFailures = 0
+	h.stats.LastLatencyMs = latency.Milliseconds()
+	h.openUntil = time.Time{}
+	h.probing = false
+}
+
+// failure 记录一次失败调用，只有可重试的失败（限流、服务端错误、超时）计入熔断，返回是否因此熔断
+func (h *deploymentHealth) failure(now time.Time, err error, retryable bool, latency time.Duration, breaker config.LLMCircuitBreakerConfig) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats.Attempts++
+	h.stats.Failures++
+	h.stats.LastError = err.Error()
+	h.stats.LastErrorAt = now
+	h.stats.LastLatencyMs = latency.Milliseconds()
+	if !retryable {
+		h.probing = false
+		return false
+	}
+	h.stats.ConsecutiveFailures++
+	if !breaker.Enabled {
+		return false
+	}
+	// 试探请求失败或连续失败达到阈值时熔断
+	if h.probing || h.stats.ConsecutiveFailures >= breaker.FailureThreshold {
+		h.openUntil = now.Add(time.Duration(breaker.Cooldown) * time.Second)
+		h.probing = false
+		h.stats.CircuitOpens++
+		return true
+	}
+	return false
+}
+
+// release 放弃放行的试探请求而不记录结果（调用方取消），下一个请求可以重新试探
+func (h *deploymentHealth) release() {
+	h.mu.Lock()
+	h.probing = false
+	h.mu.Unlock()
+}
+
+// retry 记录一次重试
+func (h *deploymentHealth) retry() {
+	h.mu.Lock()
+	h.stats.Retries++
+	h.mu.Unlock()
+}
+
+// snapshot 返回统计副本
+func (h *deploymentHealth) snapshot(now time.Time) DeploymentStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := h.stats
+	stats.State = h.state(now)
+	return stats
+}
+
+// healthRegistry 按部署名称保存熔断状态和统计
+type healthRegistry struct {
+	mu     sync.Mutex
+	byName map[string]*deploymentHealth
+}
+
+// newHealthRegistry 创建部署状态表
+func newHealthRegistry() *healthRegistry {
+	return &healthRegistry{byName: make(map[string]*deploymentHealth)}
+}
+
+// get 返回部署的状态，不存在时创建
+func (r *healthRegistry) get(name string) *deploymentHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	health, ok := r.byName[name]
+	if !ok {
+		health = &deploymentHealth{stats: DeploymentStats{Name: name}}
+		r.byName[name] = health
+	}
+	return health
+}
+
+// deployment 一个可调用的模型部署
+type deployment struct {
+	name   string
+	model  string // 请求中的 model：Azure 部署名或 OpenAI 模型名
+	client *openai.Client
+	health *deploymentHealth
+}
+
+// newDeployments 按配置创建主部署和备用部署，备用部署未配置的端点、密钥和版本沿用主部署
+func newDeployments(cfg *config.AzureOpenAIConfig, registry *healthRegistry) []*deployment {
+	deployments := []*deployment{{
+		name:   cfg.Deployment,
+		model:  cfg.Deployment,
+		client: newAzureClient(cfg.APIKey, cfg.Endpoint, cfg.APIVersion),
+		health: registry.get(cfg.Deployment),
+	}}
+	for _, fallback := range cfg.Fallbacks {
+		var client *openai.Client
+		if fallback.Provider == config.LLMProviderOpenAI {
+			clientConfig := openai.DefaultConfig(fallback.APIKey)
+			if fallback.Endpoint != "" {
+				clientConfig.BaseURL = fallback.Endpoint
+			}
+			clientConfig.HTTPClient = &retryAfterRecorder{client: &http.Client{}}
+			client = openai.NewClientWithConfig(clientConfig)
+		} else {
+			client = newAzureClient(
+				firstNonEmpty(fallback.APIKey, cfg.APIKey),
+				firstNonEmpty(fallback.Endpoint, cfg.Endpoint),
+				firstNonEmpty(fallback.APIVersion, cfg.APIVersion),
+			)
+		}
+		deployments = append(deployments, &deployment{
+			name:   fallback.Name,
+			model:  fallback.Deployment,
+			client: client,
+			health: registry.get(fallback.Name),
+		})
+	}
+	return deployments
+}
+
+// newAzureClient 创建 Azure OpenAI 客户端，记录错误响应的 Retry-After
+func newAzureClient(apiKey, endpoint, apiVersion string) *openai.Client {
+	clientConfig := openai.DefaultAzureConfig(apiKey, endpoint)
+	clientConfig.APIVersion = apiVersion
+	clientConfig.HTTPClient = &retryAfterRecorder{client: &http.Client{}}
+	return openai.NewClientWithConfig(clientConfig)
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// retryHint 一次调用的错误响应中服务端要求的等待时间
+type retryHint struct {
+	after time.Duration
+}
+
+// retryHintKey 上下文中 retryHint 的键
+type retryHintKey struct{}
+
+// retryAfterRecorder 记录错误响应的 Retry-After 头
+// go-openai 的错误类型不包含响应头，由 HTTP 层写入请求上下文中的 retryHint。
+type retryAfterRecorder struct {
+	client *http.Client
+}
+
+// Do 发送请求，错误响应带有 Retry-After 时写入上下文
+func (r *retryAfterRecorder) Do(req *http.Request) (*http.Response, error) {
+	resp, err := r.client.Do(req)
+	if resp != nil && resp.StatusCode >= http.StatusBadRequest {
+		if hint, ok := req.Context().Value(retryHintKey{}).(*retryHint); ok {
+			hint.after = parseRetryAfter(resp.Header, time.Now())
+		}
+	}
+	return resp, err
+}
+
+// parseRetryAfter 解析 Azure 的 retry-after-ms 和标准的 Retry-After（秒数或HTTP日期）
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if ms, err := strconv.Atoi(header.Get("retry-after-ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// statusCode 返回错误中的HTTP状态码，没有时为0
+func statusCode(err error) int {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode
+	}
+	return 0
+}
+
+// isRetryable 判断失败是否值得重试：限流、服务端错误、超时和网络错误
+// 请求本身无效（400、内容过滤等）时重试和换部署都不会成功；部署自身的错误见 isDeploymentError。
+func isRetryable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch code := statusCode(err); {
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError:
+		return true
+	case code > 0:
+		return false
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// isDeploymentError 判断失败是否只与当前部署有关：密钥无效、无权限或部署不存在
+// 这类错误重试没有意义，但备用部署使用各自的端点、密钥和部署名，可能成功。
+func isDeploymentError(err error) bool {
+	switch statusCode(err) {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
+	}
+	return false
+}
+
+// resilientCompleter 带重试、单次超时、熔断和备用部署的调用层
+// 每个部署最多尝试 retry.max_attempts 次，可重试的失败按 Retry-After 或带抖动的指数退避等待；
+// 部署重试耗尽、熔断、返回 401/403/404 或要求的等待超过 max_backoff_ms 时按顺序切换到下一个部署。
+type resilientCompleter struct {
+	deployments []*deployment
+	cfg         config.AzureOpenAIConfig
+	logger      *logrus.Logger
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// newResilientCompleter 创建调用层
+func newResilientCompleter(cfg *config.AzureOpenAIConfig, registry *healthRegistry, logger *logrus.Logger) *resilientCompleter {
+	return &resilientCompleter{
+		deployments: newDeployments(cfg, registry),
+		cfg:         *cfg,
+		logger:      logger,
+		now:         time.Now,
+		sleep:       sleepContext,
+	}
+}
+
+// maxAttempts 每个部署的最多尝试次数，未配置时只尝试一次
+func (r *resilientCompleter) maxAttempts() int {
+	if r.cfg.Retry.MaxAttempts < 1 {
+		return 1
+	}
+	return r.cfg.Retry.MaxAttempts
+}
+
+// withTimeout 为单次尝试设置超时，未配置时只受 ctx 限制
+func (r *resilientCompleter) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.cfg.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(r.cfg.Timeout)*time.Second)
+}
+
+// sleepContext 等待指定时间或直到 ctx 取消
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff 计算第 attempt 次失败后的等待时间：服务端给出 Retry-After 时照做，否则指数退避并在后一半区间随机抖动
+func (r *resilientCompleter) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	maxBackoff := time.Duration(r.cfg.Retry.MaxBackoffMs) * time.Millisecond
+	base := time.Duration(r.cfg.Retry.InitialBackoffMs) * time.Millisecond
+	for i := 1; i < attempt && base < maxBackoff; i++ {
+		base *= 2
+	}
+	if base > maxBackoff {
+		base = maxBackoff
+	}
+	half := base / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// do 依次在各部署上执行 call，直到成功、遇到请求本身无效的错误或全部部署失败
+func (r *resilientCompleter) do(ctx context.Context, call func(ctx context.Context, d *deployment) error) error {
+	var lastErr error
+	maxAttempts := r.maxAttempts()
+	maxBackoff := time.Duration(r.cfg.Retry.MaxBackoffMs) * time.Millisecond
+
+	for i, d := range r.deployments {
+		if i > 0 {
+			r.logger.WithFields(logrus.Fields{
+				"deployment": d.name,
+				"error":      lastErr,
+			}).Warn("Falling back to next LLM deployment")
+		}
+
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
+			if !d.health.allow(r.now(), r.cfg.CircuitBreaker) {
+				r.logger.WithField("deployment", d.name).Debug("LLM deployment circuit is open, skipping")
+				if attempt == 1 {
+					lastErr = fmt.Errorf("deployment %s: circuit open", d.name)
+				}
+				break
+			}
+
+			hint := &retryHint{}
+			start := r.now()
+			err := call(context.WithValue(ctx, retryHintKey{}, hint), d)
+			latency := r.now().Sub(start)
+			if err == nil {
+				d.health.success(latency, i > 0)
+				r.logger.WithFields(logrus.Fields{
+					"deployment": d.name,
+					"attempt":    attempt,
+					"latency_ms": latency.Milliseconds(),
+				}).Debug("LLM call succeeded")
+				return nil
+			}
+			if ctx.Err() != nil {
+				// 调用方取消或整体超时，不计入部署的失败；释放试探名额，否则半开的部署会一直被拒绝
+				d.health.release()
+				return err
+			}
+
+			retryable := isRetryable(err)
+			opened := d.health.failure(r.now(), err, retryable, latency, r.cfg.CircuitBreaker)
+			lastErr = fmt.Errorf("deployment %s: %w", d.name, err)
+			r.logger.WithFields(logrus.Fields{
+				"deployment":   d.name,
+				"attempt":      attempt,
+				"status":       statusCode(err),
+				"retryable":    retryable,
+				"retry_after":  hint.after,
+				"latency_ms":   latency.Milliseconds(),
+				"circuit_open": opened,
+			}).WithError(err).Warn("LLM call attempt failed")
+			if !retryable {
+				if isDeploymentError(err) {
+					// 部署配置问题，直接切换到下一个部署
+					break
+				}
+				return err
+			}
+			if opened || attempt == maxAttempts {
+				break
+			}
+
+			wait := r.backoff(attempt, hint.after)
+			if wait > maxBackoff {
+				// 服务端要求的等待过长，直接切换部署
+				break
+			}
+			d.health.retry()
+			if err := r.sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrLLMUnavailable, lastErr)
+}
+
+// CreateChatCompletion 带重试和备用部署的聊天完成调用，每次尝试单独计时
+func (r *resilientCompleter) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	var response openai.ChatCompletionResponse
+	err := r.do(ctx, func(ctx context.Context, d *deployment) error {
+		attemptCtx, cancel := r.withTimeout(ctx)
+		defer cancel()
+		req.Model = d.model
+		resp, err := d.client.CreateChatCompletion(attemptCtx, req)
+		if err != nil {
+			return err
+		}
+		response = resp
+		return nil
+	})
+	return response, err
+}
+
+// chunkStream 流式响应
+type chunkStream interface {
+	Recv() (openai.ChatCompletionStreamResponse, error)
+	Close() error
+}
+
+// cancelStream 关闭流时同时释放其上下文
+type cancelStream struct {
+	*openai.ChatCompletionStream
+	cancel context.CancelFunc
+}
+
+// Close 关闭连接并释放上下文
+func (s *cancelStream) Close() error {
+	err := s.ChatCompletionStream.Close()
+	s.cancel()
+	return err
+}
+
+// openStream 带重试和备用部署地建立流式连接，超时只限制到收到响应头为止，流开始后的错误不重试
+func (r *resilientCompleter) openStream(ctx context.Context, req openai.ChatCompletionRequest) (chunkStream, error) {
+	timeout := time.Duration(r.cfg.Timeout) * time.Second
+	var stream chunkStream
+	err := r.do(ctx, func(ctx context.Context, d *deployment) error {
+		streamCtx, cancel := context.WithCancel(ctx)
+		var timer *time.Timer
+		if timeout > 0 {
+			timer = time.AfterFunc(timeout, cancel)
+		}
+		req.Model = d.model
+		s, err := d.client.CreateChatCompletionStream(streamCtx, req)
+		timedOut := timer != nil && !timer.Stop()
+		if err == nil && timedOut {
+			s.Close()
+			err = context.DeadlineExceeded
+		}
+		if err != nil {
+			cancel()
+			if timedOut && ctx.Err() == nil {
+				return fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+			}
+			return err
+		}
+		stream = &cancelStream{ChatCompletionStream: s, cancel: cancel}
+		return nil
+	})
+	return stream, err
+}
+
+// stats 返回各部署的统计，按调用顺序排列
+func (r *resilientCompleter) stats() []DeploymentStats {
+	now := r.now()
+	stats := make([]DeploymentStats, 0, len(r.deployments))
+	for _, d := range r.deployments {
+		stats = append(stats, d.health.snapshot(now))
+	}
+	return stats
+}
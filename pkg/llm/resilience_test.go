@@ -0,0 +1,251 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// deploymentServer 按部署名返回预设的状态码序列，序列用完后返回成功
+type deploymentServer struct {
+	mu        sync.Mutex
+	responses map[string][]int
+	calls     map[string]int
+}
+
+func newDeploymentServer(t *testing.T, responses map[string][]int) (*deploymentServer, *httptest.Server) {
+	t.Helper()
+	d := &deploymentServer{responses: responses, calls: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// /openai/deployments/<deployment>/chat/completions
+		parts := strings.Split(r.URL.Path, "/")
+		name := parts[len(parts)-3]
+
+		d.mu.Lock()
+		d.calls[name]++
+		status := http.StatusOK
+		if queue := d.responses[name]; len(queue) > 0 {
+			status, d.responses[name] = queue[0], queue[1:]
+		}
+		d.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if status != http.StatusOK {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("retry-after-ms", "20")
+			}
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"code":"%d","message":"status %d"}}`, status, status)
+			return
+		}
+		fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"来自%s"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`, name)
+	}))
+	t.Cleanup(server.Close)
+	return d, server
+}
+
+func (d *deploymentServer) count(name string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls[name]
+}
+
+func newResilientTestClient(endpoint string, cfg config.AzureOpenAIConfig) (*AzureOpenAIClient, *[]time.Duration) {
+	cfg.Endpoint = endpoint
+	cfg.APIKey = "test-key"
+	cfg.Deployment = "primary"
+	cfg.APIVersion = "2024-10-21"
+	cfg.MaxCompletionTokens = 100
+	cfg.Timeout = 5
+	cfg.Fixtures = config.LLMFixturesConfig{Mode: config.LLMFixturesOff}
+	client := NewAzureOpenAIClient(&cfg, newTestLogger())
+
+	waits := &[]time.Duration{}
+	client.resilient.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return client, waits
+}
+
+func deploymentStats(client *AzureOpenAIClient, name string) DeploymentStats {
+	for _, stats := range client.Stats().Deployments {
+		if stats.Name == name {
+			return stats
+		}
+	}
+	return DeploymentStats{}
+}
+
+var weatherQuestion = []models.ChatMessage{{Role: "user", Content: "北京天气"}}
+
+func TestResilience_RetryAfter(t *testing.T) {
+	server, httpServer := newDeploymentServer(t, map[string][]int{"primary": {http.StatusTooManyRequests, http.StatusServiceUnavailable}})
+	client, waits := newResilientTestClient(httpServer.URL, config.AzureOpenAIConfig{
+		Retry: config.LLMRetryConfig{MaxAttempts: 3, InitialBackoffMs: 100, MaxBackoffMs: 1000},
+	})
+
+	result, err := client.ChatCompletion(context.Background(), weatherQuestion, "")
+	require.NoError(t, err)
+	assert.Equal(t, "来自primary", result)
+	assert.Equal(t, 3, server.count("primary"))
+
+	// 429 按 retry-after-ms 等待，503 按指数退避在 [100ms, 200ms] 内随机等待
+	require.Len(t, *waits, 2)
+	assert.Equal(t, 20*time.Millisecond, (*waits)[0])
+	assert.GreaterOrEqual(t, (*waits)[1], 100*time.Millisecond)
+	assert.LessOrEqual(t, (*waits)[1], 200*time.Millisecond)
+
+	stats := deploymentStats(client, "primary")
+	assert.Equal(t, int64(3), stats.Attempts)
+	assert.Equal(t, int64(2), stats.Retries)
+	assert.Equal(t, int64(1), stats.Successes)
+	assert.Equal(t, CircuitClosed, stats.State)
+}
+
+func TestResilience_Fallback(t *testing.T) {
+	server, httpServer := newDeploymentServer(t, map[string][]int{"primary": {500, 500, 500}})
+	client, _ := newResilientTestClient(httpServer.URL, config.AzureOpenAIConfig{
+		Retry:     config.LLMRetryConfig{MaxAttempts: 2, InitialBackoffMs: 10, MaxBackoffMs: 10},
+		Fallbacks: []config.LLMFallbackConfig{{Name: "backup", Deployment: "gpt-4o-backup"}},
+	})
+
+	result, err := client.ChatCompletion(context.Background(), weatherQuestion, "")
+	require.NoError(t, err)
+	assert.Equal(t, "来自gpt-4o-backup", result, "fallback inherits the primary endpoint and key")
+	assert.Equal(t, 2, server.count("primary"))
+
+	backup := deploymentStats(client, "backup")
+	assert.Equal(t, int64(1), backup.FallbackSuccesses)
+
+	// 热更新后保留统计
+	client.UpdateConfig(*client.config)
+	assert.Equal(t, int64(2), deploymentStats(client, "primary").Failures)
+}
+
+func TestResilience_CircuitBreaker(t *testing.T) {
+	server, httpServer := newDeploymentServer(t, map[string][]int{"primary": {500, 500, 500}})
+	client, _ := newResilientTestClient(httpServer.URL, config.AzureOpenAIConfig{
+		Retry:          config.LLMRetryConfig{MaxAttempts: 1, InitialBackoffMs: 10, MaxBackoffMs: 10},
+		CircuitBreaker: config.LLMCircuitBreakerConfig{Enabled: true, FailureThreshold: 2, Cooldown: 30},
+	})
+	now := time.Now()
+	client.resilient.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ChatCompletion(ctx, weatherQuestion, "")
+		assert.ErrorIs(t, err, ErrLLMUnavailable)
+	}
+	assert.Equal(t, CircuitOpen, deploymentStats(client, "primary").State)
+
+	// 熔断期间不访问部署
+	_, err := client.ChatCompletion(ctx, weatherQuestion, "")
+	assert.ErrorIs(t, err, ErrLLMUnavailable)
+	assert.Equal(t, 2, server.count("primary"))
+
+	// 冷却结束后放行一次试探请求，失败则再次熔断
+	now = now.Add(31 * time.Second)
+	assert.Equal(t, CircuitHalfOpen, deploymentStats(client, "primary").State)
+	_, err = client.ChatCompletion(ctx, weatherQuestion, "")
+	assert.ErrorIs(t, err, ErrLLMUnavailable)
+	assert.Equal(t, 3, server.count("primary"))
+	assert.Equal(t, int64(2), deploymentStats(client, "primary").CircuitOpens)
+
+	// 试探成功后关闭熔断
+	now = now.Add(31 * time.Second)
+	result, err := client.ChatCompletion(ctx, weatherQuestion, "")
+	require.NoError(t, err)
+	assert.Equal(t, "来自primary", result)
+	assert.Equal(t, CircuitClosed, deploymentStats(client, "primary").State)
+}
+
+func TestResilience_NonRetryable(t *testing.T) {
+	server, httpServer := newDeploymentServer(t, map[string][]int{"primary": {http.StatusBadRequest}})
+	client, waits := newResilientTestClient(httpServer.URL, config.AzureOpenAIConfig{
+		Retry:     config.LLMRetryConfig{MaxAttempts: 3, InitialBackoffMs: 10, MaxBackoffMs: 10},
+		Fallbacks: []config.LLMFallbackConfig{{Name: "backup", Deployment: "gpt-4o-backup"}},
+	})
+
+	_, err := client.ChatCompletion(context.Background(), weatherQuestion, "")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrLLMUnavailable)
+	assert.Equal(t, 1, server.count("primary"))
+	assert.Zero(t, server.count("gpt-4o-backup"))
+	assert.Empty(t, *waits)
+}
+
+func TestResilience_DeploymentErrorFallsBack(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			server, httpServer := newDeploymentServer(t, map[string][]int{"primary": {status}})
+			client, waits := newResilientTestClient(httpServer.URL, config.AzureOpenAIConfig{
+				Retry:     config.LLMRetryConfig{MaxAttempts: 3, InitialBackoffMs: 10, MaxBackoffMs: 10},
+				Fallbacks: []config.LLMFallbackConfig{{Name: "backup", Deployment: "gpt-4o-backup"}},
+			})
+
+			result, err := client.ChatCompletion(context.Background(), weatherQuestion, "")
+			require.NoError(t, err)
+			assert.Equal(t, "来自gpt-4o-backup", result)
+			assert.Equal(t, 1, server.count("primary"), "deployment errors are not retried")
+			assert.Empty(t, *waits)
+		})
+	}
+}
+
+func TestResilience_CancelledProbeReleasesSlot(t *testing.T) {
+	server, httpServer := newDeploymentServer(t, map[string][]int{"primary": {500, 500}})
+	client, _ := newResilientTestClient(httpServer.URL, config.AzureOpenAIConfig{
+		Retry:          config.LLMRetryConfig{MaxAttempts: 1, InitialBackoffMs: 10, MaxBackoffMs: 10},
+		CircuitBreaker: config.LLMCircuitBreakerConfig{Enabled: true, FailureThreshold: 2, Cooldown: 30},
+	})
+	now := time.Now()
+	client.resilient.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ChatCompletion(context.Background(), weatherQuestion, "")
+		assert.ErrorIs(t, err, ErrLLMUnavailable)
+	}
+	now = now.Add(31 * time.Second)
+	require.Equal(t, CircuitHalfOpen, deploymentStats(client, "primary").State)
+
+	// 调用方取消试探请求，不计入失败，也不占用试探名额
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.ChatCompletion(ctx, weatherQuestion, "")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, CircuitHalfOpen, deploymentStats(client, "primary").State)
+
+	result, err := client.ChatCompletion(context.Background(), weatherQuestion, "")
+	require.NoError(t, err)
+	assert.Equal(t, "来自primary", result)
+	assert.Equal(t, 3, server.count("primary"))
+	assert.Equal(t, CircuitClosed, deploymentStats(client, "primary").State)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	header := http.Header{}
+	assert.Zero(t, parseRetryAfter(header, now))
+
+	header.Set("Retry-After", "3")
+	assert.Equal(t, 3*time.Second, parseRetryAfter(header, now))
+
+	header.Set("Retry-After", now.Add(10*time.Second).Format(http.TimeFormat))
+	assert.Equal(t, 10*time.Second, parseRetryAfter(header, now))
+
+	header.Set("retry-after-ms", "250")
+	assert.Equal(t, 250*time.Millisecond, parseRetryAfter(header, now))
+}
@@ -0,0 +1,223 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ValidateSchema 按 JSON Schema 校验解码后的JSON值，返回全部错误，每条错误以字段路径（如 $.params.city）开头
+// 只支持工具参数常用的关键字：type、properties、required、additionalProperties、enum、items、
+// minimum、maximum、minLength、maxLength、minItems、maxItems、anyOf、oneOf（按 anyOf 处理），其余关键字被忽略。
+func ValidateSchema(schema map[string]interface{}, value interface{}) []string {
+	var errs []string
+	validateSchema(schema, value, "$", &errs)
+	return errs
+}
+
+// validateSchema 校验 value 并把错误追加到 errs
+func validateSchema(schema map[string]interface{}, value interface{}, path string, errs *[]string) {
+	if schema == nil {
+		return
+	}
+	addf := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesType(types, value) {
+		addf("expected %s, got %s", strings.Join(types, " or "), jsonType(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !inEnum(enum, value) {
+		allowed := make([]string, 0, len(enum))
+		for _, e := range enum {
+			allowed = append(allowed, jsonString(e))
+		}
+		addf("must be one of %s, got %s", strings.Join(allowed, ", "), jsonString(value))
+	}
+
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		alternatives, ok := schema[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		matched := false
+		for _, alternative := range alternatives {
+			sub, _ := alternative.(map[string]interface{})
+			var subErrs []string
+			validateSchema(sub, value, path, &subErrs)
+			if len(subErrs) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			addf("does not match any of the allowed schemas")
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateObject(schema, v, path, errs)
+	case []interface{}:
+		if n, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < n {
+			addf("must have at least %v items, got %d", n, len(v))
+		}
+		if n, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > n {
+			addf("must have at most %v items, got %d", n, len(v))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case string:
+		length := len([]rune(v))
+		if n, ok := schemaNumber(schema["minLength"]); ok && float64(length) < n {
+			addf("must be at least %v characters long", n)
+		}
+		if n, ok := schemaNumber(schema["maxLength"]); ok && float64(length) > n {
+			addf("must be at most %v characters long", n)
+		}
+	case float64:
+		if n, ok := schemaNumber(schema["minimum"]); ok && v < n {
+			addf("must be >= %v, got %v", n, v)
+		}
+		if n, ok := schemaNumber(schema["maximum"]); ok && v > n {
+			addf("must be <= %v, got %v", n, v)
+		}
+	}
+}
+
+// validateObject 校验对象的必填字段、已知字段和额外字段，按字段名排序以保证错误顺序稳定
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string, errs *[]string) {
+	for _, name := range schemaStrings(schema["required"]) {
+		if _, ok := object[name]; !ok {
+			*errs = append(*errs, fmt.Sprintf("%s.%s: is required", path, name))
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			validateSchema(property, object[name], path+"."+name, errs)
+			continue
+		}
+		if _, known := properties[name]; known {
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*errs = append(*errs, fmt.Sprintf("%s.%s: is not an allowed field", path, name))
+			}
+		case map[string]interface{}:
+			validateSchema(additional, object[name], path+"."+name, errs)
+		}
+	}
+}
+
+// schemaTypes 读取 type 关键字，可以是字符串或字符串数组
+func schemaTypes(value interface{}) []string {
+	if t, ok := value.(string); ok {
+		return []string{t}
+	}
+	return schemaStrings(value)
+}
+
+// schemaStrings 读取字符串数组，兼容JSON解码的 []interface{} 和Go代码中的 []string
+func schemaStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// schemaNumber 读取数值关键字
+func schemaNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// matchesType 判断值是否属于任一类型，integer 为没有小数部分的数字
+func matchesType(types []string, value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		switch {
+		case t == actual:
+			return true
+		case t == "integer" && actual == "number":
+			if n := value.(float64); n == math.Trunc(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonType 返回解码后JSON值的类型名
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// inEnum 判断值是否在枚举中，按JSON表示比较以兼容Go代码中的整数枚举
+func inEnum(enum []interface{}, value interface{}) bool {
+	encoded := jsonString(value)
+	for _, e := range enum {
+		if jsonString(e) == encoded {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonString 返回值的JSON表示
+func jsonString(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
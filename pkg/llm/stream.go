@@ -0,0 +1,355 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/models"
+)
+
+// 流式调用的结束原因
+const (
+	FinishStop          = "stop"           // 模型正常结束
+	FinishLength        = "length"         // 达到 max_tokens
+	FinishToolCalls     = "tool_calls"     // 模型请求调用工具
+	FinishContentFilter = "content_filter" // 内容被过滤
+)
+
+// Tool 流式调用中提供给模型的函数工具
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON Schema，与MCP工具的 inputSchema 相同
+}
+
+// ToolCall 模型请求的一次工具调用，Arguments 为拼接完成的JSON参数
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// StreamEvent 流式调用的一个事件
+// 文本增量事件只设置 Content；最后一个事件设置 FinishReason 和组装完成的 ToolCalls，或者设置 Err。
+type StreamEvent struct {
+	Content      string
+	ToolCalls    []ToolCall
+	FinishReason string
+	Err          error
+}
+
+// StreamResult 读完整个流后的结果
+type StreamResult struct {
+	Content      string
+	ToolCalls    []ToolCall
+	FinishReason string
+}
+
+// Collect 读取流直到结束，返回拼接后的文本和工具调用
+func Collect(events <-chan StreamEvent) (*StreamResult, error) {
+	result := &StreamResult{}
+	var content strings.Builder
+	for event := range events {
+		if event.Err != nil {
+			result.Content = content.String()
+			return result, event.Err
+		}
+		content.WriteString(event.Content)
+		if event.FinishReason != "" {
+			result.FinishReason = event.FinishReason
+			result.ToolCalls = event.ToolCalls
+		}
+	}
+	result.Content = content.String()
+	return result, nil
+}
+
+// streamOutputKey 上下文中流式输出回调的键
+type streamOutputKey struct{}
+
+// WithStreamOutput 返回附加了流式输出回调的上下文
+// 使用该上下文生成最终回复的LLM调用（整理搜索结果）改为流式调用，每个文本增量生成后立即交给 onDelta。
+func WithStreamOutput(ctx context.Context, onDelta func(delta string)) context.Context {
+	return context.WithValue(ctx, streamOutputKey{}, onDelta)
+}
+
+// StreamOutputFromContext 返回上下文中的流式输出回调，没有时返回 nil
+func StreamOutputFromContext(ctx context.Context) func(delta string) {
+	onDelta, _ := ctx.Value(streamOutputKey{}).(func(string))
+	return onDelta
+}
+
+// completeReply 生成交给用户的最终回复
+// 上下文带有流式输出回调时使用 ChatCompletionStream 并转发文本增量，否则使用普通调用。
+// 流中途出错时返回已生成的部分内容和错误。
+func (c *AzureOpenAIClient) completeReply(ctx context.Context, messages []models.ChatMessage, systemPrompt string) (string, error) {
+	onDelta := StreamOutputFromContext(ctx)
+	if onDelta == nil {
+		return c.ChatCompletion(ctx, messages, systemPrompt)
+	}
+
+	events, err := c.ChatCompletionStream(ctx, messages, systemPrompt)
+	if err != nil {
+		return "", err
+	}
+	var content strings.Builder
+	for event := range events {
+		if event.Err != nil {
+			return content.String(), event.Err
+		}
+		if event.Content != "" {
+			content.WriteString(event.Content)
+			onDelta(event.Content)
+		}
+	}
+	return content.String(), nil
+}
+
+// ChatCompletionStream 流式调用聊天完成API，返回的通道按生成顺序输出文本增量，结束后关闭
+// 请求发送前的错误（如超出上下文窗口、连接失败、HTTP错误）直接返回；流中途的错误作为最后一个事件的 Err 返回。
+// ctx 取消时停止读取并关闭连接，最后一个事件的 Err 为 ctx.Err()。
+// 录制回放模式下改用非流式调用，完整回复作为一个增量返回。
+func (c *AzureOpenAIClient) ChatCompletionStream(ctx context.Context, messages []models.ChatMessage, systemPrompt string, tools ...Tool) (<-chan StreamEvent, error) {
+	c.mu.RLock()
+	client, streamer, cfg := c.client, c.streamer, *c.config
+	c.mu.RUnlock()
+
+	req, promptTokens, err := buildRequest(cfg, messages, systemPrompt)
+	if err != nil {
+		return nil, err
+	}
+	req.Tools = openAITools(tools)
+
+	c.logger.WithFields(logrus.Fields{
+		"deployment":       cfg.Deployment,
+		"messages":         len(req.Messages),
+		"tools":            len(tools),
+		"estimated_tokens": promptTokens,
+	}).Debug("Calling Azure OpenAI streaming API")
+
+	if streamer == nil {
+		return c.completeAsStream(ctx, client, req)
+	}
+
+	if supportsStreamUsage(cfg.APIVersion) {
+		req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
+	stream, err := streamer.openStream(ctx, req)
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to call Azure OpenAI streaming API")
+		return nil, fmt.Errorf("Azure OpenAI API call failed: %w", err)
+	}
+
+	events := make(chan StreamEvent, 16)
+	go c.readStream(ctx, stream, promptTokens, events)
+	return events, nil
+}
+
+// readStream 读取流式响应，转发文本增量并按索引拼接工具调用参数
+func (c *AzureOpenAIClient) readStream(ctx context.Context, stream chunkStream, promptTokens int, events chan<- StreamEvent) {
+	defer close(events)
+	defer stream.Close()
+
+	var (
+		content      strings.Builder
+		finishReason string
+		usage        *openai.Usage
+		calls        = newToolCallAssembler()
+	)
+	finish := func(err error) {
+		if usage == nil {
+			// API版本不支持流式用量时按生成的内容估算
+			completion := EstimateTokens(content.String() + calls.argumentsText())
+			usage = &openai.Usage{PromptTokens: promptTokens, CompletionTokens: completion, TotalTokens: promptTokens + completion}
+		}
+		recordUsage(ctx, *usage)
+
+		event := StreamEvent{Err: err}
+		if err == nil {
+			event.FinishReason = finishReason
+			event.ToolCalls = calls.calls()
+		}
+		// 缓冲区有空位时总是送达；调用方取消后可能已不再读取，不再阻塞等待
+		select {
+		case events <- event:
+		default:
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}
+
+		c.logger.WithFields(logrus.Fields{
+			"response_length":   content.Len(),
+			"finish_reason":     finishReason,
+			"tool_calls":        len(event.ToolCalls),
+			"completion_tokens": usage.CompletionTokens,
+		}).Debug("Azure OpenAI stream finished")
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			if finishReason == "" {
+				finish(fmt.Errorf("stream ended without a finish reason: %w", io.ErrUnexpectedEOF))
+				return
+			}
+			finish(nil)
+			return
+		}
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			} else {
+				c.logger.WithError(err).Error("Azure OpenAI stream interrupted")
+			}
+			finish(err)
+			return
+		}
+
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index != 0 {
+				continue
+			}
+			for _, delta := range choice.Delta.ToolCalls {
+				calls.add(delta)
+			}
+			if choice.FinishReason != "" {
+				finishReason = string(choice.FinishReason)
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			select {
+			case events <- StreamEvent{Content: choice.Delta.Content}:
+			case <-ctx.Done():
+				finish(ctx.Err())
+				return
+			}
+		}
+	}
+}
+
+// completeAsStream 使用非流式调用（经过缓存和录制回放）并以流的形式返回结果
+func (c *AzureOpenAIClient) completeAsStream(ctx context.Context, client completer, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	resp, err := client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to call Azure OpenAI API")
+		return nil, fmt.Errorf("Azure OpenAI API call failed: %w", err)
+	}
+	recordUsage(ctx, resp.Usage)
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices returned from Azure OpenAI")
+	}
+
+	choice := resp.Choices[0]
+	calls := newToolCallAssembler()
+	for _, call := range choice.Message.ToolCalls {
+		calls.add(call)
+	}
+
+	events := make(chan StreamEvent, 2)
+	if choice.Message.Content != "" {
+		events <- StreamEvent{Content: choice.Message.Content}
+	}
+	events <- StreamEvent{FinishReason: string(choice.FinishReason), ToolCalls: calls.calls()}
+	close(events)
+	return events, nil
+}
+
+// toolCallAssembler 按索引拼接分散在多个数据块中的工具调用
+// 每个工具调用的第一个数据块带有 ID 和函数名，之后的数据块只带有参数片段。
+type toolCallAssembler struct {
+	byIndex map[int]*ToolCall
+	last    int
+}
+
+// newToolCallAssembler 创建工具调用拼接器
+func newToolCallAssembler() *toolCallAssembler {
+	return &toolCallAssembler{byIndex: make(map[int]*ToolCall), last: -1}
+}
+
+// add 合并一个工具调用片段，没有索引时视为新的调用（非流式响应）
+func (a *toolCallAssembler) add(delta openai.ToolCall) {
+	index := a.last + 1
+	if delta.Index != nil {
+		index = *delta.Index
+	}
+	call, ok := a.byIndex[index]
+	if !ok {
+		call = &ToolCall{}
+		a.byIndex[index] = call
+	}
+	if index > a.last {
+		a.last = index
+	}
+	if delta.ID != "" {
+		call.ID = delta.ID
+	}
+	if delta.Function.Name != "" {
+		call.Name = delta.Function.Name
+	}
+	call.Arguments += delta.Function.Arguments
+}
+
+// calls 按索引顺序返回组装完成的工具调用
+func (a *toolCallAssembler) calls() []ToolCall {
+	if len(a.byIndex) == 0 {
+		return nil
+	}
+	indexes := make([]int, 0, len(a.byIndex))
+	for index := range a.byIndex {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	calls := make([]ToolCall, 0, len(indexes))
+	for _, index := range indexes {
+		calls = append(calls, *a.byIndex[index])
+	}
+	return calls
+}
+
+// argumentsText 返回所有工具调用参数，用于估算token
+func (a *toolCallAssembler) argumentsText() string {
+	var text strings.Builder
+	for _, call := range a.byIndex {
+		text.WriteString(call.Name)
+		text.WriteString(call.Arguments)
+	}
+	return text.String()
+}
+
+// openAITools 转换为 OpenAI 函数工具定义
+func openAITools(tools []Tool) []openai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	result := make([]openai.Tool, 0, len(tools))
+	for _, tool := range tools {
+		result = append(result, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	return result
+}
+
+// supportsStreamUsage Azure OpenAI 从 2024-09-01-preview 开始支持 stream_options.include_usage
+func supportsStreamUsage(apiVersion string) bool {
+	return apiVersion >= "2024-09-01"
+}
@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// newStreamServer 返回按顺序发送给定SSE数据块的服务器，block 为真时发送完后等待客户端断开
+func newStreamServer(t *testing.T, chunks []string, block bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+		if block {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newStreamClient(endpoint, apiVersion string) *AzureOpenAIClient {
+	return NewAzureOpenAIClient(&config.AzureOpenAIConfig{
+		Endpoint:            endpoint,
+		APIKey:              "test-key",
+		Deployment:          "gpt-4o",
+		APIVersion:          apiVersion,
+		MaxCompletionTokens: 100,
+		Fixtures:            config.LLMFixturesConfig{Mode: config.LLMFixturesOff},
+	}, newTestLogger())
+}
+
+func TestChatCompletionStream_ToolCalls(t *testing.T) {
+	server := newStreamServer(t, []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":"正在"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"查询"}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"search","arguments":"{\"query\":\"北京\"}"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"北京\"}"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":42,"completion_tokens":7,"total_tokens":49}}`,
+	}, false)
+	client := newStreamClient(server.URL, "2024-10-21")
+
+	ctx, metadata := WithMetadata(context.Background())
+	events, err := client.ChatCompletionStream(ctx, []models.ChatMessage{{Role: "user", Content: "北京天气"}}, "", Tool{
+		Name:       "get_weather",
+		Parameters: map[string]interface{}{"type": "object"},
+	})
+	require.NoError(t, err)
+
+	var deltas []string
+	var last StreamEvent
+	for event := range events {
+		if event.Content != "" {
+			deltas = append(deltas, event.Content)
+		}
+		last = event
+	}
+	require.NoError(t, last.Err)
+	assert.Equal(t, []string{"正在", "查询"}, deltas)
+	assert.Equal(t, FinishToolCalls, last.FinishReason)
+	assert.Equal(t, []ToolCall{
+		{ID: "call_1", Name: "get_weather", Arguments: `{"city":"北京"}`},
+		{ID: "call_2", Name: "search", Arguments: `{"query":"北京"}`},
+	}, last.ToolCalls)
+	assert.Equal(t, &models.TokenUsage{PromptTokens: 42, CompletionTokens: 7, TotalTokens: 49, Calls: 1}, metadata.Usage())
+}
+
+func TestChatCompletionStream_Collect(t *testing.T) {
+	server := newStreamServer(t, []string{
+		`{"choices":[{"index":0,"delta":{"content":"北京今天"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"晴"},"finish_reason":"stop"}]}`,
+	}, false)
+	client := newStreamClient(server.URL, "2023-08-01-preview")
+
+	ctx, metadata := WithMetadata(context.Background())
+	events, err := client.ChatCompletionStream(ctx, []models.ChatMessage{{Role: "user", Content: "北京天气"}}, "")
+	require.NoError(t, err)
+	result, err := Collect(events)
+	require.NoError(t, err)
+	assert.Equal(t, "北京今天晴", result.Content)
+	assert.Equal(t, FinishStop, result.FinishReason)
+	assert.Empty(t, result.ToolCalls)
+	// 旧版本API不返回流式用量，按内容估算
+	require.NotNil(t, metadata.Usage())
+	assert.Positive(t, metadata.Usage().CompletionTokens)
+}
+
+func TestChatCompletionStream_Cancel(t *testing.T) {
+	server := newStreamServer(t, []string{
+		`{"choices":[{"index":0,"delta":{"content":"第一段"}}]}`,
+	}, true)
+	client := newStreamClient(server.URL, "2023-08-01-preview")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := client.ChatCompletionStream(ctx, []models.ChatMessage{{Role: "user", Content: "写一篇长文"}}, "")
+	require.NoError(t, err)
+
+	first := <-events
+	assert.Equal(t, "第一段", first.Content)
+	cancel()
+
+	select {
+	case last, ok := <-events:
+		require.True(t, ok)
+		assert.ErrorIs(t, last.Err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not stop after cancellation")
+	}
+	_, ok := <-events
+	assert.False(t, ok, "channel is closed after the final event")
+}
+
+func TestChatCompletionStream_UnexpectedEOF(t *testing.T) {
+	server := newStreamServer(t, []string{
+		`{"choices":[{"index":0,"delta":{"content":"一半"}}]}`,
+	}, false)
+	client := newStreamClient(server.URL, "2023-08-01-preview")
+
+	events, err := client.ChatCompletionStream(context.Background(), []models.ChatMessage{{Role: "user", Content: "北京天气"}}, "")
+	require.NoError(t, err)
+	result, err := Collect(events)
+	assert.Error(t, err)
+	assert.Equal(t, "一半", result.Content)
+}
+
+func TestChatCompletionStream_Replay(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.AzureOpenAIConfig{
+		Deployment:          "gpt-4o",
+		MaxCompletionTokens: 100,
+		Fixtures:            config.LLMFixturesConfig{Mode: config.LLMFixturesRecord, Dir: dir},
+	}
+	recorder := NewAzureOpenAIClient(&cfg, newTestLogger())
+	recorder.client = newFixtureCompleter(cfg.Fixtures, &stubCompleter{}, newTestLogger())
+	events, err := recorder.ChatCompletionStream(context.Background(), []models.ChatMessage{{Role: "user", Content: "北京天气"}}, "")
+	require.NoError(t, err)
+	_, err = Collect(events)
+	require.NoError(t, err)
+
+	cfg.Fixtures.Mode = config.LLMFixturesReplay
+	replayer := NewAzureOpenAIClient(&cfg, newTestLogger())
+	events, err = replayer.ChatCompletionStream(context.Background(), []models.ChatMessage{{Role: "user", Content: "北京天气"}}, "")
+	require.NoError(t, err)
+	result, err := Collect(events)
+	require.NoError(t, err)
+	assert.Equal(t, "回复：北京天气", result.Content)
+}
+
+func TestFormatSearchResults_StreamOutput(t *testing.T) {
+	server := newStreamServer(t, []string{
+		`{"choices":[{"index":0,"delta":{"content":"北京今天"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"晴"},"finish_reason":"stop"}]}`,
+	}, false)
+	client := newStreamClient(server.URL, "2024-10-21")
+
+	var deltas []string
+	ctx := WithStreamOutput(context.Background(), func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	reply, err := client.FormatSearchResults(ctx, "北京天气", &models.SearchResponse{
+		Results: []models.SearchResult{{Title: "天气预报", URL: "https://example.com", Content: "晴"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "北京今天晴", reply)
+	assert.Equal(t, []string{"北京今天", "晴"}, deltas)
+}
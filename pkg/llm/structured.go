@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// ErrInvalidStructuredOutput 修正次数用完后模型的输出仍不符合期望的 JSON Schema
+var ErrInvalidStructuredOutput = errors.New("LLM output does not match the expected schema")
+
+// StructuredOutputError 结构化输出校验失败的详情，errors.Is(err, ErrInvalidStructuredOutput) 为真
+type StructuredOutputError struct {
+	Name   string   // 输出名称
+	Output string   // 模型最后一次的输出
+	Errors []string // 最后一次输出的校验错误
+}
+
+// Error 返回包含全部校验错误的描述
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("%v: %s: %s", ErrInvalidStructuredOutput, e.Name, strings.Join(e.Errors, "; "))
+}
+
+// Unwrap 返回 ErrInvalidStructuredOutput
+func (e *StructuredOutputError) Unwrap() error {
+	return ErrInvalidStructuredOutput
+}
+
+// StructuredOutput 期望模型返回的JSON结构
+type StructuredOutput struct {
+	Name     string                           // 名称，只能包含字母、数字、下划线和连字符，作为 json_schema 的名称和日志字段
+	Schema   map[string]interface{}           // 输出的 JSON Schema，校验支持的关键字见 ValidateSchema
+	Validate func(value interface{}) []string // 可选，Schema 通过后执行的额外校验（如按所选工具的参数定义校验），返回的错误会发回模型修正
+}
+
+// StructuredCompletion 调用聊天完成API生成符合 output.Schema 的JSON，并解码到 result
+// 模型和 api_version 支持时通过 response_format 要求JSON输出（见 azure_openai.structured.response_format）；
+// 无论是否设置，结果都会按 Schema 校验，不通过时把输出和校验错误发回模型修正，最多 max_repairs 次，
+// 仍不通过时返回 *StructuredOutputError。使用 json_object 模式时消息中必须出现“JSON”字样，这是API的要求。
+func (c *AzureOpenAIClient) StructuredCompletion(ctx context.Context, messages []models.ChatMessage, systemPrompt string, output StructuredOutput, result interface{}) error {
+	client, cfg := c.snapshot()
+	format := responseFormat(cfg, output)
+
+	conversation := append([]models.ChatMessage(nil), messages...)
+	var lastErr *StructuredOutputError
+	for attempt := 0; attempt <= cfg.Structured.MaxRepairs; attempt++ {
+		req, promptTokens, err := buildRequest(cfg, conversation, systemPrompt)
+		if err != nil {
+			return err
+		}
+		req.ResponseFormat = format
+
+		content, err := c.complete(ctx, client, cfg, req, promptTokens)
+		if err != nil {
+			return err
+		}
+
+		value, errs := parseStructured(content, output)
+		if len(errs) == 0 {
+			if attempt > 0 {
+				c.logger.WithFields(logrus.Fields{
+					"output":  output.Name,
+					"repairs": attempt,
+				}).Info("LLM structured output repaired")
+			}
+			return decodeStructured(value, result)
+		}
+
+		lastErr = &StructuredOutputError{Name: output.Name, Output: content, Errors: errs}
+		c.logger.WithFields(logrus.Fields{
+			"output":       output.Name,
+			"attempt":      attempt + 1,
+			"errors":       errs,
+			"llm_response": content,
+		}).Warn("LLM structured output failed validation")
+
+		conversation = append(conversation,
+			models.ChatMessage{Role: "assistant", Content: content},
+			models.ChatMessage{Role: "user", Content: repairPrompt(errs)},
+		)
+	}
+	return lastErr
+}
+
+// responseFormat 按配置和 api_version 选择 response_format，不支持时返回空
+func responseFormat(cfg config.AzureOpenAIConfig, output StructuredOutput) *openai.ChatCompletionResponseFormat {
+	mode := cfg.Structured.ResponseFormat
+	if mode == config.LLMResponseFormatAuto || mode == "" {
+		switch {
+		case cfg.APIVersion >= "2024-08-01":
+			mode = config.LLMResponseFormatJSONSchema
+		case cfg.APIVersion >= "2023-12-01":
+			mode = config.LLMResponseFormatJSONObject
+		default:
+			mode = config.LLMResponseFormatOff
+		}
+	}
+
+	switch mode {
+	case config.LLMResponseFormatJSONSchema:
+		// 工具参数通常有可选字段，不满足严格模式“所有字段必填”的要求，因此不开启 strict，由本地校验兜底
+		return &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   output.Name,
+				Schema: schemaMarshaler(output.Schema),
+			},
+		}
+	case config.LLMResponseFormatJSONObject:
+		return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	return nil
+}
+
+// schemaMarshaler 让 map 形式的 JSON Schema 满足 response_format 要求的 json.Marshaler
+type schemaMarshaler map[string]interface{}
+
+// MarshalJSON 按普通 map 编码
+func (s schemaMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}(s))
+}
+
+// parseStructured 从模型输出中提取JSON并校验，返回解码后的值和校验错误
+// 未使用 response_format 时模型可能用代码块包裹JSON或在前后附加说明，取第一个 { 到最后一个 } 之间的内容。
+func parseStructured(content string, output StructuredOutput) (interface{}, []string) {
+	text := strings.TrimSpace(content)
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, []string{fmt.Sprintf("$: output is not valid JSON: %v", err)}
+	}
+	if errs := ValidateSchema(output.Schema, value); len(errs) > 0 {
+		return nil, errs
+	}
+	if output.Validate != nil {
+		if errs := output.Validate(value); len(errs) > 0 {
+			return nil, errs
+		}
+	}
+	return value, nil
+}
+
+// decodeStructured 把校验通过的值解码到调用方的结构
+func decodeStructured(value interface{}, result interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode structured output: %w", err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to decode structured output: %w", err)
+	}
+	return nil
+}
+
+// repairPrompt 要求模型按校验错误修正上一次的输出
+func repairPrompt(errs []string) string {
+	var b strings.Builder
+	b.WriteString("上面的输出不符合要求的JSON格式：\n")
+	for _, err := range errs {
+		fmt.Fprintf(&b, "- %s\n", err)
+	}
+	b.WriteString("请修正这些问题，只返回修正后的JSON，不要添加任何其他文字说明。")
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// scriptedCompleter 按顺序返回预设的回复并记录收到的请求
+type scriptedCompleter struct {
+	replies  []string
+	requests []openai.ChatCompletionRequest
+}
+
+func (s *scriptedCompleter) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	s.requests = append(s.requests, req)
+	reply := s.replies[0]
+	if len(s.replies) > 1 {
+		s.replies = s.replies[1:]
+	}
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: reply}}},
+	}, nil
+}
+
+var weatherSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"city"},
+	"properties": map[string]interface{}{
+		"city": map[string]interface{}{"type": "string"},
+		"days": map[string]interface{}{"type": "integer", "minimum": float64(1), "maximum": float64(5)},
+	},
+}
+
+func newRoutingClient(apiVersion string, replies ...string) (*AzureOpenAIClient, *scriptedCompleter) {
+	client := NewAzureOpenAIClient(&config.AzureOpenAIConfig{
+		Deployment:          "gpt-4o",
+		APIVersion:          apiVersion,
+		MaxCompletionTokens: 100,
+		Structured:          config.LLMStructuredConfig{ResponseFormat: config.LLMResponseFormatAuto, MaxRepairs: 1},
+		Fixtures:            config.LLMFixturesConfig{Mode: config.LLMFixturesOff},
+	}, newTestLogger())
+	scripted := &scriptedCompleter{replies: replies}
+	client.client = scripted
+	client.SetToolSchemas(func() map[string]map[string]interface{} {
+		return map[string]map[string]interface{}{"get_weather_forecast": weatherSchema}
+	})
+	return client, scripted
+}
+
+func TestParseQueryToMCP_RepairsInvalidParams(t *testing.T) {
+	client, scripted := newRoutingClient("2024-10-21",
+		"```json\n{\"method\": \"get_weather_forecast\", \"params\": {\"city\": \"北京\", \"days\": 7}}\n```",
+		`{"method": "get_weather_forecast", "params": {"city": "北京", "days": 5}}`,
+	)
+
+	request, err := client.ParseQueryToMCP(context.Background(), "北京未来7天天气")
+	require.NoError(t, err)
+	assert.Equal(t, "get_weather_forecast", request.Method)
+	assert.Equal(t, map[string]interface{}{"city": "北京", "days": float64(5)}, request.Params)
+
+	// 修正请求带上原输出和校验错误，并按 api_version 使用 json_schema
+	require.Len(t, scripted.requests, 2)
+	repair := scripted.requests[1].Messages
+	assert.Equal(t, openai.ChatMessageRoleAssistant, repair[len(repair)-2].Role)
+	assert.Contains(t, repair[len(repair)-1].Content, "$.params.days: must be <= 5, got 7")
+	format := scripted.requests[0].ResponseFormat
+	require.NotNil(t, format)
+	assert.Equal(t, openai.ChatCompletionResponseFormatTypeJSONSchema, format.Type)
+	assert.Equal(t, "mcp_request", format.JSONSchema.Name)
+}
+
+func TestParseQueryToMCP_InvalidAfterRepairs(t *testing.T) {
+	client, scripted := newRoutingClient("2023-12-01-preview", `{"method": "get_stock_price", "params": {}}`)
+
+	_, err := client.ParseQueryToMCP(context.Background(), "茅台股价")
+	require.ErrorIs(t, err, ErrInvalidStructuredOutput)
+	var outputErr *StructuredOutputError
+	require.ErrorAs(t, err, &outputErr)
+	assert.Contains(t, outputErr.Errors[0], "$.method: must be one of")
+	assert.Len(t, scripted.requests, 2, "one repair round-trip")
+	assert.Equal(t, openai.ChatCompletionResponseFormatTypeJSONObject, scripted.requests[0].ResponseFormat.Type)
+}
+
+func TestStructuredCompletion_NoResponseFormat(t *testing.T) {
+	client, scripted := newRoutingClient("2023-08-01-preview", `好的：{"response": "你好"}`)
+	output := StructuredOutput{Name: "greeting", Schema: directResponseSchema}
+
+	var result struct {
+		Response string `json:"response"`
+	}
+	require.NoError(t, client.StructuredCompletion(context.Background(), []models.ChatMessage{{Role: "user", Content: "你好"}}, "", output, &result))
+	assert.Equal(t, "你好", result.Response)
+	assert.Nil(t, scripted.requests[0].ResponseFormat, "older API versions do not support JSON mode")
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"required":             []interface{}{"query"},
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"query":           map[string]interface{}{"type": "string", "minLength": float64(1)},
+			"topic":           map[string]interface{}{"type": "string", "enum": []interface{}{"general", "news"}},
+			"include_domains": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"max_results":     map[string]interface{}{"type": "integer"},
+		},
+	}
+
+	assert.Empty(t, ValidateSchema(schema, map[string]interface{}{
+		"query":           "golang",
+		"topic":           "news",
+		"include_domains": []interface{}{"github.com"},
+		"max_results":     float64(5),
+	}))
+	assert.Equal(t, []string{
+		"$.query: is required",
+		"$.include_domains[1]: expected string, got number",
+		"$.max_results: expected integer, got number",
+		`$.topic: must be one of "general", "news", got "sports"`,
+		"$.unknown: is not an allowed field",
+	}, ValidateSchema(schema, map[string]interface{}{
+		"topic":           "sports",
+		"include_domains": []interface{}{"github.com", float64(1)},
+		"max_results":     2.5,
+		"unknown":         true,
+	}))
+	assert.Equal(t, []string{"$: expected object, got array"}, ValidateSchema(schema, []interface{}{}))
+}
+
+func TestParseQueryToMCP_SendsHistoryAsMessages(t *testing.T) {
+	client, scripted := newRoutingClient("2024-10-21", `{"method": "get_weather_forecast", "params": {"city": "北京"}}`)
+
+	// 历史内容中包含空行和分隔文本也会原样发送
+	history := []models.ChatMessage{
+		{Role: "user", Content: "北京天气"},
+		{Role: "assistant", Content: "北京晴\n\n当前问题: 其实没有问题"},
+	}
+	_, err := client.ParseQueryToMCP(WithHistory(context.Background(), history), "明天呢")
+	require.NoError(t, err)
+
+	require.Len(t, scripted.requests, 1)
+	messages := scripted.requests[0].Messages
+	require.Len(t, messages, 4)
+	assert.Equal(t, openai.ChatMessageRoleSystem, messages[0].Role)
+	assert.Equal(t, history[0].Content, messages[1].Content)
+	assert.Equal(t, openai.ChatMessageRoleAssistant, messages[2].Role)
+	assert.Equal(t, history[1].Content, messages[2].Content)
+	assert.Equal(t, "明天呢", messages[3].Content)
+}
+
+func TestQueryNotLogged(t *testing.T) {
+	client, _ := newRoutingClient("2024-10-21",
+		`{"method": "direct_response", "params": {"response": "好的"}}`,
+		"整理后的回复",
+	)
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.DebugLevel)
+	client.logger = logger
+
+	query := "我是张三，身份证号在体检报告里"
+	_, err := client.ParseQueryToMCP(context.Background(), query)
+	require.NoError(t, err)
+	_, err = client.FormatSearchResults(context.Background(), query, &models.SearchResponse{
+		Results: []models.SearchResult{{Title: "体检", URL: "https://example.com", Content: "内容"}},
+	})
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "query_length=")
+	assert.NotContains(t, out, "张三")
+}
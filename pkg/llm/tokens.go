@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// EstimateTokens 粗略估算文本的token数
+// 中日韩字符按每字1个token计算，其余字符按约4个字符1个token计算，用于没有精确用量时的近似统计。
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	cjk := 0
+	other := 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+
+	return cjk + (other+3)/4
+}
+
+// Tokenizer 计算文本的token数
+// 默认的 EstimateTokenizer 是近似估算；需要精确计数时可以换成与部署模型一致的BPE分词器。
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// EstimateTokenizer 基于 EstimateTokens 的分词器
+type EstimateTokenizer struct{}
+
+// CountTokens 估算文本的token数
+func (EstimateTokenizer) CountTokens(text string) int {
+	return EstimateTokens(text)
+}
+
+// EstimateMessagesTokens 估算一组对话消息的token数，每条消息额外计入角色等格式开销
+func EstimateMessagesTokens(contents ...string) int {
+	const perMessageOverhead = 4
+	total := 0
+	for _, content := range contents {
+		total += EstimateTokens(content) + perMessageOverhead
+	}
+	return total
+}
+
+// sentenceEnds 句子结束标点
+var sentenceEnds = []string{"。", "！", "？", "；", ". ", "! ", "? ", "\n"}
+
+// TruncateToTokens 将文本截断到 maxTokens 以内（按 EstimateTokens 计算），返回是否发生截断
+// 优先在段落边界截断，其次在句子边界截断，都不合适时按字符截断，不会截断在多字节字符中间。
+func TruncateToTokens(text string, maxTokens int) (string, bool) {
+	return truncateToTokens(EstimateTokenizer{}, text, maxTokens)
+}
+
+// truncateToTokens 使用指定的分词器截断文本
+func truncateToTokens(tokenizer Tokenizer, text string, maxTokens int) (string, bool) {
+	if maxTokens <= 0 || tokenizer.CountTokens(text) <= maxTokens {
+		return text, false
+	}
+
+	// token数随前缀长度单调不减，二分查找能放进预算的最长前缀
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if tokenizer.CountTokens(string(runes[:mid])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	cut := string(runes[:lo])
+
+	// 边界太靠前时宁可按字符截断，避免丢弃过多内容
+	minKeep := len(cut) / 2
+	if i := strings.LastIndex(cut, "\n\n"); i > 0 && i >= minKeep {
+		return strings.TrimSpace(cut[:i]), true
+	}
+	if i := lastSentenceEnd(cut); i > 0 && i >= minKeep {
+		return strings.TrimSpace(cut[:i]), true
+	}
+	return strings.TrimSpace(cut), true
+}
+
+// lastSentenceEnd 返回最后一个句子结束标点之后的位置，没有时返回 -1
+func lastSentenceEnd(text string) int {
+	end := -1
+	for _, mark := range sentenceEnds {
+		if i := strings.LastIndex(text, mark); i >= 0 && i+len(mark) > end {
+			end = i + len(mark)
+		}
+	}
+	return end
+}
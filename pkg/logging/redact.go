@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RedactedValue 脱敏后的占位符
+const RedactedValue = "[REDACTED]"
+
+var (
+	// 查询参数中的凭据，例如 OpenWeatherMap 的 appid
+	queryParamPattern = regexp.MustCompile(`(?i)\b(appid|api_key|apikey|api-key|access_token|key|token)=([^&\s"']+)`)
+	// JSON 中的凭据字段
+	jsonFieldPattern = regexp.MustCompile(`(?i)"(api_key|apikey|api-key|authorization|password|secret)"\s*:\s*"[^"]*"`)
+	// Authorization 头
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/\-]+=*`)
+
+	// 可选的个人信息脱敏
+	emailPattern    = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	idCardPattern   = regexp.MustCompile(`\b\d{17}[\dXx]\b`)
+	cnMobilePattern = regexp.MustCompile(`\b(?:\+?86[- ]?)?1[3-9]\d{9}\b`)
+
+	// 值一律隐藏的字段名，用户查询可能包含个人信息，与凭据一样不写入日志
+	sensitiveKeys = map[string]bool{
+		"api_key":        true,
+		"apikey":         true,
+		"appid":          true,
+		"authorization":  true,
+		"password":       true,
+		"secret":         true,
+		"token":          true,
+		"query":          true,
+		"original_query": true,
+	}
+)
+
+// Redactor 日志脱敏器，隐藏已知密钥、URL/JSON中的凭据以及可选的个人信息
+type Redactor struct {
+	mu        sync.RWMutex
+	secrets   []string
+	redactPII bool
+}
+
+// NewRedactor 创建脱敏器，secrets 为需要精确匹配隐藏的密钥值
+func NewRedactor(secrets []string, redactPII bool) *Redactor {
+	r := &Redactor{}
+	r.Update(secrets, redactPII)
+	return r
+}
+
+// Update 更新密钥列表和个人信息脱敏开关（配置热加载时使用）
+func (r *Redactor) Update(secrets []string, redactPII bool) {
+	nonEmpty := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		// 过短的值容易误伤正常文本
+		if len(secret) >= 4 {
+			nonEmpty = append(nonEmpty, secret)
+		}
+	}
+
+	r.mu.Lock()
+	r.secrets = nonEmpty
+	r.redactPII = redactPII
+	r.mu.Unlock()
+}
+
+// defaultRedactor 仅基于规则脱敏，不包含具体密钥
+var defaultRedactor = NewRedactor(nil, false)
+
+// RedactString 使用默认规则脱敏字符串（URL查询参数、JSON凭据字段、Bearer令牌）
+func RedactString(s string) string {
+	return defaultRedactor.RedactString(s)
+}
+
+// RedactString 脱敏字符串
+func (r *Redactor) RedactString(s string) string {
+	if s == "" {
+		return s
+	}
+
+	r.mu.RLock()
+	secrets, redactPII := r.secrets, r.redactPII
+	r.mu.RUnlock()
+
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, RedactedValue)
+	}
+
+	s = queryParamPattern.ReplaceAllString(s, "$1="+RedactedValue)
+	s = jsonFieldPattern.ReplaceAllString(s, `"$1":"`+RedactedValue+`"`)
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+RedactedValue)
+
+	if redactPII {
+		s = emailPattern.ReplaceAllString(s, "[EMAIL]")
+		s = idCardPattern.ReplaceAllString(s, "[ID]")
+		s = cnMobilePattern.ReplaceAllString(s, "[PHONE]")
+	}
+
+	return s
+}
+
+// redactValue 脱敏日志字段值
+func (r *Redactor) redactValue(key string, value interface{}) interface{} {
+	if sensitiveKeys[strings.ToLower(key)] {
+		return RedactedValue
+	}
+
+	switch v := value.(type) {
+	case string:
+		return r.RedactString(v)
+	case error:
+		return r.RedactString(v.Error())
+	case fmt.Stringer:
+		return r.RedactString(v.String())
+	default:
+		return value
+	}
+}
+
+// RedactionHook logrus钩子，在日志输出前统一脱敏消息和字段
+type RedactionHook struct {
+	redactor *Redactor
+}
+
+// NewRedactionHook 创建脱敏钩子
+func NewRedactionHook(redactor *Redactor) *RedactionHook {
+	return &RedactionHook{redactor: redactor}
+}
+
+// Levels 对所有日志级别生效
+func (h *RedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire 脱敏日志条目
+func (h *RedactionHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactor.RedactString(entry.Message)
+	for key, value := range entry.Data {
+		entry.Data[key] = h.redactor.redactValue(key, value)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor_RedactString(t *testing.T) {
+	redactor := NewRedactor([]string{"sk-live-abcdef"}, false)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "known secret",
+			input:    "calling with key sk-live-abcdef",
+			expected: "calling with key [REDACTED]",
+		},
+		{
+			name:     "appid query parameter",
+			input:    `Get "https://api.openweathermap.org/data/2.5/weather?appid=123456&q=Beijing": timeout`,
+			expected: `Get "https://api.openweathermap.org/data/2.5/weather?appid=[REDACTED]&q=Beijing": timeout`,
+		},
+		{
+			name:     "json api key",
+			input:    `{"api_key":"tvly-xyz","query":"go"}`,
+			expected: `{"api_key":"[REDACTED]","query":"go"}`,
+		},
+		{
+			name:     "bearer token",
+			input:    "Authorization: Bearer abc.def-ghi",
+			expected: "Authorization: Bearer [REDACTED]",
+		},
+		{
+			name:     "pii untouched when disabled",
+			input:    "我的手机号是13812345678",
+			expected: "我的手机号是13812345678",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, redactor.RedactString(tt.input))
+		})
+	}
+}
+
+func TestRedactor_PII(t *testing.T) {
+	redactor := NewRedactor(nil, true)
+
+	assert.Equal(t, "联系 [EMAIL] 或 [PHONE]", redactor.RedactString("联系 foo.bar@example.com 或 13812345678"))
+	assert.Equal(t, "身份证 [ID]", redactor.RedactString("身份证 11010519491231002X"))
+}
+
+func TestRedactionHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.AddHook(NewRedactionHook(NewRedactor([]string{"super-secret"}, true)))
+
+	logger.WithFields(logrus.Fields{
+		"api_key": "anything",
+		"query":   "请联系 13812345678",
+		"count":   3,
+	}).WithError(errors.New("request failed: appid=abc123")).Info("using super-secret")
+
+	out := buf.String()
+	assert.NotContains(t, out, "super-secret")
+	assert.NotContains(t, out, "anything")
+	assert.NotContains(t, out, "13812345678")
+	assert.NotContains(t, out, "abc123")
+	assert.Contains(t, out, "count=3")
+}
+
+func TestRedactionHook_QueryFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.AddHook(NewRedactionHook(NewRedactor(nil, false)))
+
+	// 未开启个人信息脱敏时查询字段也不会写入日志
+	logger.WithFields(logrus.Fields{
+		"query":          "北京朝阳区 张三 的体检报告",
+		"original_query": "北京朝阳区 张三 的体检报告",
+		"query_length":   12,
+	}).Info("Search request")
+
+	out := buf.String()
+	assert.NotContains(t, out, "张三")
+	assert.Contains(t, out, "query_length=12")
+}
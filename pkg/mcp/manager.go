@@ -0,0 +1,450 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+	"deer-flow-go/pkg/tools"
+)
+
+// Manager 管理多个MCP服务器客户端，按工具名路由请求
+// 支持在运行时增删或重启服务器：先启动新进程再替换，旧进程在处理完当前请求后停止，排队中的请求不受影响。
+// inprocess 服务器按当前配置在进程内创建工具注册表，stdio 服务器以子进程运行并自行读取配置。
+// 资源订阅由管理器记录，重启或新增服务器后会在新客户端上重新订阅。
+type Manager struct {
+	mu      sync.RWMutex
+	clients []*Client
+	configs map[string]config.MCPServerConfig
+	cfg     *config.Config // 进程内服务器使用的配置
+	logger  *logrus.Logger
+
+	subsMu            sync.Mutex
+	subscriptions     map[string]bool
+	onResourceUpdated func(uri string)
+}
+
+// NewManager 创建MCP服务器管理器
+func NewManager(cfg *config.Config, logger *logrus.Logger) *Manager {
+	return &Manager{
+		configs:       make(map[string]config.MCPServerConfig),
+		cfg:           cfg,
+		logger:        logger,
+		subscriptions: make(map[string]bool),
+	}
+}
+
+// Start 启动配置中的所有MCP服务器
+func (m *Manager) Start(ctx context.Context, servers []config.MCPServerConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, serverConfig := range servers {
+		client, err := m.startClient(ctx, serverConfig, m.cfg)
+		if err != nil {
+			m.stopClients(m.clients)
+			m.clients = nil
+			return err
+		}
+		m.clients = append(m.clients, client)
+		m.configs[serverConfig.Name] = serverConfig
+	}
+	return nil
+}
+
+// startClient 启动单个MCP服务器
+// cfg 为进程内服务器使用的配置；设置了 mcp.server_binary 时进程内服务器改为运行该程序的子进程。
+func (m *Manager) startClient(ctx context.Context, serverConfig config.MCPServerConfig, cfg *config.Config) (*Client, error) {
+	var client *Client
+	switch {
+	case serverConfig.EffectiveTransport() == config.MCPTransportStdio:
+		client = NewClientWithCommand(serverConfig.Name, serverConfig.Command, serverConfig.Args, m.logger)
+	case cfg.MCP.ServerBinary != "":
+		client = NewClientWithCommand(serverConfig.Name, cfg.MCP.ServerBinary, serverConfig.Args, m.logger)
+	default:
+		registry, err := tools.NewRegistryFromConfig(cfg, m.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start MCP server %q: %w", serverConfig.Name, err)
+		}
+		client = NewInProcessClient(serverConfig.Name, registry.NewMCPServer("unified-server", "1.0.0"), m.logger)
+	}
+	if err := client.Start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server %q: %w", serverConfig.Name, err)
+	}
+
+	// 恢复资源更新回调和已有的订阅
+	m.subsMu.Lock()
+	client.OnResourceUpdated(m.onResourceUpdated)
+	uris := make([]string, 0, len(m.subscriptions))
+	for uri := range m.subscriptions {
+		uris = append(uris, uri)
+	}
+	m.subsMu.Unlock()
+	for _, uri := range uris {
+		if err := client.Subscribe(ctx, uri); err != nil {
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"server": serverConfig.Name,
+				"uri":    uri,
+			}).Debug("MCP server did not accept resource subscription")
+		}
+	}
+	return client, nil
+}
+
+// stopClients 停止一组客户端
+func (m *Manager) stopClients(clients []*Client) {
+	for _, client := range clients {
+		if err := client.Stop(); err != nil {
+			m.logger.WithError(err).WithField("server", client.Name()).Error("Failed to stop MCP server")
+		}
+	}
+}
+
+// Apply 将服务器列表调整为新配置中的 mcp.servers：新增的服务器被启动，删除的被停止，命令变化的被重启
+func (m *Manager) Apply(ctx context.Context, cfg *config.Config) (added, removed, restarted []string, err error) {
+	servers := cfg.MCP.Servers
+	m.mu.RLock()
+	current := make(map[string]*Client, len(m.clients))
+	for _, client := range m.clients {
+		current[client.Name()] = client
+	}
+	oldConfigs := m.configs
+	m.mu.RUnlock()
+
+	newClients := make([]*Client, 0, len(servers))
+	newConfigs := make(map[string]config.MCPServerConfig, len(servers))
+	var started []*Client
+	for _, serverConfig := range servers {
+		oldConfig, exists := oldConfigs[serverConfig.Name]
+		if exists && reflect.DeepEqual(oldConfig, serverConfig) {
+			newClients = append(newClients, current[serverConfig.Name])
+			newConfigs[serverConfig.Name] = serverConfig
+			continue
+		}
+
+		client, startErr := m.startClient(ctx, serverConfig, cfg)
+		if startErr != nil {
+			// 回滚本次启动的服务器，保持原有状态
+			m.stopClients(started)
+			return nil, nil, nil, startErr
+		}
+		started = append(started, client)
+		newClients = append(newClients, client)
+		newConfigs[serverConfig.Name] = serverConfig
+
+		if exists {
+			restarted = append(restarted, serverConfig.Name)
+		} else {
+			added = append(added, serverConfig.Name)
+		}
+	}
+
+	kept := make(map[*Client]bool, len(newClients))
+	for _, client := range newClients {
+		kept[client] = true
+	}
+	var obsolete []*Client
+	for name, client := range current {
+		if !kept[client] {
+			obsolete = append(obsolete, client)
+		}
+		if _, exists := newConfigs[name]; !exists {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	m.mu.Lock()
+	m.clients = newClients
+	m.configs = newConfigs
+	m.cfg = cfg
+	m.mu.Unlock()
+
+	m.stopClients(obsolete)
+	return added, removed, restarted, nil
+}
+
+// Restart 按新配置重启所有MCP服务器，使搜索、天气和网页抓取设置生效
+// 进程内服务器按 cfg 重新创建，stdio 子进程重新读取配置文件。
+func (m *Manager) Restart(ctx context.Context, cfg *config.Config) error {
+	m.mu.RLock()
+	oldClients := append([]*Client(nil), m.clients...)
+	servers := make([]config.MCPServerConfig, 0, len(oldClients))
+	for _, client := range oldClients {
+		servers = append(servers, m.configs[client.Name()])
+	}
+	m.mu.RUnlock()
+
+	newClients := make([]*Client, 0, len(servers))
+	for _, serverConfig := range servers {
+		client, err := m.startClient(ctx, serverConfig, cfg)
+		if err != nil {
+			m.stopClients(newClients)
+			return err
+		}
+		newClients = append(newClients, client)
+	}
+
+	m.mu.Lock()
+	m.clients = newClients
+	m.cfg = cfg
+	m.mu.Unlock()
+
+	m.stopClients(oldClients)
+	return nil
+}
+
+// Stop 停止所有MCP服务器
+func (m *Manager) Stop() error {
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = nil
+	m.mu.Unlock()
+
+	m.stopClients(clients)
+	return nil
+}
+
+// route 选择提供指定工具的客户端，找不到时返回第一个客户端
+func (m *Manager) route(method string) (*Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.clients) == 0 {
+		return nil, fmt.Errorf("no MCP servers are running")
+	}
+	for _, client := range m.clients {
+		for _, tool := range client.Tools() {
+			if tool == method {
+				return client, nil
+			}
+		}
+	}
+	return m.clients[0], nil
+}
+
+// ProcessRequest 将请求路由到提供该工具的MCP服务器
+func (m *Manager) ProcessRequest(ctx context.Context, req *models.MCPRequest) (*models.MCPResponse, error) {
+	client, err := m.route(req.Method)
+	if err != nil {
+		return nil, err
+	}
+	return client.ProcessRequest(ctx, req)
+}
+
+// snapshot 返回当前客户端列表
+func (m *Manager) snapshot() ([]*Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.clients) == 0 {
+		return nil, fmt.Errorf("no MCP servers are running")
+	}
+	return append([]*Client(nil), m.clients...), nil
+}
+
+// ListResources 汇总所有MCP服务器提供的资源，不支持资源的服务器被跳过
+func (m *Manager) ListResources(ctx context.Context) ([]models.Resource, error) {
+	clients, err := m.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]models.Resource, 0)
+	for _, client := range clients {
+		list, err := client.ListResources(ctx)
+		if err != nil {
+			m.logger.WithError(err).WithField("server", client.Name()).Debug("Failed to list MCP resources")
+			continue
+		}
+		resources = append(resources, list...)
+	}
+	return resources, nil
+}
+
+// ReadResource 依次向各MCP服务器读取资源，返回第一个拥有该资源的服务器的内容
+func (m *Manager) ReadResource(ctx context.Context, uri string) ([]models.ResourceContent, error) {
+	clients, err := m.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, client := range clients {
+		contents, err := client.ReadResource(ctx, uri)
+		if err == nil {
+			return contents, nil
+		}
+		lastErr = err
+		if !errors.Is(err, ErrResourceNotFound) {
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"server": client.Name(),
+				"uri":    uri,
+			}).Debug("Failed to read MCP resource")
+		}
+	}
+	return nil, lastErr
+}
+
+// ListPrompts 汇总所有MCP服务器提供的提示词，不支持提示词的服务器被跳过
+func (m *Manager) ListPrompts(ctx context.Context) ([]models.PromptTemplate, error) {
+	clients, err := m.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := make([]models.PromptTemplate, 0)
+	for _, client := range clients {
+		list, err := client.ListPrompts(ctx)
+		if err != nil {
+			m.logger.WithError(err).WithField("server", client.Name()).Debug("Failed to list MCP prompts")
+			continue
+		}
+		prompts = append(prompts, list...)
+	}
+	return prompts, nil
+}
+
+// GetPrompt 依次向各MCP服务器获取提示词，返回第一个提供该提示词的服务器的渲染结果
+func (m *Manager) GetPrompt(ctx context.Context, name string, args map[string]string) ([]models.ChatMessage, error) {
+	clients, err := m.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, client := range clients {
+		messages, err := client.GetPrompt(ctx, name, args)
+		if errors.Is(err, ErrPromptNotFound) {
+			continue
+		}
+		return messages, err
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+}
+
+// maxResourceSubscriptions 管理器记录的资源订阅上限，超出后拒绝新的订阅
+const maxResourceSubscriptions = 1024
+
+// Subscribe 在所有MCP服务器上订阅资源，至少一个服务器接受时成功
+func (m *Manager) Subscribe(ctx context.Context, uri string) error {
+	clients, err := m.snapshot()
+	if err != nil {
+		return err
+	}
+
+	m.subsMu.Lock()
+	if !m.subscriptions[uri] && len(m.subscriptions) >= maxResourceSubscriptions {
+		m.subsMu.Unlock()
+		return fmt.Errorf("failed to subscribe to %s: too many resource subscriptions (max %d)", uri, maxResourceSubscriptions)
+	}
+	m.subscriptions[uri] = true
+	m.subsMu.Unlock()
+
+	var lastErr error
+	accepted := false
+	for _, client := range clients {
+		if err := client.Subscribe(ctx, uri); err != nil {
+			lastErr = err
+			continue
+		}
+		accepted = true
+	}
+	if !accepted {
+		m.subsMu.Lock()
+		delete(m.subscriptions, uri)
+		m.subsMu.Unlock()
+		return fmt.Errorf("failed to subscribe to %s: %w", uri, lastErr)
+	}
+	return nil
+}
+
+// Unsubscribe 在所有MCP服务器上取消资源订阅
+func (m *Manager) Unsubscribe(ctx context.Context, uri string) error {
+	m.subsMu.Lock()
+	delete(m.subscriptions, uri)
+	m.subsMu.Unlock()
+
+	clients, err := m.snapshot()
+	if err != nil {
+		return err
+	}
+	for _, client := range clients {
+		if err := client.Unsubscribe(ctx, uri); err != nil {
+			m.logger.WithError(err).WithField("server", client.Name()).Debug("Failed to unsubscribe MCP resource")
+		}
+	}
+	return nil
+}
+
+// OnResourceUpdated 设置资源更新回调，对之后启动的服务器同样生效
+func (m *Manager) OnResourceUpdated(fn func(uri string)) {
+	m.subsMu.Lock()
+	m.onResourceUpdated = fn
+	m.subsMu.Unlock()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, client := range m.clients {
+		client.OnResourceUpdated(fn)
+	}
+}
+
+// HealthCheck 所有MCP服务器都在运行时返回nil
+func (m *Manager) HealthCheck(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.clients) == 0 {
+		return fmt.Errorf("no MCP servers are running")
+	}
+	for _, client := range m.clients {
+		if err := client.HealthCheck(ctx); err != nil {
+			return fmt.Errorf("MCP server %q: %w", client.Name(), err)
+		}
+	}
+	return nil
+}
+
+// ToolSchemas 汇总所有MCP服务器的工具参数 JSON Schema，同名工具以靠前的服务器为准（与调用路由一致）
+func (m *Manager) ToolSchemas() map[string]map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schemas := make(map[string]map[string]interface{})
+	for _, client := range m.clients {
+		for name, schema := range client.ToolSchemas() {
+			if _, exists := schemas[name]; !exists {
+				schemas[name] = schema
+			}
+		}
+	}
+	return schemas
+}
+
+// GetCapabilities 汇总所有MCP服务器的能力信息
+func (m *Manager) GetCapabilities() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tools := make([]string, 0)
+	servers := make([]map[string]interface{}, 0, len(m.clients))
+	for _, client := range m.clients {
+		tools = append(tools, client.Tools()...)
+		servers = append(servers, client.GetCapabilities())
+	}
+
+	return map[string]interface{}{
+		"tools":       tools,
+		"servers":     servers,
+		"description": "Real MCP client with JSON-RPC 2.0 protocol",
+		"version":     "1.0.0",
+		"protocol":    "MCP 2024-11-05",
+	}
+}
@@ -1,31 +1,53 @@
 package mcp
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"os/exec"
 	"strings"
 	"sync"
-	"time"
 
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
 	"github.com/sirupsen/logrus"
 
 	"deer-flow-go/pkg/models"
 )
 
-// Client MCP协议客户端
+var (
+	// ErrResourceNotFound 服务器上不存在请求的资源
+	ErrResourceNotFound = errors.New("MCP resource not found")
+	// ErrPromptNotFound 服务器上不存在请求的提示词
+	ErrPromptNotFound = errors.New("MCP prompt not found")
+)
+
+// RPCError MCP服务器返回的JSON-RPC错误
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("MCP server error %d: %s", e.Code, e.Message)
+}
+
+// Client MCP协议客户端，连接子进程（stdio）或同一进程内的MCP服务器
 type Client struct {
-	cmd       *exec.Cmd
-	stdin     io.WriteCloser
-	stdout    io.ReadCloser
-	scanner   *bufio.Scanner
+	name      string
+	command   string         // stdio 方式的启动命令
+	args      []string       // stdio 方式的启动参数
+	embedded  EmbeddedServer // 进程内方式的服务器，为空时使用 stdio
+	tools     []string
+	schemas   map[string]map[string]interface{} // 工具名称 -> inputSchema
+	toolsMu   sync.RWMutex
+	transport transport
 	logger    *logrus.Logger
 	mutex     sync.Mutex
 	requestID int
 	running   bool
+
+	notifyMu          sync.RWMutex
+	onResourceUpdated func(uri string) // 订阅的资源更新时调用
 }
 
 // MCPJSONRPCMessage MCP JSON-RPC 2.0 消息
@@ -56,62 +78,107 @@ type CallToolParams struct {
 	Arguments map[string]interface{} `json:"arguments"`
 }
 
-// NewClient 创建MCP客户端
-func NewClient(logger *logrus.Logger) *Client {
+// NewClientWithCommand 创建使用指定命令启动MCP服务器子进程的客户端
+func NewClientWithCommand(name, command string, args []string, logger *logrus.Logger) *Client {
 	return &Client{
+		name:      name,
+		command:   command,
+		args:      args,
 		logger:    logger,
 		requestID: 0,
 		running:   false,
 	}
 }
 
-// Start 启动MCP服务器进程并建立连接
-func (c *Client) Start(ctx context.Context) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	if c.running {
-		return nil
+// NewInProcessClient 创建直接连接进程内MCP服务器的客户端，不需要子进程
+func NewInProcessClient(name string, mcpServer EmbeddedServer, logger *logrus.Logger) *Client {
+	return &Client{
+		name:     name,
+		embedded: mcpServer,
+		logger:   logger,
 	}
+}
 
-	c.logger.Info("Starting MCP server process...")
+// Name 返回服务器名称
+func (c *Client) Name() string {
+	return c.name
+}
 
-	// 启动MCP服务器进程
-	c.cmd = exec.CommandContext(ctx, "go", "run", "cmd/server/main.go")
+// Tools 返回服务器提供的工具名称列表
+func (c *Client) Tools() []string {
+	c.toolsMu.RLock()
+	defer c.toolsMu.RUnlock()
+	return append([]string(nil), c.tools...)
+}
 
-	// 创建管道
-	stdin, err := c.cmd.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdin pipe: %w", err)
+// ToolSchemas 返回服务器提供的工具的参数 JSON Schema（inputSchema），键为工具名称
+func (c *Client) ToolSchemas() map[string]map[string]interface{} {
+	c.toolsMu.RLock()
+	defer c.toolsMu.RUnlock()
+	schemas := make(map[string]map[string]interface{}, len(c.schemas))
+	for name, schema := range c.schemas {
+		schemas[name] = schema
 	}
-	c.stdin = stdin
+	return schemas
+}
 
-	stdout, err := c.cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+// hasTool 检查服务器是否提供指定工具
+func (c *Client) hasTool(name string) bool {
+	for _, tool := range c.Tools() {
+		if tool == name {
+			return true
+		}
 	}
-	c.stdout = stdout
-	c.scanner = bufio.NewScanner(stdout)
+	return false
+}
 
-	// 启动进程
-	if err := c.cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start MCP server: %w", err)
+// Start 启动MCP服务器（子进程或进程内）并建立连接
+func (c *Client) Start(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.running {
+		return nil
 	}
 
-	// 等待服务器启动
-	time.Sleep(500 * time.Millisecond)
+	if c.embedded != nil {
+		c.logger.WithField("server", c.name).Info("Connecting to in-process MCP server...")
+		c.transport = newInProcessTransport(c.embedded, c.handleNotification, c.logger)
+	} else {
+		c.logger.WithFields(logrus.Fields{
+			"server":  c.name,
+			"command": c.command,
+		}).Info("Starting MCP server process...")
+
+		t, err := startStdioTransport(ctx, c.command, c.args, c.handleNotification, c.logger)
+		if err != nil {
+			return err
+		}
+		c.transport = t
+	}
 
 	// 发送初始化消息
-	if err := c.initialize(); err != nil {
+	if err := c.initialize(ctx); err != nil {
+		c.transport.close()
 		return fmt.Errorf("failed to initialize MCP connection: %w", err)
 	}
 
+	// 获取工具列表，用于按工具名路由请求
+	if err := c.listTools(ctx); err != nil {
+		c.transport.close()
+		return fmt.Errorf("failed to list MCP tools: %w", err)
+	}
+
 	c.running = true
-	c.logger.Info("MCP server process started and initialized")
+	c.logger.WithFields(logrus.Fields{
+		"server":    c.name,
+		"transport": c.transportName(),
+		"tools":     c.Tools(),
+	}).Info("MCP server started and initialized")
 	return nil
 }
 
-// Stop 停止MCP服务器进程
+// Stop 断开连接，stdio 方式会结束服务器子进程
 func (c *Client) Stop() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -120,33 +187,32 @@ func (c *Client) Stop() error {
 		return nil
 	}
 
-	c.logger.Info("Stopping MCP server process...")
+	c.logger.WithField("server", c.name).Info("Stopping MCP server...")
 
-	if c.stdin != nil {
-		c.stdin.Close()
-	}
-	if c.stdout != nil {
-		c.stdout.Close()
-	}
-	if c.cmd != nil && c.cmd.Process != nil {
-		c.cmd.Process.Kill()
-		c.cmd.Wait()
-	}
+	c.transport.close()
 
 	c.running = false
-	c.logger.Info("MCP server process stopped")
+	c.logger.WithField("server", c.name).Info("MCP server stopped")
 	return nil
 }
 
+// transportName 返回连接方式名称
+func (c *Client) transportName() string {
+	if c.embedded != nil {
+		return "inprocess"
+	}
+	return "stdio"
+}
+
 // initialize 发送MCP初始化消息
-func (c *Client) initialize() error {
+func (c *Client) initialize(ctx context.Context) error {
 	initMsg := MCPJSONRPCMessage{
 		JSONRPC: "2.0",
 		ID:      c.getNextRequestID(),
 		Method:  "initialize",
 		Params: InitializeParams{
 			ProtocolVersion: "2024-11-05",
-			Capabilities:    map[string]interface{}{"tools": map[string]interface{}{}},
+			Capabilities:    map[string]interface{}{"tools": map[string]interface{}{}, "resources": map[string]interface{}{"subscribe": true}},
 			ClientInfo: ClientInfo{
 				Name:    "deer-flow-api-client",
 				Version: "1.0.0",
@@ -154,14 +220,55 @@ func (c *Client) initialize() error {
 		},
 	}
 
-	// 发送消息
-	if err := c.sendMessage(initMsg); err != nil {
+	_, err := c.roundTrip(ctx, initMsg)
+	return err
+}
+
+// listTools 通过 tools/list 获取服务器提供的工具
+func (c *Client) listTools(ctx context.Context) error {
+	listMsg := MCPJSONRPCMessage{
+		JSONRPC: "2.0",
+		ID:      c.getNextRequestID(),
+		Method:  "tools/list",
+	}
+
+	response, err := c.roundTrip(ctx, listMsg)
+	if err != nil {
 		return err
 	}
+	if response.Error != nil {
+		return fmt.Errorf("MCP server error: %v", response.Error)
+	}
 
-	// 读取响应
-	_, err := c.readResponse()
-	return err
+	data, err := json.Marshal(response.Result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tools result: %w", err)
+	}
+
+	var result struct {
+		Tools []struct {
+			Name        string                 `json:"name"`
+			InputSchema map[string]interface{} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal tools result: %w", err)
+	}
+
+	tools := make([]string, 0, len(result.Tools))
+	schemas := make(map[string]map[string]interface{}, len(result.Tools))
+	for _, tool := range result.Tools {
+		tools = append(tools, tool.Name)
+		if tool.InputSchema != nil {
+			schemas[tool.Name] = tool.InputSchema
+		}
+	}
+
+	c.toolsMu.Lock()
+	c.tools = tools
+	c.schemas = schemas
+	c.toolsMu.Unlock()
+	return nil
 }
 
 // ProcessRequest 处理MCP请求（真正的协议调用）
@@ -181,8 +288,8 @@ func (c *Client) ProcessRequest(ctx context.Context, req *models.MCPRequest) (*m
 	var rpcMsg MCPJSONRPCMessage
 
 	switch req.Method {
-	case "get_weather", "get_weather_forecast":
-		// 调用工具
+	case "direct_response":
+		// 直接响应不需要MCP调用
 		params, ok := req.Params.(map[string]interface{})
 		if !ok {
 			return &models.MCPResponse{
@@ -193,129 +300,252 @@ func (c *Client) ProcessRequest(ctx context.Context, req *models.MCPRequest) (*m
 			}, nil
 		}
 
-		rpcMsg = MCPJSONRPCMessage{
-			JSONRPC: "2.0",
-			ID:      c.getNextRequestID(),
-			Method:  "tools/call",
-			Params: CallToolParams{
-				Name:      req.Method,
-				Arguments: params,
-			},
-		}
-
-	case "search":
-		// 调用搜索工具
-		params, ok := req.Params.(map[string]interface{})
+		response, ok := params["response"].(string)
 		if !ok {
 			return &models.MCPResponse{
 				Error: &models.MCPError{
 					Code:    -32602,
-					Message: "Invalid params format",
+					Message: "Missing response parameter",
 				},
 			}, nil
 		}
 
-		rpcMsg = MCPJSONRPCMessage{
-			JSONRPC: "2.0",
-			ID:      c.getNextRequestID(),
-			Method:  "tools/call",
-			Params: CallToolParams{
-				Name:      req.Method,
-				Arguments: params,
+		return &models.MCPResponse{
+			Result: map[string]interface{}{
+				"content": response,
+				"type":    "direct",
 			},
-		}
+		}, nil
 
-	case "direct_response":
-		// 直接响应不需要MCP调用
-		params, ok := req.Params.(map[string]interface{})
-		if !ok {
+	default:
+		// 调用服务器提供的工具
+		if !c.hasTool(req.Method) {
 			return &models.MCPResponse{
 				Error: &models.MCPError{
-					Code:    -32602,
-					Message: "Invalid params format",
+					Code:    -32601,
+					Message: fmt.Sprintf("Method not found: %s", req.Method),
 				},
 			}, nil
 		}
 
-		response, ok := params["response"].(string)
+		params, ok := req.Params.(map[string]interface{})
 		if !ok {
 			return &models.MCPResponse{
 				Error: &models.MCPError{
 					Code:    -32602,
-					Message: "Missing response parameter",
+					Message: "Invalid params format",
 				},
 			}, nil
 		}
 
-		return &models.MCPResponse{
-			Result: map[string]interface{}{
-				"content": response,
-				"type":    "direct",
-			},
-		}, nil
-
-	default:
-		return &models.MCPResponse{
-			Error: &models.MCPError{
-				Code:    -32601,
-				Message: fmt.Sprintf("Method not found: %s", req.Method),
+		rpcMsg = MCPJSONRPCMessage{
+			JSONRPC: "2.0",
+			ID:      c.getNextRequestID(),
+			Method:  "tools/call",
+			Params: CallToolParams{
+				Name:      req.Method,
+				Arguments: params,
 			},
-		}, nil
-	}
-
-	// 发送JSON-RPC消息
-	if err := c.sendMessage(rpcMsg); err != nil {
-		return nil, fmt.Errorf("failed to send MCP message: %w", err)
+		}
 	}
 
-	// 读取响应
-	response, err := c.readResponse()
+	// 发送JSON-RPC消息并读取响应
+	response, err := c.roundTrip(ctx, rpcMsg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read MCP response: %w", err)
+		return nil, fmt.Errorf("MCP call failed: %w", err)
 	}
 
 	// 解析响应
 	return c.parseResponse(response)
 }
 
-// sendMessage 发送JSON-RPC消息
-func (c *Client) sendMessage(msg MCPJSONRPCMessage) error {
+// roundTrip 发送JSON-RPC消息并读取响应
+func (c *Client) roundTrip(ctx context.Context, msg MCPJSONRPCMessage) (*MCPJSONRPCMessage, error) {
 	data, err := json.Marshal(msg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
 	}
 
+	// 只记录元信息，不记录可能包含用户查询和密钥的完整载荷
 	c.logger.WithFields(logrus.Fields{
-		"message": string(data),
+		"id":     msg.ID,
+		"method": msg.Method,
+		"bytes":  len(data),
 	}).Debug("Sending MCP message")
 
-	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
-		return fmt.Errorf("failed to write message: %w", err)
+	data, err = c.transport.roundTrip(ctx, msg.ID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var response MCPJSONRPCMessage
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"id":        response.ID,
+		"bytes":     len(data),
+		"has_error": response.Error != nil,
+	}).Debug("Received MCP response")
+
+	return &response, nil
+}
+
+// call 发送请求并将结果解析到 result，服务器返回错误时转换为 error
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.running {
+		return fmt.Errorf("MCP client is not running")
+	}
+
+	response, err := c.roundTrip(ctx, MCPJSONRPCMessage{
+		JSONRPC: "2.0",
+		ID:      c.getNextRequestID(),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return fmt.Errorf("MCP call failed: %w", err)
+	}
+	if response.Error != nil {
+		rpcErr := &RPCError{Code: -1, Message: fmt.Sprintf("%v", response.Error)}
+		// 无法解析错误对象时保留原始文本
+		if data, err := json.Marshal(response.Error); err == nil {
+			_ = json.Unmarshal(data, rpcErr)
+		}
+		return rpcErr
+	}
+	if result == nil {
+		return nil
 	}
 
+	data, err := json.Marshal(response.Result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s result: %w", method, err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to unmarshal %s result: %w", method, err)
+	}
 	return nil
 }
 
-// readResponse 读取JSON-RPC响应
-func (c *Client) readResponse() (*MCPJSONRPCMessage, error) {
-	if !c.scanner.Scan() {
-		if err := c.scanner.Err(); err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
+// ListResources 通过 resources/list 获取服务器当前提供的资源
+func (c *Client) ListResources(ctx context.Context) ([]models.Resource, error) {
+	var result struct {
+		Resources []models.Resource `json:"resources"`
+	}
+	if err := c.call(ctx, "resources/list", map[string]interface{}{}, &result); err != nil {
+		return nil, err
+	}
+	return result.Resources, nil
+}
+
+// ReadResource 通过 resources/read 读取资源的文本内容
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]models.ResourceContent, error) {
+	var result struct {
+		Contents []models.ResourceContent `json:"contents"`
+	}
+	if err := c.call(ctx, "resources/read", map[string]interface{}{"uri": uri}, &result); err != nil {
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == mcpgo.RESOURCE_NOT_FOUND {
+			return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, rpcErr.Message)
 		}
-		return nil, fmt.Errorf("no response received")
+		return nil, err
 	}
+	return result.Contents, nil
+}
 
-	data := c.scanner.Bytes()
-	c.logger.WithFields(logrus.Fields{
-		"response": string(data),
-	}).Debug("Received MCP response")
+// ListPrompts 通过 prompts/list 获取服务器提供的提示词（只包含名称、说明和参数）
+func (c *Client) ListPrompts(ctx context.Context) ([]models.PromptTemplate, error) {
+	var result struct {
+		Prompts []models.PromptTemplate `json:"prompts"`
+	}
+	if err := c.call(ctx, "prompts/list", map[string]interface{}{}, &result); err != nil {
+		return nil, err
+	}
+	return result.Prompts, nil
+}
 
-	var response MCPJSONRPCMessage
-	if err := json.Unmarshal(data, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+// GetPrompt 通过 prompts/get 渲染提示词，返回渲染后的消息
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) ([]models.ChatMessage, error) {
+	var result struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	params := map[string]interface{}{"name": name, "arguments": args}
+	if err := c.call(ctx, "prompts/get", params, &result); err != nil {
+		// mcp-go 对未注册的提示词返回 INVALID_PARAMS，不支持提示词的服务器返回 METHOD_NOT_FOUND
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) && (rpcErr.Code == mcpgo.METHOD_NOT_FOUND ||
+			rpcErr.Code == mcpgo.INVALID_PARAMS && strings.Contains(rpcErr.Message, "not found")) {
+			return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+		}
+		return nil, err
 	}
 
-	return &response, nil
+	messages := make([]models.ChatMessage, 0, len(result.Messages))
+	for _, msg := range result.Messages {
+		if msg.Content.Type != "text" {
+			continue
+		}
+		messages = append(messages, models.ChatMessage{Role: msg.Role, Content: msg.Content.Text})
+	}
+	return messages, nil
+}
+
+// Subscribe 订阅资源更新，资源变化时调用 OnResourceUpdated 设置的回调
+func (c *Client) Subscribe(ctx context.Context, uri string) error {
+	return c.call(ctx, "resources/subscribe", map[string]interface{}{"uri": uri}, nil)
+}
+
+// Unsubscribe 取消资源订阅
+func (c *Client) Unsubscribe(ctx context.Context, uri string) error {
+	return c.call(ctx, "resources/unsubscribe", map[string]interface{}{"uri": uri}, nil)
+}
+
+// OnResourceUpdated 设置资源更新回调
+// 进程内服务器会在处理请求的过程中同步调用回调，回调中不能再调用客户端方法。
+func (c *Client) OnResourceUpdated(fn func(uri string)) {
+	c.notifyMu.Lock()
+	c.onResourceUpdated = fn
+	c.notifyMu.Unlock()
+}
+
+// handleNotification 处理服务器推送的通知
+func (c *Client) handleNotification(message []byte) {
+	var notification struct {
+		Method string `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &notification); err != nil {
+		c.logger.WithError(err).Warn("Failed to unmarshal MCP notification")
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"server": c.name,
+		"method": notification.Method,
+	}).Debug("Received MCP notification")
+
+	if notification.Method != mcpgo.MethodNotificationResourceUpdated {
+		return
+	}
+	c.notifyMu.RLock()
+	fn := c.onResourceUpdated
+	c.notifyMu.RUnlock()
+	if fn != nil {
+		fn(notification.Params.URI)
+	}
 }
 
 // parseResponse 解析MCP响应为标准格式
@@ -396,7 +626,9 @@ func (c *Client) HealthCheck(ctx context.Context) error {
 // GetCapabilities 获取能力信息
 func (c *Client) GetCapabilities() map[string]interface{} {
 	return map[string]interface{}{
-		"tools":       []string{"get_weather", "get_weather_forecast"},
+		"server":      c.name,
+		"transport":   c.transportName(),
+		"tools":       c.Tools(),
 		"description": "Real MCP client with JSON-RPC 2.0 protocol",
 		"version":     "1.0.0",
 		"protocol":    "MCP 2024-11-05",
@@ -0,0 +1,168 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+	"deer-flow-go/pkg/tools"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return logger
+}
+
+func TestInProcessClient(t *testing.T) {
+	registry := tools.NewRegistry(newTestLogger())
+	registry.Register(tools.Tool{
+		Definition: mcpgo.NewTool("echo", mcpgo.WithString("text", mcpgo.Required())),
+		Handler: func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+			return mcpgo.NewToolResultText("echo: " + request.GetString("text", "")), nil
+		},
+	})
+
+	client := NewInProcessClient("embedded", registry.NewMCPServer("test-server", "1.0.0"), newTestLogger())
+	require.NoError(t, client.Start(context.Background()))
+	defer client.Stop()
+	assert.Equal(t, []string{"echo"}, client.Tools())
+	assert.Equal(t, []interface{}{"text"}, client.ToolSchemas()["echo"]["required"])
+	assert.Equal(t, "inprocess", client.GetCapabilities()["transport"])
+
+	resp, err := client.ProcessRequest(context.Background(), &models.MCPRequest{
+		Method: "echo",
+		Params: map[string]interface{}{"text": "hello"},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, "echo: hello", resp.Result.(map[string]interface{})["content"])
+
+	resp, err = client.ProcessRequest(context.Background(), &models.MCPRequest{Method: "missing", Params: map[string]interface{}{}})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, -32601, resp.Error.Code)
+}
+
+func TestManager_InProcessByDefault(t *testing.T) {
+	t.Setenv("SECRETS_DIR", "")
+	t.Setenv("MCP_SERVER_BINARY", "")
+	t.Setenv("MCP_PROMPTS_DIR", "")
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "https://example.openai.azure.com")
+	t.Setenv("AZURE_OPENAI_DEPLOYMENT", "gpt-4")
+	t.Setenv("AZURE_OPENAI_API_KEY", "azure-key")
+	t.Setenv("TAVILY_API_KEY", "tavily-key")
+	t.Setenv("WEATHER_API_KEY", "weather-key")
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+
+	// 默认配置不需要 Go 工具链和源码目录，工具服务器直接在进程内运行
+	manager := NewManager(cfg, newTestLogger())
+	require.NoError(t, manager.Start(context.Background(), cfg.MCP.Servers))
+	defer manager.Stop()
+	require.NoError(t, manager.HealthCheck(context.Background()))
+
+	capabilities := manager.GetCapabilities()
+	assert.Contains(t, capabilities["tools"], "get_weather")
+	assert.Contains(t, capabilities["tools"], "search")
+
+	// 使用新配置重启后仍在进程内运行
+	require.NoError(t, manager.Restart(context.Background(), cfg))
+	resp, err := manager.ProcessRequest(context.Background(), &models.MCPRequest{
+		Method: "direct_response",
+		Params: map[string]interface{}{"response": "你好"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "你好", resp.Result.(map[string]interface{})["content"])
+}
+
+// staticSource 固定内容的资源来源
+type staticSource struct{}
+
+func (staticSource) Resources(ctx context.Context) []mcpgo.Resource {
+	return []mcpgo.Resource{mcpgo.NewResource("note://greeting", "问候")}
+}
+
+func (staticSource) Templates() []mcpgo.ResourceTemplate { return nil }
+
+func (staticSource) Read(ctx context.Context, uri string) ([]mcpgo.ResourceContents, error) {
+	if uri != "note://greeting" {
+		return nil, tools.ErrResourceNotFound
+	}
+	return []mcpgo.ResourceContents{mcpgo.TextResourceContents{URI: uri, MIMEType: "text/plain", Text: "你好"}}, nil
+}
+
+func TestInProcessClient_Resources(t *testing.T) {
+	registry := tools.NewRegistry(newTestLogger())
+	registry.RegisterResources(staticSource{})
+
+	manager := NewManager(&config.Config{}, newTestLogger())
+	manager.clients = []*Client{NewInProcessClient("embedded", registry.NewMCPServer("test-server", "1.0.0"), newTestLogger())}
+	require.NoError(t, manager.clients[0].Start(context.Background()))
+	defer manager.Stop()
+
+	var updated []string
+	manager.OnResourceUpdated(func(uri string) { updated = append(updated, uri) })
+
+	resources, err := manager.ListResources(context.Background())
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "note://greeting", resources[0].URI)
+
+	contents, err := manager.ReadResource(context.Background(), "note://greeting")
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	assert.Equal(t, "你好", contents[0].Text)
+
+	_, err = manager.ReadResource(context.Background(), "note://missing")
+	assert.ErrorIs(t, err, ErrResourceNotFound)
+
+	// 订阅后服务器推送的更新通知转交给回调
+	require.NoError(t, manager.Subscribe(context.Background(), "note://greeting"))
+	registry.ResourceUpdated("note://greeting")
+	registry.ResourceUpdated("note://other")
+	assert.Equal(t, []string{"note://greeting"}, updated)
+
+	require.NoError(t, manager.Unsubscribe(context.Background(), "note://greeting"))
+	registry.ResourceUpdated("note://greeting")
+	assert.Len(t, updated, 1)
+}
+
+func TestInProcessClient_Prompts(t *testing.T) {
+	registry := tools.NewRegistry(newTestLogger())
+	registry.RegisterPrompts(tools.TemplatePrompts([]models.PromptTemplate{{
+		Name:      "news_digest",
+		Template:  "请整理关于“{{.topic}}”的新闻",
+		Arguments: []models.PromptArgument{{Name: "topic", Required: true}},
+	}})...)
+
+	manager := NewManager(&config.Config{}, newTestLogger())
+	manager.clients = []*Client{NewInProcessClient("embedded", registry.NewMCPServer("test-server", "1.0.0"), newTestLogger())}
+	require.NoError(t, manager.clients[0].Start(context.Background()))
+	defer manager.Stop()
+
+	prompts, err := manager.ListPrompts(context.Background())
+	require.NoError(t, err)
+	require.Len(t, prompts, 1)
+	assert.Equal(t, "news_digest", prompts[0].Name)
+	assert.Equal(t, []models.PromptArgument{{Name: "topic", Required: true}}, prompts[0].Arguments)
+
+	messages, err := manager.GetPrompt(context.Background(), "news_digest", map[string]string{"topic": "人工智能"})
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChatMessage{{Role: "user", Content: "请整理关于“人工智能”的新闻"}}, messages)
+
+	_, err = manager.GetPrompt(context.Background(), "missing", nil)
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+
+	// 参数错误以服务器错误返回
+	_, err = manager.GetPrompt(context.Background(), "news_digest", nil)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Contains(t, rpcErr.Message, `"topic" is required`)
+}
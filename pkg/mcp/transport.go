@@ -0,0 +1,190 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+)
+
+// transport 与MCP服务器交换JSON-RPC消息，调用方保证同一时间只有一个请求
+// 服务器主动推送的通知（没有 id 的消息）交给创建传输时传入的处理函数。
+type transport interface {
+	// roundTrip 发送一条请求并返回 id 对应的响应
+	roundTrip(ctx context.Context, id int, request []byte) ([]byte, error)
+	// close 断开连接并释放资源
+	close()
+}
+
+// notificationHandler 处理服务器推送的通知消息
+type notificationHandler func(message []byte)
+
+// EmbeddedServer 可在进程内连接的MCP服务器，tools.Server 和 mcp-go 的 server.MCPServer 都满足该接口
+type EmbeddedServer interface {
+	HandleMessage(ctx context.Context, message json.RawMessage) mcpgo.JSONRPCMessage
+}
+
+// notifyingServer 支持推送通知的进程内服务器
+type notifyingServer interface {
+	SetNotificationHandler(fn func(mcpgo.JSONRPCNotification))
+}
+
+// stdioTransport 通过子进程的标准输入输出通信，每行一条消息
+// 后台协程持续读取输出：通知交给处理函数，响应按 id 交给等待中的请求。
+type stdioTransport struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    io.ReadCloser
+	responses chan stdioResponse
+	done      chan struct{} // 输出读取结束后关闭
+	readErr   error
+	logger    *logrus.Logger
+}
+
+// stdioResponse 读取到的一条响应
+type stdioResponse struct {
+	id   int
+	data []byte
+}
+
+// startStdioTransport 启动MCP服务器子进程
+func startStdioTransport(ctx context.Context, command string, args []string, onNotification notificationHandler, logger *logrus.Logger) (*stdioTransport, error) {
+	cmd := exec.CommandContext(ctx, command, args...)
+
+	// 创建管道
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	// 启动进程
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server: %w", err)
+	}
+
+	// 等待服务器启动
+	time.Sleep(500 * time.Millisecond)
+
+	t := &stdioTransport{
+		cmd:       cmd,
+		stdin:     stdin,
+		stdout:    stdout,
+		responses: make(chan stdioResponse, 8),
+		done:      make(chan struct{}),
+		logger:    logger,
+	}
+	go t.readLoop(onNotification)
+	return t, nil
+}
+
+// readLoop 读取服务器输出直到管道关闭
+func (t *stdioTransport) readLoop(onNotification notificationHandler) {
+	defer close(t.done)
+
+	scanner := bufio.NewScanner(t.stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+
+		var header struct {
+			ID     *int   `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(line, &header); err != nil {
+			t.logger.WithError(err).Warn("Ignoring malformed MCP message")
+			continue
+		}
+		if header.ID == nil {
+			if header.Method != "" && onNotification != nil {
+				onNotification(line)
+			}
+			continue
+		}
+		t.responses <- stdioResponse{id: *header.ID, data: line}
+	}
+	t.readErr = scanner.Err()
+}
+
+func (t *stdioTransport) roundTrip(ctx context.Context, id int, request []byte) ([]byte, error) {
+	if _, err := t.stdin.Write(append(request, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write message: %w", err)
+	}
+
+	for {
+		select {
+		case response := <-t.responses:
+			// 丢弃之前已放弃等待的请求的迟到响应
+			if response.id != id {
+				t.logger.WithField("id", response.id).Debug("Discarding stale MCP response")
+				continue
+			}
+			return response.data, nil
+		case <-t.done:
+			if t.readErr != nil {
+				return nil, fmt.Errorf("failed to read response: %w", t.readErr)
+			}
+			return nil, fmt.Errorf("no response received")
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// close 关闭管道并结束服务器子进程
+func (t *stdioTransport) close() {
+	t.stdin.Close()
+	t.stdout.Close()
+	if t.cmd.Process != nil {
+		t.cmd.Process.Kill()
+		t.cmd.Wait()
+	}
+}
+
+// inProcessTransport 将消息直接交给同一进程内的MCP服务器处理，不经过子进程和序列化管道
+type inProcessTransport struct {
+	server EmbeddedServer
+}
+
+// newInProcessTransport 连接进程内服务器，服务器支持推送通知时转交给 onNotification
+func newInProcessTransport(embedded EmbeddedServer, onNotification notificationHandler, logger *logrus.Logger) *inProcessTransport {
+	if notifier, ok := embedded.(notifyingServer); ok && onNotification != nil {
+		notifier.SetNotificationHandler(func(notification mcpgo.JSONRPCNotification) {
+			data, err := json.Marshal(notification)
+			if err != nil {
+				logger.WithError(err).Warn("Failed to marshal MCP notification")
+				return
+			}
+			onNotification(data)
+		})
+	}
+	return &inProcessTransport{server: embedded}
+}
+
+func (t *inProcessTransport) roundTrip(ctx context.Context, id int, request []byte) ([]byte, error) {
+	response := t.server.HandleMessage(ctx, request)
+	if response == nil {
+		return nil, fmt.Errorf("no response received")
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return data, nil
+}
+
+// close 停止接收服务器推送的通知
+func (t *inProcessTransport) close() {
+	if notifier, ok := t.server.(notifyingServer); ok {
+		notifier.SetNotificationHandler(nil)
+	}
+}
@@ -10,16 +10,35 @@ type ChatMessage struct {
 
 // ChatRequest 聊天请求结构
 type ChatRequest struct {
-	Messages []ChatMessage `json:"messages"`
-	Query    string        `json:"query"` // 用户输入的问题
+	Messages        []ChatMessage     `json:"messages"`
+	Query           string            `json:"query"`                      // 用户输入的问题
+	Resources       []string          `json:"resources,omitempty"`        // 作为参考资料附加的MCP资源URI
+	Prompt          string            `json:"prompt,omitempty"`           // MCP提示词名称，渲染结果作为问题（Query 非空时附加在其后）
+	PromptArguments map[string]string `json:"prompt_arguments,omitempty"` // 提示词参数
 }
 
 // ChatResponse 聊天响应结构
 type ChatResponse struct {
-	Response  string    `json:"response"`
-	Timestamp time.Time `json:"timestamp"`
-	Success   bool      `json:"success"`
-	Error     string    `json:"error,omitempty"`
+	Response  string            `json:"response"`
+	Timestamp time.Time         `json:"timestamp"`
+	Success   bool              `json:"success"`
+	Error     string            `json:"error,omitempty"`
+	Usage     *TokenUsage       `json:"usage,omitempty"` // 处理本次请求的所有LLM调用的token用量合计
+	Metadata  *ResponseMetadata `json:"metadata,omitempty"`
+}
+
+// TokenUsage LLM调用的token用量
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	Calls            int `json:"calls"` // LLM调用次数
+}
+
+// ResponseMetadata 响应的处理信息
+type ResponseMetadata struct {
+	PromptVersions map[string]string `json:"prompt_versions,omitempty"` // 本次使用的系统提示词名称 -> 版本
+	ToolCalls      []string          `json:"tool_calls,omitempty"`      // 本次调用的MCP工具，按调用顺序
 }
 
 // MCPRequest MCP协议请求结构
@@ -40,6 +59,21 @@ type MCPError struct {
 	Message string `json:"message"`
 }
 
+// Resource MCP资源描述
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContent MCP资源的文本内容
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
 // SearchRequest 搜索请求结构
 type SearchRequest struct {
 	Query       string `json:"query"`
@@ -49,10 +83,12 @@ type SearchRequest struct {
 
 // SearchResult 搜索结果结构
 type SearchResult struct {
-	Title   string  `json:"title"`
-	URL     string  `json:"url"`
-	Content string  `json:"content"`
-	Score   float64 `json:"score"`
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	Content       string  `json:"content"`
+	Score         float64 `json:"score"`
+	RawContent    string  `json:"raw_content,omitempty"`    // 网页完整正文，仅在请求时返回
+	PublishedDate string  `json:"published_date,omitempty"` // 发布时间（如果提供方返回）
 }
 
 // SearchResponse 搜索响应结构
@@ -71,9 +107,20 @@ type WorkflowState struct {
 	FinalResult string      `json:"final_result"` // 最终结果
 }
 
-// PromptTemplate 提示词模板
+// PromptTemplate 提示词模板，Template 为 Go text/template 模板，参数以 {{.参数名}} 引用
 type PromptTemplate struct {
-	Name     string `json:"name"`
-	Template string `json:"template"`
-	Type     string `json:"type"` // query_parser, result_formatter
+	Name        string           `json:"name" yaml:"name"`
+	Description string           `json:"description,omitempty" yaml:"description"`
+	Arguments   []PromptArgument `json:"arguments,omitempty" yaml:"arguments"`
+	Template    string           `json:"template,omitempty" yaml:"template"`
+	Type        string           `json:"type,omitempty" yaml:"type"` // query_parser, result_formatter, prompt
+	Version     string           `json:"version,omitempty" yaml:"version"`
+	Weight      int              `json:"weight,omitempty" yaml:"weight"` // 同名模板多个版本之间的A/B流量权重
+}
+
+// PromptArgument 提示词参数
+type PromptArgument struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description"`
+	Required    bool   `json:"required,omitempty" yaml:"required"`
 }
\ No newline at end of file
@@ -0,0 +1,152 @@
+// Package prompts 提示词包和系统提示词模板的加载与渲染
+// 提示词包是目录中的 YAML 文件，每个文件包含一组带参数的 text/template 模板，
+// 由MCP服务器通过 prompts/list、prompts/get 提供，修改后无需重新编译API服务。
+// 系统提示词模板是带 YAML 元数据的 .tmpl 文件，供LLM客户端渲染查询路由和结果整理的系统提示词，
+// 同名模板可以有多个版本按权重分流。
+package prompts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"deer-flow-go/pkg/models"
+)
+
+// TypePrompt 提示词包中模板的默认类型
+const TypePrompt = "prompt"
+
+// ErrInvalidArguments 渲染参数缺失或未声明
+var ErrInvalidArguments = errors.New("invalid prompt arguments")
+
+// packFile 提示词包文件格式
+type packFile struct {
+	Prompts []models.PromptTemplate `yaml:"prompts"`
+}
+
+// LoadDir 加载目录下所有 .yaml/.yml 提示词包，按文件名顺序返回，名称在所有包中必须唯一
+func LoadDir(dir string) ([]models.PromptTemplate, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts dir: %w", err)
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	var templates []models.PromptTemplate
+	seen := make(map[string]string)
+	for _, file := range files {
+		pack, err := loadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, tmpl := range pack {
+			if previous, exists := seen[tmpl.Name]; exists {
+				return nil, fmt.Errorf("%s: prompt %q is already defined in %s", file, tmpl.Name, previous)
+			}
+			seen[tmpl.Name] = file
+			templates = append(templates, tmpl)
+		}
+	}
+	return templates, nil
+}
+
+// loadFile 加载并校验单个提示词包
+func loadFile(file string) ([]models.PromptTemplate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt pack: %w", err)
+	}
+	var pack packFile
+	if err := yaml.Unmarshal(data, &pack); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt pack %s: %w", file, err)
+	}
+
+	for i := range pack.Prompts {
+		tmpl := &pack.Prompts[i]
+		if tmpl.Type == "" {
+			tmpl.Type = TypePrompt
+		}
+		if err := Validate(*tmpl); err != nil {
+			return nil, fmt.Errorf("%s: prompts[%d]: %w", file, i, err)
+		}
+	}
+	return pack.Prompts, nil
+}
+
+// Validate 校验模板名称、参数声明和模板语法
+func Validate(tmpl models.PromptTemplate) error {
+	if tmpl.Name == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(tmpl.Template) == "" {
+		return fmt.Errorf("prompt %q: template is required", tmpl.Name)
+	}
+	names := make(map[string]bool, len(tmpl.Arguments))
+	for _, arg := range tmpl.Arguments {
+		if arg.Name == "" {
+			return fmt.Errorf("prompt %q: argument name is required", tmpl.Name)
+		}
+		if names[arg.Name] {
+			return fmt.Errorf("prompt %q: duplicate argument %q", tmpl.Name, arg.Name)
+		}
+		names[arg.Name] = true
+	}
+	if _, err := parse(tmpl); err != nil {
+		return fmt.Errorf("prompt %q: %w", tmpl.Name, err)
+	}
+	return nil
+}
+
+// Render 使用参数渲染模板
+// 必填参数缺失或传入未声明的参数时返回 ErrInvalidArguments；未传入的可选参数按空字符串处理。
+func Render(tmpl models.PromptTemplate, args map[string]string) (string, error) {
+	data := make(map[string]string, len(tmpl.Arguments))
+	declared := make(map[string]bool, len(tmpl.Arguments))
+	for _, arg := range tmpl.Arguments {
+		declared[arg.Name] = true
+		value := args[arg.Name]
+		if arg.Required && strings.TrimSpace(value) == "" {
+			return "", fmt.Errorf("%w: %q is required", ErrInvalidArguments, arg.Name)
+		}
+		data[arg.Name] = value
+	}
+	for name := range args {
+		if !declared[name] {
+			return "", fmt.Errorf("%w: unknown argument %q", ErrInvalidArguments, name)
+		}
+	}
+
+	t, err := parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %q: %w", tmpl.Name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// parse 解析模板，引用未声明的参数时渲染报错
+func parse(tmpl models.PromptTemplate) (*template.Template, error) {
+	t, err := template.New(tmpl.Name).Option("missingkey=error").Parse(tmpl.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return t, nil
+}
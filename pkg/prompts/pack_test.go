@@ -0,0 +1,109 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/models"
+)
+
+func writePack(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	writePack(t, dir, "b.yaml", `
+prompts:
+  - name: news_digest
+    template: "新闻: {{.topic}}"
+    arguments:
+      - name: topic
+        required: true
+`)
+	writePack(t, dir, "a.yml", `
+prompts:
+  - name: weather_briefing
+    description: 天气简报
+    type: result_formatter
+    template: "{{.city}}天气"
+`)
+	writePack(t, dir, "README.md", "not a pack")
+
+	templates, err := LoadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	assert.Equal(t, "weather_briefing", templates[0].Name)
+	assert.Equal(t, "result_formatter", templates[0].Type)
+	assert.Equal(t, "news_digest", templates[1].Name)
+	assert.Equal(t, TypePrompt, templates[1].Type)
+	assert.True(t, templates[1].Arguments[0].Required)
+
+	// 名称在所有包中必须唯一
+	writePack(t, dir, "c.yaml", `
+prompts:
+  - name: news_digest
+    template: "重复"
+`)
+	_, err = LoadDir(dir)
+	assert.ErrorContains(t, err, `prompt "news_digest" is already defined`)
+
+	// 模板语法错误
+	dir = t.TempDir()
+	writePack(t, dir, "bad.yaml", `
+prompts:
+  - name: broken
+    template: "{{.city"
+`)
+	_, err = LoadDir(dir)
+	assert.ErrorContains(t, err, "failed to parse template")
+
+	_, err = LoadDir(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestLoadDir_ShippedPacks(t *testing.T) {
+	templates, err := LoadDir(filepath.Join("..", "..", "prompts"))
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(templates))
+	for _, tmpl := range templates {
+		names = append(names, tmpl.Name)
+	}
+	assert.Contains(t, names, "weather_briefing")
+	assert.Contains(t, names, "news_digest")
+}
+
+func TestRender(t *testing.T) {
+	tmpl := models.PromptTemplate{
+		Name:     "weather_briefing",
+		Template: "{{.city}}未来{{if .days}}{{.days}}{{else}}3{{end}}天",
+		Arguments: []models.PromptArgument{
+			{Name: "city", Required: true},
+			{Name: "days"},
+		},
+	}
+
+	text, err := Render(tmpl, map[string]string{"city": "北京"})
+	require.NoError(t, err)
+	assert.Equal(t, "北京未来3天", text)
+
+	text, err = Render(tmpl, map[string]string{"city": "北京", "days": "5"})
+	require.NoError(t, err)
+	assert.Equal(t, "北京未来5天", text)
+
+	_, err = Render(tmpl, map[string]string{"days": "5"})
+	assert.ErrorIs(t, err, ErrInvalidArguments)
+
+	_, err = Render(tmpl, map[string]string{"city": "北京", "lang": "en"})
+	assert.ErrorIs(t, err, ErrInvalidArguments)
+
+	// 引用未声明的参数时渲染失败
+	_, err = Render(models.PromptTemplate{Name: "typo", Template: "{{.cty}}"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,300 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"deer-flow-go/pkg/models"
+)
+
+// 内置系统提示词名称，同时也是模板类型
+const (
+	QueryParser     = "query_parser"
+	ResultFormatter = "result_formatter"
+)
+
+// ErrTemplateNotFound 注册表中没有该名称的模板
+var ErrTemplateNotFound = errors.New("prompt template not found")
+
+//go:embed templates/*.tmpl
+var builtinFS embed.FS
+
+// builtin 内置模板注册表，启动时解析，模板有误属于编译期错误
+var builtin = mustLoadBuiltin()
+
+// Data 系统提示词模板可使用的变量
+type Data struct {
+	Date   string   // 当前日期，如 2025-01-02
+	Locale string   // 回答使用的语言区域，如 zh-CN
+	Tools  []string // MCP服务器提供的工具名称
+}
+
+// Rendered 渲染后的系统提示词及其来源版本
+type Rendered struct {
+	Name    string
+	Version string
+	Text    string
+}
+
+// variant 同名模板的一个版本
+type variant struct {
+	tmpl   models.PromptTemplate
+	parsed *template.Template
+}
+
+// Registry 系统提示词模板注册表，同名模板可以有多个版本，按权重分流（A/B测试）
+// 注册表创建后只读，可以并发使用；重新加载时创建新的注册表替换。
+type Registry struct {
+	variants map[string][]variant
+}
+
+// templateFuncs 模板中可用的函数
+var templateFuncs = template.FuncMap{
+	"join":      strings.Join,
+	"hasPrefix": strings.HasPrefix,
+	"upper":     strings.ToUpper,
+	"lower":     strings.ToLower,
+}
+
+// frontMatter .tmpl 文件头部的 YAML 元数据
+type frontMatter struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+	Weight      *int   `yaml:"weight"`
+}
+
+// Builtin 返回内置模板注册表
+func Builtin() *Registry {
+	return builtin
+}
+
+// mustLoadBuiltin 加载内置模板
+func mustLoadBuiltin() *Registry {
+	templates, err := loadTemplates(builtinFS, "templates")
+	if err != nil {
+		panic(fmt.Sprintf("invalid builtin prompt templates: %v", err))
+	}
+	registry, err := newRegistry(templates)
+	if err != nil {
+		panic(fmt.Sprintf("invalid builtin prompt templates: %v", err))
+	}
+	return registry
+}
+
+// LoadRegistry 加载目录下的 .tmpl 模板文件
+// 目录中出现的模板名称整体替换内置模板的所有版本，未出现的名称继续使用内置模板。
+func LoadRegistry(dir string) (*Registry, error) {
+	templates, err := loadTemplates(os.DirFS(dir), ".")
+	if err != nil {
+		return nil, err
+	}
+
+	overridden := make(map[string]bool, len(templates))
+	for _, tmpl := range templates {
+		overridden[tmpl.Name] = true
+	}
+	for name, variants := range builtin.variants {
+		if overridden[name] {
+			continue
+		}
+		for _, v := range variants {
+			templates = append(templates, v.tmpl)
+		}
+	}
+	return newRegistry(templates)
+}
+
+// loadTemplates 读取目录下的 .tmpl 文件（按文件名顺序）
+func loadTemplates(fsys fs.FS, dir string) ([]models.PromptTemplate, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt templates dir: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.ToLower(filepath.Ext(entry.Name())) == ".tmpl" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	templates := make([]models.PromptTemplate, 0, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		tmpl, err := parseTemplateFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates, nil
+}
+
+// parseTemplateFile 解析模板文件：以 --- 包围的 YAML 元数据，之后是模板正文
+func parseTemplateFile(data []byte) (models.PromptTemplate, error) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return models.PromptTemplate{}, errors.New("missing front matter (file must start with ---)")
+	}
+	header, body, found := strings.Cut(content[len("---\n"):], "\n---\n")
+	if !found {
+		return models.PromptTemplate{}, errors.New("unterminated front matter")
+	}
+
+	var meta frontMatter
+	if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+		return models.PromptTemplate{}, fmt.Errorf("failed to parse front matter: %w", err)
+	}
+
+	tmpl := models.PromptTemplate{
+		Name:        meta.Name,
+		Description: meta.Description,
+		Template:    body,
+		Type:        meta.Type,
+		Version:     meta.Version,
+		Weight:      1,
+	}
+	if tmpl.Type == "" {
+		tmpl.Type = tmpl.Name
+	}
+	if meta.Weight != nil {
+		tmpl.Weight = *meta.Weight
+	}
+	return tmpl, nil
+}
+
+// newRegistry 校验并解析模板，同名模板的版本号不能重复，且至少有一个版本权重大于0
+func newRegistry(templates []models.PromptTemplate) (*Registry, error) {
+	r := &Registry{variants: make(map[string][]variant)}
+	for _, tmpl := range templates {
+		if tmpl.Name == "" {
+			return nil, errors.New("name is required")
+		}
+		if tmpl.Version == "" {
+			return nil, fmt.Errorf("template %q: version is required", tmpl.Name)
+		}
+		if tmpl.Weight < 0 {
+			return nil, fmt.Errorf("template %q %s: weight must not be negative", tmpl.Name, tmpl.Version)
+		}
+		if strings.TrimSpace(tmpl.Template) == "" {
+			return nil, fmt.Errorf("template %q %s: template is required", tmpl.Name, tmpl.Version)
+		}
+		for _, existing := range r.variants[tmpl.Name] {
+			if existing.tmpl.Version == tmpl.Version {
+				return nil, fmt.Errorf("template %q: duplicate version %s", tmpl.Name, tmpl.Version)
+			}
+		}
+
+		parsed, err := template.New(tmpl.Name).Funcs(templateFuncs).Parse(tmpl.Template)
+		if err != nil {
+			return nil, fmt.Errorf("template %q %s: failed to parse template: %w", tmpl.Name, tmpl.Version, err)
+		}
+		r.variants[tmpl.Name] = append(r.variants[tmpl.Name], variant{tmpl: tmpl, parsed: parsed})
+	}
+
+	for name, variants := range r.variants {
+		total := 0
+		for _, v := range variants {
+			total += v.tmpl.Weight
+		}
+		if total == 0 {
+			return nil, fmt.Errorf("template %q: all versions have weight 0", name)
+		}
+	}
+	return r, nil
+}
+
+// Templates 返回所有模板（按名称、版本排序）
+func (r *Registry) Templates() []models.PromptTemplate {
+	var templates []models.PromptTemplate
+	for _, variants := range r.variants {
+		for _, v := range variants {
+			templates = append(templates, v.tmpl)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Name != templates[j].Name {
+			return templates[i].Name < templates[j].Name
+		}
+		return templates[i].Version < templates[j].Version
+	})
+	return templates
+}
+
+// Select 按权重选择模板版本
+// key 非空时按其哈希选择，同一 key（如同一查询）总是得到同一版本；为空时随机选择。
+func (r *Registry) Select(name, key string) (models.PromptTemplate, error) {
+	v, err := r.selectVariant(name, key)
+	if err != nil {
+		return models.PromptTemplate{}, err
+	}
+	return v.tmpl, nil
+}
+
+// selectVariant 按权重选择模板版本
+func (r *Registry) selectVariant(name, key string) (variant, error) {
+	variants := r.variants[name]
+	if len(variants) == 0 {
+		return variant{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	total := 0
+	for _, v := range variants {
+		total += v.tmpl.Weight
+	}
+
+	var point int
+	if key == "" {
+		point = rand.Intn(total)
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		point = int(h.Sum32() % uint32(total))
+	}
+
+	for _, v := range variants {
+		if point < v.tmpl.Weight {
+			return v, nil
+		}
+		point -= v.tmpl.Weight
+	}
+	return variants[len(variants)-1], nil
+}
+
+// Render 选择模板版本并渲染
+func (r *Registry) Render(name, key string, data Data) (Rendered, error) {
+	v, err := r.selectVariant(name, key)
+	if err != nil {
+		return Rendered{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := v.parsed.Execute(&buf, data); err != nil {
+		return Rendered{}, fmt.Errorf("failed to render template %q %s: %w", name, v.tmpl.Version, err)
+	}
+	return Rendered{
+		Name:    name,
+		Version: v.tmpl.Version,
+		Text:    strings.TrimSpace(buf.String()),
+	}, nil
+}
@@ -0,0 +1,138 @@
+package prompts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTemplate 在目录中写入一个模板文件
+func writeTemplate(t *testing.T, dir, file, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0o600))
+}
+
+func TestBuiltin(t *testing.T) {
+	registry := Builtin()
+
+	rendered, err := registry.Render(QueryParser, "北京天气", Data{
+		Date:   "2025-01-02",
+		Locale: "zh-CN",
+		Tools:  []string{"get_weather", "search"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, QueryParser, rendered.Name)
+	assert.Equal(t, "v1", rendered.Version)
+	assert.Contains(t, rendered.Text, "今天是 2025-01-02")
+	assert.Contains(t, rendered.Text, "当前可用的工具：get_weather, search")
+	assert.Contains(t, rendered.Text, "只返回JSON格式")
+
+	// 未提供的变量对应的段落不输出
+	rendered, err = registry.Render(QueryParser, "北京天气", Data{})
+	require.NoError(t, err)
+	assert.NotContains(t, rendered.Text, "今天是")
+	assert.NotContains(t, rendered.Text, "当前可用的工具")
+
+	rendered, err = registry.Render(ResultFormatter, "", Data{Locale: "zh-CN"})
+	require.NoError(t, err)
+	assert.Contains(t, rendered.Text, "请用中文回答")
+	rendered, err = registry.Render(ResultFormatter, "", Data{Locale: "en-US"})
+	require.NoError(t, err)
+	assert.Contains(t, rendered.Text, "请使用 en-US 对应的语言回答")
+
+	_, err = registry.Render("unknown", "", Data{})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestLoadRegistry(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "formatter-v2.tmpl", `---
+name: result_formatter
+version: v2
+weight: 3
+---
+简洁地回答：{{.Locale | upper}}
+`)
+	writeTemplate(t, dir, "formatter-v3.tmpl", `---
+name: result_formatter
+version: v3
+weight: 0
+---
+暂停的版本
+`)
+	writeTemplate(t, dir, "README.md", "不是模板")
+
+	registry, err := LoadRegistry(dir)
+	require.NoError(t, err)
+
+	// 目录中的版本替换内置 result_formatter，query_parser 仍使用内置模板
+	var versions []string
+	for _, tmpl := range registry.Templates() {
+		versions = append(versions, tmpl.Name+"@"+tmpl.Version)
+	}
+	assert.Equal(t, []string{"query_parser@v1", "result_formatter@v2", "result_formatter@v3"}, versions)
+
+	rendered, err := registry.Render(ResultFormatter, "", Data{Locale: "en-us"})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", rendered.Version)
+	assert.Equal(t, "简洁地回答：EN-US", rendered.Text)
+
+	tmpl, err := registry.Select(ResultFormatter, "")
+	require.NoError(t, err)
+	assert.Equal(t, ResultFormatter, tmpl.Type, "type defaults to the name")
+	assert.Equal(t, 3, tmpl.Weight)
+}
+
+func TestLoadRegistry_Invalid(t *testing.T) {
+	cases := map[string][]string{
+		"missing front matter": {"只有正文"},
+		"missing version":      {"---\nname: query_parser\n---\n正文"},
+		"duplicate version":    {"---\nname: a\nversion: v1\n---\n正文", "---\nname: a\nversion: v1\n---\n正文"},
+		"zero total weight":    {"---\nname: a\nversion: v1\nweight: 0\n---\n正文"},
+		"bad syntax":           {"---\nname: a\nversion: v1\n---\n{{.Date"},
+	}
+	for name, files := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, content := range files {
+				writeTemplate(t, dir, fmt.Sprintf("%d.tmpl", i), content)
+			}
+			_, err := LoadRegistry(dir)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := LoadRegistry(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestRegistry_WeightedSelection(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "a.tmpl", "---\nname: query_parser\nversion: control\nweight: 1\n---\nA")
+	writeTemplate(t, dir, "b.tmpl", "---\nname: query_parser\nversion: treatment\nweight: 3\n---\nB")
+	registry, err := LoadRegistry(dir)
+	require.NoError(t, err)
+
+	// 同一 key 总是选中同一版本
+	first, err := registry.Select(QueryParser, "上海明天会下雨吗")
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		again, err := registry.Select(QueryParser, "上海明天会下雨吗")
+		require.NoError(t, err)
+		assert.Equal(t, first.Version, again.Version)
+	}
+
+	// 不同 key 大致按权重分流
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		tmpl, err := registry.Select(QueryParser, fmt.Sprintf("query-%d", i))
+		require.NoError(t, err)
+		counts[tmpl.Version]++
+	}
+	assert.InDelta(t, 1000, counts["control"], 150)
+	assert.InDelta(t, 3000, counts["treatment"], 150)
+}
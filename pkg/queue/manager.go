@@ -19,6 +19,7 @@ type RequestTask struct {
 	Context  context.Context
 	Response chan *TaskResult
 	Created  time.Time
+	Timeout  time.Duration // 处理超时，提交时从当前配置读取
 }
 
 // TaskResult 任务结果
@@ -39,7 +40,7 @@ type QueueConfig struct {
 type QueueManager struct {
 	config      *QueueConfig
 	taskQueue   chan *RequestTask
-	workerPool  chan chan *RequestTask
+	workerPool  chan *Worker
 	workers     []*Worker
 	logger      *logrus.Logger
 	processor   RequestProcessor
@@ -76,7 +77,7 @@ func NewQueueManager(config *QueueConfig, processor RequestProcessor, logger *lo
 	qm := &QueueManager{
 		config:     config,
 		taskQueue:  make(chan *RequestTask, config.QueueSize),
-		workerPool: make(chan chan *RequestTask, config.MaxWorkers),
+		workerPool: make(chan *Worker, config.MaxWorkers),
 		workers:    make([]*Worker, config.MaxWorkers),
 		logger:     logger,
 		processor:  processor,
@@ -125,19 +126,89 @@ func (qm *QueueManager) Stop() {
 	close(qm.taskQueue)
 
 	// 停止所有工作协程
+	qm.mu.RLock()
 	for _, worker := range qm.workers {
 		worker.Stop()
 	}
+	qm.mu.RUnlock()
 
 	qm.logger.Info("Queue manager stopped")
 }
 
+// Resize 动态调整工作协程数量
+// 扩容时立即启动新协程；缩容时停止多余的协程，它们正在处理的任务会继续完成，队列中的任务不受影响。
+func (qm *QueueManager) Resize(maxWorkers int) error {
+	if maxWorkers <= 0 {
+		return fmt.Errorf("max workers must be positive, got %d", maxWorkers)
+	}
+
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	current := len(qm.workers)
+	if maxWorkers == current {
+		return nil
+	}
+
+	running := atomic.LoadInt32(&qm.running) == 1
+	if maxWorkers > current {
+		for i := current; i < maxWorkers; i++ {
+			worker := NewWorker(i+1, qm.workerPool, qm.processor, qm.logger)
+			qm.workers = append(qm.workers, worker)
+			if running {
+				worker.Start()
+			}
+		}
+	} else {
+		for _, worker := range qm.workers[maxWorkers:] {
+			worker.Stop()
+		}
+		qm.workers = qm.workers[:maxWorkers]
+	}
+	qm.config.MaxWorkers = maxWorkers
+
+	qm.logger.WithFields(logrus.Fields{
+		"old_workers": current,
+		"new_workers": maxWorkers,
+	}).Info("Worker pool resized")
+
+	return nil
+}
+
+// UpdateTimeouts 动态更新请求超时和队列等待超时，对之后提交的请求生效
+func (qm *QueueManager) UpdateTimeouts(requestTimeout, queueTimeout time.Duration) {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	if requestTimeout > 0 {
+		qm.config.RequestTimeout = requestTimeout
+	}
+	if queueTimeout > 0 {
+		qm.config.QueueTimeout = queueTimeout
+	}
+}
+
+// timeouts 读取当前的超时配置
+func (qm *QueueManager) timeouts() (requestTimeout, queueTimeout time.Duration) {
+	qm.mu.RLock()
+	defer qm.mu.RUnlock()
+	return qm.config.RequestTimeout, qm.config.QueueTimeout
+}
+
+// RequestTimeout 返回当前的请求超时，热加载后立即反映新值
+func (qm *QueueManager) RequestTimeout() time.Duration {
+	requestTimeout, _ := qm.timeouts()
+	return requestTimeout
+}
+
 // SubmitRequest 提交请求到队列
 func (qm *QueueManager) SubmitRequest(ctx context.Context, query string) (*models.ChatResponse, error) {
 	if atomic.LoadInt32(&qm.running) == 0 {
 		return nil, fmt.Errorf("queue manager is not running")
 	}
 
+	requestTimeout, queueTimeout := qm.timeouts()
+
 	// 创建任务
 	task := &RequestTask{
 		ID:       fmt.Sprintf("task_%d_%d", time.Now().UnixNano(), atomic.AddInt64(&qm.totalRequests, 1)),
@@ -145,20 +216,21 @@ func (qm *QueueManager) SubmitRequest(ctx context.Context, query string) (*model
 		Context:  ctx,
 		Response: make(chan *TaskResult, 1),
 		Created:  time.Now(),
+		Timeout:  requestTimeout,
 	}
 
 	qm.logger.WithFields(logrus.Fields{
-		"task_id": task.ID,
-		"query":   query,
+		"task_id":      task.ID,
+		"query_length": len(query),
 	}).Debug("Submitting request to queue")
 
 	// 尝试将任务加入队列
 	select {
 	case qm.taskQueue <- task:
 		atomic.AddInt64(&qm.queuedCount, 1)
-	case <-time.After(qm.config.QueueTimeout):
+	case <-time.After(queueTimeout):
 		atomic.AddInt64(&qm.failedCount, 1)
-		return nil, fmt.Errorf("request queue is full, timeout after %v", qm.config.QueueTimeout)
+		return nil, fmt.Errorf("request queue is full, timeout after %v", queueTimeout)
 	case <-ctx.Done():
 		atomic.AddInt64(&qm.failedCount, 1)
 		return nil, ctx.Err()
@@ -173,9 +245,9 @@ func (qm *QueueManager) SubmitRequest(ctx context.Context, query string) (*model
 		}
 		atomic.AddInt64(&qm.processedCount, 1)
 		return result.Response, nil
-	case <-time.After(qm.config.RequestTimeout):
+	case <-time.After(requestTimeout):
 		atomic.AddInt64(&qm.failedCount, 1)
-		return nil, fmt.Errorf("request timeout after %v", qm.config.RequestTimeout)
+		return nil, fmt.Errorf("request timeout after %v", requestTimeout)
 	case <-ctx.Done():
 		atomic.AddInt64(&qm.failedCount, 1)
 		return nil, ctx.Err()
@@ -194,27 +266,40 @@ func (qm *QueueManager) dispatcher() {
 				return // 队列已关闭
 			}
 
-			// 获取可用的工作协程
+			qm.assignTask(task)
+		}
+	}
+}
+
+// assignTask 将任务分发给空闲的工作协程
+// 缩容后被停止的协程可能仍在工作池中留有登记，遇到时直接丢弃该登记并尝试下一个协程。
+func (qm *QueueManager) assignTask(task *RequestTask) {
+	_, queueTimeout := qm.timeouts()
+	deadline := time.After(queueTimeout)
+
+	for {
+		// 获取可用的工作协程
+		select {
+		case worker := <-qm.workerPool:
+			// 将任务分发给工作协程
 			select {
-			case workerTaskQueue := <-qm.workerPool:
-				// 将任务分发给工作协程
-				select {
-				case workerTaskQueue <- task:
-					atomic.AddInt64(&qm.queuedCount, -1)
-				case <-time.After(1 * time.Second):
-					// 工作协程超时，返回错误
-					task.Response <- &TaskResult{
-						Error: fmt.Errorf("worker assignment timeout"),
-					}
-					atomic.AddInt64(&qm.queuedCount, -1)
-				}
-			case <-time.After(qm.config.QueueTimeout):
-				// 没有可用的工作协程
-				task.Response <- &TaskResult{
-					Error: fmt.Errorf("no available workers, timeout after %v", qm.config.QueueTimeout),
-				}
+			case worker.taskQueue <- task:
 				atomic.AddInt64(&qm.queuedCount, -1)
+				return
+			case <-worker.quit:
+				// 工作协程已停止，尝试下一个
+				qm.logger.WithFields(logrus.Fields{
+					"task_id":   task.ID,
+					"worker_id": worker.id,
+				}).Debug("Skipping retired worker")
+			}
+		case <-deadline:
+			// 没有可用的工作协程
+			task.Response <- &TaskResult{
+				Error: fmt.Errorf("no available workers, timeout after %v", queueTimeout),
 			}
+			atomic.AddInt64(&qm.queuedCount, -1)
+			return
 		}
 	}
 }
@@ -294,4 +294,94 @@ func TestQueueManager_StartStop(t *testing.T) {
 	
 	// 测试重复停止（应该不会panic）
 	manager.Stop()
-}
\ No newline at end of file
+}
+func TestQueueManager_Resize(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	mockProcessor := &MockRequestProcessor{
+		processDelay: 100 * time.Millisecond,
+	}
+	testResponse := &models.ChatResponse{Response: "response"}
+	mockProcessor.On("ProcessRequest", mock.Anything, mock.AnythingOfType("string")).Return(testResponse, nil)
+
+	config := &QueueConfig{
+		MaxWorkers:     1,
+		QueueSize:      20,
+		RequestTimeout: 5 * time.Second,
+		QueueTimeout:   3 * time.Second,
+	}
+
+	manager := NewQueueManager(config, mockProcessor, logger)
+	err := manager.Start()
+	assert.NoError(t, err)
+	defer manager.Stop()
+
+	// 扩容后并发处理
+	assert.NoError(t, manager.Resize(4))
+	assert.Equal(t, 4, manager.GetStats()["max_workers"])
+
+	submitAll := func(n int) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := manager.SubmitRequest(context.Background(), "resize test")
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+	}
+
+	start := time.Now()
+	submitAll(4)
+	assert.Less(t, time.Since(start), 350*time.Millisecond, "4 workers should process 4 requests concurrently")
+
+	// 缩容后队列中的请求仍然全部完成，且不会在已停止的协程上等待
+	assert.NoError(t, manager.Resize(1))
+	assert.Equal(t, 1, manager.GetStats()["max_workers"])
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := manager.SubmitRequest(context.Background(), "resize test")
+		assert.NoError(t, err)
+	}
+	assert.Less(t, time.Since(start), 700*time.Millisecond, "retired workers should be skipped without waiting")
+	submitAll(3)
+
+	// 非法数量
+	assert.Error(t, manager.Resize(0))
+}
+
+// deadlineProcessor 记录处理请求时上下文的截止时间
+type deadlineProcessor struct {
+	remaining chan time.Duration
+}
+
+func (p *deadlineProcessor) ProcessRequest(ctx context.Context, query string) (*models.ChatResponse, error) {
+	deadline, _ := ctx.Deadline()
+	p.remaining <- time.Until(deadline)
+	return &models.ChatResponse{Response: "ok"}, nil
+}
+
+func TestQueueManager_UpdateTimeoutsAppliesToWorkers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	processor := &deadlineProcessor{remaining: make(chan time.Duration, 1)}
+	manager := NewQueueManager(&QueueConfig{
+		MaxWorkers:     1,
+		QueueSize:      1,
+		RequestTimeout: 5 * time.Second,
+		QueueTimeout:   time.Second,
+	}, processor, logger)
+	assert.NoError(t, manager.Start())
+	defer manager.Stop()
+
+	manager.UpdateTimeouts(90*time.Second, 0)
+	_, err := manager.SubmitRequest(context.Background(), "timeout update")
+	assert.NoError(t, err)
+
+	remaining := <-processor.remaining
+	assert.Greater(t, remaining, 60*time.Second, "worker should use the reloaded request timeout")
+}
@@ -9,10 +9,13 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// defaultTaskTimeout 任务未指定超时时的处理超时
+const defaultTaskTimeout = 30 * time.Second
+
 // Worker 工作协程
 type Worker struct {
 	id         int
-	workerPool chan chan *RequestTask
+	workerPool chan *Worker
 	taskQueue  chan *RequestTask
 	processor  RequestProcessor
 	logger     *logrus.Logger
@@ -21,7 +24,7 @@ type Worker struct {
 }
 
 // NewWorker 创建新的工作协程
-func NewWorker(id int, workerPool chan chan *RequestTask, processor RequestProcessor, logger *logrus.Logger) *Worker {
+func NewWorker(id int, workerPool chan *Worker, processor RequestProcessor, logger *logrus.Logger) *Worker {
 	return &Worker{
 		id:         id,
 		workerPool: workerPool,
@@ -47,9 +50,16 @@ func (w *Worker) Start() {
 		}()
 
 		for {
-			// 将自己的任务队列注册到工作池中
+			// 已收到停止信号时不再登记到工作池
 			select {
-			case w.workerPool <- w.taskQueue:
+			case <-w.quit:
+				return
+			default:
+			}
+
+			// 将自己注册到工作池中
+			select {
+			case w.workerPool <- w:
 				// 等待任务
 				select {
 				case task := <-w.taskQueue:
@@ -78,9 +88,9 @@ func (w *Worker) Stop() {
 func (w *Worker) processTask(task *RequestTask) {
 	start := time.Now()
 	w.logger.WithFields(logrus.Fields{
-		"worker_id": w.id,
-		"task_id":   task.ID,
-		"query":     task.Query,
+		"worker_id":    w.id,
+		"task_id":      task.ID,
+		"query_length": len(task.Query),
 	}).Debug("Processing task")
 
 	defer func() {
@@ -97,8 +107,12 @@ func (w *Worker) processTask(task *RequestTask) {
 		}
 	}()
 
-	// 创建带超时的上下文
-	ctx, cancel := context.WithTimeout(task.Context, 30*time.Second)
+	// 创建带超时的上下文，未指定超时时使用默认值
+	timeout := task.Timeout
+	if timeout <= 0 {
+		timeout = defaultTaskTimeout
+	}
+	ctx, cancel := context.WithTimeout(task.Context, timeout)
 	defer cancel()
 
 	// 处理请求
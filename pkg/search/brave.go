@@ -0,0 +1,181 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// braveMaxCount Brave Web Search API 单次请求允许的最大结果数
+const braveMaxCount = 20
+
+// htmlTagPattern 匹配摘要中的HTML标签（如 <strong>）
+var htmlTagPattern = regexp.MustCompile(`<[^>]+>`)
+
+// BraveClient Brave Search API 客户端
+type BraveClient struct {
+	config     *config.BraveConfig
+	maxResults int
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+// BraveSearchResponse Brave Web Search API响应结构
+type BraveSearchResponse struct {
+	Query struct {
+		Original string `json:"original"`
+	} `json:"query"`
+	Web struct {
+		Results []BraveResult `json:"results"`
+	} `json:"web"`
+	Results []BraveResult `json:"results"` // 新闻搜索接口
+}
+
+// BraveResult Brave搜索结果
+type BraveResult struct {
+	Title         string   `json:"title"`
+	URL           string   `json:"url"`
+	Description   string   `json:"description"`
+	Age           string   `json:"age"`
+	PageAge       string   `json:"page_age"`
+	ExtraSnippets []string `json:"extra_snippets"`
+}
+
+// braveFreshness time_range 到 Brave freshness 参数的映射
+var braveFreshness = map[string]string{
+	"day":   "pd",
+	"week":  "pw",
+	"month": "pm",
+	"year":  "py",
+}
+
+// braveQuery 将域名过滤转换为 site: 查询运算符
+func braveQuery(query string, opts SearchOptions) string {
+	var parts []string
+	if len(opts.IncludeDomains) > 0 {
+		sites := make([]string, len(opts.IncludeDomains))
+		for i, domain := range opts.IncludeDomains {
+			sites[i] = "site:" + domain
+		}
+		parts = append(parts, "("+strings.Join(sites, " OR ")+")")
+	}
+	for _, domain := range opts.ExcludeDomains {
+		parts = append(parts, "-site:"+domain)
+	}
+	if len(parts) == 0 {
+		return query
+	}
+	return query + " " + strings.Join(parts, " ")
+}
+
+// stripHTML 去除摘要中的HTML标签并反转义实体
+func stripHTML(text string) string {
+	return html.UnescapeString(htmlTagPattern.ReplaceAllString(text, ""))
+}
+
+// NewBraveClient 创建Brave客户端
+func NewBraveClient(cfg *config.BraveConfig, maxResults int, timeout time.Duration, logger *logrus.Logger) *BraveClient {
+	return &BraveClient{
+		config:     cfg,
+		maxResults: maxResults,
+		httpClient: &http.Client{Timeout: timeout},
+		logger:     logger,
+	}
+}
+
+// Name 返回提供方名称
+func (c *BraveClient) Name() string {
+	return config.SearchProviderBrave
+}
+
+// Search 使用默认选项执行搜索
+func (c *BraveClient) Search(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return c.SearchWithOptions(ctx, query, SearchOptions{})
+}
+
+// SearchWithOptions 执行搜索
+// 域名过滤转换为 site: 查询运算符，time_range 映射为 freshness，topic=news 使用新闻搜索接口，
+// include_raw_content 请求额外摘要作为正文。
+func (c *BraveClient) SearchWithOptions(ctx context.Context, query string, opts SearchOptions) (*models.SearchResponse, error) {
+	count := opts.maxResultsOr(c.maxResults)
+	if count <= 0 || count > braveMaxCount {
+		count = braveMaxCount
+	}
+
+	params := url.Values{}
+	params.Set("q", braveQuery(query, opts))
+	params.Set("count", strconv.Itoa(count))
+	if freshness, ok := braveFreshness[opts.TimeRange]; ok {
+		params.Set("freshness", freshness)
+	}
+	if opts.IncludeRawContent {
+		params.Set("extra_snippets", "true")
+	}
+
+	path := "/web/search"
+	if opts.Topic == TopicNews {
+		path = "/news/search"
+	}
+	endpoint := strings.TrimRight(c.config.BaseURL, "/") + path + "?" + params.Encode()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("X-Subscription-Token", c.config.APIKey)
+
+	c.logger.WithField("max_results", count).Debug("Sending Brave search request")
+
+	var braveResp BraveSearchResponse
+	if err := doJSON(c.httpClient, httpReq, "Brave", &braveResp); err != nil {
+		c.logger.WithError(err).Error("Brave search request failed")
+		return nil, err
+	}
+
+	// 网页搜索结果在 web.results 中，新闻搜索结果在顶层 results 中
+	braveResults := braveResp.Web.Results
+	if opts.Topic == TopicNews {
+		braveResults = braveResp.Results
+	}
+
+	results := make([]models.SearchResult, len(braveResults))
+	for i, result := range braveResults {
+		// Brave不返回相关性分数，按排名给出递减的分数
+		results[i] = models.SearchResult{
+			Title:         stripHTML(result.Title),
+			URL:           result.URL,
+			Content:       stripHTML(result.Description),
+			Score:         1 / float64(i+1),
+			PublishedDate: result.PageAge,
+		}
+		if results[i].PublishedDate == "" {
+			results[i].PublishedDate = result.Age
+		}
+		if len(result.ExtraSnippets) > 0 {
+			snippets := make([]string, len(result.ExtraSnippets))
+			for j, snippet := range result.ExtraSnippets {
+				snippets[j] = stripHTML(snippet)
+			}
+			results[i].RawContent = strings.Join(snippets, "\n")
+		}
+	}
+
+	searchResp := &models.SearchResponse{
+		Query:   query,
+		Results: filterDomains(results, opts.IncludeDomains, opts.ExcludeDomains),
+	}
+
+	c.logger.WithField("results_count", len(searchResp.Results)).Debug("Brave search completed")
+	return searchResp, nil
+}
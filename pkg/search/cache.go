@@ -0,0 +1,417 @@
+package search
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/models"
+)
+
+// CacheStatsToolName 返回缓存统计的MCP工具名
+const CacheStatsToolName = "search_cache_stats"
+
+// CacheStats 搜索缓存统计
+type CacheStats struct {
+	Hits      int64   `json:"hits"`      // 命中次数（内存或磁盘）
+	DiskHits  int64   `json:"disk_hits"` // 其中来自磁盘的命中次数
+	Misses    int64   `json:"misses"`    // 未命中并实际调用提供方的次数
+	Coalesced int64   `json:"coalesced"` // 合并到进行中请求的次数
+	Evictions int64   `json:"evictions"` // LRU淘汰次数
+	Entries   int     `json:"entries"`   // 当前内存条目数
+	HitRatio  float64 `json:"hit_ratio"` // (hits+coalesced)/总请求数
+}
+
+// CachedResult 缓存中的一条搜索结果概要
+type CachedResult struct {
+	Key       string    `json:"key"`
+	Query     string    `json:"query"`
+	Results   int       `json:"results"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// cacheEntry 缓存条目
+type cacheEntry struct {
+	key       string
+	response  *models.SearchResponse
+	expiresAt time.Time
+}
+
+// diskEntry 磁盘缓存文件内容
+type diskEntry struct {
+	ExpiresAt time.Time              `json:"expires_at"`
+	Response  *models.SearchResponse `json:"response"`
+}
+
+// flight 进行中的搜索请求
+type flight struct {
+	done     chan struct{}
+	response *models.SearchResponse
+	err      error
+}
+
+// Cache 搜索结果缓存：内存LRU + 可选磁盘存储，并合并并发的相同请求
+// 缓存键由提供方名称、规范化后的查询和搜索选项计算得到，错误结果不会被缓存。
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+	dir        string
+	logger     *logrus.Logger
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	flights map[string]*flight
+
+	hits      int64
+	diskHits  int64
+	misses    int64
+	coalesced int64
+	evictions int64
+
+	onStore  func(key string)                      // 新结果写入缓存后调用，可为空
+	onAccess func(ctx context.Context, key string) // 每次搜索成功返回后调用（包括命中缓存），可为空
+}
+
+// NewCache 创建搜索缓存，dir 为空时只使用内存
+func NewCache(ttl time.Duration, maxEntries int, dir string, logger *logrus.Logger) (*Cache, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create search cache dir: %w", err)
+		}
+	}
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		dir:        dir,
+		logger:     logger,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		flights:    make(map[string]*flight),
+	}, nil
+}
+
+// Wrap 返回带缓存的提供方
+func (c *Cache) Wrap(provider Provider) Provider {
+	return &cachedProvider{provider: provider, cache: c}
+}
+
+// Stats 返回缓存统计
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.lru.Len()
+	c.mu.Unlock()
+
+	stats := CacheStats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		DiskHits:  atomic.LoadInt64(&c.diskHits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Coalesced: atomic.LoadInt64(&c.coalesced),
+		Evictions: atomic.LoadInt64(&c.evictions),
+		Entries:   entries,
+	}
+	if total := stats.Hits + stats.Misses + stats.Coalesced; total > 0 {
+		stats.HitRatio = float64(stats.Hits+stats.Coalesced) / float64(total)
+	}
+	return stats
+}
+
+// OnStore 设置新结果写入缓存后的回调，用于发布缓存变化
+func (c *Cache) OnStore(fn func(key string)) {
+	c.mu.Lock()
+	c.onStore = fn
+	c.mu.Unlock()
+}
+
+// OnAccess 设置每次搜索成功返回后的回调，ctx 为调用方的上下文，用于记录哪些调用方用到了该结果
+func (c *Cache) OnAccess(fn func(ctx context.Context, key string)) {
+	c.mu.Lock()
+	c.onAccess = fn
+	c.mu.Unlock()
+}
+
+// Entries 返回未过期的内存条目概要，按最近使用排序
+func (c *Cache) Entries() []CachedResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]CachedResult, 0, c.lru.Len())
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*cacheEntry)
+		if now.After(entry.expiresAt) {
+			continue
+		}
+		entries = append(entries, CachedResult{
+			Key:       entry.key,
+			Query:     entry.response.Query,
+			Results:   len(entry.response.Results),
+			ExpiresAt: entry.expiresAt,
+		})
+	}
+	return entries
+}
+
+// Lookup 按缓存键读取未过期的结果，不影响命中统计和LRU顺序
+func (c *Cache) Lookup(key string) (*models.SearchResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return cloneResponse(entry.response), true
+}
+
+// search 查询缓存，未命中时调用 fetch，相同键的并发请求只会调用一次 fetch
+func (c *Cache) search(ctx context.Context, key string, fetch func(context.Context) (*models.SearchResponse, error)) (*models.SearchResponse, error) {
+	c.mu.Lock()
+	if response, ok := c.getLocked(key); ok {
+		c.mu.Unlock()
+		atomic.AddInt64(&c.hits, 1)
+		return response, nil
+	}
+	if f, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		atomic.AddInt64(&c.coalesced, 1)
+		return f.wait(ctx)
+	}
+	f := &flight{done: make(chan struct{})}
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	go c.run(ctx, key, f, fetch)
+	return f.wait(ctx)
+}
+
+// run 执行一次实际的搜索并写入缓存
+// 使用与调用方取消解耦的上下文，调用方放弃等待时其他合并的请求仍能拿到结果。
+func (c *Cache) run(ctx context.Context, key string, f *flight, fetch func(context.Context) (*models.SearchResponse, error)) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+		close(f.done)
+	}()
+
+	if response, ok := c.loadDisk(key); ok {
+		atomic.AddInt64(&c.hits, 1)
+		atomic.AddInt64(&c.diskHits, 1)
+		c.mu.Lock()
+		c.putLocked(key, response, time.Now().Add(c.ttl))
+		c.mu.Unlock()
+		f.response = response
+		return
+	}
+
+	atomic.AddInt64(&c.misses, 1)
+	response, err := fetch(context.WithoutCancel(ctx))
+	if err != nil {
+		f.err = err
+		return
+	}
+
+	expiresAt := time.Now().Add(c.ttl)
+	c.mu.Lock()
+	c.putLocked(key, cloneResponse(response), expiresAt)
+	onStore := c.onStore
+	c.mu.Unlock()
+	c.storeDisk(key, response, expiresAt)
+	f.response = response
+
+	if onStore != nil {
+		onStore(key)
+	}
+}
+
+// wait 等待进行中的请求完成，返回结果副本
+func (f *flight) wait(ctx context.Context) (*models.SearchResponse, error) {
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return nil, f.err
+		}
+		return cloneResponse(f.response), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// getLocked 读取未过期的内存条目，调用方需持有锁
+func (c *Cache) getLocked(key string) (*models.SearchResponse, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return cloneResponse(entry.response), true
+}
+
+// putLocked 写入内存条目并按LRU淘汰，调用方需持有锁
+func (c *Cache) putLocked(key string, response *models.SearchResponse, expiresAt time.Time) {
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &cacheEntry{key: key, response: response, expiresAt: expiresAt}
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, response: response, expiresAt: expiresAt})
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		atomic.AddInt64(&c.evictions, 1)
+	}
+}
+
+// diskPath 返回缓存键对应的磁盘文件路径
+func (c *Cache) diskPath(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// loadDisk 从磁盘读取未过期的缓存，过期文件会被删除
+func (c *Cache) loadDisk(key string) (*models.SearchResponse, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.diskPath(key))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			c.logger.WithError(err).Warn("Failed to read search cache file")
+		}
+		return nil, false
+	}
+
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil || time.Now().After(entry.ExpiresAt) {
+		os.Remove(c.diskPath(key))
+		return nil, false
+	}
+	return entry.Response, true
+}
+
+// storeDisk 将结果写入磁盘，先写临时文件再重命名以避免读到不完整的文件
+func (c *Cache) storeDisk(key string, response *models.SearchResponse, expiresAt time.Time) {
+	if c.dir == "" {
+		return
+	}
+	data, err := json.Marshal(diskEntry{ExpiresAt: expiresAt, Response: response})
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to encode search cache entry")
+		return
+	}
+
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to write search cache file")
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		c.logger.WithError(errors.Join(writeErr, closeErr)).Warn("Failed to write search cache file")
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.diskPath(key)); err != nil {
+		os.Remove(tmp.Name())
+		c.logger.WithError(err).Warn("Failed to write search cache file")
+	}
+}
+
+// cachedProvider 带缓存的搜索提供方
+type cachedProvider struct {
+	provider Provider
+	cache    *Cache
+}
+
+// Name 返回被包装的提供方名称
+func (p *cachedProvider) Name() string {
+	return p.provider.Name()
+}
+
+// SearchWithOptions 优先从缓存返回结果
+func (p *cachedProvider) SearchWithOptions(ctx context.Context, query string, opts SearchOptions) (*models.SearchResponse, error) {
+	key := cacheKey(p.provider.Name(), query, opts)
+	response, err := p.cache.search(ctx, key, func(ctx context.Context) (*models.SearchResponse, error) {
+		return p.provider.SearchWithOptions(ctx, query, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 规范化后相同的查询共用缓存，返回时保留调用方的原始查询
+	response.Query = query
+
+	p.cache.mu.Lock()
+	onAccess := p.cache.onAccess
+	p.cache.mu.Unlock()
+	if onAccess != nil {
+		onAccess(ctx, key)
+	}
+	return response, nil
+}
+
+// cacheKey 计算缓存键：提供方 + 规范化查询 + 规范化选项的SHA-256
+func cacheKey(provider, query string, opts SearchOptions) string {
+	include := append([]string(nil), opts.IncludeDomains...)
+	exclude := append([]string(nil), opts.ExcludeDomains...)
+	sort.Strings(include)
+	sort.Strings(exclude)
+
+	raw := fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00%s\x00%s\x00%s\x00%s\x00%t",
+		provider,
+		NormalizeQuery(query),
+		opts.MaxResults,
+		opts.SearchDepth,
+		strings.Join(include, ","),
+		strings.Join(exclude, ","),
+		opts.TimeRange,
+		opts.Topic,
+		opts.IncludeRawContent,
+	)
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeQuery 规范化查询：转为小写、合并空白、去掉首尾标点，使近似相同的查询共用缓存
+func NormalizeQuery(query string) string {
+	fields := strings.FieldsFunc(strings.ToLower(query), unicode.IsSpace)
+	normalized := strings.Join(fields, " ")
+	return strings.TrimFunc(normalized, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+	})
+}
+
+// cloneResponse 深拷贝搜索结果，避免调用方修改缓存中的数据
+func cloneResponse(response *models.SearchResponse) *models.SearchResponse {
+	if response == nil {
+		return nil
+	}
+	clone := *response
+	clone.Results = append([]models.SearchResult(nil), response.Results...)
+	return &clone
+}
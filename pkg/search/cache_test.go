@@ -0,0 +1,138 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/models"
+)
+
+// countingProvider 记录调用次数的提供方
+type countingProvider struct {
+	calls   int64
+	delay   time.Duration
+	release chan struct{}
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) SearchWithOptions(ctx context.Context, query string, opts SearchOptions) (*models.SearchResponse, error) {
+	atomic.AddInt64(&p.calls, 1)
+	if p.release != nil {
+		<-p.release
+	}
+	time.Sleep(p.delay)
+	return &models.SearchResponse{
+		Query:   query,
+		Results: []models.SearchResult{{Title: "result", URL: "https://example.com", Content: query}},
+	}, nil
+}
+
+func TestCache_HitAndNormalization(t *testing.T) {
+	cache, err := NewCache(time.Minute, 10, "", newTestLogger())
+	require.NoError(t, err)
+	provider := &countingProvider{}
+	cached := cache.Wrap(provider)
+
+	resp, err := cached.SearchWithOptions(context.Background(), "Go  语言？", SearchOptions{})
+	require.NoError(t, err)
+	// 修改返回值不影响缓存
+	resp.Results[0].Title = "modified"
+
+	resp, err = cached.SearchWithOptions(context.Background(), " go 语言", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, " go 语言", resp.Query)
+	assert.Equal(t, "result", resp.Results[0].Title)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&provider.calls))
+
+	// 选项不同使用不同的缓存键
+	_, err = cached.SearchWithOptions(context.Background(), "go 语言", SearchOptions{Topic: TopicNews})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&provider.calls))
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.Equal(t, 2, stats.Entries)
+}
+
+func TestCache_TTLAndEviction(t *testing.T) {
+	cache, err := NewCache(50*time.Millisecond, 2, "", newTestLogger())
+	require.NoError(t, err)
+	provider := &countingProvider{}
+	cached := cache.Wrap(provider)
+	ctx := context.Background()
+
+	for _, query := range []string{"a", "b", "c"} {
+		_, err := cached.SearchWithOptions(ctx, query, SearchOptions{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), cache.Stats().Evictions)
+	assert.Equal(t, 2, cache.Stats().Entries)
+
+	// "a" 已被淘汰
+	_, err = cached.SearchWithOptions(ctx, "a", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), atomic.LoadInt64(&provider.calls))
+
+	// 过期后重新请求
+	time.Sleep(60 * time.Millisecond)
+	_, err = cached.SearchWithOptions(ctx, "a", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), atomic.LoadInt64(&provider.calls))
+}
+
+func TestCache_Coalescing(t *testing.T) {
+	cache, err := NewCache(time.Minute, 10, "", newTestLogger())
+	require.NoError(t, err)
+	provider := &countingProvider{release: make(chan struct{})}
+	cached := cache.Wrap(provider)
+
+	const concurrency = 10
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := cached.SearchWithOptions(context.Background(), "same query", SearchOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, resp.Results, 1)
+		}()
+	}
+
+	// 等待所有请求进入等待状态后再放行
+	require.Eventually(t, func() bool {
+		stats := cache.Stats()
+		return stats.Misses+stats.Coalesced == concurrency
+	}, time.Second, 5*time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&provider.calls))
+	assert.Equal(t, int64(concurrency-1), cache.Stats().Coalesced)
+}
+
+func TestCache_DiskStore(t *testing.T) {
+	dir := t.TempDir()
+	provider := &countingProvider{}
+
+	cache, err := NewCache(time.Minute, 10, dir, newTestLogger())
+	require.NoError(t, err)
+	_, err = cache.Wrap(provider).SearchWithOptions(context.Background(), "persisted", SearchOptions{})
+	require.NoError(t, err)
+
+	// 新的缓存实例（模拟重启）从磁盘读取
+	restarted, err := NewCache(time.Minute, 10, dir, newTestLogger())
+	require.NoError(t, err)
+	resp, err := restarted.Wrap(provider).SearchWithOptions(context.Background(), "persisted", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "persisted", resp.Results[0].Content)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&provider.calls))
+	assert.Equal(t, int64(1), restarted.Stats().DiskHits)
+}
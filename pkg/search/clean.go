@@ -0,0 +1,290 @@
+package search
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// rerankWeight 重排时相关性分值所占的权重，其余权重保留给提供方原始排名
+const rerankWeight = 0.5
+
+// shingleSize 近似重复检测使用的字符片段长度
+const shingleSize = 3
+
+// trackingParams 规范化URL时去掉的跟踪参数
+var trackingParams = map[string]bool{
+	"gclid": true, "fbclid": true, "msclkid": true, "yclid": true,
+	"spm": true, "from": true, "ref": true, "ref_src": true, "share_token": true,
+}
+
+// contentSentenceEnds 截断摘要时优先使用的句子结束标点
+var contentSentenceEnds = []string{"。", "！", "？", "；", ". ", "! ", "? ", "\n"}
+
+// Cleaner 搜索结果后处理管线：
+// 过滤空结果和低分结果 → 按规范化URL去重 → 按摘要相似度去除近似重复 → 按查询相关性重排 → 限制单个域名的结果数 → 截断过长的摘要。
+type Cleaner struct {
+	cfg    config.SearchCleanConfig
+	scorer Scorer // 为空表示不重排
+	logger *logrus.Logger
+}
+
+// NewCleaner 根据配置创建结果后处理管线
+func NewCleaner(cfg *config.SearchCleanConfig, logger *logrus.Logger) *Cleaner {
+	c := &Cleaner{cfg: *cfg, logger: logger}
+	if cfg.Rerank == config.SearchRerankBM25 {
+		c.scorer = NewBM25Scorer()
+	}
+	return c
+}
+
+// WithScorer 返回使用指定打分器的副本，scorer 为空时不重排
+func (c *Cleaner) WithScorer(scorer Scorer) *Cleaner {
+	clone := *c
+	clone.scorer = scorer
+	return &clone
+}
+
+// Clean 对搜索结果执行后处理，返回新的结果，不修改传入的响应
+func (c *Cleaner) Clean(query string, response *models.SearchResponse) *models.SearchResponse {
+	if response == nil {
+		return nil
+	}
+
+	originalCount := len(response.Results)
+	results := make([]models.SearchResult, 0, originalCount)
+	for _, result := range response.Results {
+		if strings.TrimSpace(result.Content) == "" || result.Score < c.cfg.MinScore {
+			continue
+		}
+		results = append(results, result)
+	}
+
+	results = dedupeByURL(results)
+	results = dedupeByContent(results, c.cfg.DuplicateThreshold)
+	if c.scorer != nil && query != "" {
+		results = rerank(c.scorer, query, results)
+	}
+	results = limitPerDomain(results, c.cfg.MaxPerDomain)
+	for i := range results {
+		results[i].Content = TruncateContent(results[i].Content, c.cfg.MaxContentLength)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"original_count": originalCount,
+		"cleaned_count":  len(results),
+		"reranked":       c.scorer != nil,
+	}).Debug("Search results cleaned")
+
+	cleaned := *response
+	cleaned.Results = results
+	return &cleaned
+}
+
+// CanonicalURL 规范化URL用于去重：忽略协议、www前缀、片段、跟踪参数、参数顺序和末尾斜杠
+func CanonicalURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSpace(rawURL))
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+
+	canonical := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if encoded := query.Encode(); encoded != "" {
+		canonical += "?" + encoded
+	}
+	return canonical
+}
+
+// dedupeByURL 按规范化URL去重，保留排名靠前的结果
+func dedupeByURL(results []models.SearchResult) []models.SearchResult {
+	seen := make(map[string]bool, len(results))
+	unique := results[:0]
+	for _, result := range results {
+		key := CanonicalURL(result.URL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, result)
+	}
+	return unique
+}
+
+// dedupeByContent 去除摘要近似重复（字符片段 Jaccard 相似度达到阈值）的结果，保留排名靠前的结果
+func dedupeByContent(results []models.SearchResult, threshold float64) []models.SearchResult {
+	if threshold <= 0 {
+		return results
+	}
+	kept := results[:0]
+	var keptShingles []map[string]bool
+	for _, result := range results {
+		shingles := contentShingles(result.Content)
+		duplicate := false
+		for _, other := range keptShingles {
+			if jaccard(shingles, other) >= threshold {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		kept = append(kept, result)
+		keptShingles = append(keptShingles, shingles)
+	}
+	return kept
+}
+
+// contentShingles 将文本规范化（转小写、去掉空白和标点）后切分为定长字符片段
+func contentShingles(text string) map[string]bool {
+	runes := make([]rune, 0, len(text))
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, unicode.ToLower(r))
+		}
+	}
+	shingles := make(map[string]bool)
+	if len(runes) < shingleSize {
+		if len(runes) > 0 {
+			shingles[string(runes)] = true
+		}
+		return shingles
+	}
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		shingles[string(runes[i:i+shingleSize])] = true
+	}
+	return shingles
+}
+
+// jaccard 计算两个集合的 Jaccard 相似度
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	intersection := 0
+	for key := range a {
+		if b[key] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// rerank 结合提供方原始排名和打分器分值重新排序
+// 不同提供方的评分范围不一致，因此原始排名按位置折算为 [0,1]，相关性分值按最高分归一化。
+func rerank(scorer Scorer, query string, results []models.SearchResult) []models.SearchResult {
+	if len(results) < 2 {
+		return results
+	}
+
+	docs := make([]string, len(results))
+	for i, result := range results {
+		docs[i] = result.Title + " " + result.Content
+	}
+	scores := scorer.Score(query, docs)
+
+	maxScore := 0.0
+	for _, score := range scores {
+		if score > maxScore {
+			maxScore = score
+		}
+	}
+	if maxScore <= 0 {
+		return results
+	}
+
+	n := float64(len(results))
+	combined := make([]float64, len(results))
+	for i := range results {
+		prior := 1 - float64(i)/n
+		combined[i] = (1-rerankWeight)*prior + rerankWeight*scores[i]/maxScore
+	}
+
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return combined[order[a]] > combined[order[b]]
+	})
+
+	reranked := make([]models.SearchResult, len(results))
+	for i, idx := range order {
+		reranked[i] = results[idx]
+	}
+	return reranked
+}
+
+// limitPerDomain 限制每个域名保留的结果数，max <= 0 表示不限制
+func limitPerDomain(results []models.SearchResult, max int) []models.SearchResult {
+	if max <= 0 {
+		return results
+	}
+	counts := make(map[string]int)
+	limited := results[:0]
+	for _, result := range results {
+		domain := resultDomain(result.URL)
+		if counts[domain] >= max {
+			continue
+		}
+		counts[domain]++
+		limited = append(limited, result)
+	}
+	return limited
+}
+
+// resultDomain 返回结果URL的主机名（去掉 www 前缀）
+func resultDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// TruncateContent 将文本截断到 maxBytes 字节以内并追加 "..."
+// 截断点不会落在多字节字符中间，并优先选择后半段中的句子结束位置。
+func TruncateContent(text string, maxBytes int) string {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return text
+	}
+
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	truncated := text[:cut]
+
+	end := -1
+	for _, mark := range contentSentenceEnds {
+		if i := strings.LastIndex(truncated, mark); i >= 0 && i+len(mark) > end {
+			end = i + len(mark)
+		}
+	}
+	if end >= cut/2 {
+		truncated = truncated[:end]
+	}
+	return strings.TrimRight(truncated, " \n") + "..."
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+func newTestCleaner() *Cleaner {
+	return NewCleaner(&config.SearchCleanConfig{
+		Rerank:             config.SearchRerankBM25,
+		MaxPerDomain:       2,
+		MaxContentLength:   1000,
+		DuplicateThreshold: 0.85,
+	}, newTestLogger())
+}
+
+func resultURLs(results []models.SearchResult) []string {
+	urls := make([]string, len(results))
+	for i, result := range results {
+		urls[i] = result.URL
+	}
+	return urls
+}
+
+func TestCanonicalURL(t *testing.T) {
+	assert.Equal(t, "example.com/a?id=1", CanonicalURL("https://www.example.com/a/?utm_source=x&id=1#top"))
+	assert.Equal(t, CanonicalURL("http://Example.com/a?b=2&a=1"), CanonicalURL("https://example.com/a?a=1&b=2"))
+	assert.NotEqual(t, CanonicalURL("https://example.com/a?id=1"), CanonicalURL("https://example.com/a?id=2"))
+}
+
+func TestCleaner_Dedupe(t *testing.T) {
+	response := &models.SearchResponse{
+		Query:  "go",
+		Answer: "answer",
+		Results: []models.SearchResult{
+			{Title: "A", URL: "https://example.com/post", Content: "Go 1.23 adds range-over-func iterators to the language."},
+			{Title: "A copy", URL: "http://www.example.com/post/?utm_campaign=feed", Content: "different text"},
+			{Title: "Mirror", URL: "https://mirror.org/post", Content: "Go 1.23 adds range-over-func iterators to the language!"},
+			{Title: "Other", URL: "https://other.org/post", Content: "An unrelated article about Rust."},
+			{Title: "Empty", URL: "https://empty.org", Content: "  "},
+		},
+	}
+
+	cleaned := newTestCleaner().WithScorer(nil).Clean("go", response)
+	assert.Equal(t, []string{"https://example.com/post", "https://other.org/post"}, resultURLs(cleaned.Results))
+	assert.Equal(t, "answer", cleaned.Answer)
+	// 不修改传入的响应
+	assert.Len(t, response.Results, 5)
+}
+
+func TestCleaner_RerankAndDomainLimit(t *testing.T) {
+	response := &models.SearchResponse{Results: []models.SearchResult{
+		{Title: "天气预报", URL: "https://weather.com/a", Content: "明天多云，气温适中。", Score: 0.9},
+		{Title: "天气新闻", URL: "https://weather.com/b", Content: "全国天气概况。", Score: 0.8},
+		{Title: "天气图表", URL: "https://weather.com/c", Content: "天气雷达图。", Score: 0.7},
+		{Title: "Go 语言迭代器", URL: "https://go.dev/blog/range-functions", Content: "Go 语言迭代器 range over func 的设计与用法。", Score: 0.1},
+	}}
+
+	cleaned := newTestCleaner().Clean("Go 语言迭代器", response)
+	require.Len(t, cleaned.Results, 3)
+	// 与查询最相关的结果被提前，同一域名最多保留两条
+	assert.Equal(t, "https://go.dev/blog/range-functions", cleaned.Results[0].URL)
+	assert.Equal(t, []string{"https://weather.com/a", "https://weather.com/b"}, resultURLs(cleaned.Results[1:]))
+}
+
+func TestTruncateContent(t *testing.T) {
+	short := "短文本"
+	assert.Equal(t, short, TruncateContent(short, 1000))
+
+	// 按字节截断时不能切断多字节字符
+	text := strings.Repeat("人工智能", 300)
+	truncated := TruncateContent(text, 1000)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.LessOrEqual(t, len(truncated), 1003)
+	assert.True(t, strings.HasSuffix(truncated, "..."))
+
+	// 优先在句子结束处截断
+	text = strings.Repeat("这是一个完整的句子。", 40)
+	truncated = TruncateContent(text, 1000)
+	assert.True(t, strings.HasSuffix(truncated, "。..."), truncated)
+	assert.LessOrEqual(t, len(truncated), 1003)
+}
+
+func TestTavilyClient_CleanResults(t *testing.T) {
+	client := NewTavilyClient(&config.TavilyConfig{APIKey: "key"}, newTestLogger())
+	cleaned := client.CleanResults(&models.SearchResponse{Query: "q", Results: []models.SearchResult{
+		{URL: "https://a.com", Content: strings.Repeat("中文内容", 200), Score: 0.5},
+		{URL: "https://b.com", Content: "low score", Score: 0.05},
+	}})
+
+	require.Len(t, cleaned.Results, 1)
+	assert.Equal(t, "q", cleaned.Query)
+	assert.True(t, utf8.ValidString(cleaned.Results[0].Content))
+	assert.LessOrEqual(t, len(cleaned.Results[0].Content), 1003)
+}
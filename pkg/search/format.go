@@ -0,0 +1,26 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"deer-flow-go/pkg/models"
+)
+
+// FormatResults 将搜索结果格式化为工具返回文本
+func FormatResults(query string, resp *models.SearchResponse) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔍 搜索结果 \"%s\":\n\n", query))
+	for i, result := range resp.Results {
+		sb.WriteString(fmt.Sprintf("%d. **%s**\n", i+1, result.Title))
+		sb.WriteString(fmt.Sprintf("   📄 %s\n", result.Content))
+		sb.WriteString(fmt.Sprintf("   🔗 %s\n", result.URL))
+		if result.RawContent != "" {
+			sb.WriteString(fmt.Sprintf("   📝 正文:\n%s\n", result.RawContent))
+		}
+		if i < len(resp.Results)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,313 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// localSnippetRunes 本地索引结果摘要的字符数
+const localSnippetRunes = 300
+
+// LocalDocument 本地索引中的文档，.jsonl/.json 文件中的每条记录使用该结构
+type LocalDocument struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Content string `json:"content"`
+
+	terms map[string]int
+}
+
+// LocalIndex 基于本地文档的搜索索引，启动时一次性加载到内存
+// 支持的文件：.md/.markdown/.txt（标题取第一个 "# " 标题或文件名），.jsonl 和 .json（LocalDocument 列表）。
+type LocalIndex struct {
+	docs       []*LocalDocument
+	docFreq    map[string]int
+	maxResults int
+	logger     *logrus.Logger
+}
+
+// NewLocalIndex 从目录或单个文件加载本地索引
+func NewLocalIndex(path string, maxResults int, logger *logrus.Logger) (*LocalIndex, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &LocalIndex{
+		docFreq:    make(map[string]int),
+		maxResults: maxResults,
+		logger:     logger,
+	}
+
+	if info.IsDir() {
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return idx.loadFile(p)
+		})
+	} else {
+		err = idx.loadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range idx.docs {
+		doc.terms = make(map[string]int)
+		for _, term := range tokenize(doc.Title + "\n" + doc.Content) {
+			doc.terms[term]++
+		}
+		for term := range doc.terms {
+			idx.docFreq[term]++
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"path":      path,
+		"documents": len(idx.docs),
+	}).Info("Local search index loaded")
+
+	return idx, nil
+}
+
+// loadFile 按扩展名加载单个文件，不支持的文件类型会被跳过
+func (idx *LocalIndex) loadFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".txt":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		content := string(data)
+		title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		for _, line := range strings.Split(content, "\n") {
+			if strings.HasPrefix(line, "# ") {
+				title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+				break
+			}
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		idx.docs = append(idx.docs, &LocalDocument{Title: title, URL: "file://" + filepath.ToSlash(abs), Content: content})
+	case ".jsonl":
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var doc LocalDocument
+			if err := json.Unmarshal([]byte(text), &doc); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			idx.docs = append(idx.docs, &doc)
+		}
+		return scanner.Err()
+	case ".json":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var docs []*LocalDocument
+		if err := json.Unmarshal(data, &docs); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		idx.docs = append(idx.docs, docs...)
+	}
+	return nil
+}
+
+// Name 返回提供方名称
+func (idx *LocalIndex) Name() string {
+	return config.SearchProviderLocal
+}
+
+// Search 使用默认选项检索
+func (idx *LocalIndex) Search(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return idx.SearchWithOptions(ctx, query, SearchOptions{})
+}
+
+// SearchWithOptions 在本地文档中检索，按TF-IDF打分并归一化到0-1
+// 支持结果数、域名过滤和完整正文，时间范围和类别对本地文档没有意义，会被忽略。
+func (idx *LocalIndex) SearchWithOptions(ctx context.Context, query string, opts SearchOptions) (*models.SearchResponse, error) {
+	queryTerms := uniqueTerms(tokenize(query))
+	if len(queryTerms) == 0 {
+		return &models.SearchResponse{Query: query, Results: []models.SearchResult{}}, nil
+	}
+
+	type scored struct {
+		doc   *LocalDocument
+		score float64
+	}
+	var matches []scored
+	total := float64(len(idx.docs))
+	for _, doc := range idx.docs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !docMatchesDomains(doc, opts) {
+			continue
+		}
+		score := 0.0
+		for _, term := range queryTerms {
+			tf := float64(doc.terms[term])
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log(1 + total/float64(idx.docFreq[term]))
+			score += tf / (tf + 1.2) * idf
+		}
+		if score > 0 {
+			matches = append(matches, scored{doc: doc, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if maxResults := opts.maxResultsOr(idx.maxResults); maxResults > 0 && len(matches) > maxResults {
+		matches = matches[:maxResults]
+	}
+
+	searchResp := &models.SearchResponse{
+		Query:   query,
+		Results: make([]models.SearchResult, len(matches)),
+	}
+	for i, match := range matches {
+		searchResp.Results[i] = models.SearchResult{
+			Title:   match.doc.Title,
+			URL:     match.doc.URL,
+			Content: snippet(match.doc.Content, queryTerms, localSnippetRunes),
+			Score:   match.score / matches[0].score,
+		}
+		if opts.IncludeRawContent {
+			searchResp.Results[i].RawContent = match.doc.Content
+		}
+	}
+
+	idx.logger.WithField("results_count", len(searchResp.Results)).Debug("Local index search completed")
+	return searchResp, nil
+}
+
+// docMatchesDomains 判断文档URL是否满足域名过滤条件
+func docMatchesDomains(doc *LocalDocument, opts SearchOptions) bool {
+	if len(opts.IncludeDomains) == 0 && len(opts.ExcludeDomains) == 0 {
+		return true
+	}
+	return len(filterDomains([]models.SearchResult{{URL: doc.URL}}, opts.IncludeDomains, opts.ExcludeDomains)) == 1
+}
+
+// tokenize 将文本切分为检索词：英文和数字按单词切分并转为小写，中日韩文字按相邻两字切分
+func tokenize(text string) []string {
+	var (
+		terms []string
+		word  []rune
+		cjk   []rune
+	)
+	flushWord := func() {
+		if len(word) > 0 {
+			terms = append(terms, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			terms = append(terms, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			terms = append(terms, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return terms
+}
+
+// isCJK 判断是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// uniqueTerms 去除重复的检索词
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	unique := terms[:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// snippet 截取首个命中检索词附近的文本作为摘要
+func snippet(content string, terms []string, size int) string {
+	runes := []rune(strings.Join(strings.Fields(content), " "))
+	if len(runes) <= size {
+		return string(runes)
+	}
+
+	lower := strings.ToLower(string(runes))
+	start := 0
+	for _, term := range terms {
+		if pos := strings.Index(lower, term); pos >= 0 {
+			start = len([]rune(lower[:pos])) - size/4
+			break
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start+size > len(runes) {
+		start = len(runes) - size
+	}
+
+	text := string(runes[start : start+size])
+	if start > 0 {
+		text = "..." + text
+	}
+	if start+size < len(runes) {
+		text += "..."
+	}
+	return text
+}
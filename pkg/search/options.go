@@ -0,0 +1,181 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"deer-flow-go/pkg/models"
+)
+
+// 搜索选项取值
+const (
+	DepthBasic    = "basic"
+	DepthAdvanced = "advanced"
+
+	TopicGeneral = "general"
+	TopicNews    = "news"
+)
+
+// TimeRanges 支持的时间范围
+var TimeRanges = []string{"day", "week", "month", "year"}
+
+// maxSearchResults 单次搜索允许的最大结果数
+const maxSearchResults = 20
+
+// SearchOptions 单次搜索的选项，零值表示使用提供方的默认配置
+// 提供方不支持的选项会尽量在本地处理（如按域名过滤结果），无法处理时忽略。
+type SearchOptions struct {
+	MaxResults        int      `json:"max_results,omitempty"`
+	SearchDepth       string   `json:"search_depth,omitempty"`    // basic | advanced
+	IncludeDomains    []string `json:"include_domains,omitempty"` // 只返回这些域名（含子域名）的结果
+	ExcludeDomains    []string `json:"exclude_domains,omitempty"` // 排除这些域名（含子域名）的结果
+	TimeRange         string   `json:"time_range,omitempty"`      // day | week | month | year
+	Topic             string   `json:"topic,omitempty"`           // general | news
+	IncludeRawContent bool     `json:"include_raw_content,omitempty"`
+}
+
+// Validate 校验选项取值
+func (o SearchOptions) Validate() error {
+	if o.MaxResults < 0 || o.MaxResults > maxSearchResults {
+		return fmt.Errorf("max_results must be between 1 and %d, got %d", maxSearchResults, o.MaxResults)
+	}
+	if o.SearchDepth != "" && o.SearchDepth != DepthBasic && o.SearchDepth != DepthAdvanced {
+		return fmt.Errorf("search_depth must be %q or %q, got %q", DepthBasic, DepthAdvanced, o.SearchDepth)
+	}
+	if o.Topic != "" && o.Topic != TopicGeneral && o.Topic != TopicNews {
+		return fmt.Errorf("topic must be %q or %q, got %q", TopicGeneral, TopicNews, o.Topic)
+	}
+	if o.TimeRange != "" && !containsString(TimeRanges, o.TimeRange) {
+		return fmt.Errorf("time_range must be one of %v, got %q", TimeRanges, o.TimeRange)
+	}
+	return nil
+}
+
+// maxResultsOr 返回选项中的结果数，未设置时使用默认值
+func (o SearchOptions) maxResultsOr(defaultValue int) int {
+	if o.MaxResults > 0 {
+		return o.MaxResults
+	}
+	return defaultValue
+}
+
+// SearchToolOptions 返回 search 工具的参数定义
+func SearchToolOptions(providers *Registry) []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithDescription("搜索互联网信息，返回相关的搜索结果"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("搜索查询关键词"),
+		),
+		mcp.WithNumber("max_results",
+			mcp.Description(fmt.Sprintf("最大返回结果数量（1-%d），默认为5", maxSearchResults)),
+		),
+		mcp.WithString("search_depth",
+			mcp.Description("搜索深度，advanced 结果更全面但更慢"),
+			mcp.Enum(DepthBasic, DepthAdvanced),
+		),
+		mcp.WithArray("include_domains",
+			mcp.Description("只返回这些域名的结果，例如 [\"github.com\"]"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("exclude_domains",
+			mcp.Description("排除这些域名的结果"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("time_range",
+			mcp.Description("只返回指定时间范围内发布的结果"),
+			mcp.Enum(TimeRanges...),
+		),
+		mcp.WithString("topic",
+			mcp.Description("搜索类别，查询新闻时使用 news"),
+			mcp.Enum(TopicGeneral, TopicNews),
+		),
+		mcp.WithBoolean("include_raw_content",
+			mcp.Description("是否返回网页的完整正文（如果提供方支持）"),
+		),
+		mcp.WithString("provider",
+			mcp.Description(fmt.Sprintf("搜索服务提供方，默认为%s", providers.Default())),
+			mcp.Enum(providers.Names()...),
+		),
+	}
+}
+
+// ParseSearchOptions 从 search 工具调用参数中解析搜索选项
+func ParseSearchOptions(request mcp.CallToolRequest) (SearchOptions, error) {
+	opts := SearchOptions{
+		MaxResults:        request.GetInt("max_results", 0),
+		SearchDepth:       request.GetString("search_depth", ""),
+		IncludeDomains:    normalizeDomains(request.GetStringSlice("include_domains", nil)),
+		ExcludeDomains:    normalizeDomains(request.GetStringSlice("exclude_domains", nil)),
+		TimeRange:         request.GetString("time_range", ""),
+		Topic:             request.GetString("topic", ""),
+		IncludeRawContent: request.GetBool("include_raw_content", false),
+	}
+	return opts, opts.Validate()
+}
+
+// normalizeDomains 规范化域名列表，允许传入完整URL
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if strings.Contains(domain, "://") {
+			if u, err := url.Parse(domain); err == nil {
+				domain = u.Hostname()
+			}
+		}
+		domain = strings.TrimPrefix(strings.TrimSuffix(domain, "/"), "www.")
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
+
+// filterDomains 按域名过滤结果，用于不支持域名参数的提供方
+func filterDomains(results []models.SearchResult, include, exclude []string) []models.SearchResult {
+	if len(include) == 0 && len(exclude) == 0 {
+		return results
+	}
+	filtered := results[:0]
+	for _, result := range results {
+		host := ""
+		if u, err := url.Parse(result.URL); err == nil {
+			host = strings.ToLower(u.Hostname())
+		}
+		if len(include) > 0 && !matchesAnyDomain(host, include) {
+			continue
+		}
+		if matchesAnyDomain(host, exclude) {
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+// matchesAnyDomain 判断主机名是否属于任一域名（含子域名）
+func matchesAnyDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
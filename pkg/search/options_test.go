@@ -0,0 +1,128 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+)
+
+func TestParseSearchOptions(t *testing.T) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{
+		"query":               "golang",
+		"max_results":         float64(8),
+		"search_depth":        "basic",
+		"include_domains":     []any{"https://www.GitHub.com/", "go.dev"},
+		"time_range":          "week",
+		"topic":               "news",
+		"include_raw_content": true,
+	}
+
+	opts, err := ParseSearchOptions(request)
+	require.NoError(t, err)
+	assert.Equal(t, 8, opts.MaxResults)
+	assert.Equal(t, "basic", opts.SearchDepth)
+	assert.Equal(t, []string{"github.com", "go.dev"}, opts.IncludeDomains)
+	assert.Equal(t, "week", opts.TimeRange)
+	assert.Equal(t, "news", opts.Topic)
+	assert.True(t, opts.IncludeRawContent)
+
+	request.Params.Arguments = map[string]any{"query": "golang", "time_range": "decade"}
+	_, err = ParseSearchOptions(request)
+	assert.Error(t, err)
+
+	request.Params.Arguments = map[string]any{"query": "golang", "max_results": float64(100)}
+	_, err = ParseSearchOptions(request)
+	assert.Error(t, err)
+}
+
+func TestTavilyClient_SearchWithOptions(t *testing.T) {
+	var got TavilySearchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Write([]byte(`{"results": []}`))
+	}))
+	defer server.Close()
+
+	client := NewTavilyClient(&config.TavilyConfig{APIKey: "key", BaseURL: server.URL, MaxResults: 5, SearchDepth: "advanced"}, newTestLogger())
+
+	_, err := client.Search(context.Background(), "golang")
+	require.NoError(t, err)
+	assert.Equal(t, 5, got.MaxResults)
+	assert.Equal(t, "advanced", got.SearchDepth)
+
+	_, err = client.SearchWithOptions(context.Background(), "golang", SearchOptions{
+		MaxResults:        3,
+		SearchDepth:       "basic",
+		IncludeDomains:    []string{"go.dev"},
+		ExcludeDomains:    []string{"example.com"},
+		TimeRange:         "month",
+		Topic:             "news",
+		IncludeRawContent: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.MaxResults)
+	assert.Equal(t, "basic", got.SearchDepth)
+	assert.Equal(t, []string{"go.dev"}, got.IncludeDomains)
+	assert.Equal(t, []string{"example.com"}, got.ExcludeDomains)
+	assert.Equal(t, "month", got.TimeRange)
+	assert.Equal(t, "news", got.Topic)
+	assert.True(t, got.IncludeRawContent)
+}
+
+func TestSearXNGClient_SearchWithOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "news", r.URL.Query().Get("categories"))
+		assert.Equal(t, "day", r.URL.Query().Get("time_range"))
+		w.Write([]byte(`{"results": [
+			{"title": "A", "url": "https://blog.go.dev/a", "content": "a"},
+			{"title": "B", "url": "https://example.com/b", "content": "b"},
+			{"title": "C", "url": "https://go.dev/c", "content": "c"}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewSearXNGClient(&config.SearXNGConfig{BaseURL: server.URL}, 5, 0, newTestLogger())
+	resp, err := client.SearchWithOptions(context.Background(), "golang", SearchOptions{
+		Topic:          "news",
+		TimeRange:      "day",
+		IncludeDomains: []string{"go.dev"},
+		MaxResults:     1,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "https://blog.go.dev/a", resp.Results[0].URL)
+}
+
+func TestBraveClient_SearchWithOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/news/search", r.URL.Path)
+		assert.Equal(t, "golang (site:go.dev) -site:example.com", r.URL.Query().Get("q"))
+		assert.Equal(t, "pw", r.URL.Query().Get("freshness"))
+		assert.Equal(t, "true", r.URL.Query().Get("extra_snippets"))
+		w.Write([]byte(`{"results": [
+			{"title": "Go 1.23", "url": "https://go.dev/blog", "description": "release", "extra_snippets": ["one", "<b>two</b>"]}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewBraveClient(&config.BraveConfig{APIKey: "key", BaseURL: server.URL}, 5, 0, newTestLogger())
+	resp, err := client.SearchWithOptions(context.Background(), "golang", SearchOptions{
+		Topic:             "news",
+		TimeRange:         "week",
+		IncludeDomains:    []string{"go.dev"},
+		ExcludeDomains:    []string{"example.com"},
+		IncludeRawContent: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "one\ntwo", resp.Results[0].RawContent)
+}
@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// Provider 搜索服务提供方
+type Provider interface {
+	// Name 返回提供方名称，与配置中的 search.provider 对应
+	Name() string
+	// SearchWithOptions 按选项执行搜索并返回统一格式的结果
+	SearchWithOptions(ctx context.Context, query string, opts SearchOptions) (*models.SearchResponse, error)
+}
+
+// Registry 已配置的搜索提供方集合
+type Registry struct {
+	providers   map[string]Provider
+	defaultName string
+	cache       *Cache // 为空表示未启用缓存
+	cleaner     *Cleaner
+}
+
+// NewRegistry 根据配置创建所有可用的搜索提供方
+// 只有配置完整（密钥、地址或索引路径已设置）的提供方才会注册，默认提供方必须可用。
+func NewRegistry(cfg *config.Config, logger *logrus.Logger) (*Registry, error) {
+	timeout := time.Duration(cfg.Search.Timeout) * time.Second
+	r := &Registry{
+		providers:   make(map[string]Provider),
+		defaultName: cfg.Search.Provider,
+		cleaner:     NewCleaner(&cfg.Search.Clean, logger),
+	}
+
+	if cfg.Search.Cache.Enabled {
+		cache, err := NewCache(time.Duration(cfg.Search.Cache.TTL)*time.Second, cfg.Search.Cache.MaxEntries, cfg.Search.Cache.Dir, logger)
+		if err != nil {
+			return nil, err
+		}
+		r.cache = cache
+	}
+
+	if cfg.Tavily.APIKey != "" {
+		tavily := NewTavilyClient(&cfg.Tavily, logger)
+		tavily.httpClient.Timeout = timeout
+		r.Register(tavily)
+	}
+	if cfg.Search.SearXNG.BaseURL != "" {
+		r.Register(NewSearXNGClient(&cfg.Search.SearXNG, cfg.Search.MaxResults, timeout, logger))
+	}
+	if cfg.Search.Brave.APIKey != "" {
+		r.Register(NewBraveClient(&cfg.Search.Brave, cfg.Search.MaxResults, timeout, logger))
+	}
+	if cfg.Search.Local.Path != "" {
+		local, err := NewLocalIndex(cfg.Search.Local.Path, cfg.Search.MaxResults, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load local search index: %w", err)
+		}
+		r.Register(local)
+	}
+
+	if _, ok := r.providers[r.defaultName]; !ok {
+		return nil, fmt.Errorf("default search provider %q is not configured", r.defaultName)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"providers": r.Names(),
+		"default":   r.defaultName,
+		"cache":     r.cache != nil,
+	}).Info("Search providers initialized")
+
+	return r, nil
+}
+
+// Cleaner 返回搜索结果后处理管线
+func (r *Registry) Cleaner() *Cleaner {
+	return r.cleaner
+}
+
+// Register 注册搜索提供方，同名提供方会被替换；启用缓存时自动包装缓存层
+func (r *Registry) Register(provider Provider) {
+	if r.cache != nil {
+		provider = r.cache.Wrap(provider)
+	}
+	r.providers[provider.Name()] = provider
+}
+
+// CacheStats 返回搜索缓存统计，未启用缓存时第二个返回值为 false
+func (r *Registry) CacheStats() (CacheStats, bool) {
+	if r.cache == nil {
+		return CacheStats{}, false
+	}
+	return r.cache.Stats(), true
+}
+
+// Cache 返回搜索缓存，未启用缓存时返回 nil
+func (r *Registry) Cache() *Cache {
+	return r.cache
+}
+
+// Get 按名称获取提供方，名称为空时返回默认提供方
+func (r *Registry) Get(name string) (Provider, error) {
+	if name == "" {
+		name = r.defaultName
+	}
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("search provider %q is not available, configured providers: %v", name, r.Names())
+	}
+	return provider, nil
+}
+
+// Default 返回默认提供方名称
+func (r *Registry) Default() string {
+	return r.defaultName
+}
+
+// Names 返回已注册的提供方名称（按字母排序）
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
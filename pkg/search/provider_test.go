@@ -0,0 +1,160 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return logger
+}
+
+func TestTavilyClient_BaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		var req TavilySearchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "tavily-key", req.APIKey)
+		assert.Equal(t, "golang", req.Query)
+
+		json.NewEncoder(w).Encode(TavilySearchResponse{
+			Answer:  "Go is a language",
+			Results: []TavilyResult{{Title: "Go", URL: "https://go.dev", Content: "The Go language", Score: 0.9}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewTavilyClient(&config.TavilyConfig{APIKey: "tavily-key", BaseURL: server.URL, MaxResults: 5}, newTestLogger())
+	resp, err := client.Search(context.Background(), "golang")
+	require.NoError(t, err)
+	assert.Equal(t, "Go is a language", resp.Answer)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "https://go.dev", resp.Results[0].URL)
+}
+
+func TestTavilyClient_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewTavilyClient(&config.TavilyConfig{APIKey: "bad", BaseURL: server.URL}, newTestLogger())
+	_, err := client.Search(context.Background(), "golang")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401")
+}
+
+func TestSearXNGClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+		assert.Equal(t, "google,bing", r.URL.Query().Get("engines"))
+		w.Write([]byte(`{
+			"query": "golang",
+			"answers": [{"answer": "Go"}],
+			"results": [
+				{"title": "A", "url": "https://a.example", "content": "a", "score": 2.5},
+				{"title": "B", "url": "https://b.example", "content": "b", "score": 1.0},
+				{"title": "C", "url": "https://c.example", "content": "c", "score": 0.5}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewSearXNGClient(&config.SearXNGConfig{BaseURL: server.URL, Engines: []string{"google", "bing"}}, 2, 0, newTestLogger())
+	resp, err := client.Search(context.Background(), "golang")
+	require.NoError(t, err)
+	assert.Equal(t, "Go", resp.Answer)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "A", resp.Results[0].Title)
+}
+
+func TestBraveClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/web/search", r.URL.Path)
+		assert.Equal(t, "brave-key", r.Header.Get("X-Subscription-Token"))
+		assert.Equal(t, "3", r.URL.Query().Get("count"))
+		w.Write([]byte(`{"web": {"results": [
+			{"title": "Go &amp; friends", "url": "https://go.dev", "description": "The <strong>Go</strong> language"},
+			{"title": "Tour", "url": "https://go.dev/tour", "description": "A tour of Go"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	client := NewBraveClient(&config.BraveConfig{APIKey: "brave-key", BaseURL: server.URL}, 3, 0, newTestLogger())
+	resp, err := client.Search(context.Background(), "golang")
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "Go & friends", resp.Results[0].Title)
+	assert.Equal(t, "The Go language", resp.Results[0].Content)
+	assert.Greater(t, resp.Results[0].Score, resp.Results[1].Score)
+}
+
+func TestLocalIndex(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.md"), []byte("# Go语言\n\nGo语言是一门并发友好的编程语言，goroutine 非常轻量。"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rust.txt"), []byte("Rust 是一门注重内存安全的系统编程语言。"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docs.jsonl"), []byte(
+		`{"title": "Kubernetes", "url": "https://k8s.io", "content": "Kubernetes orchestrates containers"}`+"\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ignored.bin"), []byte{0, 1, 2}, 0o600))
+
+	idx, err := NewLocalIndex(dir, 5, newTestLogger())
+	require.NoError(t, err)
+
+	resp, err := idx.Search(context.Background(), "goroutine 并发")
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Results)
+	assert.Equal(t, "Go语言", resp.Results[0].Title)
+	assert.Equal(t, 1.0, resp.Results[0].Score)
+
+	resp, err = idx.Search(context.Background(), "kubernetes containers")
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "https://k8s.io", resp.Results[0].URL)
+}
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o600))
+
+	cfg := &config.Config{
+		Tavily: config.TavilyConfig{APIKey: "tavily-key"},
+		Search: config.SearchConfig{
+			Provider:   config.SearchProviderLocal,
+			MaxResults: 5,
+			Timeout:    10,
+			Local:      config.LocalIndexConfig{Path: dir},
+		},
+	}
+	registry, err := NewRegistry(cfg, newTestLogger())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"local", "tavily"}, registry.Names())
+
+	provider, err := registry.Get("")
+	require.NoError(t, err)
+	assert.Equal(t, "local", provider.Name())
+
+	provider, err = registry.Get("tavily")
+	require.NoError(t, err)
+	assert.Equal(t, "tavily", provider.Name())
+
+	_, err = registry.Get("brave")
+	assert.Error(t, err)
+
+	cfg.Search.Provider = config.SearchProviderSearXNG
+	_, err = NewRegistry(cfg, newTestLogger())
+	assert.Error(t, err)
+}
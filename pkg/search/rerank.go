@@ -0,0 +1,73 @@
+package search
+
+import (
+	"math"
+)
+
+// Scorer 相关性打分器，用于按查询对搜索结果重排
+// 返回值与 docs 一一对应，分值越高越相关，不同打分器的分值范围可以不同。
+type Scorer interface {
+	Score(query string, docs []string) []float64
+}
+
+// BM25Scorer 以当前结果集为语料计算 BM25 分值
+// 分词方式与本地索引一致：英文按单词，中日韩文字按相邻两字。
+type BM25Scorer struct {
+	K1 float64 // 词频饱和参数
+	B  float64 // 文档长度归一化参数
+}
+
+// NewBM25Scorer 创建使用常用参数（k1=1.2, b=0.75）的 BM25 打分器
+func NewBM25Scorer() *BM25Scorer {
+	return &BM25Scorer{K1: 1.2, B: 0.75}
+}
+
+// Score 计算每个文档对查询的 BM25 分值
+func (s *BM25Scorer) Score(query string, docs []string) []float64 {
+	scores := make([]float64, len(docs))
+	queryTerms := uniqueTerms(tokenize(query))
+	if len(queryTerms) == 0 || len(docs) == 0 {
+		return scores
+	}
+
+	termFreqs := make([]map[string]int, len(docs))
+	docLens := make([]int, len(docs))
+	docFreq := make(map[string]int)
+	totalLen := 0
+	for i, doc := range docs {
+		terms := tokenize(doc)
+		freqs := make(map[string]int, len(terms))
+		for _, term := range terms {
+			freqs[term]++
+		}
+		for term := range freqs {
+			docFreq[term]++
+		}
+		termFreqs[i] = freqs
+		docLens[i] = len(terms)
+		totalLen += len(terms)
+	}
+	avgLen := float64(totalLen) / float64(len(docs))
+	if avgLen == 0 {
+		return scores
+	}
+
+	n := float64(len(docs))
+	for _, term := range queryTerms {
+		df := float64(docFreq[term])
+		if df == 0 {
+			continue
+		}
+		// 使用恒为正的 IDF，避免在小语料中所有文档都包含的词产生负分
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for i, freqs := range termFreqs {
+			tf := float64(freqs[term])
+			if tf == 0 {
+				continue
+			}
+			norm := s.K1 * (1 - s.B + s.B*float64(docLens[i])/avgLen)
+			scores[i] += idf * tf * (s.K1 + 1) / (tf + norm)
+		}
+	}
+	return scores
+}
@@ -0,0 +1,140 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// SearXNGClient 自建 SearXNG 实例的搜索客户端
+// 实例需要在 settings.yml 的 search.formats 中开启 json。
+type SearXNGClient struct {
+	config     *config.SearXNGConfig
+	maxResults int
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+// SearXNGResponse SearXNG JSON API响应结构
+type SearXNGResponse struct {
+	Query   string            `json:"query"`
+	Results []SearXNGResult   `json:"results"`
+	Answers []json.RawMessage `json:"answers"` // 不同版本为字符串或 {"answer": "..."} 对象
+}
+
+// SearXNGResult SearXNG搜索结果
+type SearXNGResult struct {
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	Content       string  `json:"content"`
+	Engine        string  `json:"engine"`
+	Score         float64 `json:"score"`
+	PublishedDate string  `json:"publishedDate"`
+}
+
+// NewSearXNGClient 创建SearXNG客户端
+func NewSearXNGClient(cfg *config.SearXNGConfig, maxResults int, timeout time.Duration, logger *logrus.Logger) *SearXNGClient {
+	return &SearXNGClient{
+		config:     cfg,
+		maxResults: maxResults,
+		httpClient: &http.Client{Timeout: timeout},
+		logger:     logger,
+	}
+}
+
+// Name 返回提供方名称
+func (c *SearXNGClient) Name() string {
+	return config.SearchProviderSearXNG
+}
+
+// Search 使用默认选项执行搜索
+func (c *SearXNGClient) Search(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return c.SearchWithOptions(ctx, query, SearchOptions{})
+}
+
+// SearchWithOptions 执行搜索
+// topic 映射为 categories，time_range 直接透传；SearXNG 不支持域名参数，改为在本地过滤结果。
+func (c *SearXNGClient) SearchWithOptions(ctx context.Context, query string, opts SearchOptions) (*models.SearchResponse, error) {
+	maxResults := opts.maxResultsOr(c.maxResults)
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	if opts.Topic != "" {
+		params.Set("categories", opts.Topic)
+	}
+	if opts.TimeRange != "" {
+		params.Set("time_range", opts.TimeRange)
+	}
+	if c.config.Language != "" {
+		params.Set("language", c.config.Language)
+	}
+	if len(c.config.Engines) > 0 {
+		params.Set("engines", strings.Join(c.config.Engines, ","))
+	}
+
+	endpoint := strings.TrimRight(c.config.BaseURL, "/") + "/search?" + params.Encode()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+
+	c.logger.WithField("max_results", maxResults).Debug("Sending SearXNG search request")
+
+	var searxResp SearXNGResponse
+	if err := doJSON(c.httpClient, httpReq, "SearXNG", &searxResp); err != nil {
+		c.logger.WithError(err).Error("SearXNG search request failed")
+		return nil, err
+	}
+
+	results := make([]models.SearchResult, len(searxResp.Results))
+	for i, result := range searxResp.Results {
+		results[i] = models.SearchResult{
+			Title:         result.Title,
+			URL:           result.URL,
+			Content:       result.Content,
+			Score:         result.Score,
+			PublishedDate: result.PublishedDate,
+		}
+	}
+	results = filterDomains(results, opts.IncludeDomains, opts.ExcludeDomains)
+	if maxResults > 0 && len(results) > maxResults {
+		results = results[:maxResults]
+	}
+
+	searchResp := &models.SearchResponse{
+		Query:   query,
+		Answer:  firstSearXNGAnswer(searxResp.Answers),
+		Results: results,
+	}
+
+	c.logger.WithField("results_count", len(searchResp.Results)).Debug("SearXNG search completed")
+	return searchResp, nil
+}
+
+// firstSearXNGAnswer 提取第一个直接答案
+func firstSearXNGAnswer(answers []json.RawMessage) string {
+	for _, raw := range answers {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil && text != "" {
+			return text
+		}
+		var obj struct {
+			Answer string `json:"answer"`
+		}
+		if err := json.Unmarshal(raw, &obj); err == nil && obj.Answer != "" {
+			return obj.Answer
+		}
+	}
+	return ""
+}
@@ -5,8 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -15,6 +15,9 @@ import (
 	"deer-flow-go/pkg/models"
 )
 
+// defaultTavilyBaseURL Tavily官方API地址
+const defaultTavilyBaseURL = "https://api.tavily.com"
+
 // TavilyClient Tavily搜索客户端
 type TavilyClient struct {
 	config     *config.TavilyConfig
@@ -33,6 +36,8 @@ type TavilySearchRequest struct {
 	MaxResults        int      `json:"max_results,omitempty"`
 	IncludeDomains    []string `json:"include_domains,omitempty"`
 	ExcludeDomains    []string `json:"exclude_domains,omitempty"`
+	Topic             string   `json:"topic,omitempty"`
+	TimeRange         string   `json:"time_range,omitempty"`
 }
 
 // TavilySearchResponse Tavily API响应结构
@@ -72,17 +77,45 @@ func NewTavilyClient(cfg *config.TavilyConfig, logger *logrus.Logger) *TavilyCli
 	}
 }
 
-// Search 执行搜索
+// Name 返回提供方名称
+func (c *TavilyClient) Name() string {
+	return config.SearchProviderTavily
+}
+
+// endpoint 返回搜索接口地址，未配置 base_url 时使用官方地址
+func (c *TavilyClient) endpoint() string {
+	baseURL := c.config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultTavilyBaseURL
+	}
+	return strings.TrimRight(baseURL, "/") + "/search"
+}
+
+// Search 使用配置中的默认选项执行搜索
 func (c *TavilyClient) Search(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return c.SearchWithOptions(ctx, query, SearchOptions{})
+}
+
+// SearchWithOptions 执行搜索，未设置的选项使用配置中的默认值
+func (c *TavilyClient) SearchWithOptions(ctx context.Context, query string, opts SearchOptions) (*models.SearchResponse, error) {
+	searchDepth := opts.SearchDepth
+	if searchDepth == "" {
+		searchDepth = c.config.SearchDepth
+	}
+
 	// 构建请求
 	req := TavilySearchRequest{
 		APIKey:            c.config.APIKey,
 		Query:             query,
-		SearchDepth:       c.config.SearchDepth,
+		SearchDepth:       searchDepth,
 		IncludeAnswer:     true,
 		IncludeImages:     false,
-		IncludeRawContent: false,
-		MaxResults:        c.config.MaxResults,
+		IncludeRawContent: opts.IncludeRawContent,
+		MaxResults:        opts.maxResultsOr(c.config.MaxResults),
+		IncludeDomains:    opts.IncludeDomains,
+		ExcludeDomains:    opts.ExcludeDomains,
+		Topic:             opts.Topic,
+		TimeRange:         opts.TimeRange,
 	}
 
 	// 序列化请求
@@ -92,43 +125,25 @@ func (c *TavilyClient) Search(ctx context.Context, query string) (*models.Search
 	}
 
 	c.logger.WithFields(logrus.Fields{
-		"query":        query,
-		"search_depth": c.config.SearchDepth,
-		"max_results":  c.config.MaxResults,
+		"query_length": len(query),
+		"search_depth": req.SearchDepth,
+		"max_results":  req.MaxResults,
+		"topic":        req.Topic,
+		"time_range":   req.TimeRange,
 	}).Debug("Sending Tavily search request")
 	// 创建HTTP请求
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.tavily.com/search", bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.endpoint(), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	// 发送请求
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		c.logger.WithFields(logrus.Fields{
-			"status_code": resp.StatusCode,
-			"response":    string(respBody),
-		}).Error("Tavily API returned error")
-		return nil, fmt.Errorf("Tavily API error: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
-	// 解析响应
+	// 发送请求并解析响应
 	var tavilyResp TavilySearchResponse
-	if err := json.Unmarshal(respBody, &tavilyResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := doJSON(c.httpClient, httpReq, "Tavily", &tavilyResp); err != nil {
+		c.logger.WithError(err).Error("Tavily search request failed")
+		return nil, err
 	}
 
 	c.logger.WithFields(logrus.Fields{
@@ -146,10 +161,12 @@ func (c *TavilyClient) Search(ctx context.Context, query string) (*models.Search
 
 	for i, result := range tavilyResp.Results {
 		searchResp.Results[i] = models.SearchResult{
-			Title:   result.Title,
-			URL:     result.URL,
-			Content: result.Content,
-			Score:   result.Score,
+			Title:         result.Title,
+			URL:           result.URL,
+			Content:       result.Content,
+			Score:         result.Score,
+			RawContent:    result.RawContent,
+			PublishedDate: result.PublishedDate,
 		}
 	}
 
@@ -157,32 +174,16 @@ func (c *TavilyClient) Search(ctx context.Context, query string) (*models.Search
 }
 
 // CleanResults 清理和优化搜索结果
+// 丢弃空摘要和评分低于0.1的结果，去除重复结果，并将摘要按字符边界截断到1000字节以内。
 func (c *TavilyClient) CleanResults(results *models.SearchResponse) *models.SearchResponse {
+	cleaner := NewCleaner(&config.SearchCleanConfig{
+		Rerank:             config.SearchRerankNone,
+		MaxContentLength:   1000,
+		MinScore:           0.1,
+		DuplicateThreshold: 0.85,
+	}, c.logger)
 	if results == nil {
 		return nil
 	}
-
-	// 过滤和清理结果
-	cleanedResults := make([]models.SearchResult, 0, len(results.Results))
-	for _, result := range results.Results {
-		// 跳过空内容或评分过低的结果
-		if result.Content == "" || result.Score < 0.1 {
-			continue
-		}
-
-		// 限制内容长度
-		if len(result.Content) > 1000 {
-			result.Content = result.Content[:1000] + "..."
-		}
-
-		cleanedResults = append(cleanedResults, result)
-	}
-
-	results.Results = cleanedResults
-	c.logger.WithFields(logrus.Fields{
-		"original_count": len(results.Results),
-		"cleaned_count":  len(cleanedResults),
-	}).Debug("Search results cleaned")
-
-	return results
+	return cleaner.Clean(results.Query, results)
 }
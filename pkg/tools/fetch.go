@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/fetch"
+)
+
+// FetchTools 返回网页抓取工具
+func FetchTools(fetcher *fetch.Fetcher, logger *logrus.Logger) []Tool {
+	return []Tool{{
+		Definition: mcp.NewTool(fetch.ToolName,
+			mcp.WithDescription("下载网页并提取标题和正文（markdown），用于阅读搜索结果引用的完整文章"),
+			mcp.WithString("url",
+				mcp.Required(),
+				mcp.Description("要读取的网页地址，仅支持 http/https"),
+			),
+			mcp.WithNumber("max_tokens",
+				mcp.Description(fmt.Sprintf("正文的token预算，超出部分按段落截断，默认且最大为%d", fetcher.DefaultMaxTokens())),
+			),
+		),
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return handleFetchURL(ctx, request, fetcher, logger)
+		},
+	}}
+}
+
+// handleFetchURL 处理网页抓取请求
+func handleFetchURL(ctx context.Context, request mcp.CallToolRequest, fetcher *fetch.Fetcher, logger *logrus.Logger) (*mcp.CallToolResult, error) {
+	logger.WithFields(logrus.Fields{
+		"tool": fetch.ToolName,
+	}).Debug("Processing fetch_url request")
+
+	// 解析请求参数
+	rawURL, err := request.RequireString("url")
+	if err != nil {
+		logger.WithError(err).Error("Failed to parse url parameter")
+		return mcp.NewToolResultError(fmt.Sprintf("参数解析失败: %v", err)), nil
+	}
+	if rawURL == "" {
+		return mcp.NewToolResultError("网页地址不能为空"), nil
+	}
+
+	// token预算只能调小，不能超过配置的上限
+	maxTokens := request.GetInt("max_tokens", 0)
+	if maxTokens <= 0 || maxTokens > fetcher.DefaultMaxTokens() {
+		maxTokens = fetcher.DefaultMaxTokens()
+	}
+
+	// 抓取网页
+	page, err := fetcher.Fetch(ctx, rawURL, maxTokens)
+	if err != nil {
+		logger.WithError(err).WithField("url", rawURL).Warn("Failed to fetch URL")
+		switch {
+		case errors.Is(err, fetch.ErrRobotsDisallowed):
+			return mcp.NewToolResultError("该网站的 robots.txt 禁止抓取此页面"), nil
+		case errors.Is(err, fetch.ErrUnsupportedContentType):
+			return mcp.NewToolResultError(fmt.Sprintf("不支持的内容类型，只能读取HTML或纯文本页面: %v", err)), nil
+		case errors.Is(err, fetch.ErrPrivateAddress):
+			return mcp.NewToolResultError("不允许访问内网或本机地址"), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("读取网页失败: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(fetch.FormatPage(page)), nil
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"deer-flow-go/pkg/models"
+	"deer-flow-go/pkg/prompts"
+)
+
+// ErrPromptNotFound 获取了未注册的提示词
+var ErrPromptNotFound = errors.New("prompt not found")
+
+// Prompt 一个MCP提示词：名称、参数定义和渲染函数
+type Prompt struct {
+	Definition mcp.Prompt
+	Handler    server.PromptHandlerFunc
+}
+
+// TemplatePrompts 将提示词模板转换为MCP提示词，渲染结果作为一条用户消息返回
+func TemplatePrompts(templates []models.PromptTemplate) []Prompt {
+	result := make([]Prompt, 0, len(templates))
+	for _, tmpl := range templates {
+		opts := []mcp.PromptOption{mcp.WithPromptDescription(tmpl.Description)}
+		for _, arg := range tmpl.Arguments {
+			argOpts := []mcp.ArgumentOption{mcp.ArgumentDescription(arg.Description)}
+			if arg.Required {
+				argOpts = append(argOpts, mcp.RequiredArgument())
+			}
+			opts = append(opts, mcp.WithArgument(arg.Name, argOpts...))
+		}
+
+		tmpl := tmpl
+		result = append(result, Prompt{
+			Definition: mcp.NewPrompt(tmpl.Name, opts...),
+			Handler: func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+				text, err := prompts.Render(tmpl, request.Params.Arguments)
+				if err != nil {
+					return nil, err
+				}
+				return mcp.NewGetPromptResult(tmpl.Description, []mcp.PromptMessage{
+					mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+				}), nil
+			},
+		})
+	}
+	return result
+}
+
+// RegisterPrompts 注册提示词，同名提示词会被替换
+func (r *Registry) RegisterPrompts(prompts ...Prompt) {
+	for _, prompt := range prompts {
+		name := prompt.Definition.Name
+		if _, exists := r.prompts[name]; !exists {
+			r.promptOrder = append(r.promptOrder, name)
+		}
+		r.prompts[name] = prompt
+	}
+}
+
+// Prompts 返回已注册提示词的定义（按注册顺序）
+func (r *Registry) Prompts() []mcp.Prompt {
+	defs := make([]mcp.Prompt, 0, len(r.promptOrder))
+	for _, name := range r.promptOrder {
+		defs = append(defs, r.prompts[name].Definition)
+	}
+	return defs
+}
+
+// GetPrompt 在进程内渲染提示词，参数与MCP prompts/get 的 arguments 相同
+func (r *Registry) GetPrompt(ctx context.Context, name string, args map[string]string) (*mcp.GetPromptResult, error) {
+	prompt, ok := r.prompts[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	return prompt.Handler(ctx, request)
+}
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/models"
+)
+
+func TestRegistry_Prompts(t *testing.T) {
+	registry := NewRegistry(newTestLogger())
+	registry.RegisterPrompts(TemplatePrompts([]models.PromptTemplate{{
+		Name:        "weather_briefing",
+		Description: "天气简报",
+		Template:    "请为{{.city}}生成天气简报",
+		Arguments:   []models.PromptArgument{{Name: "city", Description: "城市", Required: true}},
+	}})...)
+
+	result, err := registry.GetPrompt(context.Background(), "weather_briefing", map[string]string{"city": "北京"})
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, mcp.RoleUser, result.Messages[0].Role)
+	assert.Equal(t, "请为北京生成天气简报", result.Messages[0].Content.(mcp.TextContent).Text)
+
+	_, err = registry.GetPrompt(context.Background(), "missing", nil)
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+
+	// 通过MCP协议获取
+	mcpClient, err := client.NewInProcessClient(registry.NewMCPServer("test-server", "1.0.0").MCPServer)
+	require.NoError(t, err)
+	defer mcpClient.Close()
+	ctx := context.Background()
+	require.NoError(t, mcpClient.Start(ctx))
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	_, err = mcpClient.Initialize(ctx, initRequest)
+	require.NoError(t, err)
+
+	list, err := mcpClient.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Prompts, 1)
+	assert.Equal(t, "weather_briefing", list.Prompts[0].Name)
+	require.Len(t, list.Prompts[0].Arguments, 1)
+	assert.True(t, list.Prompts[0].Arguments[0].Required)
+
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = "weather_briefing"
+	_, err = mcpClient.GetPrompt(ctx, request)
+	assert.ErrorContains(t, err, `"city" is required`)
+}
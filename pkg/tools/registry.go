@@ -0,0 +1,181 @@
+// Package tools 统一的MCP工具注册表
+// 每个工具只声明一次参数定义和处理函数，注册表既可以作为MCP服务器通过 stdio/HTTP 提供，
+// 也可以在进程内直接调用。注册表同时管理MCP资源（天气快照、缓存的搜索结果、研究报告）和提示词。
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/fetch"
+	"deer-flow-go/pkg/prompts"
+	"deer-flow-go/pkg/search"
+	"deer-flow-go/pkg/weather"
+)
+
+// ErrToolNotFound 调用了未注册的工具
+var ErrToolNotFound = errors.New("tool not found")
+
+// internalTools 供管理接口调用的内部工具，不出现在LLM的工具列表和路由输出中
+var internalTools = map[string]bool{
+	search.CacheStatsToolName: true,
+	ReportToolName:            true,
+}
+
+// IsInternal 判断工具是否为内部工具，内部工具只能由API直接调用，不交给LLM选择
+func IsInternal(name string) bool {
+	return internalTools[name]
+}
+
+// Tool 一个MCP工具：参数定义和处理函数
+type Tool struct {
+	Definition mcp.Tool
+	Handler    server.ToolHandlerFunc
+}
+
+// Registry 工具注册表，按注册顺序保存工具、资源来源和提示词
+type Registry struct {
+	tools       map[string]Tool
+	order       []string
+	sources     []ResourceSource
+	prompts     map[string]Prompt
+	promptOrder []string
+	logger      *logrus.Logger
+
+	serversMu sync.Mutex
+	servers   []*Server // 由 NewMCPServer 创建的服务器，用于推送资源通知
+}
+
+// NewRegistry 创建空的工具注册表
+func NewRegistry(logger *logrus.Logger) *Registry {
+	return &Registry{
+		tools:   make(map[string]Tool),
+		prompts: make(map[string]Prompt),
+		logger:  logger,
+	}
+}
+
+// NewRegistryFromConfig 根据配置创建天气、搜索和网页抓取服务，注册全部工具，并加载提示词包
+func NewRegistryFromConfig(cfg *config.Config, logger *logrus.Logger) (*Registry, error) {
+	searchProviders, err := search.NewRegistry(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize search providers: %w", err)
+	}
+	weatherService, err := weather.NewService(&cfg.Weather, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize weather providers: %w", err)
+	}
+	fetcher := fetch.NewFetcher(&cfg.Fetch, logger)
+
+	r := NewRegistry(logger)
+	weatherResources := NewWeatherResources(weatherService, r, logger)
+	r.Register(WeatherTools(weatherService, weatherResources, logger)...)
+	r.Register(SearchTools(searchProviders, logger)...)
+	r.Register(FetchTools(fetcher, logger)...)
+
+	reports := NewReportResources(r, logger)
+	r.Register(ReportTools(reports, logger)...)
+
+	r.RegisterResources(weatherResources, reports)
+	if cache := searchProviders.Cache(); cache != nil {
+		r.RegisterResources(NewSearchResources(cache, r, logger))
+	}
+
+	if cfg.MCP.PromptsDir != "" {
+		templates, err := prompts.LoadDir(cfg.MCP.PromptsDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt packs: %w", err)
+		}
+		r.RegisterPrompts(TemplatePrompts(templates)...)
+	}
+	return r, nil
+}
+
+// Register 注册工具，同名工具会被替换
+func (r *Registry) Register(tools ...Tool) {
+	for _, tool := range tools {
+		name := tool.Definition.Name
+		if _, exists := r.tools[name]; !exists {
+			r.order = append(r.order, name)
+		}
+		r.tools[name] = tool
+	}
+}
+
+// Get 按名称获取工具
+func (r *Registry) Get(name string) (Tool, bool) {
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Names 返回已注册的工具名称（按注册顺序）
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.order...)
+}
+
+// Tools 返回已注册工具的参数定义（按注册顺序）
+func (r *Registry) Tools() []mcp.Tool {
+	defs := make([]mcp.Tool, 0, len(r.order))
+	for _, name := range r.order {
+		defs = append(defs, r.tools[name].Definition)
+	}
+	return defs
+}
+
+// Call 在进程内调用工具，参数与MCP tools/call 的 arguments 相同
+func (r *Registry) Call(ctx context.Context, name string, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	tool, ok := r.tools[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrToolNotFound, name)
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	return tool.Handler(ctx, request)
+}
+
+// NewMCPServer 创建提供全部已注册工具、资源和提示词的MCP服务器
+// 可通过 Server.ServeStdio 启动，也可将内嵌的 MCPServer 交给 HTTP 传输（HTTP 传输不支持资源订阅通知）。
+func (r *Registry) NewMCPServer(name, version string) *Server {
+	mcpServer := server.NewMCPServer(name, version,
+		server.WithResourceCapabilities(true, true),
+		server.WithPromptCapabilities(false),
+	)
+	serverTools := make([]server.ServerTool, 0, len(r.order))
+	for _, toolName := range r.order {
+		tool := r.tools[toolName]
+		serverTools = append(serverTools, server.ServerTool{Tool: tool.Definition, Handler: tool.Handler})
+	}
+	mcpServer.AddTools(serverTools...)
+
+	serverPrompts := make([]server.ServerPrompt, 0, len(r.promptOrder))
+	for _, promptName := range r.promptOrder {
+		prompt := r.prompts[promptName]
+		serverPrompts = append(serverPrompts, server.ServerPrompt{Prompt: prompt.Definition, Handler: prompt.Handler})
+	}
+	mcpServer.AddPrompts(serverPrompts...)
+
+	r.logger.WithFields(logrus.Fields{
+		"server":  name,
+		"tools":   r.order,
+		"prompts": r.promptOrder,
+	}).Debug("MCP server created from tool registry")
+
+	s := &Server{
+		MCPServer:     mcpServer,
+		registry:      r,
+		subscriptions: make(map[string]bool),
+	}
+	r.serversMu.Lock()
+	r.servers = append(r.servers, s)
+	r.serversMu.Unlock()
+	return s
+}
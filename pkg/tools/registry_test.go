@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/search"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return logger
+}
+
+// newTestRegistry 创建包含本地索引搜索工具和一个回显工具的注册表
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.md"), []byte("# Go语言\n\nGo语言是一门并发友好的编程语言，goroutine 非常轻量。"), 0o600))
+
+	providers, err := search.NewRegistry(&config.Config{
+		Search: config.SearchConfig{
+			Provider:   config.SearchProviderLocal,
+			MaxResults: 5,
+			Timeout:    10,
+			Local:      config.LocalIndexConfig{Path: dir},
+		},
+	}, newTestLogger())
+	require.NoError(t, err)
+
+	registry := NewRegistry(newTestLogger())
+	registry.Register(SearchTools(providers, newTestLogger())...)
+	registry.Register(Tool{
+		Definition: mcp.NewTool("echo", mcp.WithString("text", mcp.Required())),
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(request.GetString("text", "")), nil
+		},
+	})
+	return registry
+}
+
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	require.NotEmpty(t, result.Content)
+	text, ok := mcp.AsTextContent(result.Content[0])
+	require.True(t, ok)
+	return text.Text
+}
+
+func TestRegistry_Call(t *testing.T) {
+	registry := newTestRegistry(t)
+	assert.Equal(t, []string{"search", "echo"}, registry.Names())
+
+	result, err := registry.Call(context.Background(), "search", map[string]interface{}{"query": "goroutine"})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Contains(t, resultText(t, result), "🔍 搜索结果 \"goroutine\"")
+	assert.Contains(t, resultText(t, result), "1. **Go语言**")
+
+	// 参数错误以工具错误结果返回
+	result, err = registry.Call(context.Background(), "search", map[string]interface{}{"query": ""})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	_, err = registry.Call(context.Background(), "missing", nil)
+	assert.ErrorIs(t, err, ErrToolNotFound)
+
+	// 同名工具替换原有定义，保持注册顺序
+	registry.Register(Tool{
+		Definition: mcp.NewTool("search"),
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("replaced"), nil
+		},
+	})
+	assert.Equal(t, []string{"search", "echo"}, registry.Names())
+	result, err = registry.Call(context.Background(), "search", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "replaced", resultText(t, result))
+}
+
+func TestRegistry_NewMCPServer(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	mcpClient, err := client.NewInProcessClient(registry.NewMCPServer("test-server", "1.0.0").MCPServer)
+	require.NoError(t, err)
+	defer mcpClient.Close()
+
+	ctx := context.Background()
+	require.NoError(t, mcpClient.Start(ctx))
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	_, err = mcpClient.Initialize(ctx, initRequest)
+	require.NoError(t, err)
+
+	tools, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+	}
+	assert.ElementsMatch(t, []string{"search", "echo"}, names)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "echo"
+	request.Params.Arguments = map[string]interface{}{"text": "hello"}
+	result, err := mcpClient.CallTool(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resultText(t, result))
+}
+
+func TestIsInternal(t *testing.T) {
+	assert.True(t, IsInternal(search.CacheStatsToolName))
+	assert.True(t, IsInternal(ReportToolName))
+	assert.False(t, IsInternal("search"))
+	assert.False(t, IsInternal("get_weather"))
+}
@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+)
+
+// ReportToolName 保存研究报告的内部工具，工作流整理好搜索结果后调用
+const ReportToolName = "save_report"
+
+// reportResourcePrefix 研究报告资源的URI前缀
+const reportResourcePrefix = "report://recent/"
+
+// reportMaxEntries 最多保留的研究报告数，超出时丢弃最早的报告
+const reportMaxEntries = 100
+
+// report 一份研究报告
+type report struct {
+	id        string
+	owner     string
+	query     string
+	text      string
+	createdAt time.Time
+}
+
+// ReportResources 最近的研究报告资源来源
+// 工作流根据搜索结果生成的回复通过 save_report 保存，发布为 report://recent/{id}。
+// 报告只对生成它的所有者可见（见 WithOwner）；调用方上下文中没有所有者时不保存。
+type ReportResources struct {
+	notifier Notifier
+	logger   *logrus.Logger
+
+	mu      sync.Mutex
+	reports []report // 按生成时间排列，最早的在前
+}
+
+// NewReportResources 创建研究报告资源来源，notifier 可为空
+func NewReportResources(notifier Notifier, logger *logrus.Logger) *ReportResources {
+	return &ReportResources{notifier: notifier, logger: logger}
+}
+
+// ReportResourceURI 返回研究报告资源的URI
+func ReportResourceURI(id string) string {
+	return reportResourcePrefix + id
+}
+
+// ReportTools 返回保存研究报告的内部工具
+func ReportTools(reports *ReportResources, logger *logrus.Logger) []Tool {
+	return []Tool{{
+		Definition: mcp.NewTool(ReportToolName,
+			mcp.WithDescription("保存一份研究报告，发布为只对调用方可见的 report:// 资源"),
+			mcp.WithString("query", mcp.Required(), mcp.Description("报告对应的问题")),
+			mcp.WithString("report", mcp.Required(), mcp.Description("报告内容")),
+		),
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			query, err := request.RequireString("query")
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("参数解析失败: %v", err)), nil
+			}
+			text, err := request.RequireString("report")
+			if err != nil || text == "" {
+				return mcp.NewToolResultError("报告内容不能为空"), nil
+			}
+			uri, err := reports.Save(ctx, query, text)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			logger.WithField("uri", uri).Debug("Research report saved")
+			return mcp.NewToolResultText(uri), nil
+		},
+	}}
+}
+
+// Save 保存调用方的研究报告并返回资源URI
+func (r *ReportResources) Save(ctx context.Context, query, text string) (string, error) {
+	owner, ok := OwnerFromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("caller is unknown, report not saved")
+	}
+	id, err := newReportID()
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	if len(r.reports) >= reportMaxEntries {
+		r.reports = append(r.reports[:0], r.reports[len(r.reports)-reportMaxEntries+1:]...)
+	}
+	r.reports = append(r.reports, report{
+		id:        id,
+		owner:     owner,
+		query:     query,
+		text:      text,
+		createdAt: time.Now(),
+	})
+	r.mu.Unlock()
+
+	if r.notifier != nil {
+		r.notifier.ResourceListChanged()
+	}
+	return ReportResourceURI(id), nil
+}
+
+// newReportID 生成随机的报告ID，URI无法被其他调用方猜测
+func newReportID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate report id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Resources 返回调用方的研究报告，最新的在前
+func (r *ReportResources) Resources(ctx context.Context) []mcp.Resource {
+	owner, ok := OwnerFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	resources := make([]mcp.Resource, 0)
+	for i := len(r.reports) - 1; i >= 0; i-- {
+		rep := r.reports[i]
+		if rep.owner != owner {
+			continue
+		}
+		resources = append(resources, mcp.NewResource(ReportResourceURI(rep.id), "研究报告: "+rep.query,
+			mcp.WithResourceDescription("生成于 "+rep.createdAt.Format(time.RFC3339)),
+			mcp.WithMIMEType("text/markdown"),
+		))
+	}
+	return resources
+}
+
+// Templates 报告ID随机生成，不提供URI模板
+func (r *ReportResources) Templates() []mcp.ResourceTemplate {
+	return nil
+}
+
+// Read 读取调用方的研究报告，其他所有者的报告视为不存在
+func (r *ReportResources) Read(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	id, ok := strings.CutPrefix(uri, reportResourcePrefix)
+	if !ok {
+		return nil, ErrResourceNotFound
+	}
+	owner, known := OwnerFromContext(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rep := range r.reports {
+		if rep.id == id && known && rep.owner == owner {
+			return []mcp.ResourceContents{mcp.TextResourceContents{
+				URI:      uri,
+				MIMEType: "text/markdown",
+				Text:     fmt.Sprintf("# %s\n\n%s", rep.query, rep.text),
+			}}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: report %s", ErrResourceNotFound, id)
+}
@@ -0,0 +1,262 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 资源订阅方法，mcp-go 服务器没有实现，由 Server 处理
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+)
+
+// maxResourceSubscriptions 每个服务器接受的资源订阅上限，超出后拒绝新的订阅
+const maxResourceSubscriptions = 1024
+
+// ErrResourceNotFound 资源不存在或URI不属于任何资源来源
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ownerKey 上下文中资源所有者的键
+type ownerKey struct{}
+
+// WithOwner 返回附加了资源所有者的上下文
+// 搜索结果、研究报告等包含用户问题的资源只对产生它们的所有者可见，API服务以请求所属的租户作为所有者。
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFromContext 返回上下文中的资源所有者，第二个返回值表示上下文是否带有所有者
+func OwnerFromContext(ctx context.Context) (string, bool) {
+	owner, ok := ctx.Value(ownerKey{}).(string)
+	return owner, ok
+}
+
+// ResourceSource 动态资源来源：列出当前可用的资源、支持的URI模板，并按URI读取内容
+// 按所有者隔离的来源从 ctx 中的所有者（WithOwner）判断调用方能看到哪些资源。
+type ResourceSource interface {
+	// Resources 返回调用方当前可用的具体资源
+	Resources(ctx context.Context) []mcp.Resource
+	// Templates 返回可按参数构造的资源URI模板
+	Templates() []mcp.ResourceTemplate
+	// Read 读取资源内容，URI不属于该来源时返回 ErrResourceNotFound
+	Read(ctx context.Context, uri string) ([]mcp.ResourceContents, error)
+}
+
+// Notifier 资源来源在内容变化时通知已连接的客户端
+type Notifier interface {
+	// ResourceUpdated 资源内容已变化，订阅了该URI的客户端会收到 notifications/resources/updated
+	ResourceUpdated(uri string)
+	// ResourceListChanged 可用资源列表已变化
+	ResourceListChanged()
+}
+
+// Server 由注册表创建的MCP服务器
+// 工具调用等请求交给 mcp-go 处理；资源的列表、读取和订阅由 Server 直接处理，
+// 以便读取注册表中动态变化的资源并向订阅方推送更新通知。
+type Server struct {
+	*server.MCPServer
+	registry *Registry
+
+	mu            sync.Mutex
+	subscriptions map[string]bool
+	notify        func(mcp.JSONRPCNotification)
+}
+
+// SetNotificationHandler 设置服务器推送通知的处理函数，进程内连接和 stdio 传输使用
+func (s *Server) SetNotificationHandler(fn func(mcp.JSONRPCNotification)) {
+	s.mu.Lock()
+	s.notify = fn
+	s.mu.Unlock()
+}
+
+// HandleMessage 处理一条JSON-RPC消息，通知消息返回 nil
+func (s *Server) HandleMessage(ctx context.Context, message json.RawMessage) mcp.JSONRPCMessage {
+	var request struct {
+		ID     *mcp.RequestId `json:"id"`
+		Method string         `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &request); err != nil || request.ID == nil {
+		return s.MCPServer.HandleMessage(ctx, message)
+	}
+	id := *request.ID
+
+	switch mcp.MCPMethod(request.Method) {
+	case mcp.MethodResourcesList:
+		return newResponse(id, mcp.ListResourcesResult{Resources: s.registry.Resources(ctx)})
+	case mcp.MethodResourcesTemplatesList:
+		return newResponse(id, mcp.ListResourceTemplatesResult{ResourceTemplates: s.registry.ResourceTemplates()})
+	case mcp.MethodResourcesRead:
+		contents, err := s.registry.ReadResource(ctx, request.Params.URI)
+		if errors.Is(err, ErrResourceNotFound) {
+			return mcp.NewJSONRPCError(id, mcp.RESOURCE_NOT_FOUND, err.Error(), map[string]string{"uri": request.Params.URI})
+		}
+		if err != nil {
+			return mcp.NewJSONRPCError(id, mcp.INTERNAL_ERROR, err.Error(), nil)
+		}
+		return newResponse(id, mcp.ReadResourceResult{Contents: contents})
+	case methodResourcesSubscribe, methodResourcesUnsubscribe:
+		if request.Params.URI == "" {
+			return mcp.NewJSONRPCError(id, mcp.INVALID_PARAMS, "uri is required", nil)
+		}
+		s.mu.Lock()
+		if request.Method == methodResourcesSubscribe {
+			if !s.subscriptions[request.Params.URI] && len(s.subscriptions) >= maxResourceSubscriptions {
+				s.mu.Unlock()
+				return mcp.NewJSONRPCError(id, mcp.INVALID_REQUEST, fmt.Sprintf("too many resource subscriptions (max %d)", maxResourceSubscriptions), nil)
+			}
+			s.subscriptions[request.Params.URI] = true
+		} else {
+			delete(s.subscriptions, request.Params.URI)
+		}
+		s.mu.Unlock()
+		return newResponse(id, mcp.EmptyResult{})
+	}
+	return s.MCPServer.HandleMessage(ctx, message)
+}
+
+// newResponse 创建成功响应
+func newResponse(id mcp.RequestId, result any) mcp.JSONRPCResponse {
+	return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: id, Result: result}
+}
+
+// resourceUpdated 向订阅了该URI的客户端推送更新通知
+func (s *Server) resourceUpdated(uri string) {
+	s.mu.Lock()
+	subscribed, notify := s.subscriptions[uri], s.notify
+	s.mu.Unlock()
+	if subscribed && notify != nil {
+		notify(newNotification(mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri}))
+	}
+}
+
+// resourceListChanged 向客户端推送资源列表变化通知
+func (s *Server) resourceListChanged() {
+	s.mu.Lock()
+	notify := s.notify
+	s.mu.Unlock()
+	if notify != nil {
+		notify(newNotification(mcp.MethodNotificationResourcesListChanged, nil))
+	}
+}
+
+// newNotification 创建通知消息
+func newNotification(method string, params map[string]any) mcp.JSONRPCNotification {
+	return mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: method,
+			Params: mcp.NotificationParams{AdditionalFields: params},
+		},
+	}
+}
+
+// ServeStdio 通过标准输入输出提供服务，每行一条消息，资源更新通知与响应写入同一输出
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	var writeMu sync.Mutex
+	write := func(message any) {
+		data, err := json.Marshal(message)
+		if err != nil {
+			s.registry.logger.WithError(err).Error("Failed to marshal MCP message")
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if _, err := out.Write(append(data, '\n')); err != nil {
+			s.registry.logger.WithError(err).Error("Failed to write MCP message")
+		}
+	}
+	s.SetNotificationHandler(func(notification mcp.JSONRPCNotification) {
+		write(notification)
+	})
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := append([]byte(nil), scanner.Bytes()...)
+		if len(line) == 0 {
+			continue
+		}
+		if response := s.HandleMessage(ctx, line); response != nil {
+			write(response)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read MCP message: %w", err)
+	}
+	return nil
+}
+
+// RegisterResources 注册资源来源
+func (r *Registry) RegisterResources(sources ...ResourceSource) {
+	r.sources = append(r.sources, sources...)
+}
+
+// Resources 返回所有来源中调用方当前可用的资源
+func (r *Registry) Resources(ctx context.Context) []mcp.Resource {
+	resources := make([]mcp.Resource, 0)
+	for _, source := range r.sources {
+		resources = append(resources, source.Resources(ctx)...)
+	}
+	return resources
+}
+
+// ResourceTemplates 返回所有来源支持的资源URI模板
+func (r *Registry) ResourceTemplates() []mcp.ResourceTemplate {
+	templates := make([]mcp.ResourceTemplate, 0)
+	for _, source := range r.sources {
+		templates = append(templates, source.Templates()...)
+	}
+	return templates
+}
+
+// ReadResource 在进程内读取资源，依次交给各来源处理
+func (r *Registry) ReadResource(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	for _, source := range r.sources {
+		contents, err := source.Read(ctx, uri)
+		if errors.Is(err, ErrResourceNotFound) {
+			continue
+		}
+		if err != nil {
+			r.logger.WithError(err).WithField("uri", uri).Warn("Failed to read resource")
+		}
+		return contents, err
+	}
+	return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+}
+
+// ResourceUpdated 实现 Notifier，通知所有服务器上订阅了该URI的客户端
+func (r *Registry) ResourceUpdated(uri string) {
+	r.logger.WithField("uri", uri).Debug("Resource updated")
+	for _, s := range r.activeServers() {
+		s.resourceUpdated(uri)
+	}
+}
+
+// ResourceListChanged 实现 Notifier，通知所有服务器的客户端资源列表已变化
+func (r *Registry) ResourceListChanged() {
+	for _, s := range r.activeServers() {
+		s.resourceListChanged()
+	}
+}
+
+// activeServers 返回由注册表创建的服务器
+func (r *Registry) activeServers() []*Server {
+	r.serversMu.Lock()
+	defer r.serversMu.Unlock()
+	return append([]*Server(nil), r.servers...)
+}
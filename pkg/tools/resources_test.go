@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/search"
+)
+
+// fakeSource 内存中的资源来源
+type fakeSource struct {
+	texts map[string]string
+}
+
+func (f *fakeSource) Resources(ctx context.Context) []mcp.Resource {
+	return []mcp.Resource{mcp.NewResource("note://greeting", "问候")}
+}
+
+func (f *fakeSource) Templates() []mcp.ResourceTemplate {
+	return []mcp.ResourceTemplate{mcp.NewResourceTemplate("note://{name}", "笔记")}
+}
+
+func (f *fakeSource) Read(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	text, ok := f.texts[uri]
+	if !ok {
+		return nil, ErrResourceNotFound
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: "text/plain", Text: text}}, nil
+}
+
+// handle 向服务器发送一条请求并返回解码后的响应
+func handle(t *testing.T, srv *Server, request string) map[string]interface{} {
+	t.Helper()
+	response := srv.HandleMessage(context.Background(), json.RawMessage(request))
+	require.NotNil(t, response)
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return decoded
+}
+
+func TestServer_Resources(t *testing.T) {
+	registry := NewRegistry(newTestLogger())
+	registry.RegisterResources(&fakeSource{texts: map[string]string{"note://greeting": "你好"}})
+	srv := registry.NewMCPServer("test-server", "1.0.0")
+
+	var mu sync.Mutex
+	var notifications []string
+	srv.SetNotificationHandler(func(notification mcp.JSONRPCNotification) {
+		mu.Lock()
+		defer mu.Unlock()
+		uri, _ := notification.Params.AdditionalFields["uri"].(string)
+		notifications = append(notifications, strings.TrimSpace(notification.Method+" "+uri))
+	})
+
+	resp := handle(t, srv, `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`)
+	resources := resp["result"].(map[string]interface{})["resources"].([]interface{})
+	require.Len(t, resources, 1)
+	assert.Equal(t, "note://greeting", resources[0].(map[string]interface{})["uri"])
+
+	resp = handle(t, srv, `{"jsonrpc":"2.0","id":2,"method":"resources/templates/list"}`)
+	templates := resp["result"].(map[string]interface{})["resourceTemplates"].([]interface{})
+	require.Len(t, templates, 1)
+	assert.Equal(t, "note://{name}", templates[0].(map[string]interface{})["uriTemplate"])
+
+	resp = handle(t, srv, `{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"note://greeting"}}`)
+	contents := resp["result"].(map[string]interface{})["contents"].([]interface{})
+	require.Len(t, contents, 1)
+	assert.Equal(t, "你好", contents[0].(map[string]interface{})["text"])
+
+	// 不存在的资源返回 -32002
+	resp = handle(t, srv, `{"jsonrpc":"2.0","id":4,"method":"resources/read","params":{"uri":"note://missing"}}`)
+	assert.EqualValues(t, mcp.RESOURCE_NOT_FOUND, resp["error"].(map[string]interface{})["code"])
+
+	// 只有订阅了的资源会收到更新通知
+	resp = handle(t, srv, `{"jsonrpc":"2.0","id":5,"method":"resources/subscribe","params":{"uri":"note://greeting"}}`)
+	assert.Nil(t, resp["error"])
+	registry.ResourceUpdated("note://greeting")
+	registry.ResourceUpdated("note://other")
+	registry.ResourceListChanged()
+
+	handle(t, srv, `{"jsonrpc":"2.0","id":6,"method":"resources/unsubscribe","params":{"uri":"note://greeting"}}`)
+	registry.ResourceUpdated("note://greeting")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"notifications/resources/updated note://greeting",
+		"notifications/resources/list_changed",
+	}, notifications)
+
+	// 其他请求仍由 mcp-go 处理
+	resp = handle(t, srv, `{"jsonrpc":"2.0","id":7,"method":"ping"}`)
+	assert.Nil(t, resp["error"])
+}
+
+func TestSearchResources(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.md"), []byte("# Go语言\n\ngoroutine 非常轻量。"), 0o600))
+	providers, err := search.NewRegistry(&config.Config{
+		Search: config.SearchConfig{
+			Provider:   config.SearchProviderLocal,
+			MaxResults: 5,
+			Timeout:    10,
+			Local:      config.LocalIndexConfig{Path: dir},
+			Cache:      config.SearchCacheConfig{Enabled: true, TTL: 60, MaxEntries: 10},
+		},
+	}, newTestLogger())
+	require.NoError(t, err)
+
+	registry := NewRegistry(newTestLogger())
+	registry.Register(SearchTools(providers, newTestLogger())...)
+	registry.RegisterResources(NewSearchResources(providers.Cache(), registry, newTestLogger()))
+	alice := WithOwner(context.Background(), "alice")
+	bob := WithOwner(context.Background(), "bob")
+	assert.Empty(t, registry.Resources(alice))
+
+	_, err = registry.Call(alice, "search", map[string]interface{}{"query": "goroutine"})
+	require.NoError(t, err)
+
+	resources := registry.Resources(alice)
+	require.Len(t, resources, 1)
+	uri := resources[0].URI
+	assert.True(t, strings.HasPrefix(uri, "search://results/"))
+	assert.Equal(t, "搜索结果: goroutine", resources[0].Name)
+
+	contents, err := registry.ReadResource(alice, uri)
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	assert.Contains(t, contents[0].(mcp.TextResourceContents).Text, "1. **Go语言**")
+
+	// 其他所有者和没有所有者的调用方既看不到也读不到该搜索结果
+	for name, ctx := range map[string]context.Context{"other owner": bob, "no owner": context.Background()} {
+		assert.Empty(t, registry.Resources(ctx), name)
+		_, err = registry.ReadResource(ctx, uri)
+		assert.ErrorIs(t, err, ErrResourceNotFound, name)
+	}
+
+	// 其他所有者执行相同的搜索后才可见，结果仍共用缓存
+	_, err = registry.Call(bob, "search", map[string]interface{}{"query": "goroutine"})
+	require.NoError(t, err)
+	require.Len(t, registry.Resources(bob), 1)
+	assert.Equal(t, uri, registry.Resources(bob)[0].URI)
+
+	_, err = registry.ReadResource(alice, "search://results/unknown")
+	assert.ErrorIs(t, err, ErrResourceNotFound)
+}
+
+func TestReportResources(t *testing.T) {
+	registry := NewRegistry(newTestLogger())
+	reports := NewReportResources(registry, newTestLogger())
+	registry.Register(ReportTools(reports, newTestLogger())...)
+	registry.RegisterResources(reports)
+	alice := WithOwner(context.Background(), "alice")
+	bob := WithOwner(context.Background(), "bob")
+
+	result, err := registry.Call(alice, ReportToolName, map[string]interface{}{"query": "goroutine 是什么", "report": "goroutine 是轻量线程。"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	uri := resultText(t, result)
+	assert.True(t, strings.HasPrefix(uri, "report://recent/"))
+
+	resources := registry.Resources(alice)
+	require.Len(t, resources, 1)
+	assert.Equal(t, uri, resources[0].URI)
+	assert.Equal(t, "研究报告: goroutine 是什么", resources[0].Name)
+	contents, err := registry.ReadResource(alice, uri)
+	require.NoError(t, err)
+	assert.Equal(t, "# goroutine 是什么\n\ngoroutine 是轻量线程。", contents[0].(mcp.TextResourceContents).Text)
+
+	assert.Empty(t, registry.Resources(bob))
+	_, err = registry.ReadResource(bob, uri)
+	assert.ErrorIs(t, err, ErrResourceNotFound)
+
+	// 没有所有者时不保存
+	result, err = registry.Call(context.Background(), ReportToolName, map[string]interface{}{"query": "q", "report": "r"})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	// 只保留最近的报告
+	for i := 0; i < reportMaxEntries; i++ {
+		_, err := reports.Save(alice, fmt.Sprintf("问题%d", i), "内容")
+		require.NoError(t, err)
+	}
+	resources = registry.Resources(alice)
+	assert.Len(t, resources, reportMaxEntries)
+	assert.Equal(t, fmt.Sprintf("研究报告: 问题%d", reportMaxEntries-1), resources[0].Name)
+	_, err = registry.ReadResource(alice, uri)
+	assert.ErrorIs(t, err, ErrResourceNotFound)
+}
+
+func TestParseWeatherResourceURI(t *testing.T) {
+	city, kind, ok := parseWeatherResourceURI(WeatherResourceURI("New York", weatherResourceForecast))
+	require.True(t, ok)
+	assert.Equal(t, "New York", city)
+	assert.Equal(t, weatherResourceForecast, kind)
+
+	city, kind, ok = parseWeatherResourceURI("weather://北京/current")
+	require.True(t, ok)
+	assert.Equal(t, "北京", city)
+	assert.Equal(t, weatherResourceCurrent, kind)
+
+	for _, uri := range []string{"weather://Beijing/hourly", "weather:///current", "search://results/abc"} {
+		_, _, ok = parseWeatherResourceURI(uri)
+		assert.False(t, ok, uri)
+	}
+}
+
+func TestServer_SubscriptionLimit(t *testing.T) {
+	registry := NewRegistry(newTestLogger())
+	srv := registry.NewMCPServer("test-server", "1.0.0")
+
+	for i := 0; i < maxResourceSubscriptions; i++ {
+		resp := handle(t, srv, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"resources/subscribe","params":{"uri":"note://%d"}}`, i, i))
+		require.Nil(t, resp["error"])
+	}
+
+	// 达到上限后拒绝新的URI，已订阅的URI可以重复订阅
+	resp := handle(t, srv, `{"jsonrpc":"2.0","id":"over","method":"resources/subscribe","params":{"uri":"note://over"}}`)
+	assert.NotNil(t, resp["error"])
+	resp = handle(t, srv, `{"jsonrpc":"2.0","id":"again","method":"resources/subscribe","params":{"uri":"note://0"}}`)
+	assert.Nil(t, resp["error"])
+
+	// 取消订阅后释放名额
+	handle(t, srv, `{"jsonrpc":"2.0","id":"unsub","method":"resources/unsubscribe","params":{"uri":"note://0"}}`)
+	resp = handle(t, srv, `{"jsonrpc":"2.0","id":"retry","method":"resources/subscribe","params":{"uri":"note://over"}}`)
+	assert.Nil(t, resp["error"])
+}
+
+func TestWeatherResources_SnapshotLimit(t *testing.T) {
+	snapshots := NewWeatherResources(nil, nil, newTestLogger())
+	for i := 0; i <= weatherMaxSnapshots; i++ {
+		snapshots.store(fmt.Sprintf("city-%d", i), weatherResourceCurrent, "晴")
+	}
+
+	resources := snapshots.Resources(context.Background())
+	assert.Len(t, resources, weatherMaxSnapshots)
+	for _, resource := range resources {
+		assert.NotEqual(t, WeatherResourceURI("city-0", weatherResourceCurrent), resource.URI, "oldest snapshot should be evicted")
+	}
+}
@@ -0,0 +1,213 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/search"
+)
+
+// SearchTools 返回搜索工具，启用缓存时还包括缓存统计工具
+// 缓存统计工具供 /api/search/cache/stats 查询，不在LLM提示词中暴露。
+func SearchTools(searchProviders *search.Registry, logger *logrus.Logger) []Tool {
+	tools := []Tool{{
+		Definition: mcp.NewTool("search", search.SearchToolOptions(searchProviders)...),
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return handleSearch(ctx, request, searchProviders, logger)
+		},
+	}}
+
+	if _, enabled := searchProviders.CacheStats(); enabled {
+		tools = append(tools, Tool{
+			Definition: mcp.NewTool(search.CacheStatsToolName,
+				mcp.WithDescription("返回搜索结果缓存的命中、未命中和合并请求统计（JSON）"),
+			),
+			Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				stats, _ := searchProviders.CacheStats()
+				data, err := json.Marshal(stats)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("统计信息序列化失败: %v", err)), nil
+				}
+				return mcp.NewToolResultText(string(data)), nil
+			},
+		})
+	}
+	return tools
+}
+
+// handleSearch 处理搜索请求
+func handleSearch(ctx context.Context, request mcp.CallToolRequest, searchProviders *search.Registry, logger *logrus.Logger) (*mcp.CallToolResult, error) {
+	logger.WithFields(logrus.Fields{
+		"tool": "search",
+	}).Debug("Processing search request")
+
+	// 解析请求参数
+	query, err := request.RequireString("query")
+	if err != nil {
+		logger.WithError(err).Error("Failed to parse query parameter")
+		return mcp.NewToolResultError(fmt.Sprintf("参数解析失败: %v", err)), nil
+	}
+	if query == "" {
+		return mcp.NewToolResultError("搜索查询不能为空"), nil
+	}
+
+	opts, err := search.ParseSearchOptions(request)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("参数解析失败: %v", err)), nil
+	}
+
+	// 选择搜索服务提供方
+	provider, err := searchProviders.Get(request.GetString("provider", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	logger.WithFields(logrus.Fields{
+		"provider":    provider.Name(),
+		"max_results": opts.MaxResults,
+		"topic":       opts.Topic,
+		"time_range":  opts.TimeRange,
+	}).Debug("Selected search provider")
+
+	// 执行搜索
+	searchResults, err := provider.SearchWithOptions(ctx, query, opts)
+	if err != nil {
+		logger.WithError(err).Error("Failed to perform search")
+		return mcp.NewToolResultError(fmt.Sprintf("搜索失败: %v", err)), nil
+	}
+
+	// 去重、重排并截断摘要
+	searchResults = searchProviders.Cleaner().Clean(query, searchResults)
+
+	return mcp.NewToolResultText(search.FormatResults(query, searchResults)), nil
+}
+
+// searchResourcePrefix 缓存搜索结果资源的URI前缀
+const searchResourcePrefix = "search://results/"
+
+// SearchResourceURI 返回缓存搜索结果资源的URI
+func SearchResourceURI(key string) string {
+	return searchResourcePrefix + key
+}
+
+// SearchResources 缓存的搜索结果资源来源
+// 每条未过期的缓存结果发布为 search://results/{key}。搜索缓存在所有调用方之间共用，
+// 但资源只对执行过该搜索的所有者可见：查询文本和结果属于发起搜索的一方。
+// 调用方上下文中没有所有者时（如子进程方式运行的服务器、外部MCP客户端）不发布搜索结果。
+type SearchResources struct {
+	cache    *search.Cache
+	notifier Notifier
+	logger   *logrus.Logger
+
+	mu     sync.Mutex
+	owners map[string]map[string]bool // 缓存键 -> 执行过该搜索的所有者
+}
+
+// NewSearchResources 创建缓存搜索结果资源来源，notifier 可为空
+func NewSearchResources(cache *search.Cache, notifier Notifier, logger *logrus.Logger) *SearchResources {
+	s := &SearchResources{
+		cache:    cache,
+		notifier: notifier,
+		logger:   logger,
+		owners:   make(map[string]map[string]bool),
+	}
+	cache.OnAccess(s.recordAccess)
+	if notifier != nil {
+		cache.OnStore(func(key string) {
+			notifier.ResourceUpdated(SearchResourceURI(key))
+		})
+	}
+	return s
+}
+
+// recordAccess 记录调用方用到了该搜索结果，所有者第一次用到时通知资源列表变化
+func (s *SearchResources) recordAccess(ctx context.Context, key string) {
+	owner, ok := OwnerFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	owners, exists := s.owners[key]
+	if !exists {
+		s.pruneLocked()
+		owners = make(map[string]bool)
+		s.owners[key] = owners
+	}
+	added := !owners[owner]
+	owners[owner] = true
+	s.mu.Unlock()
+
+	if added && s.notifier != nil {
+		s.logger.WithField("key", key).Debug("Search result cached, publishing resource")
+		s.notifier.ResourceListChanged()
+	}
+}
+
+// pruneLocked 丢弃已不在缓存中的结果的所有者记录，调用方需持有锁
+func (s *SearchResources) pruneLocked() {
+	live := make(map[string]bool)
+	for _, entry := range s.cache.Entries() {
+		live[entry.Key] = true
+	}
+	for key := range s.owners {
+		if !live[key] {
+			delete(s.owners, key)
+		}
+	}
+}
+
+// visible 判断调用方是否可以看到该搜索结果
+func (s *SearchResources) visible(ctx context.Context, key string) bool {
+	owner, ok := OwnerFromContext(ctx)
+	if !ok {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.owners[key][owner]
+}
+
+// Resources 返回调用方执行过的、未过期的缓存搜索结果
+func (s *SearchResources) Resources(ctx context.Context) []mcp.Resource {
+	entries := s.cache.Entries()
+	resources := make([]mcp.Resource, 0, len(entries))
+	for _, entry := range entries {
+		if !s.visible(ctx, entry.Key) {
+			continue
+		}
+		resources = append(resources, mcp.NewResource(SearchResourceURI(entry.Key), "搜索结果: "+entry.Query,
+			mcp.WithResourceDescription(fmt.Sprintf("%d 条结果，缓存至 %s", entry.Results, entry.ExpiresAt.Format(time.RFC3339))),
+			mcp.WithMIMEType("text/plain"),
+		))
+	}
+	return resources
+}
+
+// Templates 缓存键无法由调用方构造，不提供URI模板
+func (s *SearchResources) Templates() []mcp.ResourceTemplate {
+	return nil
+}
+
+// Read 读取缓存的搜索结果
+func (s *SearchResources) Read(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	key, ok := strings.CutPrefix(uri, searchResourcePrefix)
+	if !ok {
+		return nil, ErrResourceNotFound
+	}
+	response, ok := s.cache.Lookup(key)
+	if !ok || !s.visible(ctx, key) {
+		return nil, fmt.Errorf("%w: search result %s is not cached or has expired", ErrResourceNotFound, key)
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      uri,
+		MIMEType: "text/plain",
+		Text:     search.FormatResults(response.Query, response),
+	}}, nil
+}
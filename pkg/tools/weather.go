@@ -0,0 +1,372 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/weather"
+)
+
+// WeatherTools 返回天气相关工具：当前天气、天气预报、空气质量，以及有提供方支持时的气象预警
+// snapshots 不为空时，使用默认选项查询到的当前天气会同步更新对应的天气快照资源。
+func WeatherTools(weatherService *weather.Service, snapshots *WeatherResources, logger *logrus.Logger) []Tool {
+	h := &weatherHandlers{service: weatherService, snapshots: snapshots, logger: logger}
+	tools := []Tool{
+		{
+			Definition: mcp.NewTool("get_weather",
+				append([]mcp.ToolOption{
+					mcp.WithDescription("获取指定城市的当前天气信息"),
+				}, weather.CityToolOptions(weatherService)...)...,
+			),
+			Handler: h.current,
+		},
+		{
+			Definition: mcp.NewTool("get_weather_forecast", weather.ForecastToolOptions(weatherService)...),
+			Handler:    h.forecast,
+		},
+		{
+			Definition: mcp.NewTool("get_air_quality", weather.AirQualityToolOptions(weatherService)...),
+			Handler:    h.airQuality,
+		},
+	}
+
+	// 气象预警工具仅在有提供方支持时注册，目前需要 OpenWeatherMap One Call 3.0
+	if weatherService.SupportsAlerts() {
+		tools = append(tools, Tool{
+			Definition: mcp.NewTool("get_weather_alerts", weather.AlertToolOptions(weatherService)...),
+			Handler:    h.alerts,
+		})
+	} else {
+		logger.Info("No weather provider supports alerts, get_weather_alerts is disabled")
+	}
+	return tools
+}
+
+// weatherHandlers 天气工具的处理函数
+type weatherHandlers struct {
+	service   *weather.Service
+	snapshots *WeatherResources
+	logger    *logrus.Logger
+}
+
+// parseCityRequest 解析天气工具共用的 city 参数和选项，参数无效时返回错误结果
+func (h *weatherHandlers) parseCityRequest(tool string, request mcp.CallToolRequest) (string, weather.Options, *mcp.CallToolResult) {
+	h.logger.WithFields(logrus.Fields{
+		"tool": tool,
+	}).Debugf("Processing %s request", tool)
+
+	city, err := request.RequireString("city")
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to parse city parameter")
+		return "", weather.Options{}, mcp.NewToolResultError(fmt.Sprintf("参数解析失败: %v", err))
+	}
+	if city == "" {
+		return "", weather.Options{}, mcp.NewToolResultError("城市名称不能为空")
+	}
+
+	opts, err := weather.ParseOptions(request)
+	if err != nil {
+		return "", weather.Options{}, mcp.NewToolResultError(fmt.Sprintf("参数解析失败: %v", err))
+	}
+	return city, opts, nil
+}
+
+// errorResult 将查询错误转换为工具结果：歧义地名列出候选地点，找不到地点时提示检查名称
+func (h *weatherHandlers) errorResult(tool string, err error, message string) *mcp.CallToolResult {
+	var ambiguous *weather.AmbiguousLocationError
+	if errors.As(err, &ambiguous) {
+		return mcp.NewToolResultText(weather.FormatCandidates(ambiguous))
+	}
+	if errors.Is(err, weather.ErrLocationNotFound) {
+		return mcp.NewToolResultError(fmt.Sprintf("找不到该地点，请检查名称是否正确: %v", err))
+	}
+	if errors.Is(err, weather.ErrNotSupported) {
+		return mcp.NewToolResultError(fmt.Sprintf("所选天气提供方不支持该查询: %v", err))
+	}
+	h.logger.WithError(err).WithField("tool", tool).Error("Weather tool call failed")
+	return mcp.NewToolResultError(fmt.Sprintf("%s: %v", message, err))
+}
+
+// current 处理获取当前天气请求
+func (h *weatherHandlers) current(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	city, opts, result := h.parseCityRequest("get_weather", request)
+	if result != nil {
+		return result, nil
+	}
+
+	provider := request.GetString("provider", "")
+	data, err := h.service.Current(ctx, provider, city, opts)
+	if err != nil {
+		return h.errorResult("get_weather", err, "获取天气信息失败"), nil
+	}
+	text := weather.FormatCurrent(data, opts)
+
+	// 快照资源只保存默认提供方和默认选项的结果
+	if h.snapshots != nil && provider == "" && opts == (weather.Options{}) {
+		h.snapshots.store(city, weatherResourceCurrent, text)
+	}
+	return mcp.NewToolResultText(text), nil
+}
+
+// forecast 处理获取天气预报请求，支持逐日和逐小时两种模式
+func (h *weatherHandlers) forecast(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	city, opts, result := h.parseCityRequest("get_weather_forecast", request)
+	if result != nil {
+		return result, nil
+	}
+	provider := request.GetString("provider", "")
+
+	if request.GetString("mode", weather.ForecastModeDaily) == weather.ForecastModeHourly {
+		hourly, err := h.service.Hourly(ctx, provider, city, request.GetInt("hours", weather.DefaultForecastHours), opts)
+		if err != nil {
+			return h.errorResult("get_weather_forecast", err, "获取天气预报失败"), nil
+		}
+		if len(hourly) == 0 {
+			return mcp.NewToolResultError("没有可用的天气预报数据"), nil
+		}
+		return mcp.NewToolResultText(weather.FormatHourlyForecast(hourly, opts)), nil
+	}
+
+	// 天数默认为1天，上限由提供方决定
+	daily, err := h.service.Forecast(ctx, provider, city, request.GetInt("days", 1), opts)
+	if err != nil {
+		return h.errorResult("get_weather_forecast", err, "获取天气预报失败"), nil
+	}
+	if len(daily) == 0 {
+		return mcp.NewToolResultError("没有可用的天气预报数据"), nil
+	}
+	return mcp.NewToolResultText(weather.FormatDailyForecast(daily, opts)), nil
+}
+
+// airQuality 处理获取空气质量请求
+func (h *weatherHandlers) airQuality(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	city, opts, result := h.parseCityRequest("get_air_quality", request)
+	if result != nil {
+		return result, nil
+	}
+
+	aq, err := h.service.AirQuality(ctx, request.GetString("provider", ""), city, opts)
+	if err != nil {
+		return h.errorResult("get_air_quality", err, "获取空气质量失败"), nil
+	}
+	return mcp.NewToolResultText(weather.FormatAirQuality(aq, opts)), nil
+}
+
+// alerts 处理获取气象预警请求
+func (h *weatherHandlers) alerts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	city, opts, result := h.parseCityRequest("get_weather_alerts", request)
+	if result != nil {
+		return result, nil
+	}
+
+	// 先解析地名，以便在没有预警时也能给出明确的地点名称
+	loc, err := h.service.Resolve(ctx, city, opts.Lang)
+	if err != nil {
+		return h.errorResult("get_weather_alerts", err, "获取气象预警失败"), nil
+	}
+	alerts, err := h.service.Alerts(ctx, request.GetString("provider", ""), city, opts)
+	if err != nil {
+		return h.errorResult("get_weather_alerts", err, "获取气象预警失败"), nil
+	}
+	return mcp.NewToolResultText(weather.FormatAlerts(loc.DisplayName(), alerts, opts)), nil
+}
+
+// 天气快照资源类型
+const (
+	weatherResourceCurrent  = "current"
+	weatherResourceForecast = "forecast"
+
+	weatherSnapshotTTL    = 10 * time.Minute // 快照超过该时间后读取时重新查询
+	weatherMaxSnapshots   = 256              // 最多保存的快照数，超出时淘汰最早更新的快照
+	weatherForecastDays   = 3                // 预报快照包含的天数
+	weatherResourcePrefix = "weather://"
+)
+
+// WeatherResourceURI 返回城市天气快照资源的URI，kind 为 current 或 forecast
+func WeatherResourceURI(city, kind string) string {
+	return weatherResourcePrefix + url.PathEscape(city) + "/" + kind
+}
+
+// weatherSnapshot 一个城市天气快照
+type weatherSnapshot struct {
+	city      string
+	kind      string
+	text      string
+	updatedAt time.Time
+}
+
+// WeatherResources 城市天气快照资源来源
+// 资源URI形如 weather://Beijing/current 和 weather://Beijing/forecast，
+// 读取时快照过期则重新查询；查询结果变化时通知订阅方。
+type WeatherResources struct {
+	service  *weather.Service
+	notifier Notifier
+	logger   *logrus.Logger
+
+	mu        sync.Mutex
+	snapshots map[string]weatherSnapshot // 按URI保存
+}
+
+// NewWeatherResources 创建天气快照资源来源，notifier 可为空
+func NewWeatherResources(weatherService *weather.Service, notifier Notifier, logger *logrus.Logger) *WeatherResources {
+	return &WeatherResources{
+		service:   weatherService,
+		notifier:  notifier,
+		logger:    logger,
+		snapshots: make(map[string]weatherSnapshot),
+	}
+}
+
+// Resources 返回已查询过的城市天气快照，天气数据是公开的，所有调用方看到相同的快照
+func (w *WeatherResources) Resources(ctx context.Context) []mcp.Resource {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	uris := make([]string, 0, len(w.snapshots))
+	for uri := range w.snapshots {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	resources := make([]mcp.Resource, 0, len(uris))
+	for _, uri := range uris {
+		snapshot := w.snapshots[uri]
+		title := "当前天气"
+		if snapshot.kind == weatherResourceForecast {
+			title = fmt.Sprintf("未来%d天天气预报", weatherForecastDays)
+		}
+		resources = append(resources, mcp.NewResource(uri, snapshot.city+" "+title,
+			mcp.WithResourceDescription(fmt.Sprintf("%s的%s快照，更新于 %s", snapshot.city, title, snapshot.updatedAt.Format(time.RFC3339))),
+			mcp.WithMIMEType("text/plain"),
+		))
+	}
+	return resources
+}
+
+// Templates 返回天气快照的URI模板
+func (w *WeatherResources) Templates() []mcp.ResourceTemplate {
+	return []mcp.ResourceTemplate{
+		mcp.NewResourceTemplate(weatherResourcePrefix+"{city}/"+weatherResourceCurrent, "城市当前天气",
+			mcp.WithTemplateDescription("指定城市的当前天气快照"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		mcp.NewResourceTemplate(weatherResourcePrefix+"{city}/"+weatherResourceForecast, "城市天气预报",
+			mcp.WithTemplateDescription(fmt.Sprintf("指定城市未来%d天的天气预报快照", weatherForecastDays)),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+	}
+}
+
+// Read 读取天气快照，快照不存在或已过期时重新查询
+func (w *WeatherResources) Read(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	city, kind, ok := parseWeatherResourceURI(uri)
+	if !ok {
+		return nil, ErrResourceNotFound
+	}
+
+	w.mu.Lock()
+	snapshot, exists := w.snapshots[uri]
+	w.mu.Unlock()
+	if !exists || time.Since(snapshot.updatedAt) > weatherSnapshotTTL {
+		text, err := w.fetch(ctx, city, kind)
+		if err != nil {
+			return nil, err
+		}
+		snapshot = w.store(city, kind, text)
+	}
+
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      uri,
+		MIMEType: "text/plain",
+		Text:     snapshot.text,
+	}}, nil
+}
+
+// fetch 使用默认提供方和默认选项查询天气
+func (w *WeatherResources) fetch(ctx context.Context, city, kind string) (string, error) {
+	opts := weather.Options{}
+	if kind == weatherResourceForecast {
+		daily, err := w.service.Forecast(ctx, "", city, weatherForecastDays, opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to get weather forecast for %s: %w", city, err)
+		}
+		return weather.FormatDailyForecast(daily, opts), nil
+	}
+
+	data, err := w.service.Current(ctx, "", city, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to get current weather for %s: %w", city, err)
+	}
+	return weather.FormatCurrent(data, opts), nil
+}
+
+// evictLocked 快照数达到上限时淘汰最早更新的快照，调用方需持有 w.mu
+func (w *WeatherResources) evictLocked() bool {
+	evicted := false
+	for len(w.snapshots) >= weatherMaxSnapshots {
+		oldestURI, oldest := "", time.Time{}
+		for uri, snapshot := range w.snapshots {
+			if oldestURI == "" || snapshot.updatedAt.Before(oldest) {
+				oldestURI, oldest = uri, snapshot.updatedAt
+			}
+		}
+		delete(w.snapshots, oldestURI)
+		evicted = true
+	}
+	return evicted
+}
+
+// store 保存快照，新增快照时通知资源列表变化，内容变化时通知订阅方
+func (w *WeatherResources) store(city, kind, text string) weatherSnapshot {
+	uri := WeatherResourceURI(city, kind)
+	snapshot := weatherSnapshot{city: city, kind: kind, text: text, updatedAt: time.Now()}
+
+	w.mu.Lock()
+	previous, existed := w.snapshots[uri]
+	evicted := false
+	if !existed {
+		evicted = w.evictLocked()
+	}
+	w.snapshots[uri] = snapshot
+	w.mu.Unlock()
+
+	if w.notifier == nil {
+		return snapshot
+	}
+	if !existed || evicted {
+		w.notifier.ResourceListChanged()
+	} else if previous.text != text {
+		w.notifier.ResourceUpdated(uri)
+	}
+	return snapshot
+}
+
+// parseWeatherResourceURI 解析 weather://{city}/{kind}
+func parseWeatherResourceURI(uri string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(uri, weatherResourcePrefix)
+	if !ok {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, "/")
+	if i <= 0 {
+		return "", "", false
+	}
+	kind := rest[i+1:]
+	if kind != weatherResourceCurrent && kind != weatherResourceForecast {
+		return "", "", false
+	}
+	city, err := url.PathUnescape(rest[:i])
+	if err != nil || city == "" {
+		return "", "", false
+	}
+	return city, kind, true
+}
@@ -0,0 +1,362 @@
+// Package usage 按请求、API密钥和租户记录LLM token与工具调用用量，计算费用并执行配额
+package usage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+// DefaultTenant 未启用鉴权时请求所属的租户
+const DefaultTenant = "default"
+
+// ErrQuotaExceeded 租户已用完配额
+var ErrQuotaExceeded = errors.New("usage quota exceeded")
+
+// Identity 请求的计费身份
+type Identity struct {
+	APIKey string `json:"api_key"` // API密钥名称，未启用鉴权时为空
+	Tenant string `json:"tenant"`
+}
+
+// identityKey 上下文中计费身份的键
+type identityKey struct{}
+
+// WithIdentity 返回附加了计费身份的上下文，队列处理该请求后按此身份记账
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// identityFromContext 返回上下文中的计费身份，没有时为默认租户
+func identityFromContext(ctx context.Context) Identity {
+	if identity, ok := ctx.Value(identityKey{}).(Identity); ok {
+		return identity
+	}
+	return Identity{Tenant: DefaultTenant}
+}
+
+// Record 一次请求的用量记录
+type Record struct {
+	Time             time.Time      `json:"time"`
+	APIKey           string         `json:"api_key,omitempty"`
+	Tenant           string         `json:"tenant"`
+	PromptTokens     int            `json:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens"`
+	LLMCalls         int            `json:"llm_calls"`
+	ToolCalls        map[string]int `json:"tool_calls,omitempty"`
+	Cost             float64        `json:"cost"` // 按记录时的价格表计算
+	Success          bool           `json:"success"`
+}
+
+// TotalTokens 记录的token总数
+func (r Record) TotalTokens() int {
+	return r.PromptTokens + r.CompletionTokens
+}
+
+// totals 租户在一个统计周期内的累计用量
+type totals struct {
+	tokens int
+	cost   float64
+}
+
+// QuotaError 超出配额的详细信息
+type QuotaError struct {
+	Tenant  string
+	Period  string // daily | monthly
+	Metric  string // cost | tokens
+	Limit   float64
+	Used    float64
+	ResetAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("tenant %q exceeded %s %s quota (%.4g/%.4g), resets at %s",
+		e.Tenant, e.Period, e.Metric, e.Used, e.Limit, e.ResetAt.Format(time.RFC3339))
+}
+
+// Unwrap 使 errors.Is(err, ErrQuotaExceeded) 成立
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// Ledger 用量账本
+// 记录保存在内存中供报表查询（超过保留天数的记录会被清理），配置了记录文件时同时追加写入文件，
+// 启动时从文件恢复保留期内的记录。按自然日、自然月（UTC）累计的租户用量用于配额检查。
+type Ledger struct {
+	mu      sync.Mutex
+	cfg     config.UsageConfig
+	records []Record
+	daily   map[string]map[string]*totals // 租户 -> 日期(2006-01-02) -> 用量
+	monthly map[string]map[string]*totals // 租户 -> 月份(2006-01) -> 用量
+	file    *os.File
+	now     func() time.Time
+	logger  *logrus.Logger
+}
+
+// NewLedger 创建用量账本，配置了记录文件时加载其中保留期内的记录
+func NewLedger(cfg config.UsageConfig, logger *logrus.Logger) (*Ledger, error) {
+	l := &Ledger{
+		cfg:     cfg,
+		daily:   make(map[string]map[string]*totals),
+		monthly: make(map[string]map[string]*totals),
+		now:     time.Now,
+		logger:  logger,
+	}
+	if cfg.LedgerFile == "" {
+		return l, nil
+	}
+
+	if err := l.load(cfg.LedgerFile); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.LedgerFile), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create usage ledger dir: %w", err)
+	}
+	file, err := os.OpenFile(cfg.LedgerFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	l.file = file
+
+	logger.WithFields(logrus.Fields{
+		"file":    cfg.LedgerFile,
+		"records": len(l.records),
+	}).Info("Usage ledger loaded")
+	return l, nil
+}
+
+// load 读取记录文件中保留期内的记录
+func (l *Ledger) load(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	cutoff := l.cutoff()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			l.logger.WithError(err).WithField("line", line).Warn("Skipping malformed usage record")
+			continue
+		}
+		if record.Time.Before(cutoff) {
+			continue
+		}
+		l.add(record)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭记录文件
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// UpdateConfig 热更新价格表、配额和保留天数，记录文件的变化需要重启
+// 新价格只用于之后的记录，已有记录的费用不变。
+func (l *Ledger) UpdateConfig(cfg config.UsageConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cfg.LedgerFile = l.cfg.LedgerFile
+	l.cfg = cfg
+	l.prune()
+}
+
+// Config 返回当前配置
+func (l *Ledger) Config() config.UsageConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cfg
+}
+
+// Cost 按价格表计算费用
+func Cost(prices config.PriceTable, promptTokens, completionTokens int, toolCalls map[string]int) float64 {
+	cost := float64(promptTokens)/1000*prices.PromptPer1KTokens +
+		float64(completionTokens)/1000*prices.CompletionPer1KTokens
+	for tool, calls := range toolCalls {
+		cost += float64(calls) * prices.ToolCalls[tool]
+	}
+	return cost
+}
+
+// Record 记录一次请求的用量，按当前价格表计算费用
+func (l *Ledger) Record(identity Identity, response *models.ChatResponse) Record {
+	record := Record{
+		APIKey:  identity.APIKey,
+		Tenant:  identity.Tenant,
+		Success: response.Success,
+	}
+	if response.Usage != nil {
+		record.PromptTokens = response.Usage.PromptTokens
+		record.CompletionTokens = response.Usage.CompletionTokens
+		record.LLMCalls = response.Usage.Calls
+	}
+	if response.Metadata != nil && len(response.Metadata.ToolCalls) > 0 {
+		record.ToolCalls = make(map[string]int, len(response.Metadata.ToolCalls))
+		for _, tool := range response.Metadata.ToolCalls {
+			record.ToolCalls[tool]++
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.Time = l.now().UTC()
+	record.Cost = Cost(l.cfg.Prices, record.PromptTokens, record.CompletionTokens, record.ToolCalls)
+	l.add(record)
+	l.prune()
+
+	if l.file != nil {
+		data, err := json.Marshal(record)
+		if err == nil {
+			_, err = l.file.Write(append(data, '\n'))
+		}
+		if err != nil {
+			l.logger.WithError(err).Error("Failed to append usage record")
+		}
+	}
+	return record
+}
+
+// add 保存记录并累计租户用量，调用方持有锁
+func (l *Ledger) add(record Record) {
+	l.records = append(l.records, record)
+	day := record.Time.UTC().Format("2006-01-02")
+	month := record.Time.UTC().Format("2006-01")
+	accumulate(l.daily, record.Tenant, day, record)
+	accumulate(l.monthly, record.Tenant, month, record)
+}
+
+// accumulate 累计一个周期的用量
+func accumulate(periods map[string]map[string]*totals, tenant, period string, record Record) {
+	byPeriod, ok := periods[tenant]
+	if !ok {
+		byPeriod = make(map[string]*totals)
+		periods[tenant] = byPeriod
+	}
+	t, ok := byPeriod[period]
+	if !ok {
+		t = &totals{}
+		byPeriod[period] = t
+	}
+	t.tokens += record.TotalTokens()
+	t.cost += record.Cost
+}
+
+// cutoff 保留期的起点
+func (l *Ledger) cutoff() time.Time {
+	days := l.cfg.RetentionDays
+	if days <= 0 {
+		days = 90
+	}
+	return l.now().UTC().AddDate(0, 0, -days)
+}
+
+// prune 清理保留期之前的记录和已经结束的统计周期，调用方持有锁
+func (l *Ledger) prune() {
+	cutoff := l.cutoff()
+	i := 0
+	for i < len(l.records) && l.records[i].Time.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		l.records = append([]Record(nil), l.records[i:]...)
+	}
+
+	now := l.now().UTC()
+	today, thisMonth := now.Format("2006-01-02"), now.Format("2006-01")
+	for _, byPeriod := range l.daily {
+		for day := range byPeriod {
+			if day < today {
+				delete(byPeriod, day)
+			}
+		}
+	}
+	for _, byPeriod := range l.monthly {
+		for month := range byPeriod {
+			if month < thisMonth {
+				delete(byPeriod, month)
+			}
+		}
+	}
+}
+
+// Check 检查租户本日、本月的用量是否已达到配额，达到时返回 *QuotaError
+// 检查在请求排队之前进行，同时在处理中的请求可能使用量略微超出配额。
+func (l *Ledger) Check(tenant string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now().UTC()
+	quota := l.cfg.QuotaFor(tenant)
+	day, month := l.periodTotals(tenant, now)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+	checks := []struct {
+		period  string
+		metric  string
+		limit   float64
+		used    float64
+		resetAt time.Time
+	}{
+		{"daily", "cost", quota.DailyCost, day.cost, tomorrow},
+		{"daily", "tokens", float64(quota.DailyTokens), float64(day.tokens), tomorrow},
+		{"monthly", "cost", quota.MonthlyCost, month.cost, nextMonth},
+		{"monthly", "tokens", float64(quota.MonthlyTokens), float64(month.tokens), nextMonth},
+	}
+	for _, check := range checks {
+		if check.limit > 0 && check.used >= check.limit {
+			return &QuotaError{
+				Tenant:  tenant,
+				Period:  check.period,
+				Metric:  check.metric,
+				Limit:   check.limit,
+				Used:    check.used,
+				ResetAt: check.resetAt,
+			}
+		}
+	}
+	return nil
+}
+
+// periodTotals 返回租户本日和本月的累计用量，调用方持有锁
+func (l *Ledger) periodTotals(tenant string, now time.Time) (totals, totals) {
+	var day, month totals
+	if t, ok := l.daily[tenant][now.Format("2006-01-02")]; ok {
+		day = *t
+	}
+	if t, ok := l.monthly[tenant][now.Format("2006-01")]; ok {
+		month = *t
+	}
+	return day, month
+}
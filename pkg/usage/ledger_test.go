@@ -0,0 +1,158 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/models"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return logger
+}
+
+func testConfig() config.UsageConfig {
+	return config.UsageConfig{
+		Enabled:       true,
+		RetentionDays: 30,
+		Currency:      "USD",
+		Prices: config.PriceTable{
+			PromptPer1KTokens:     0.01,
+			CompletionPer1KTokens: 0.03,
+			ToolCalls:             map[string]float64{"search": 0.005},
+		},
+		DefaultQuota: config.QuotaConfig{DailyTokens: 5000},
+		Quotas: map[string]config.QuotaConfig{
+			"team-a": {DailyCost: 0.05},
+		},
+	}
+}
+
+// response 构造带用量的聊天响应
+func response(prompt, completion int, tools ...string) *models.ChatResponse {
+	return &models.ChatResponse{
+		Success:  true,
+		Usage:    &models.TokenUsage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion, Calls: 1},
+		Metadata: &models.ResponseMetadata{ToolCalls: tools},
+	}
+}
+
+// stubProcessor 返回固定响应的处理器
+type stubProcessor struct {
+	response *models.ChatResponse
+}
+
+func (p *stubProcessor) ProcessRequest(ctx context.Context, query string) (*models.ChatResponse, error) {
+	return p.response, nil
+}
+
+func TestLedger_RecordAndQuota(t *testing.T) {
+	ledger, err := NewLedger(testConfig(), newTestLogger())
+	require.NoError(t, err)
+	now := time.Date(2025, 3, 31, 23, 0, 0, 0, time.UTC)
+	ledger.now = func() time.Time { return now }
+
+	record := ledger.Record(Identity{APIKey: "ci", Tenant: "team-a"}, response(1000, 500, "search"))
+	assert.InDelta(t, 0.01+0.015+0.005, record.Cost, 1e-9)
+	assert.Equal(t, map[string]int{"search": 1}, record.ToolCalls)
+	require.NoError(t, ledger.Check("team-a"))
+
+	ledger.Record(Identity{APIKey: "ci", Tenant: "team-a"}, response(3000, 0))
+	err = ledger.Check("team-a")
+	var quotaErr *QuotaError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	assert.Equal(t, "daily", quotaErr.Period)
+	assert.Equal(t, "cost", quotaErr.Metric)
+	assert.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), quotaErr.ResetAt)
+
+	// 其他租户使用默认配额
+	require.NoError(t, ledger.Check("team-b"))
+	ledger.Record(Identity{Tenant: "team-b"}, response(4000, 1000))
+	require.ErrorIs(t, ledger.Check("team-b"), ErrQuotaExceeded)
+
+	// 第二天配额重置
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, ledger.Check("team-a"))
+	require.NoError(t, ledger.Check("team-b"))
+
+	status := ledger.QuotaStatus("team-a")
+	assert.Zero(t, status.Daily.Cost)
+	assert.Zero(t, status.Monthly.Cost, "new month")
+}
+
+func TestLedger_Report(t *testing.T) {
+	ledger, err := NewLedger(testConfig(), newTestLogger())
+	require.NoError(t, err)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	ledger.now = func() time.Time { return now }
+
+	ledger.Record(Identity{APIKey: "ci", Tenant: "team-a"}, response(100, 10, "search"))
+	ledger.Record(Identity{APIKey: "web", Tenant: "team-a"}, response(200, 20, "get_weather"))
+	now = now.Add(24 * time.Hour)
+	failed := response(300, 0)
+	failed.Success = false
+	ledger.Record(Identity{APIKey: "bot", Tenant: "team-b"}, failed)
+
+	report, err := ledger.Report(ReportQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", report.Currency)
+	assert.Equal(t, 3, report.Total.Requests)
+	assert.Equal(t, 1, report.Total.Failed)
+	assert.Equal(t, 630, report.Total.TotalTokens)
+	require.Len(t, report.Groups, 2)
+	assert.Equal(t, "team-a", report.Groups[0].Key)
+	assert.Equal(t, map[string]int{"search": 1, "get_weather": 1}, report.Groups[0].ToolCalls)
+
+	report, err = ledger.Report(ReportQuery{Tenant: "team-a", GroupBy: GroupByAPIKey})
+	require.NoError(t, err)
+	require.Len(t, report.Groups, 2)
+	assert.Equal(t, "ci", report.Groups[0].Key)
+	assert.Equal(t, 110, report.Groups[0].TotalTokens)
+
+	report, err = ledger.Report(ReportQuery{GroupBy: GroupByDay, From: time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.Len(t, report.Groups, 1)
+	assert.Equal(t, "2025-03-11", report.Groups[0].Key)
+
+	_, err = ledger.Report(ReportQuery{GroupBy: "model"})
+	assert.Error(t, err)
+}
+
+func TestLedger_Persistence(t *testing.T) {
+	cfg := testConfig()
+	cfg.LedgerFile = filepath.Join(t.TempDir(), "usage", "ledger.jsonl")
+
+	ledger, err := NewLedger(cfg, newTestLogger())
+	require.NoError(t, err)
+	meter := NewMeter(&stubProcessor{response: response(1000, 500, "search")}, ledger, newTestLogger())
+	ctx := WithIdentity(context.Background(), Identity{APIKey: "ci", Tenant: "team-a"})
+	_, err = meter.ProcessRequest(ctx, "北京天气")
+	require.NoError(t, err)
+	_, err = meter.ProcessRequest(context.Background(), "上海天气")
+	require.NoError(t, err)
+	require.NoError(t, ledger.Close())
+
+	// 重新打开后恢复记录和当日用量
+	reopened, err := NewLedger(cfg, newTestLogger())
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	report, err := reopened.Report(ReportQuery{})
+	require.NoError(t, err)
+	require.Len(t, report.Groups, 2)
+	assert.Equal(t, DefaultTenant, report.Groups[0].Key)
+	assert.Equal(t, "team-a", report.Groups[1].Key)
+	assert.InDelta(t, 0.03, report.Groups[1].Cost, 1e-9)
+	assert.InDelta(t, 0.03, reopened.QuotaStatus("team-a").Daily.Cost, 1e-9)
+}
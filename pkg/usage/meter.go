@@ -0,0 +1,47 @@
+package usage
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/models"
+)
+
+// Processor 处理查询并在响应中返回用量的处理器（工作流）
+type Processor interface {
+	ProcessRequest(ctx context.Context, query string) (*models.ChatResponse, error)
+}
+
+// Meter 包装请求处理器，每个请求处理完成后按上下文中的计费身份记账
+// 在队列工作协程中记账，调用方等待超时放弃的请求同样会被记录。
+type Meter struct {
+	next   Processor
+	ledger *Ledger
+	logger *logrus.Logger
+}
+
+// NewMeter 创建记账处理器
+func NewMeter(next Processor, ledger *Ledger, logger *logrus.Logger) *Meter {
+	return &Meter{next: next, ledger: ledger, logger: logger}
+}
+
+// ProcessRequest 处理请求并记录用量
+func (m *Meter) ProcessRequest(ctx context.Context, query string) (*models.ChatResponse, error) {
+	response, err := m.next.ProcessRequest(ctx, query)
+	if response == nil {
+		return response, err
+	}
+
+	identity := identityFromContext(ctx)
+	record := m.ledger.Record(identity, response)
+	m.logger.WithFields(logrus.Fields{
+		"tenant":            record.Tenant,
+		"api_key":           record.APIKey,
+		"prompt_tokens":     record.PromptTokens,
+		"completion_tokens": record.CompletionTokens,
+		"tool_calls":        record.ToolCalls,
+		"cost":              record.Cost,
+	}).Debug("Usage recorded")
+	return response, err
+}
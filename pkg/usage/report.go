@@ -0,0 +1,163 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"deer-flow-go/pkg/config"
+)
+
+// 报表分组方式
+const (
+	GroupByTenant = "tenant"
+	GroupByAPIKey = "api_key"
+	GroupByDay    = "day"
+)
+
+// ReportQuery 报表查询条件，空字段表示不过滤
+type ReportQuery struct {
+	From    time.Time // 包含
+	To      time.Time // 不包含，零值表示截至当前时间
+	Tenant  string
+	APIKey  string
+	GroupBy string // tenant | api_key | day，默认 tenant
+}
+
+// Summary 一组记录的用量汇总
+type Summary struct {
+	Key              string         `json:"key,omitempty"`
+	Requests         int            `json:"requests"`
+	Failed           int            `json:"failed"`
+	PromptTokens     int            `json:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens"`
+	TotalTokens      int            `json:"total_tokens"`
+	LLMCalls         int            `json:"llm_calls"`
+	ToolCalls        map[string]int `json:"tool_calls"`
+	Cost             float64        `json:"cost"`
+}
+
+// add 累加一条记录
+func (s *Summary) add(record Record) {
+	s.Requests++
+	if !record.Success {
+		s.Failed++
+	}
+	s.PromptTokens += record.PromptTokens
+	s.CompletionTokens += record.CompletionTokens
+	s.TotalTokens += record.TotalTokens()
+	s.LLMCalls += record.LLMCalls
+	for tool, calls := range record.ToolCalls {
+		s.ToolCalls[tool] += calls
+	}
+	s.Cost += record.Cost
+}
+
+// Report 用量报表
+type Report struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Currency string    `json:"currency"`
+	GroupBy  string    `json:"group_by"`
+	Total    Summary   `json:"total"`
+	Groups   []Summary `json:"groups"`
+}
+
+// PeriodUsage 一个统计周期内的用量
+type PeriodUsage struct {
+	Tokens  int       `json:"tokens"`
+	Cost    float64   `json:"cost"`
+	ResetAt time.Time `json:"reset_at"`
+}
+
+// QuotaStatus 租户的配额和本日、本月已用量
+type QuotaStatus struct {
+	Tenant  string             `json:"tenant"`
+	Quota   config.QuotaConfig `json:"quota"`
+	Daily   PeriodUsage        `json:"daily"`
+	Monthly PeriodUsage        `json:"monthly"`
+}
+
+// Report 按条件汇总保留期内的记录
+func (l *Ledger) Report(query ReportQuery) (*Report, error) {
+	groupBy := query.GroupBy
+	if groupBy == "" {
+		groupBy = GroupByTenant
+	}
+	var keyOf func(Record) string
+	switch groupBy {
+	case GroupByTenant:
+		keyOf = func(r Record) string { return r.Tenant }
+	case GroupByAPIKey:
+		keyOf = func(r Record) string { return r.APIKey }
+	case GroupByDay:
+		keyOf = func(r Record) string { return r.Time.UTC().Format("2006-01-02") }
+	default:
+		return nil, fmt.Errorf("unknown group_by %q, must be one of tenant, api_key, day", groupBy)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	to := query.To
+	if to.IsZero() {
+		to = l.now().UTC().Add(time.Nanosecond)
+	}
+	report := &Report{
+		From:     query.From,
+		To:       to,
+		Currency: l.cfg.Currency,
+		GroupBy:  groupBy,
+		Total:    Summary{ToolCalls: map[string]int{}},
+		Groups:   []Summary{},
+	}
+
+	groups := make(map[string]*Summary)
+	for _, record := range l.records {
+		if record.Time.Before(query.From) || !record.Time.Before(to) {
+			continue
+		}
+		if (query.Tenant != "" && record.Tenant != query.Tenant) || (query.APIKey != "" && record.APIKey != query.APIKey) {
+			continue
+		}
+		key := keyOf(record)
+		group, ok := groups[key]
+		if !ok {
+			group = &Summary{Key: key, ToolCalls: map[string]int{}}
+			groups[key] = group
+		}
+		group.add(record)
+		report.Total.add(record)
+	}
+
+	for _, group := range groups {
+		report.Groups = append(report.Groups, *group)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Key < report.Groups[j].Key
+	})
+	return report, nil
+}
+
+// QuotaStatus 返回租户的配额和本日、本月已用量
+func (l *Ledger) QuotaStatus(tenant string) QuotaStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now().UTC()
+	day, month := l.periodTotals(tenant, now)
+	return QuotaStatus{
+		Tenant: tenant,
+		Quota:  l.cfg.QuotaFor(tenant),
+		Daily: PeriodUsage{
+			Tokens:  day.tokens,
+			Cost:    day.cost,
+			ResetAt: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC),
+		},
+		Monthly: PeriodUsage{
+			Tokens:  month.tokens,
+			Cost:    month.cost,
+			ResetAt: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package weather
+
+import (
+	"context"
+	"math"
+)
+
+// AirQuality 空气质量，污染物浓度单位为 μg/m³
+type AirQuality struct {
+	Location  string  `json:"location"`
+	AQI       int     `json:"aqi"`                 // 美国EPA标准AQI（0-500）
+	Estimated bool    `json:"estimated,omitempty"` // AQI 由 PM2.5/PM10 浓度估算，而非提供方直接给出
+	PM25      float64 `json:"pm2_5"`
+	PM10      float64 `json:"pm10"`
+	O3        float64 `json:"o3"`
+	NO2       float64 `json:"no2"`
+	SO2       float64 `json:"so2"`
+	CO        float64 `json:"co"`
+	Timestamp string  `json:"timestamp"` // RFC3339，带当地UTC偏移
+}
+
+// AirQualityProvider 支持查询空气质量的提供方
+type AirQualityProvider interface {
+	Provider
+	// AirQuality 获取指定地点当前的空气质量
+	AirQuality(ctx context.Context, loc Location, opts Options) (*AirQuality, error)
+}
+
+// aqiBreakpoint 浓度区间与AQI区间的对应关系
+type aqiBreakpoint struct {
+	cLow, cHigh float64
+	iLow, iHigh int
+}
+
+// EPA 2024 年修订的 PM2.5 和 PM10 分段（24小时平均浓度，μg/m³）
+var (
+	pm25Breakpoints = []aqiBreakpoint{
+		{0, 9.0, 0, 50},
+		{9.1, 35.4, 51, 100},
+		{35.5, 55.4, 101, 150},
+		{55.5, 125.4, 151, 200},
+		{125.5, 225.4, 201, 300},
+		{225.5, 325.4, 301, 500},
+	}
+	pm10Breakpoints = []aqiBreakpoint{
+		{0, 54, 0, 50},
+		{55, 154, 51, 100},
+		{155, 254, 101, 150},
+		{255, 354, 151, 200},
+		{355, 424, 201, 300},
+		{425, 604, 301, 500},
+	}
+)
+
+// subIndex 按分段线性插值计算单项污染物的AQI，超出最高分段时返回500
+func subIndex(concentration float64, breakpoints []aqiBreakpoint) int {
+	for _, bp := range breakpoints {
+		if concentration <= bp.cHigh {
+			c := math.Max(concentration, bp.cLow)
+			return int(math.Round(float64(bp.iHigh-bp.iLow)/(bp.cHigh-bp.cLow)*(c-bp.cLow) + float64(bp.iLow)))
+		}
+	}
+	return 500
+}
+
+// estimateAQI 由 PM2.5 和 PM10 浓度估算AQI，取两者中的较大值
+// 提供方只给出实时浓度时使用，EPA 标准要求的是24小时平均值，因此结果只是近似值。
+func estimateAQI(pm25, pm10 float64) int {
+	pm25 = math.Floor(pm25*10) / 10 // EPA 规定 PM2.5 截断到0.1，PM10 截断到整数
+	pm10 = math.Floor(pm10)
+	a, b := subIndex(pm25, pm25Breakpoints), subIndex(pm10, pm10Breakpoints)
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// aqiCategory 返回AQI所属的等级（0-5）：优、中等、对敏感人群不健康、不健康、非常不健康、危险
+func aqiCategory(aqi int) int {
+	switch {
+	case aqi <= 50:
+		return 0
+	case aqi <= 100:
+		return 1
+	case aqi <= 150:
+		return 2
+	case aqi <= 200:
+		return 3
+	case aqi <= 300:
+		return 4
+	default:
+		return 5
+	}
+}
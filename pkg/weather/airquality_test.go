@@ -0,0 +1,143 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+)
+
+// newFixtureServer 按请求路径返回 testdata 中录制的接口响应
+func newFixtureServer(t *testing.T, fixtures map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		require.NoError(t, err)
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var beijing = Location{Name: "北京市", Country: "中国", Latitude: 39.9075, Longitude: 116.3972, Timezone: "Asia/Shanghai"}
+
+func TestWeatherClient_CurrentAstronomy(t *testing.T) {
+	srv := newFixtureServer(t, map[string]string{"/weather": "owm_weather.json"})
+	client := NewWeatherClient(&WeatherConfig{APIKey: "owm-key", BaseURL: srv.URL, Timeout: 5}, newTestLogger())
+
+	data, err := client.Current(context.Background(), beijing, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 32.1, data.FeelsLike)
+	assert.Equal(t, "2024-06-01T04:46:00+08:00", data.Sunrise)
+	assert.Equal(t, "2024-06-01T19:38:00+08:00", data.Sunset)
+
+	text := FormatCurrent(data, Options{})
+	assert.Contains(t, text, "🤒 体感温度: 32.1°C")
+	assert.Contains(t, text, "🌅 日出: 04:46  🌇 日落: 19:38")
+}
+
+func TestOpenMeteoClient_CurrentAstronomy(t *testing.T) {
+	srv := newFixtureServer(t, map[string]string{"/forecast": "openmeteo_current.json"})
+	client := NewOpenMeteoClient(&config.OpenMeteoConfig{BaseURL: srv.URL}, 5*time.Second, newTestLogger())
+
+	data, err := client.Current(context.Background(), beijing, Options{Lang: LangEn})
+	require.NoError(t, err)
+	assert.Equal(t, 29.7, data.FeelsLike)
+	assert.Equal(t, "partly cloudy", data.Description)
+	assert.Equal(t, "2024-06-01T04:46:00+08:00", data.Sunrise)
+	assert.Equal(t, "2024-06-01T19:38:00+08:00", data.Sunset)
+}
+
+func TestAirQuality(t *testing.T) {
+	owm := newFixtureServer(t, map[string]string{"/air_pollution": "owm_air_pollution.json"})
+	client := NewWeatherClient(&WeatherConfig{APIKey: "owm-key", BaseURL: owm.URL, Timeout: 5}, newTestLogger())
+
+	// OpenWeatherMap 只有1-5级指数，按PM2.5/PM10估算美国AQI
+	aq, err := client.AirQuality(context.Background(), beijing, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 89, aq.AQI)
+	assert.True(t, aq.Estimated)
+	assert.Equal(t, 29.4, aq.PM25)
+	assert.Equal(t, "2024-06-01T12:00:00+08:00", aq.Timestamp)
+
+	text := FormatAirQuality(aq, Options{})
+	assert.Contains(t, text, "89 - 中等（按PM2.5/PM10估算）")
+	assert.Contains(t, text, "可以正常户外运动")
+
+	aqSrv := newFixtureServer(t, map[string]string{"/air-quality": "openmeteo_air_quality.json"})
+	openMeteo := NewOpenMeteoClient(&config.OpenMeteoConfig{AirQualityURL: aqSrv.URL}, 5*time.Second, newTestLogger())
+	aq, err = openMeteo.AirQuality(context.Background(), beijing, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 156, aq.AQI)
+	assert.False(t, aq.Estimated)
+
+	text = FormatAirQuality(aq, Options{Lang: LangEn})
+	assert.Contains(t, text, "156 - Unhealthy")
+	assert.Contains(t, text, "sensitive groups should avoid it")
+}
+
+func TestEstimateAQI(t *testing.T) {
+	assert.Equal(t, 0, estimateAQI(0, 0))
+	assert.Equal(t, 50, estimateAQI(9.0, 10))
+	assert.Equal(t, 100, estimateAQI(35.4, 10))
+	// PM10 较高时以 PM10 为准
+	assert.Equal(t, 151, estimateAQI(5, 255))
+	assert.Equal(t, 500, estimateAQI(600, 0))
+	assert.Equal(t, 5, aqiCategory(350))
+}
+
+func TestAlerts(t *testing.T) {
+	geoSrv := newGeocodingServer(t)
+	oneCall := newFixtureServer(t, map[string]string{"/onecall": "owm_onecall_alerts.json"})
+	cfg := &config.WeatherConfig{
+		Provider:   config.WeatherProviderOpenMeteo,
+		APIKey:     "owm-key",
+		BaseURL:    "http://127.0.0.1:0",
+		Timeout:    5,
+		OneCallURL: oneCall.URL,
+		OpenMeteo:  config.OpenMeteoConfig{BaseURL: "http://127.0.0.1:0"},
+		Geocoding:  config.GeocodingConfig{BaseURL: geoSrv.URL, Language: "zh"},
+	}
+	service, err := NewService(cfg, newTestLogger())
+	require.NoError(t, err)
+	assert.True(t, service.SupportsAlerts())
+
+	// 默认提供方 Open-Meteo 不支持预警时自动使用 OpenWeatherMap
+	alerts, err := service.Alerts(context.Background(), "", "北京", Options{})
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	assert.Equal(t, "台风橙色预警", alerts[0].Event)
+	assert.Equal(t, "深圳市气象台", alerts[0].Sender)
+	assert.Equal(t, "2024-06-01T12:00:00+08:00", alerts[0].Start)
+	assert.Equal(t, []string{"Wind", "Flood"}, alerts[0].Tags)
+
+	text := FormatAlerts("深圳市, 中国", alerts, Options{})
+	assert.Contains(t, text, "当前有 2 条生效的气象预警")
+	assert.Contains(t, text, "1. 台风橙色预警 (深圳市气象台)")
+	assert.Contains(t, text, "06-01 12:00 ~ 06-02 12:00 (UTC+08:00)")
+	assert.Contains(t, FormatAlerts("深圳市, 中国", nil, Options{}), "目前没有生效的气象预警")
+
+	// 显式指定不支持预警的提供方时报错
+	_, err = service.Alerts(context.Background(), config.WeatherProviderOpenMeteo, "北京", Options{})
+	assert.True(t, errors.Is(err, ErrNotSupported))
+
+	// 没有 OpenWeatherMap 密钥时没有提供方支持预警
+	cfg.APIKey = ""
+	service, err = NewService(cfg, newTestLogger())
+	require.NoError(t, err)
+	assert.False(t, service.SupportsAlerts())
+}
@@ -0,0 +1,20 @@
+package weather
+
+import "context"
+
+// Alert 气象预警，内容由发布机构提供，语言取决于发布机构
+type Alert struct {
+	Event       string   `json:"event"`
+	Sender      string   `json:"sender,omitempty"`
+	Start       string   `json:"start"` // RFC3339，带当地UTC偏移
+	End         string   `json:"end"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// AlertProvider 支持查询气象预警的提供方
+type AlertProvider interface {
+	Provider
+	// Alerts 获取指定地点当前生效的气象预警，没有预警时返回空列表
+	Alerts(ctx context.Context, loc Location, opts Options) ([]Alert, error)
+}
@@ -0,0 +1,114 @@
+package weather
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// ForecastModeDaily 逐日预报
+	ForecastModeDaily = "daily"
+	// ForecastModeHourly 逐小时预报
+	ForecastModeHourly = "hourly"
+
+	// DefaultForecastHours 逐小时预报的默认小时数
+	DefaultForecastHours = 24
+	// MaxForecastHours 逐小时预报的最大小时数，避免返回给LLM的文本过长
+	MaxForecastHours = 48
+)
+
+// DailyForecast 逐日预报，由当天（当地日期）所有时段的数据汇总得到
+type DailyForecast struct {
+	Location                 string  `json:"location"`
+	Date                     string  `json:"date"` // 当地日期 YYYY-MM-DD
+	TempMin                  float64 `json:"temp_min"`
+	TempMax                  float64 `json:"temp_max"`
+	Description              string  `json:"description"` // 当天的主要天气
+	Humidity                 int     `json:"humidity"`    // 平均相对湿度
+	WindSpeedMax             float64 `json:"wind_speed_max"`
+	Precipitation            float64 `json:"precipitation"`             // 降水总量（mm）
+	PrecipitationProbability int     `json:"precipitation_probability"` // 最大降水概率（%）
+}
+
+// HourlyForecast 逐小时预报，OpenWeatherMap 的时间间隔为3小时
+type HourlyForecast struct {
+	Location                 string  `json:"location"`
+	Time                     string  `json:"time"` // RFC3339，带当地UTC偏移
+	Temperature              float64 `json:"temperature"`
+	Description              string  `json:"description"`
+	Humidity                 int     `json:"humidity"`
+	WindSpeed                float64 `json:"wind_speed"`
+	Precipitation            float64 `json:"precipitation"`             // 该时段降水量（mm）
+	PrecipitationProbability int     `json:"precipitation_probability"` // 降水概率（%）
+}
+
+// forecastSlot 一个预报时段的数据，用于汇总逐日预报
+type forecastSlot struct {
+	Time                     time.Time // 当地时间
+	Temperature              float64
+	TempMin                  float64
+	TempMax                  float64
+	Description              string
+	Humidity                 int
+	WindSpeed                float64
+	Precipitation            float64
+	PrecipitationProbability float64 // 0-1
+}
+
+// aggregateDaily 将预报时段按当地日期汇总为逐日预报，返回前 days 天（包括只剩部分时段的今天）
+// 主要天气取当天出现次数最多的描述，次数相同时取较早出现的。
+func aggregateDaily(location string, slots []forecastSlot, days int) []DailyForecast {
+	var forecasts []DailyForecast
+	var order []string
+	var counts map[string]int
+	var humiditySum, n int
+
+	for _, slot := range slots {
+		date := slot.Time.Format("2006-01-02")
+		if len(forecasts) == 0 || forecasts[len(forecasts)-1].Date != date {
+			if len(forecasts) == days {
+				break
+			}
+			forecasts = append(forecasts, DailyForecast{
+				Location: location,
+				Date:     date,
+				TempMin:  slot.TempMin,
+				TempMax:  slot.TempMax,
+			})
+			order = nil
+			counts = make(map[string]int)
+			humiditySum, n = 0, 0
+		}
+
+		day := &forecasts[len(forecasts)-1]
+		day.TempMin = math.Min(day.TempMin, slot.TempMin)
+		day.TempMax = math.Max(day.TempMax, slot.TempMax)
+		day.WindSpeedMax = math.Max(day.WindSpeedMax, slot.WindSpeed)
+		day.Precipitation += slot.Precipitation
+		if p := int(math.Round(slot.PrecipitationProbability * 100)); p > day.PrecipitationProbability {
+			day.PrecipitationProbability = p
+		}
+
+		if _, seen := counts[slot.Description]; !seen {
+			order = append(order, slot.Description)
+		}
+		counts[slot.Description]++
+		day.Description = dominant(order, counts)
+
+		humiditySum += slot.Humidity
+		n++
+		day.Humidity = int(math.Round(float64(humiditySum) / float64(n)))
+	}
+	return forecasts
+}
+
+// dominant 返回出现次数最多的描述，次数相同时取 order 中较早的
+func dominant(order []string, counts map[string]int) string {
+	best := order[0]
+	for _, desc := range order[1:] {
+		if counts[desc] > counts[best] {
+			best = desc
+		}
+	}
+	return best
+}
@@ -0,0 +1,243 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// labels 工具返回文本中的固定文案
+type labels struct {
+	current      string // 参数：地点
+	forecast     string // 参数：地点、天数
+	hourly       string // 参数：地点、时区
+	temperature  string
+	conditions   string
+	humidity     string
+	wind         string
+	windMax      string
+	precip       string // 参数：降水量、单位、概率
+	observedAt   string
+	unknownValue string
+	feelsLike    string
+	sunrise      string
+	sunset       string
+
+	airQuality  string // 参数：地点
+	aqi         string
+	aqiEstimate string
+	aqiLevels   [6]string
+	aqiAdvice   [6]string
+	advice      string
+	pollutants  string
+	updatedAt   string
+	alerts      string // 参数：地点、预警数量
+	noAlerts    string // 参数：地点
+	validTime   string
+}
+
+var labelsByLang = map[string]labels{
+	LangZh: {
+		current:      "🌤️ %[1]s 当前天气:",
+		forecast:     "📅 %[1]s %[2]d天天气预报:",
+		hourly:       "🕒 %[1]s 逐小时天气预报（%[2]s）:",
+		temperature:  "🌡️ 温度",
+		conditions:   "☁️ 天气",
+		humidity:     "💧 湿度",
+		wind:         "💨 风速",
+		windMax:      "💨 最大风速",
+		precip:       "🌧️ 降水: %.1f %s（概率 %d%%）",
+		observedAt:   "⏰ 观测时间",
+		unknownValue: "未知",
+		feelsLike:    "🤒 体感温度",
+		sunrise:      "🌅 日出",
+		sunset:       "🌇 日落",
+
+		airQuality:  "🌫️ %[1]s 空气质量:",
+		aqi:         "📊 AQI（美国标准）",
+		aqiEstimate: "（按PM2.5/PM10估算）",
+		aqiLevels:   [6]string{"优", "中等", "对敏感人群不健康", "不健康", "非常不健康", "危险"},
+		aqiAdvice: [6]string{
+			"空气质量令人满意，适合户外运动",
+			"可以正常户外运动，极少数对污染异常敏感的人应减少长时间剧烈运动",
+			"儿童、老人及心肺疾病患者应减少长时间或剧烈的户外运动，其他人可以正常活动",
+			"所有人应减少长时间或剧烈的户外运动，敏感人群应避免户外运动",
+			"所有人应避免户外运动，敏感人群应留在室内",
+			"所有人应避免户外活动，关闭门窗",
+		},
+		advice:     "🏃 建议",
+		pollutants: "🧪 污染物",
+		updatedAt:  "⏰ 更新时间",
+		alerts:     "⚠️ %[1]s 当前有 %[2]d 条生效的气象预警:",
+		noAlerts:   "✅ %[1]s 目前没有生效的气象预警",
+		validTime:  "⏰ 有效时间",
+	},
+	LangEn: {
+		current:      "🌤️ Current weather in %[1]s:",
+		forecast:     "📅 %[2]d-day forecast for %[1]s:",
+		hourly:       "🕒 Hourly forecast for %[1]s (%[2]s):",
+		temperature:  "🌡️ Temperature",
+		conditions:   "☁️ Conditions",
+		humidity:     "💧 Humidity",
+		wind:         "💨 Wind",
+		windMax:      "💨 Max wind",
+		precip:       "🌧️ Precipitation: %.1f %s (%d%% chance)",
+		observedAt:   "⏰ Observed at",
+		unknownValue: "unknown",
+		feelsLike:    "🤒 Feels like",
+		sunrise:      "🌅 Sunrise",
+		sunset:       "🌇 Sunset",
+
+		airQuality:  "🌫️ Air quality in %[1]s:",
+		aqi:         "📊 AQI (US EPA)",
+		aqiEstimate: " (estimated from PM2.5/PM10)",
+		aqiLevels:   [6]string{"Good", "Moderate", "Unhealthy for sensitive groups", "Unhealthy", "Very unhealthy", "Hazardous"},
+		aqiAdvice: [6]string{
+			"Air quality is satisfactory, a good time to exercise outdoors",
+			"Fine for outdoor exercise; unusually sensitive people should limit prolonged exertion",
+			"Children, older adults and people with heart or lung disease should limit prolonged or heavy outdoor exertion",
+			"Everyone should limit prolonged or heavy outdoor exertion; sensitive groups should avoid it",
+			"Everyone should avoid outdoor exertion; sensitive groups should stay indoors",
+			"Everyone should avoid all outdoor activity and keep windows closed",
+		},
+		advice:     "🏃 Advice",
+		pollutants: "🧪 Pollutants",
+		updatedAt:  "⏰ Updated at",
+		alerts:     "⚠️ %[2]d active weather alert(s) for %[1]s:",
+		noAlerts:   "✅ No active weather alerts for %[1]s",
+		validTime:  "⏰ Valid",
+	},
+}
+
+// unitLabels 各单位制的温度、风速和降水单位
+var unitLabels = map[string]struct{ temp, speed, precip string }{
+	UnitsMetric:   {"°C", "m/s", "mm"},
+	UnitsImperial: {"°F", "mph", "in"},
+}
+
+// FormatCurrent 将当前天气格式化为工具返回文本
+func FormatCurrent(data *WeatherData, opts Options) string {
+	l, u := labelsByLang[opts.lang()], unitLabels[opts.units()]
+	lines := []string{
+		fmt.Sprintf(l.current, data.Location),
+		fmt.Sprintf("%s: %.1f%s", l.temperature, data.Temperature, u.temp),
+		fmt.Sprintf("%s: %.1f%s", l.feelsLike, data.FeelsLike, u.temp),
+		fmt.Sprintf("%s: %s", l.conditions, data.Description),
+		fmt.Sprintf("%s: %d%%", l.humidity, data.Humidity),
+		fmt.Sprintf("%s: %.1f %s", l.wind, data.WindSpeed, u.speed),
+	}
+	if data.Sunrise != "" && data.Sunset != "" {
+		lines = append(lines, fmt.Sprintf("%s: %s  %s: %s", l.sunrise, formatLocalTime(data.Sunrise, "15:04", l.unknownValue, false),
+			l.sunset, formatLocalTime(data.Sunset, "15:04", l.unknownValue, false)))
+	}
+	lines = append(lines, fmt.Sprintf("%s: %s", l.observedAt, formatLocalTime(data.Timestamp, "2006-01-02 15:04", l.unknownValue, true)))
+	return strings.Join(lines, "\n")
+}
+
+// FormatDailyForecast 将逐日预报格式化为工具返回文本
+func FormatDailyForecast(forecasts []DailyForecast, opts Options) string {
+	l, u := labelsByLang[opts.lang()], unitLabels[opts.units()]
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(l.forecast, forecasts[0].Location, len(forecasts)) + "\n")
+	for _, day := range forecasts {
+		sb.WriteString(fmt.Sprintf("\n%s:\n", day.Date))
+		sb.WriteString(fmt.Sprintf("%s: %.1f ~ %.1f%s\n", l.temperature, day.TempMin, day.TempMax, u.temp))
+		sb.WriteString(fmt.Sprintf("%s: %s\n", l.conditions, day.Description))
+		sb.WriteString(fmt.Sprintf(l.precip+"\n", day.Precipitation, u.precip, day.PrecipitationProbability))
+		sb.WriteString(fmt.Sprintf("%s: %d%%\n", l.humidity, day.Humidity))
+		sb.WriteString(fmt.Sprintf("%s: %.1f %s\n", l.windMax, day.WindSpeedMax, u.speed))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// FormatHourlyForecast 将逐小时预报格式化为工具返回文本，每个时段一行
+func FormatHourlyForecast(forecasts []HourlyForecast, opts Options) string {
+	l, u := labelsByLang[opts.lang()], unitLabels[opts.units()]
+	zone := l.unknownValue
+	if t, err := time.Parse(time.RFC3339, forecasts[0].Time); err == nil {
+		_, offset := t.Zone()
+		zone = formatUTCOffset(offset)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(l.hourly, forecasts[0].Location, zone) + "\n")
+	for _, hour := range forecasts {
+		sb.WriteString(fmt.Sprintf("%s  %.1f%s  %s  💧%d%%  💨%.1f %s  🌧️%.1f %s (%d%%)\n",
+			formatLocalTime(hour.Time, "01-02 15:04", l.unknownValue, false),
+			hour.Temperature, u.temp, hour.Description, hour.Humidity, hour.WindSpeed, u.speed,
+			hour.Precipitation, u.precip, hour.PrecipitationProbability))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// FormatAirQuality 将空气质量格式化为工具返回文本，包含等级和户外活动建议
+func FormatAirQuality(aq *AirQuality, opts Options) string {
+	l := labelsByLang[opts.lang()]
+	category := aqiCategory(aq.AQI)
+	aqi := fmt.Sprintf("%s: %d - %s", l.aqi, aq.AQI, l.aqiLevels[category])
+	if aq.Estimated {
+		aqi += l.aqiEstimate
+	}
+	lines := []string{
+		fmt.Sprintf(l.airQuality, aq.Location),
+		aqi,
+		fmt.Sprintf("%s: %s", l.advice, l.aqiAdvice[category]),
+		fmt.Sprintf("%s (μg/m³): PM2.5 %.1f, PM10 %.1f, O₃ %.1f, NO₂ %.1f, SO₂ %.1f, CO %.1f",
+			l.pollutants, aq.PM25, aq.PM10, aq.O3, aq.NO2, aq.SO2, aq.CO),
+		fmt.Sprintf("%s: %s", l.updatedAt, formatLocalTime(aq.Timestamp, "2006-01-02 15:04", l.unknownValue, true)),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maxAlertDescriptionRunes 预警描述保留的最大字符数
+const maxAlertDescriptionRunes = 500
+
+// FormatAlerts 将气象预警格式化为工具返回文本，没有预警时明确说明
+func FormatAlerts(location string, alerts []Alert, opts Options) string {
+	l := labelsByLang[opts.lang()]
+	if len(alerts) == 0 {
+		return fmt.Sprintf(l.noAlerts, location)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(l.alerts, location, len(alerts)) + "\n")
+	for i, alert := range alerts {
+		sb.WriteString(fmt.Sprintf("\n%d. %s", i+1, alert.Event))
+		if alert.Sender != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", alert.Sender))
+		}
+		sb.WriteString(fmt.Sprintf("\n%s: %s ~ %s\n", l.validTime,
+			formatLocalTime(alert.Start, "01-02 15:04", l.unknownValue, false),
+			formatLocalTime(alert.End, "01-02 15:04", l.unknownValue, true)))
+		if alert.Description != "" {
+			sb.WriteString(truncateRunes(alert.Description, maxAlertDescriptionRunes) + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// truncateRunes 按字符截断文本，超出时追加省略号
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:max])) + "..."
+}
+
+// formatLocalTime 将RFC3339时间按其自带的UTC偏移格式化，withZone 为 true 时附加 "(UTC+08:00)"
+func formatLocalTime(value, layout, unknown string, withZone bool) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if value == "" {
+			return unknown
+		}
+		return value
+	}
+	text := t.Format(layout)
+	if withZone {
+		_, offset := t.Zone()
+		text += fmt.Sprintf(" (%s)", formatUTCOffset(offset))
+	}
+	return text
+}
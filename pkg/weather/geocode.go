@@ -0,0 +1,282 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+)
+
+const (
+	// geocodeCandidates 每次地名查询返回的候选数量
+	geocodeCandidates = 10
+	// dominanceRatio 人口最多的候选达到第二名的该倍数时直接采用，否则视为有歧义
+	dominanceRatio = 5
+	// maxGeocodeCacheEntries 地名解析结果缓存的最大条目数
+	maxGeocodeCacheEntries = 1000
+)
+
+// ErrLocationNotFound 找不到匹配的地点
+var ErrLocationNotFound = errors.New("location not found")
+
+// coordinatesPattern 直接以 "纬度,经度" 形式给出的坐标
+var coordinatesPattern = regexp.MustCompile(`^\s*(-?\d{1,2}(?:\.\d+)?)\s*[,，]\s*(-?\d{1,3}(?:\.\d+)?)\s*$`)
+
+// Location 地名解析得到的地点
+type Location struct {
+	Name        string  `json:"name"`
+	Admin1      string  `json:"admin1,omitempty"` // 一级行政区（省、州）
+	Admin2      string  `json:"admin2,omitempty"` // 二级行政区（市、县）
+	Country     string  `json:"country,omitempty"`
+	CountryCode string  `json:"country_code,omitempty"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Timezone    string  `json:"timezone,omitempty"`
+	Population  int     `json:"population,omitempty"`
+}
+
+// DisplayName 返回用于展示的地点名称，如 "Springfield, Illinois, United States"
+func (l Location) DisplayName() string {
+	parts := []string{l.Name}
+	for _, part := range []string{l.Admin1, l.Country} {
+		if part != "" && part != parts[len(parts)-1] {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AmbiguousLocationError 地名对应多个地点且无法确定时返回，包含候选地点
+type AmbiguousLocationError struct {
+	Query      string
+	Candidates []Location
+}
+
+func (e *AmbiguousLocationError) Error() string {
+	return fmt.Sprintf("location %q is ambiguous: %d candidates", e.Query, len(e.Candidates))
+}
+
+// geocodingResponse Open-Meteo Geocoding API响应结构
+type geocodingResponse struct {
+	Results []struct {
+		Name        string  `json:"name"`
+		Latitude    float64 `json:"latitude"`
+		Longitude   float64 `json:"longitude"`
+		CountryCode string  `json:"country_code"`
+		Country     string  `json:"country"`
+		Admin1      string  `json:"admin1"`
+		Admin2      string  `json:"admin2"`
+		Timezone    string  `json:"timezone"`
+		Population  int     `json:"population"`
+	} `json:"results"`
+}
+
+// Geocoder 地名解析器，基于 Open-Meteo Geocoding API，支持任意语言的地名
+type Geocoder struct {
+	baseURL    string
+	language   string
+	httpClient *http.Client
+	logger     *logrus.Logger
+
+	mu    sync.Mutex
+	cache map[string]Location
+}
+
+// NewGeocoder 创建地名解析器
+func NewGeocoder(cfg *config.GeocodingConfig, timeout time.Duration, logger *logrus.Logger) *Geocoder {
+	return &Geocoder{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		language:   cfg.Language,
+		httpClient: &http.Client{Timeout: timeout},
+		logger:     logger,
+		cache:      make(map[string]Location),
+	}
+}
+
+// Search 按名称查询候选地点，按相关性和人口排序，language 为空时使用配置的语言
+func (g *Geocoder) Search(ctx context.Context, name string, count int, language string) ([]Location, error) {
+	if language == "" {
+		language = g.language
+	}
+
+	params := url.Values{}
+	params.Set("name", name)
+	params.Set("count", strconv.Itoa(count))
+	params.Set("language", language)
+	params.Set("format", "json")
+
+	var resp geocodingResponse
+	if err := getJSON(ctx, g.httpClient, g.baseURL+"/search?"+params.Encode(), "Geocoding", &resp); err != nil {
+		return nil, err
+	}
+
+	locations := make([]Location, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		locations = append(locations, Location{
+			Name:        r.Name,
+			Admin1:      r.Admin1,
+			Admin2:      r.Admin2,
+			Country:     r.Country,
+			CountryCode: r.CountryCode,
+			Latitude:    r.Latitude,
+			Longitude:   r.Longitude,
+			Timezone:    r.Timezone,
+			Population:  r.Population,
+		})
+	}
+	return locations, nil
+}
+
+// Resolve 将地名解析为唯一的地点，返回的地名使用 language 语言（为空时使用配置的语言）
+// 支持 "纬度,经度" 坐标和 "地名, 省/州/国家" 形式的限定；多个候选无法区分时返回 *AmbiguousLocationError。
+func (g *Geocoder) Resolve(ctx context.Context, place, language string) (Location, error) {
+	place = strings.TrimSpace(place)
+	if place == "" {
+		return Location{}, fmt.Errorf("place name cannot be empty")
+	}
+	if loc, ok := parseCoordinates(place); ok {
+		return loc, nil
+	}
+
+	key := language + "|" + strings.ToLower(place)
+	g.mu.Lock()
+	loc, ok := g.cache[key]
+	g.mu.Unlock()
+	if ok {
+		return loc, nil
+	}
+
+	name, qualifiers := splitQualifiers(place)
+	candidates, err := g.Search(ctx, name, geocodeCandidates, language)
+	if err != nil {
+		return Location{}, err
+	}
+	if len(qualifiers) > 0 {
+		// 限定词可能与返回的地名语言不一致，无法匹配时列出全部候选供用户选择
+		filtered := filterByQualifiers(candidates, qualifiers)
+		if len(filtered) == 0 && len(candidates) > 0 {
+			return Location{}, &AmbiguousLocationError{Query: place, Candidates: candidates}
+		}
+		candidates = filtered
+	}
+
+	loc, err = pickLocation(place, candidates)
+	if err != nil {
+		return Location{}, err
+	}
+
+	g.logger.WithFields(logrus.Fields{
+		"place":     place,
+		"location":  loc.DisplayName(),
+		"latitude":  loc.Latitude,
+		"longitude": loc.Longitude,
+	}).Debug("Place resolved")
+
+	g.mu.Lock()
+	if len(g.cache) >= maxGeocodeCacheEntries {
+		g.cache = make(map[string]Location)
+	}
+	g.cache[key] = loc
+	g.mu.Unlock()
+
+	return loc, nil
+}
+
+// pickLocation 从候选中选出唯一地点
+func pickLocation(place string, candidates []Location) (Location, error) {
+	switch len(candidates) {
+	case 0:
+		return Location{}, fmt.Errorf("%w: %s", ErrLocationNotFound, place)
+	case 1:
+		return candidates[0], nil
+	}
+
+	// 人口明显更多的地点（如 Paris, France 相比 Paris, Texas）视为用户所指
+	top, second := candidates[0], candidates[1]
+	if top.Population > 0 && top.Population >= dominanceRatio*second.Population {
+		return top, nil
+	}
+	return Location{}, &AmbiguousLocationError{Query: place, Candidates: candidates}
+}
+
+// splitQualifiers 拆分 "地名, 限定1, 限定2" 形式的输入
+func splitQualifiers(place string) (string, []string) {
+	parts := strings.FieldsFunc(place, func(r rune) bool { return r == ',' || r == '，' })
+	if len(parts) == 0 {
+		return place, nil
+	}
+	var qualifiers []string
+	for _, part := range parts[1:] {
+		if q := strings.TrimSpace(part); q != "" {
+			qualifiers = append(qualifiers, strings.ToLower(q))
+		}
+	}
+	return strings.TrimSpace(parts[0]), qualifiers
+}
+
+// filterByQualifiers 保留行政区或国家与所有限定词匹配的候选
+func filterByQualifiers(candidates []Location, qualifiers []string) []Location {
+	var matched []Location
+	for _, c := range candidates {
+		fields := strings.ToLower(strings.Join([]string{c.Admin1, c.Admin2, c.Country, c.CountryCode}, "|"))
+		all := true
+		for _, q := range qualifiers {
+			if !strings.Contains(fields, q) {
+				all = false
+				break
+			}
+		}
+		if all {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// parseCoordinates 解析 "纬度,经度" 形式的坐标
+func parseCoordinates(place string) (Location, bool) {
+	m := coordinatesPattern.FindStringSubmatch(place)
+	if m == nil {
+		return Location{}, false
+	}
+	lat, _ := strconv.ParseFloat(m[1], 64)
+	lon, _ := strconv.ParseFloat(m[2], 64)
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return Location{}, false
+	}
+	return Location{
+		Name:      fmt.Sprintf("%.4f,%.4f", lat, lon),
+		Latitude:  lat,
+		Longitude: lon,
+	}, true
+}
+
+// FormatCandidates 将歧义地名的候选地点格式化为提示文本
+func FormatCandidates(err *AmbiguousLocationError) string {
+	text := fmt.Sprintf("📍 找到多个名为\"%s\"的地点，请补充省份/州或国家后重试（例如\"%s, %s\"）：\n",
+		err.Query, err.Candidates[0].Name, firstNonEmpty(err.Candidates[0].Admin1, err.Candidates[0].Country))
+	for i, c := range err.Candidates {
+		text += fmt.Sprintf("%d. %s (%.2f, %.2f)\n", i+1, c.DisplayName(), c.Latitude, c.Longitude)
+	}
+	return strings.TrimSuffix(text, "\n")
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
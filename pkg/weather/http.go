@@ -0,0 +1,38 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodyLength 错误信息中保留的响应体长度
+const maxErrorBodyLength = 256
+
+// getJSON 发送GET请求并将JSON响应解析到 out，非200状态码返回包含响应体摘要的错误
+// 请求URL可能携带密钥，网络错误会经过 sanitizeError 处理。
+func getJSON(ctx context.Context, client *http.Client, requestURL, provider string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", sanitizeError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+		return fmt.Errorf("%s API request failed with status %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
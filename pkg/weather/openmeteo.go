@@ -0,0 +1,339 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+)
+
+// openMeteoMaxForecastDays Open-Meteo 支持的最大预报天数
+const openMeteoMaxForecastDays = 16
+
+// wmoDescriptions WMO天气代码对应的中文描述
+var wmoDescriptions = map[int]string{
+	0:  "晴",
+	1:  "大部晴朗",
+	2:  "局部多云",
+	3:  "阴",
+	45: "雾",
+	48: "雾凇",
+	51: "小毛毛雨",
+	53: "毛毛雨",
+	55: "大毛毛雨",
+	56: "冻毛毛雨",
+	57: "强冻毛毛雨",
+	61: "小雨",
+	63: "中雨",
+	65: "大雨",
+	66: "冻雨",
+	67: "强冻雨",
+	71: "小雪",
+	73: "中雪",
+	75: "大雪",
+	77: "雪粒",
+	80: "小阵雨",
+	81: "阵雨",
+	82: "强阵雨",
+	85: "阵雪",
+	86: "强阵雪",
+	95: "雷阵雨",
+	96: "雷阵雨伴有小冰雹",
+	99: "雷阵雨伴有大冰雹",
+}
+
+// wmoDescriptionsEn WMO天气代码对应的英文描述
+var wmoDescriptionsEn = map[int]string{
+	0:  "clear sky",
+	1:  "mainly clear",
+	2:  "partly cloudy",
+	3:  "overcast",
+	45: "fog",
+	48: "depositing rime fog",
+	51: "light drizzle",
+	53: "drizzle",
+	55: "dense drizzle",
+	56: "freezing drizzle",
+	57: "dense freezing drizzle",
+	61: "light rain",
+	63: "moderate rain",
+	65: "heavy rain",
+	66: "freezing rain",
+	67: "heavy freezing rain",
+	71: "light snow",
+	73: "moderate snow",
+	75: "heavy snow",
+	77: "snow grains",
+	80: "light rain showers",
+	81: "rain showers",
+	82: "violent rain showers",
+	85: "snow showers",
+	86: "heavy snow showers",
+	95: "thunderstorm",
+	96: "thunderstorm with slight hail",
+	99: "thunderstorm with heavy hail",
+}
+
+// describeWMOCode 返回WMO天气代码在指定语言下的描述
+func describeWMOCode(code int, lang string) string {
+	descriptions, unknown := wmoDescriptions, "未知"
+	if lang == LangEn {
+		descriptions, unknown = wmoDescriptionsEn, "unknown"
+	}
+	if desc, ok := descriptions[code]; ok {
+		return desc
+	}
+	return unknown
+}
+
+// openMeteoResponse Open-Meteo Forecast API响应结构，时间为Unix时间戳（timeformat=unixtime）
+type openMeteoResponse struct {
+	UTCOffsetSeconds int `json:"utc_offset_seconds"`
+	Current          struct {
+		Time             int64   `json:"time"`
+		Temperature      float64 `json:"temperature_2m"`
+		FeelsLike        float64 `json:"apparent_temperature"`
+		RelativeHumidity float64 `json:"relative_humidity_2m"`
+		WeatherCode      int     `json:"weather_code"`
+		WindSpeed        float64 `json:"wind_speed_10m"`
+	} `json:"current"`
+	Daily struct {
+		Time                     []int64   `json:"time"`
+		WeatherCode              []int     `json:"weather_code"`
+		TemperatureMax           []float64 `json:"temperature_2m_max"`
+		TemperatureMin           []float64 `json:"temperature_2m_min"`
+		RelativeHumidity         []float64 `json:"relative_humidity_2m_mean"`
+		WindSpeedMax             []float64 `json:"wind_speed_10m_max"`
+		PrecipitationSum         []float64 `json:"precipitation_sum"`
+		PrecipitationProbability []float64 `json:"precipitation_probability_max"` // 部分模型没有概率数据，值为null
+		Sunrise                  []int64   `json:"sunrise"`
+		Sunset                   []int64   `json:"sunset"`
+	} `json:"daily"`
+	Hourly struct {
+		Time                     []int64   `json:"time"`
+		Temperature              []float64 `json:"temperature_2m"`
+		RelativeHumidity         []float64 `json:"relative_humidity_2m"`
+		WeatherCode              []int     `json:"weather_code"`
+		WindSpeed                []float64 `json:"wind_speed_10m"`
+		Precipitation            []float64 `json:"precipitation"`
+		PrecipitationProbability []float64 `json:"precipitation_probability"`
+	} `json:"hourly"`
+}
+
+// OpenMeteoClient Open-Meteo 天气客户端，无需密钥
+type OpenMeteoClient struct {
+	baseURL       string
+	airQualityURL string
+	httpClient    *http.Client
+	logger        *logrus.Logger
+}
+
+// NewOpenMeteoClient 创建 Open-Meteo 客户端
+func NewOpenMeteoClient(cfg *config.OpenMeteoConfig, timeout time.Duration, logger *logrus.Logger) *OpenMeteoClient {
+	return &OpenMeteoClient{
+		baseURL:       strings.TrimSuffix(cfg.BaseURL, "/"),
+		airQualityURL: strings.TrimSuffix(cfg.AirQualityURL, "/"),
+		httpClient:    &http.Client{Timeout: timeout},
+		logger:        logger,
+	}
+}
+
+// Name 返回提供方名称
+func (c *OpenMeteoClient) Name() string {
+	return config.WeatherProviderOpenMeteo
+}
+
+// MaxForecastDays 返回支持的最大预报天数
+func (c *OpenMeteoClient) MaxForecastDays() int {
+	return openMeteoMaxForecastDays
+}
+
+// Current 获取指定地点的当前天气
+func (c *OpenMeteoClient) Current(ctx context.Context, loc Location, opts Options) (*WeatherData, error) {
+	params := c.baseParams(loc, opts)
+	params.Set("current", "temperature_2m,apparent_temperature,relative_humidity_2m,weather_code,wind_speed_10m")
+	params.Set("daily", "sunrise,sunset")
+	params.Set("forecast_days", "1")
+
+	var resp openMeteoResponse
+	if err := getJSON(ctx, c.httpClient, c.baseURL+"/forecast?"+params.Encode(), "Open-Meteo", &resp); err != nil {
+		return nil, err
+	}
+
+	zone := opts.zone(resp.UTCOffsetSeconds)
+	data := &WeatherData{
+		Location:    loc.DisplayName(),
+		Temperature: resp.Current.Temperature,
+		FeelsLike:   resp.Current.FeelsLike,
+		Description: describeWMOCode(resp.Current.WeatherCode, opts.lang()),
+		Humidity:    int(math.Round(resp.Current.RelativeHumidity)),
+		WindSpeed:   resp.Current.WindSpeed,
+		Timestamp:   time.Unix(resp.Current.Time, 0).In(zone).Format(time.RFC3339),
+	}
+	if len(resp.Daily.Sunrise) > 0 && len(resp.Daily.Sunset) > 0 {
+		data.Sunrise = formatUnix(resp.Daily.Sunrise[0], zone)
+		data.Sunset = formatUnix(resp.Daily.Sunset[0], zone)
+	}
+	return data, nil
+}
+
+// AirQuality 通过 Open-Meteo 空气质量接口获取指定地点当前的空气质量
+func (c *OpenMeteoClient) AirQuality(ctx context.Context, loc Location, opts Options) (*AirQuality, error) {
+	params := c.baseParams(loc, opts)
+	params.Del("wind_speed_unit")
+	params.Set("current", "us_aqi,pm2_5,pm10,ozone,nitrogen_dioxide,sulphur_dioxide,carbon_monoxide")
+
+	var resp struct {
+		UTCOffsetSeconds int `json:"utc_offset_seconds"`
+		Current          struct {
+			Time  int64    `json:"time"`
+			USAQI *float64 `json:"us_aqi"` // 数据不足时为null
+			PM25  float64  `json:"pm2_5"`
+			PM10  float64  `json:"pm10"`
+			O3    float64  `json:"ozone"`
+			NO2   float64  `json:"nitrogen_dioxide"`
+			SO2   float64  `json:"sulphur_dioxide"`
+			CO    float64  `json:"carbon_monoxide"`
+		} `json:"current"`
+	}
+	if err := getJSON(ctx, c.httpClient, c.airQualityURL+"/air-quality?"+params.Encode(), "Open-Meteo Air Quality", &resp); err != nil {
+		return nil, err
+	}
+
+	cur := resp.Current
+	aq := &AirQuality{
+		Location:  loc.DisplayName(),
+		PM25:      cur.PM25,
+		PM10:      cur.PM10,
+		O3:        cur.O3,
+		NO2:       cur.NO2,
+		SO2:       cur.SO2,
+		CO:        cur.CO,
+		Timestamp: time.Unix(cur.Time, 0).In(opts.zone(resp.UTCOffsetSeconds)).Format(time.RFC3339),
+	}
+	if cur.USAQI != nil {
+		aq.AQI = int(math.Round(*cur.USAQI))
+	} else {
+		aq.AQI, aq.Estimated = estimateAQI(cur.PM25, cur.PM10), true
+	}
+	return aq, nil
+}
+
+// Forecast 获取指定地点未来若干天的逐日预报
+func (c *OpenMeteoClient) Forecast(ctx context.Context, loc Location, days int, opts Options) ([]DailyForecast, error) {
+	params := c.baseParams(loc, opts)
+	params.Set("daily", "weather_code,temperature_2m_max,temperature_2m_min,relative_humidity_2m_mean,"+
+		"wind_speed_10m_max,precipitation_sum,precipitation_probability_max")
+	params.Set("forecast_days", strconv.Itoa(days))
+
+	var resp openMeteoResponse
+	if err := getJSON(ctx, c.httpClient, c.baseURL+"/forecast?"+params.Encode(), "Open-Meteo", &resp); err != nil {
+		return nil, err
+	}
+
+	daily := resp.Daily
+	n := len(daily.Time)
+	if n == 0 {
+		return nil, fmt.Errorf("no forecast data available")
+	}
+	if !allLen(n, len(daily.WeatherCode), len(daily.TemperatureMax), len(daily.TemperatureMin), len(daily.RelativeHumidity),
+		len(daily.WindSpeedMax), len(daily.PrecipitationSum), len(daily.PrecipitationProbability)) {
+		return nil, fmt.Errorf("malformed Open-Meteo daily forecast")
+	}
+
+	zone := opts.zone(resp.UTCOffsetSeconds)
+	forecasts := make([]DailyForecast, 0, n)
+	for i := 0; i < n && i < days; i++ {
+		forecasts = append(forecasts, DailyForecast{
+			Location:                 loc.DisplayName(),
+			Date:                     time.Unix(daily.Time[i], 0).In(zone).Format("2006-01-02"),
+			TempMin:                  daily.TemperatureMin[i],
+			TempMax:                  daily.TemperatureMax[i],
+			Description:              describeWMOCode(daily.WeatherCode[i], opts.lang()),
+			Humidity:                 int(math.Round(daily.RelativeHumidity[i])),
+			WindSpeedMax:             daily.WindSpeedMax[i],
+			Precipitation:            daily.PrecipitationSum[i],
+			PrecipitationProbability: int(math.Round(daily.PrecipitationProbability[i])),
+		})
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"location":      loc.DisplayName(),
+		"forecast_days": len(forecasts),
+	}).Debug("Open-Meteo forecast fetched")
+
+	return forecasts, nil
+}
+
+// Hourly 获取指定地点从当前小时开始的逐小时预报
+func (c *OpenMeteoClient) Hourly(ctx context.Context, loc Location, hours int, opts Options) ([]HourlyForecast, error) {
+	params := c.baseParams(loc, opts)
+	params.Set("hourly", "temperature_2m,relative_humidity_2m,weather_code,wind_speed_10m,precipitation,precipitation_probability")
+	params.Set("forecast_hours", strconv.Itoa(hours))
+
+	var resp openMeteoResponse
+	if err := getJSON(ctx, c.httpClient, c.baseURL+"/forecast?"+params.Encode(), "Open-Meteo", &resp); err != nil {
+		return nil, err
+	}
+
+	hourly := resp.Hourly
+	n := len(hourly.Time)
+	if n == 0 {
+		return nil, fmt.Errorf("no forecast data available")
+	}
+	if !allLen(n, len(hourly.Temperature), len(hourly.RelativeHumidity), len(hourly.WeatherCode),
+		len(hourly.WindSpeed), len(hourly.Precipitation), len(hourly.PrecipitationProbability)) {
+		return nil, fmt.Errorf("malformed Open-Meteo hourly forecast")
+	}
+
+	zone := opts.zone(resp.UTCOffsetSeconds)
+	forecasts := make([]HourlyForecast, 0, n)
+	for i := 0; i < n && i < hours; i++ {
+		forecasts = append(forecasts, HourlyForecast{
+			Location:                 loc.DisplayName(),
+			Time:                     time.Unix(hourly.Time[i], 0).In(zone).Format(time.RFC3339),
+			Temperature:              hourly.Temperature[i],
+			Description:              describeWMOCode(hourly.WeatherCode[i], opts.lang()),
+			Humidity:                 int(math.Round(hourly.RelativeHumidity[i])),
+			WindSpeed:                hourly.WindSpeed[i],
+			Precipitation:            hourly.Precipitation[i],
+			PrecipitationProbability: int(math.Round(hourly.PrecipitationProbability[i])),
+		})
+	}
+	return forecasts, nil
+}
+
+// allLen 检查各数组长度不小于 n
+func allLen(n int, lengths ...int) bool {
+	for _, l := range lengths {
+		if l < n {
+			return false
+		}
+	}
+	return true
+}
+
+// baseParams 构建坐标、时区和单位参数，始终按公制单位（风速 m/s）查询
+// 指定时区时逐日数据按该时区分日，否则按地点的当地时区。
+func (c *OpenMeteoClient) baseParams(loc Location, opts Options) url.Values {
+	timezone := TimezoneAuto
+	if tz, err := opts.location(); err == nil && tz != nil {
+		timezone = tz.String()
+	}
+
+	params := url.Values{}
+	params.Set("latitude", strconv.FormatFloat(loc.Latitude, 'f', 4, 64))
+	params.Set("longitude", strconv.FormatFloat(loc.Longitude, 'f', 4, 64))
+	params.Set("timezone", timezone)
+	params.Set("timeformat", "unixtime")
+	params.Set("wind_speed_unit", "ms")
+	return params
+}
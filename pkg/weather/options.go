@@ -0,0 +1,179 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// 天气选项取值
+const (
+	UnitsMetric   = "metric"   // 摄氏度、m/s、毫米
+	UnitsImperial = "imperial" // 华氏度、mph、英寸
+
+	LangZh = "zh"
+	LangEn = "en"
+
+	// TimezoneAuto 使用地点的当地时区
+	TimezoneAuto = "auto"
+)
+
+// Options 单次天气查询的选项，零值表示公制单位、中文输出和地点的当地时区
+// 提供方始终按公制单位查询，单位换算在返回前统一处理。
+type Options struct {
+	Units    string `json:"units,omitempty"`    // metric | imperial
+	Lang     string `json:"lang,omitempty"`     // zh | en，同时决定天气描述和地名的语言
+	Timezone string `json:"timezone,omitempty"` // IANA 时区名（如 America/New_York），为空或 auto 时使用地点的当地时区
+}
+
+// Validate 校验选项取值
+func (o Options) Validate() error {
+	if o.Units != "" && o.Units != UnitsMetric && o.Units != UnitsImperial {
+		return fmt.Errorf("units must be %q or %q, got %q", UnitsMetric, UnitsImperial, o.Units)
+	}
+	if o.Lang != "" && o.Lang != LangZh && o.Lang != LangEn {
+		return fmt.Errorf("lang must be %q or %q, got %q", LangZh, LangEn, o.Lang)
+	}
+	if _, err := o.location(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// units 返回单位制，未设置时为公制
+func (o Options) units() string {
+	if o.Units == "" {
+		return UnitsMetric
+	}
+	return o.Units
+}
+
+// lang 返回输出语言，未设置时为中文
+func (o Options) lang() string {
+	if o.Lang == "" {
+		return LangZh
+	}
+	return o.Lang
+}
+
+// location 返回指定的时区，未指定时返回 nil 表示使用地点的当地时区
+func (o Options) location() (*time.Location, error) {
+	if o.Timezone == "" || strings.EqualFold(o.Timezone, TimezoneAuto) {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(o.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", o.Timezone)
+	}
+	return loc, nil
+}
+
+// zone 返回展示时间使用的时区：指定了时区时使用指定时区，否则使用提供方返回的当地UTC偏移
+func (o Options) zone(utcOffsetSeconds int) *time.Location {
+	if loc, err := o.location(); err == nil && loc != nil {
+		return loc
+	}
+	return time.FixedZone(formatUTCOffset(utcOffsetSeconds), utcOffsetSeconds)
+}
+
+// zoneForLocation 返回地点的展示时区，用于提供方不返回UTC偏移的接口
+// 指定了时区时使用指定时区，否则使用地名解析得到的当地时区，都没有时使用UTC。
+func (o Options) zoneForLocation(loc Location) *time.Location {
+	if tz, err := o.location(); err == nil && tz != nil {
+		return tz
+	}
+	if loc.Timezone != "" {
+		if tz, err := time.LoadLocation(loc.Timezone); err == nil {
+			return tz
+		}
+	}
+	return time.UTC
+}
+
+// formatUTCOffset 将UTC偏移秒数格式化为 "UTC+08:00" 形式
+func formatUTCOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	return fmt.Sprintf("UTC%c%02d:%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// convertCurrent 将公制的当前天气换算为所选单位制
+func (o Options) convertCurrent(data *WeatherData) {
+	if o.units() != UnitsImperial {
+		return
+	}
+	data.Temperature = celsiusToFahrenheit(data.Temperature)
+	data.FeelsLike = celsiusToFahrenheit(data.FeelsLike)
+	data.WindSpeed = msToMph(data.WindSpeed)
+}
+
+// convertDaily 将公制的逐日预报换算为所选单位制
+func (o Options) convertDaily(forecasts []DailyForecast) {
+	if o.units() != UnitsImperial {
+		return
+	}
+	for i := range forecasts {
+		f := &forecasts[i]
+		f.TempMin = celsiusToFahrenheit(f.TempMin)
+		f.TempMax = celsiusToFahrenheit(f.TempMax)
+		f.WindSpeedMax = msToMph(f.WindSpeedMax)
+		f.Precipitation = mmToInches(f.Precipitation)
+	}
+}
+
+// convertHourly 将公制的逐小时预报换算为所选单位制
+func (o Options) convertHourly(forecasts []HourlyForecast) {
+	if o.units() != UnitsImperial {
+		return
+	}
+	for i := range forecasts {
+		f := &forecasts[i]
+		f.Temperature = celsiusToFahrenheit(f.Temperature)
+		f.WindSpeed = msToMph(f.WindSpeed)
+		f.Precipitation = mmToInches(f.Precipitation)
+	}
+}
+
+func celsiusToFahrenheit(c float64) float64 { return c*9/5 + 32 }
+
+func msToMph(ms float64) float64 { return ms * 3600 / 1609.344 }
+
+func mmToInches(mm float64) float64 { return mm / 25.4 }
+
+// ParseOptions 从天气工具调用参数中解析选项
+func ParseOptions(request mcp.CallToolRequest) (Options, error) {
+	opts := Options{
+		Units:    request.GetString("units", ""),
+		Lang:     request.GetString("lang", ""),
+		Timezone: strings.TrimSpace(request.GetString("timezone", "")),
+	}
+	return opts, opts.Validate()
+}
+
+// optionToolOptions 返回天气工具共用的 units、lang 和 timezone 参数定义
+func optionToolOptions() []mcp.ToolOption {
+	return append([]mcp.ToolOption{
+		mcp.WithString("units",
+			mcp.Description("单位制，默认为metric（°C、m/s、mm），imperial 为°F、mph、英寸"),
+			mcp.Enum(UnitsMetric, UnitsImperial),
+		),
+	}, displayToolOptions()...)
+}
+
+// displayToolOptions 返回与单位无关的 lang 和 timezone 参数定义
+func displayToolOptions() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithString("lang",
+			mcp.Description("返回内容的语言，默认为zh"),
+			mcp.Enum(LangZh, LangEn),
+		),
+		mcp.WithString("timezone",
+			mcp.Description("显示时间使用的IANA时区，例如 Asia/Shanghai、America/New_York；默认为auto，即地点的当地时间"),
+		),
+	}
+}
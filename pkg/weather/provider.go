@@ -0,0 +1,313 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+)
+
+// Provider 天气服务提供方，按地名解析后的坐标查询
+type Provider interface {
+	// Name 返回提供方名称，与配置中的 weather.provider 对应
+	Name() string
+	// MaxForecastDays 返回支持的最大预报天数
+	MaxForecastDays() int
+	// Current 获取当前天气，数值使用公制单位
+	Current(ctx context.Context, loc Location, opts Options) (*WeatherData, error)
+	// Forecast 获取未来若干天的逐日预报（当地日期，包括今天），数值使用公制单位
+	Forecast(ctx context.Context, loc Location, days int, opts Options) ([]DailyForecast, error)
+	// Hourly 获取未来若干小时的逐小时预报，数值使用公制单位
+	Hourly(ctx context.Context, loc Location, hours int, opts Options) ([]HourlyForecast, error)
+}
+
+// ErrNotSupported 提供方不支持所请求的查询（如 Open-Meteo 不提供气象预警）
+var ErrNotSupported = errors.New("not supported by weather provider")
+
+// Service 天气服务：先将任意语言的地名解析为坐标，再交给所选提供方查询
+type Service struct {
+	geocoder    *Geocoder
+	providers   map[string]Provider
+	defaultName string
+	logger      *logrus.Logger
+}
+
+// NewService 根据配置创建天气服务
+// Open-Meteo 无需密钥总是可用，OpenWeatherMap 仅在配置了密钥时注册，默认提供方必须可用。
+func NewService(cfg *config.WeatherConfig, logger *logrus.Logger) (*Service, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	s := &Service{
+		geocoder:    NewGeocoder(&cfg.Geocoding, timeout, logger),
+		providers:   make(map[string]Provider),
+		defaultName: cfg.Provider,
+		logger:      logger,
+	}
+
+	if cfg.APIKey != "" {
+		s.Register(NewWeatherClient(&WeatherConfig{
+			APIKey:     cfg.APIKey,
+			BaseURL:    cfg.BaseURL,
+			Timeout:    cfg.Timeout,
+			OneCallURL: cfg.OneCallURL,
+		}, logger))
+	}
+	s.Register(NewOpenMeteoClient(&cfg.OpenMeteo, timeout, logger))
+
+	if _, ok := s.providers[s.defaultName]; !ok {
+		return nil, fmt.Errorf("default weather provider %q is not configured", s.defaultName)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"providers": s.Names(),
+		"default":   s.defaultName,
+	}).Info("Weather providers initialized")
+
+	return s, nil
+}
+
+// Register 注册天气提供方，同名提供方会被替换
+func (s *Service) Register(provider Provider) {
+	s.providers[provider.Name()] = provider
+}
+
+// Get 返回指定名称的提供方，name 为空时返回默认提供方
+func (s *Service) Get(name string) (Provider, error) {
+	if name == "" {
+		name = s.defaultName
+	}
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("weather provider %q is not configured, available: %v", name, s.Names())
+	}
+	return provider, nil
+}
+
+// Default 返回默认提供方名称
+func (s *Service) Default() string {
+	return s.defaultName
+}
+
+// Names 返回已注册的提供方名称（按字母排序）
+func (s *Service) Names() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve 将地名解析为坐标，地名使用 lang 语言，有歧义时返回 *AmbiguousLocationError
+func (s *Service) Resolve(ctx context.Context, place, lang string) (Location, error) {
+	return s.geocoder.Resolve(ctx, place, lang)
+}
+
+// Current 查询地点的当前天气，结果按 opts 换算单位
+func (s *Service) Current(ctx context.Context, providerName, place string, opts Options) (*WeatherData, error) {
+	provider, err := s.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := s.Resolve(ctx, place, opts.Lang)
+	if err != nil {
+		return nil, err
+	}
+	data, err := provider.Current(ctx, loc, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.convertCurrent(data)
+	return data, nil
+}
+
+// Forecast 查询地点的逐日预报，天数超出提供方上限时按上限查询
+func (s *Service) Forecast(ctx context.Context, providerName, place string, days int, opts Options) ([]DailyForecast, error) {
+	provider, err := s.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		days = 1
+	}
+	if max := provider.MaxForecastDays(); days > max {
+		days = max
+	}
+	loc, err := s.Resolve(ctx, place, opts.Lang)
+	if err != nil {
+		return nil, err
+	}
+	forecasts, err := provider.Forecast(ctx, loc, days, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.convertDaily(forecasts)
+	return forecasts, nil
+}
+
+// Hourly 查询地点的逐小时预报，小时数限制在 1 到 MaxForecastHours 之间
+func (s *Service) Hourly(ctx context.Context, providerName, place string, hours int, opts Options) ([]HourlyForecast, error) {
+	provider, err := s.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if hours <= 0 {
+		hours = DefaultForecastHours
+	}
+	if hours > MaxForecastHours {
+		hours = MaxForecastHours
+	}
+	loc, err := s.Resolve(ctx, place, opts.Lang)
+	if err != nil {
+		return nil, err
+	}
+	forecasts, err := provider.Hourly(ctx, loc, hours, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.convertHourly(forecasts)
+	return forecasts, nil
+}
+
+// ForecastToolOptions 返回 get_weather_forecast 工具的参数定义
+func ForecastToolOptions(s *Service) []mcp.ToolOption {
+	return append([]mcp.ToolOption{
+		mcp.WithDescription("获取指定城市的天气预报：逐日模式返回每天的最高/最低温度、降水量和降水概率、主要天气和最大风速，逐小时模式返回未来若干小时的详细预报"),
+		mcp.WithNumber("days",
+			mcp.Description("逐日预报天数（包括今天），默认为1天，超出提供方上限时按上限返回"),
+		),
+		mcp.WithString("mode",
+			mcp.Description("预报模式，默认为daily"),
+			mcp.Enum(ForecastModeDaily, ForecastModeHourly),
+		),
+		mcp.WithNumber("hours",
+			mcp.Description(fmt.Sprintf("逐小时模式的小时数，默认为%d，最大为%d；openweathermap 的时间间隔为3小时", DefaultForecastHours, MaxForecastHours)),
+		),
+	}, CityToolOptions(s)...)
+}
+
+// AirQuality 查询地点当前的空气质量
+func (s *Service) AirQuality(ctx context.Context, providerName, place string, opts Options) (*AirQuality, error) {
+	provider, err := s.capable(providerName, supportsAirQuality)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := s.Resolve(ctx, place, opts.Lang)
+	if err != nil {
+		return nil, err
+	}
+	return provider.(AirQualityProvider).AirQuality(ctx, loc, opts)
+}
+
+// Alerts 查询地点当前生效的气象预警
+func (s *Service) Alerts(ctx context.Context, providerName, place string, opts Options) ([]Alert, error) {
+	provider, err := s.capable(providerName, supportsAlerts)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := s.Resolve(ctx, place, opts.Lang)
+	if err != nil {
+		return nil, err
+	}
+	return provider.(AlertProvider).Alerts(ctx, loc, opts)
+}
+
+// SupportsAlerts 是否有已注册的提供方支持气象预警
+func (s *Service) SupportsAlerts() bool {
+	_, err := s.capable("", supportsAlerts)
+	return err == nil
+}
+
+func supportsAirQuality(p Provider) bool {
+	_, ok := p.(AirQualityProvider)
+	return ok
+}
+
+func supportsAlerts(p Provider) bool {
+	_, ok := p.(AlertProvider)
+	return ok
+}
+
+// capable 返回支持某项查询的提供方
+// 指定了提供方时它必须支持该查询；未指定时优先使用默认提供方，不支持时按名称顺序选择其他提供方。
+func (s *Service) capable(name string, supports func(Provider) bool) (Provider, error) {
+	if name != "" {
+		provider, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if !supports(provider) {
+			return nil, fmt.Errorf("%w: %s", ErrNotSupported, name)
+		}
+		return provider, nil
+	}
+
+	if provider := s.providers[s.defaultName]; supports(provider) {
+		return provider, nil
+	}
+	for _, n := range s.Names() {
+		if supports(s.providers[n]) {
+			return s.providers[n], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no configured provider supports this query", ErrNotSupported)
+}
+
+// namesSupporting 返回支持某项查询的提供方名称
+func (s *Service) namesSupporting(supports func(Provider) bool) []string {
+	var names []string
+	for _, n := range s.Names() {
+		if supports(s.providers[n]) {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// AirQualityToolOptions 返回 get_air_quality 工具的参数定义
+func AirQualityToolOptions(s *Service) []mcp.ToolOption {
+	return append([]mcp.ToolOption{
+		mcp.WithDescription("获取指定城市当前的空气质量：美国标准AQI、等级、户外活动建议和主要污染物浓度，可用于回答\"适不适合户外跑步\"等问题"),
+		cityToolOption(),
+		mcp.WithString("provider",
+			mcp.Description(fmt.Sprintf("天气服务提供方，默认为%s", s.Default())),
+			mcp.Enum(s.namesSupporting(supportsAirQuality)...),
+		),
+	}, displayToolOptions()...)
+}
+
+// AlertToolOptions 返回 get_weather_alerts 工具的参数定义
+func AlertToolOptions(s *Service) []mcp.ToolOption {
+	return append([]mcp.ToolOption{
+		mcp.WithDescription("获取指定城市当前生效的气象预警（台风、暴雨、高温、寒潮等），没有预警时明确说明"),
+		cityToolOption(),
+		mcp.WithString("provider",
+			mcp.Description("天气服务提供方，默认使用第一个支持气象预警的提供方"),
+			mcp.Enum(s.namesSupporting(supportsAlerts)...),
+		),
+	}, displayToolOptions()...)
+}
+
+// cityToolOption 返回天气工具共用的 city 参数定义
+func cityToolOption() mcp.ToolOption {
+	return mcp.WithString("city",
+		mcp.Required(),
+		mcp.Description("城市或地点名称，任意语言均可，例如：北京、東京、New York；重名地点可加省份/州或国家，如\"Springfield, Illinois\"，也可直接给出\"纬度,经度\""),
+	)
+}
+
+// CityToolOptions 返回天气工具共用的 city、provider、units、lang 和 timezone 参数定义
+func CityToolOptions(s *Service) []mcp.ToolOption {
+	return append([]mcp.ToolOption{
+		cityToolOption(),
+		mcp.WithString("provider",
+			mcp.Description(fmt.Sprintf("天气服务提供方，默认为%s", s.Default())),
+			mcp.Enum(s.Names()...),
+		),
+	}, optionToolOptions()...)
+}
@@ -2,23 +2,30 @@ package weather
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
+
+	"deer-flow-go/pkg/config"
+	"deer-flow-go/pkg/logging"
 )
 
 // WeatherConfig 天气服务配置
 type WeatherConfig struct {
-	APIKey  string `yaml:"api_key"`
-	BaseURL string `yaml:"base_url"`
-	Timeout int    `yaml:"timeout"`
+	APIKey     string `yaml:"api_key"`
+	BaseURL    string `yaml:"base_url"`
+	Timeout    int    `yaml:"timeout"`
+	OneCallURL string `yaml:"one_call_url"` // One Call 3.0 接口地址，用于查询气象预警
 }
 
-// WeatherClient 天气服务客户端
+// WeatherClient OpenWeatherMap 天气服务客户端
 type WeatherClient struct {
 	config     *WeatherConfig
 	httpClient *http.Client
@@ -32,15 +39,21 @@ type WeatherData struct {
 	Description string  `json:"description"`
 	Humidity    int     `json:"humidity"`
 	WindSpeed   float64 `json:"wind_speed"`
-	Timestamp   string  `json:"timestamp"`
+	Timestamp   string  `json:"timestamp"` // 观测时间，RFC3339，带当地UTC偏移
+	FeelsLike   float64 `json:"feels_like"`
+	Sunrise     string  `json:"sunrise,omitempty"` // 当天日出时间，RFC3339；极昼极夜时为空
+	Sunset      string  `json:"sunset,omitempty"`
 }
 
 // WeatherAPIResponse OpenWeatherMap API响应结构
 type WeatherAPIResponse struct {
-	Name string `json:"name"`
-	Main struct {
-		Temp     float64 `json:"temp"`
-		Humidity int     `json:"humidity"`
+	Name     string `json:"name"`
+	Dt       int64  `json:"dt"`       // 观测时间（Unix时间戳）
+	Timezone int    `json:"timezone"` // 与UTC的偏移秒数
+	Main     struct {
+		Temp      float64 `json:"temp"`
+		FeelsLike float64 `json:"feels_like"`
+		Humidity  int     `json:"humidity"`
 	} `json:"main"`
 	Weather []struct {
 		Description string `json:"description"`
@@ -48,6 +61,10 @@ type WeatherAPIResponse struct {
 	Wind struct {
 		Speed float64 `json:"speed"`
 	} `json:"wind"`
+	Sys struct {
+		Sunrise int64 `json:"sunrise"`
+		Sunset  int64 `json:"sunset"`
+	} `json:"sys"`
 }
 
 // NewWeatherClient 创建新的天气客户端
@@ -61,51 +78,137 @@ func NewWeatherClient(config *WeatherConfig, logger *logrus.Logger) *WeatherClie
 	}
 }
 
-// GetWeather 获取指定城市的天气信息
+// Name 返回提供方名称
+func (w *WeatherClient) Name() string {
+	return config.WeatherProviderOpenWeatherMap
+}
+
+// MaxForecastDays 返回支持的最大预报天数（5天/3小时预报接口）
+func (w *WeatherClient) MaxForecastDays() int {
+	return 5
+}
+
+// Current 获取指定地点的当前天气
+func (w *WeatherClient) Current(ctx context.Context, loc Location, opts Options) (*WeatherData, error) {
+	data, err := w.fetchCurrent(ctx, coordinateParams(loc), opts)
+	if err != nil {
+		return nil, err
+	}
+	data.Location = loc.DisplayName()
+	return data, nil
+}
+
+// Forecast 获取指定地点的逐日预报，由每天所有3小时时段汇总得到
+func (w *WeatherClient) Forecast(ctx context.Context, loc Location, days int, opts Options) ([]DailyForecast, error) {
+	slots, err := w.fetchForecastSlots(ctx, coordinateParams(loc), opts)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateDaily(loc.DisplayName(), slots, days), nil
+}
+
+// Hourly 获取指定地点未来若干小时的预报，时间间隔为3小时
+func (w *WeatherClient) Hourly(ctx context.Context, loc Location, hours int, opts Options) ([]HourlyForecast, error) {
+	slots, err := w.fetchForecastSlots(ctx, coordinateParams(loc), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	end := slots[0].Time.Add(time.Duration(hours) * time.Hour)
+	var forecasts []HourlyForecast
+	for _, slot := range slots {
+		if !slot.Time.Before(end) {
+			break
+		}
+		forecasts = append(forecasts, HourlyForecast{
+			Location:                 loc.DisplayName(),
+			Time:                     slot.Time.Format(time.RFC3339),
+			Temperature:              slot.Temperature,
+			Description:              slot.Description,
+			Humidity:                 slot.Humidity,
+			WindSpeed:                slot.WindSpeed,
+			Precipitation:            slot.Precipitation,
+			PrecipitationProbability: int(math.Round(slot.PrecipitationProbability * 100)),
+		})
+	}
+	return forecasts, nil
+}
+
+// GetWeather 获取指定城市的天气信息（按城市名直接查询，不经过地名解析）
 func (w *WeatherClient) GetWeather(ctx context.Context, city string) (*WeatherData, error) {
 	w.logger.WithFields(logrus.Fields{
 		"city": city,
 	}).Debug("Fetching weather data")
 
-	// 构建请求URL
 	params := url.Values{}
 	params.Add("q", city)
-	params.Add("appid", w.config.APIKey)
-	params.Add("units", "metric") // 使用摄氏度
-	params.Add("lang", "zh_cn")   // 中文描述
+	return w.fetchCurrent(ctx, params, Options{})
+}
 
-	requestURL := fmt.Sprintf("%s/weather?%s", w.config.BaseURL, params.Encode())
+// GetForecast 获取逐日预报（按城市名直接查询，不经过地名解析）
+func (c *WeatherClient) GetForecast(ctx context.Context, city string, days int) ([]DailyForecast, error) {
+	c.logger.WithFields(logrus.Fields{
+		"city": city,
+		"days": days,
+	}).Debug("Getting weather forecast")
 
-	// 创建HTTP请求
-	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if city == "" {
+		return nil, fmt.Errorf("city name cannot be empty")
+	}
+	if days <= 0 || days > c.MaxForecastDays() {
+		days = 1 // 限制预报天数在1-5天之间
 	}
 
-	// 发送请求
-	resp, err := w.httpClient.Do(req)
+	params := url.Values{}
+	params.Add("q", city)
+	slots, err := c.fetchForecastSlots(ctx, params, Options{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return aggregateDaily(city, slots, days), nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+// coordinateParams 构建按坐标查询的参数
+func coordinateParams(loc Location) url.Values {
+	params := url.Values{}
+	params.Add("lat", strconv.FormatFloat(loc.Latitude, 'f', 4, 64))
+	params.Add("lon", strconv.FormatFloat(loc.Longitude, 'f', 4, 64))
+	return params
+}
+
+// owmLang 将输出语言转换为 OpenWeatherMap 的 lang 参数
+func owmLang(lang string) string {
+	if lang == LangEn {
+		return "en"
 	}
+	return "zh_cn"
+}
+
+// fetchCurrent 调用 /weather 接口获取当前天气，观测时间按城市时区（或指定时区）表示
+func (w *WeatherClient) fetchCurrent(ctx context.Context, params url.Values, opts Options) (*WeatherData, error) {
+	params.Add("appid", w.config.APIKey)
+	params.Add("units", "metric") // 始终按公制查询，单位换算由 Options 统一处理
+	params.Add("lang", owmLang(opts.lang()))
+
+	requestURL := fmt.Sprintf("%s/weather?%s", w.config.BaseURL, params.Encode())
 
 	// 解析响应
 	var apiResp WeatherAPIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := getJSON(ctx, w.httpClient, requestURL, "OpenWeatherMap", &apiResp); err != nil {
+		return nil, err
 	}
 
 	// 转换为内部数据结构
+	zone := opts.zone(apiResp.Timezone)
 	weatherData := &WeatherData{
 		Location:    apiResp.Name,
 		Temperature: apiResp.Main.Temp,
+		FeelsLike:   apiResp.Main.FeelsLike,
 		Humidity:    apiResp.Main.Humidity,
 		WindSpeed:   apiResp.Wind.Speed,
-		Timestamp:   time.Now().Format(time.RFC3339),
+		Timestamp:   observedAt(apiResp.Dt).In(zone).Format(time.RFC3339),
+		Sunrise:     formatUnix(apiResp.Sys.Sunrise, zone),
+		Sunset:      formatUnix(apiResp.Sys.Sunset, zone),
 	}
 
 	if len(apiResp.Weather) > 0 {
@@ -121,116 +224,193 @@ func (w *WeatherClient) GetWeather(ctx context.Context, city string) (*WeatherDa
 	return weatherData, nil
 }
 
-// GetForecast 获取天气预报
-func (c *WeatherClient) GetForecast(ctx context.Context, city string, days int) ([]WeatherData, error) {
-	c.logger.WithFields(logrus.Fields{
-		"city": city,
-		"days": days,
-	}).Debug("Getting weather forecast")
-
-	if city == "" {
-		return nil, fmt.Errorf("city name cannot be empty")
-	}
+// owmForecastResponse OpenWeatherMap 5天/3小时预报接口响应结构
+type owmForecastResponse struct {
+	List []struct {
+		Dt   int64 `json:"dt"`
+		Main struct {
+			Temp     float64 `json:"temp"`
+			TempMin  float64 `json:"temp_min"`
+			TempMax  float64 `json:"temp_max"`
+			Humidity int     `json:"humidity"`
+		} `json:"main"`
+		Weather []struct {
+			Description string `json:"description"`
+		} `json:"weather"`
+		Wind struct {
+			Speed float64 `json:"speed"`
+		} `json:"wind"`
+		Pop  float64 `json:"pop"`
+		Rain struct {
+			ThreeHours float64 `json:"3h"`
+		} `json:"rain"`
+		Snow struct {
+			ThreeHours float64 `json:"3h"`
+		} `json:"snow"`
+	} `json:"list"`
+	City struct {
+		Name     string `json:"name"`
+		Timezone int    `json:"timezone"` // 与UTC的偏移秒数
+	} `json:"city"`
+}
 
-	if days <= 0 || days > 5 {
-		days = 1 // 限制预报天数在1-5天之间
-	}
+// fetchForecastSlots 调用 /forecast 接口获取全部3小时时段（共5天40个），时间转换为城市时区（或指定时区）
+func (c *WeatherClient) fetchForecastSlots(ctx context.Context, params url.Values, opts Options) ([]forecastSlot, error) {
+	params.Add("appid", c.config.APIKey)
+	params.Add("units", "metric")
+	params.Add("lang", owmLang(opts.lang()))
 
-	// 构建API请求URL (使用5天预报API)
-	url := fmt.Sprintf("%s/forecast?q=%s&appid=%s&units=metric&lang=zh_cn&cnt=%d",
-		c.config.BaseURL, city, c.config.APIKey, days*8) // 每天8个时间点
+	requestURL := fmt.Sprintf("%s/forecast?%s", c.config.BaseURL, params.Encode())
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		c.logger.WithError(err).Error("Failed to create forecast request")
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	var forecastResp owmForecastResponse
+	if err := getJSON(ctx, c.httpClient, requestURL, "OpenWeatherMap", &forecastResp); err != nil {
+		c.logger.WithError(err).Error("Failed to get forecast")
+		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		c.logger.WithError(err).Error("Failed to execute forecast request")
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+	if len(forecastResp.List) == 0 {
+		return nil, fmt.Errorf("no forecast data available")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.logger.WithField("status_code", resp.StatusCode).Error("API returned non-200 status")
-		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	// dt_txt 是UTC时间，按城市时区换算后再分日，否则东八区的早晨时段会被算到前一天
+	zone := opts.zone(forecastResp.City.Timezone)
+	slots := make([]forecastSlot, 0, len(forecastResp.List))
+	for _, item := range forecastResp.List {
+		description := "未知"
+		if len(item.Weather) > 0 {
+			description = item.Weather[0].Description
+		}
+		slots = append(slots, forecastSlot{
+			Time:                     time.Unix(item.Dt, 0).In(zone),
+			Temperature:              item.Main.Temp,
+			TempMin:                  item.Main.TempMin,
+			TempMax:                  item.Main.TempMax,
+			Description:              description,
+			Humidity:                 item.Main.Humidity,
+			WindSpeed:                item.Wind.Speed,
+			Precipitation:            item.Rain.ThreeHours + item.Snow.ThreeHours,
+			PrecipitationProbability: item.Pop,
+		})
 	}
 
-	var forecastResp struct {
+	c.logger.WithFields(logrus.Fields{
+		"city":  forecastResp.City.Name,
+		"slots": len(slots),
+	}).Info("Successfully retrieved weather forecast")
+
+	return slots, nil
+}
+
+// AirQuality 获取指定地点的空气质量，AQI 由 PM2.5/PM10 浓度估算
+func (w *WeatherClient) AirQuality(ctx context.Context, loc Location, opts Options) (*AirQuality, error) {
+	params := coordinateParams(loc)
+	params.Add("appid", w.config.APIKey)
+	requestURL := fmt.Sprintf("%s/air_pollution?%s", w.config.BaseURL, params.Encode())
+
+	var resp struct {
 		List []struct {
-			Main struct {
-				Temp     float64 `json:"temp"`
-				Humidity int     `json:"humidity"`
-			} `json:"main"`
-			Weather []struct {
-				Description string `json:"description"`
-			} `json:"weather"`
-			Wind struct {
-				Speed float64 `json:"speed"`
-			} `json:"wind"`
-			DtTxt string `json:"dt_txt"`
+			Dt         int64 `json:"dt"`
+			Components struct {
+				CO   float64 `json:"co"`
+				NO2  float64 `json:"no2"`
+				O3   float64 `json:"o3"`
+				SO2  float64 `json:"so2"`
+				PM25 float64 `json:"pm2_5"`
+				PM10 float64 `json:"pm10"`
+			} `json:"components"`
 		} `json:"list"`
-		City struct {
-			Name string `json:"name"`
-		} `json:"city"`
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&forecastResp); err != nil {
-		c.logger.WithError(err).Error("Failed to decode forecast response")
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := getJSON(ctx, w.httpClient, requestURL, "OpenWeatherMap", &resp); err != nil {
+		return nil, err
 	}
-
-	if len(forecastResp.List) == 0 {
-		return nil, fmt.Errorf("no forecast data available")
+	if len(resp.List) == 0 {
+		return nil, fmt.Errorf("no air quality data available")
 	}
 
-	// 处理预报数据，每天取中午12点的数据作为代表
-	var forecasts []WeatherData
-	processedDays := make(map[string]bool)
-
-	for _, item := range forecastResp.List {
-		// 提取日期部分
-		date := item.DtTxt[:10] // YYYY-MM-DD
-		if processedDays[date] {
-			continue // 跳过已处理的日期
-		}
+	// OpenWeatherMap 自己的指数只有1-5级，统一换算为EPA标准的AQI
+	item := resp.List[0]
+	c := item.Components
+	return &AirQuality{
+		Location:  loc.DisplayName(),
+		AQI:       estimateAQI(c.PM25, c.PM10),
+		Estimated: true,
+		PM25:      c.PM25,
+		PM10:      c.PM10,
+		O3:        c.O3,
+		NO2:       c.NO2,
+		SO2:       c.SO2,
+		CO:        c.CO,
+		Timestamp: observedAt(item.Dt).In(opts.zoneForLocation(loc)).Format(time.RFC3339),
+	}, nil
+}
 
-		// 只处理中午12点的数据，或者如果没有12点数据则取第一个
-		if len(item.DtTxt) >= 13 && item.DtTxt[11:13] != "12" && len(forecasts) < days {
-			// 如果不是12点且还没有这一天的数据，先跳过
-			continue
-		}
+// Alerts 通过 One Call 3.0 接口获取指定地点当前生效的气象预警
+func (w *WeatherClient) Alerts(ctx context.Context, loc Location, opts Options) ([]Alert, error) {
+	params := coordinateParams(loc)
+	params.Add("appid", w.config.APIKey)
+	params.Add("exclude", "current,minutely,hourly,daily")
+	params.Add("lang", owmLang(opts.lang()))
+	requestURL := fmt.Sprintf("%s/onecall?%s", w.config.OneCallURL, params.Encode())
+
+	var resp struct {
+		TimezoneOffset int `json:"timezone_offset"`
+		Alerts         []struct {
+			SenderName  string   `json:"sender_name"`
+			Event       string   `json:"event"`
+			Start       int64    `json:"start"`
+			End         int64    `json:"end"`
+			Description string   `json:"description"`
+			Tags        []string `json:"tags"`
+		} `json:"alerts"`
+	}
+	if err := getJSON(ctx, w.httpClient, requestURL, "OpenWeatherMap One Call", &resp); err != nil {
+		return nil, err
+	}
 
-		description := "未知"
-		if len(item.Weather) > 0 {
-			description = item.Weather[0].Description
-		}
+	zone := opts.zone(resp.TimezoneOffset)
+	alerts := make([]Alert, 0, len(resp.Alerts))
+	for _, a := range resp.Alerts {
+		alerts = append(alerts, Alert{
+			Event:       a.Event,
+			Sender:      a.SenderName,
+			Start:       formatUnix(a.Start, zone),
+			End:         formatUnix(a.End, zone),
+			Description: strings.TrimSpace(a.Description),
+			Tags:        a.Tags,
+		})
+	}
 
-		weatherData := WeatherData{
-			Location:    forecastResp.City.Name,
-			Temperature: item.Main.Temp,
-			Description: description,
-			Humidity:    item.Main.Humidity,
-			WindSpeed:   item.Wind.Speed,
-			Timestamp:   item.DtTxt,
-		}
+	w.logger.WithFields(logrus.Fields{
+		"location": loc.DisplayName(),
+		"alerts":   len(alerts),
+	}).Debug("Weather alerts fetched")
 
-		forecasts = append(forecasts, weatherData)
-		processedDays[date] = true
+	return alerts, nil
+}
 
-		if len(forecasts) >= days {
-			break
-		}
+// formatUnix 将Unix时间戳格式化为指定时区的RFC3339时间，0 表示没有该时间
+func formatUnix(ts int64, zone *time.Location) string {
+	if ts == 0 {
+		return ""
 	}
+	return time.Unix(ts, 0).In(zone).Format(time.RFC3339)
+}
 
-	c.logger.WithFields(logrus.Fields{
-		"city":          city,
-		"forecast_days": len(forecasts),
-	}).Info("Successfully retrieved weather forecast")
+// observedAt 返回观测时间，接口未返回时使用当前时间
+func observedAt(dt int64) time.Time {
+	if dt == 0 {
+		return time.Now()
+	}
+	return time.Unix(dt, 0)
+}
 
-	return forecasts, nil
+// sanitizeError 隐藏HTTP错误中请求URL携带的appid，避免密钥出现在日志和工具返回结果中
+func sanitizeError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = logging.RedactString(urlErr.URL)
+	}
+	return err
 }
 
 // HealthCheck 健康检查
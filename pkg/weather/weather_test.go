@@ -0,0 +1,309 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deer-flow-go/pkg/config"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return logger
+}
+
+// geocodingFixtures 地名查询的模拟返回
+var geocodingFixtures = map[string][]map[string]interface{}{
+	"北京": {
+		{"name": "北京市", "latitude": 39.9075, "longitude": 116.3972, "country": "中国", "country_code": "CN", "admin1": "北京市", "population": 18960744},
+		{"name": "北京", "latitude": 30.1, "longitude": 110.2, "country": "中国", "country_code": "CN", "admin1": "湖北省", "population": 0},
+	},
+	"Springfield": {
+		{"name": "Springfield", "latitude": 39.8017, "longitude": -89.6437, "country": "United States", "country_code": "US", "admin1": "Illinois", "population": 116250},
+		{"name": "Springfield", "latitude": 37.2153, "longitude": -93.2982, "country": "United States", "country_code": "US", "admin1": "Missouri", "population": 166810},
+		{"name": "Springfield", "latitude": 42.1015, "longitude": -72.5898, "country": "United States", "country_code": "US", "admin1": "Massachusetts", "population": 153606},
+	},
+}
+
+func newGeocodingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "zh", r.URL.Query().Get("language"))
+		results, ok := geocodingFixtures[r.URL.Query().Get("name")]
+		if !ok {
+			// 找不到时 Open-Meteo 不返回 results 字段
+			w.Write([]byte(`{"generationtime_ms": 0.1}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestGeocoder(t *testing.T) *Geocoder {
+	srv := newGeocodingServer(t)
+	return NewGeocoder(&config.GeocodingConfig{BaseURL: srv.URL, Language: "zh"}, 5*time.Second, newTestLogger())
+}
+
+func TestGeocoder_Resolve(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+	ctx := context.Background()
+
+	// 人口明显更多的候选直接采用
+	loc, err := geocoder.Resolve(ctx, "北京", "")
+	require.NoError(t, err)
+	assert.Equal(t, "北京市, 中国", loc.DisplayName())
+	assert.InDelta(t, 39.9075, loc.Latitude, 1e-6)
+
+	// 同名且规模相近的地点返回候选列表
+	_, err = geocoder.Resolve(ctx, "Springfield", "")
+	var ambiguous *AmbiguousLocationError
+	require.True(t, errors.As(err, &ambiguous))
+	assert.Len(t, ambiguous.Candidates, 3)
+	assert.Contains(t, FormatCandidates(ambiguous), "Springfield, Missouri, United States")
+
+	// 限定省份/州后可以确定唯一地点
+	loc, err = geocoder.Resolve(ctx, "Springfield, Illinois", "")
+	require.NoError(t, err)
+	assert.Equal(t, "Illinois", loc.Admin1)
+
+	// 直接给出坐标时不查询
+	loc, err = geocoder.Resolve(ctx, "31.23, 121.47", "")
+	require.NoError(t, err)
+	assert.InDelta(t, 121.47, loc.Longitude, 1e-6)
+
+	_, err = geocoder.Resolve(ctx, "Atlantis", "")
+	assert.ErrorIs(t, err, ErrLocationNotFound)
+}
+
+func TestOpenMeteoClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "/forecast", r.URL.Path)
+		assert.Equal(t, "39.9075", q.Get("latitude"))
+		assert.Equal(t, "ms", q.Get("wind_speed_unit"))
+		assert.Equal(t, "unixtime", q.Get("timeformat"))
+		assert.Equal(t, "auto", q.Get("timezone"))
+
+		// 时间为Unix时间戳，北京时间比UTC早8小时
+		switch {
+		case q.Get("current") != "":
+			w.Write([]byte(`{"utc_offset_seconds": 28800, "current": {"time": 1717214400, "temperature_2m": 28.4, "relative_humidity_2m": 41.6, "weather_code": 2, "wind_speed_10m": 3.2}}`))
+		case q.Get("hourly") != "":
+			assert.Equal(t, "2", q.Get("forecast_hours"))
+			w.Write([]byte(`{"utc_offset_seconds": 28800, "hourly": {
+				"time": [1717218000, 1717221600],
+				"temperature_2m": [28.9, 29.3],
+				"relative_humidity_2m": [40, 38],
+				"weather_code": [2, 95],
+				"wind_speed_10m": [3.1, 6.4],
+				"precipitation": [0, 4.2],
+				"precipitation_probability": [10, null]
+			}}`))
+		default:
+			assert.Equal(t, "2", q.Get("forecast_days"))
+			w.Write([]byte(`{"utc_offset_seconds": 28800, "daily": {
+				"time": [1717171200, 1717257600],
+				"weather_code": [61, 0],
+				"temperature_2m_max": [27.5, 31.2],
+				"temperature_2m_min": [19.1, 20.4],
+				"relative_humidity_2m_mean": [70, 45],
+				"wind_speed_10m_max": [5.5, 3.1],
+				"precipitation_sum": [6.3, 0],
+				"precipitation_probability_max": [80, 5]
+			}}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := NewOpenMeteoClient(&config.OpenMeteoConfig{BaseURL: srv.URL}, 5*time.Second, newTestLogger())
+	loc := Location{Name: "北京市", Country: "中国", Latitude: 39.9075, Longitude: 116.3972}
+
+	current, err := client.Current(context.Background(), loc, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "北京市, 中国", current.Location)
+	assert.Equal(t, "2024-06-01T12:00:00+08:00", current.Timestamp)
+	assert.Equal(t, 28.4, current.Temperature)
+	assert.Equal(t, 42, current.Humidity)
+	assert.Equal(t, "局部多云", current.Description)
+
+	forecast, err := client.Forecast(context.Background(), loc, 2, Options{})
+	require.NoError(t, err)
+	require.Len(t, forecast, 2)
+	assert.Equal(t, DailyForecast{
+		Location:                 "北京市, 中国",
+		Date:                     "2024-06-01",
+		TempMin:                  19.1,
+		TempMax:                  27.5,
+		Description:              "小雨",
+		Humidity:                 70,
+		WindSpeedMax:             5.5,
+		Precipitation:            6.3,
+		PrecipitationProbability: 80,
+	}, forecast[0])
+	assert.Equal(t, "2024-06-02", forecast[1].Date)
+
+	hourly, err := client.Hourly(context.Background(), loc, 2, Options{})
+	require.NoError(t, err)
+	require.Len(t, hourly, 2)
+	assert.Equal(t, "2024-06-01T14:00:00+08:00", hourly[1].Time)
+	assert.Equal(t, "雷阵雨", hourly[1].Description)
+	assert.Equal(t, 4.2, hourly[1].Precipitation)
+	// 没有概率数据时为0
+	assert.Equal(t, 0, hourly[1].PrecipitationProbability)
+}
+
+// owmForecastFixture 北京（UTC+8）的3小时预报，首个时段为当地 2024-06-01 20:00
+const owmForecastFixture = `{
+	"city": {"name": "Beijing", "timezone": 28800},
+	"list": [
+		{"dt": 1717243200, "main": {"temp": 24, "temp_min": 23.5, "temp_max": 24, "humidity": 60}, "weather": [{"description": "晴"}], "wind": {"speed": 2}, "pop": 0},
+		{"dt": 1717254000, "main": {"temp": 22, "temp_min": 21.8, "temp_max": 22, "humidity": 70}, "weather": [{"description": "多云"}], "wind": {"speed": 3}, "pop": 0.1},
+		{"dt": 1717264800, "main": {"temp": 20, "temp_min": 19.6, "temp_max": 20, "humidity": 80}, "weather": [{"description": "小雨"}], "wind": {"speed": 4}, "pop": 0.6, "rain": {"3h": 1.2}},
+		{"dt": 1717275600, "main": {"temp": 19, "temp_min": 18.7, "temp_max": 19, "humidity": 85}, "weather": [{"description": "小雨"}], "wind": {"speed": 5.5}, "pop": 0.8, "rain": {"3h": 2.3}},
+		{"dt": 1717286400, "main": {"temp": 23, "temp_min": 23, "temp_max": 23.4, "humidity": 65}, "weather": [{"description": "多云"}], "wind": {"speed": 3}, "pop": 0.2},
+		{"dt": 1717297200, "main": {"temp": 27, "temp_min": 27, "temp_max": 27.8, "humidity": 50}, "weather": [{"description": "多云"}], "wind": {"speed": 2.5}, "pop": 0},
+		{"dt": 1717308000, "main": {"temp": 28, "temp_min": 28, "temp_max": 28.6, "humidity": 45}, "weather": [{"description": "多云"}], "wind": {"speed": 2}, "pop": 0}
+	]
+}`
+
+func TestWeatherClient_Forecast(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/forecast", r.URL.Path)
+		// 获取全部时段后在本地汇总，不再用 cnt 截断
+		assert.Empty(t, r.URL.Query().Get("cnt"))
+		w.Write([]byte(owmForecastFixture))
+	}))
+	defer srv.Close()
+
+	client := NewWeatherClient(&WeatherConfig{APIKey: "owm-key", BaseURL: srv.URL, Timeout: 5}, newTestLogger())
+	loc := Location{Name: "北京市", Country: "中国", Latitude: 39.9075, Longitude: 116.3972}
+
+	forecast, err := client.Forecast(context.Background(), loc, 3, Options{})
+	require.NoError(t, err)
+	// 当天只剩晚上的时段也要保留，之后按当地日期汇总
+	require.Len(t, forecast, 2)
+	assert.Equal(t, DailyForecast{
+		Location:                 "北京市, 中国",
+		Date:                     "2024-06-01",
+		TempMin:                  21.8,
+		TempMax:                  24,
+		Description:              "晴",
+		Humidity:                 65,
+		WindSpeedMax:             3,
+		Precipitation:            0,
+		PrecipitationProbability: 10,
+	}, forecast[0])
+	assert.Equal(t, "2024-06-02", forecast[1].Date)
+	assert.Equal(t, 18.7, forecast[1].TempMin)
+	assert.Equal(t, 28.6, forecast[1].TempMax)
+	assert.Equal(t, "多云", forecast[1].Description)
+	assert.InDelta(t, 3.5, forecast[1].Precipitation, 1e-9)
+	assert.Equal(t, 80, forecast[1].PrecipitationProbability)
+	assert.Equal(t, 5.5, forecast[1].WindSpeedMax)
+
+	forecast, err = client.Forecast(context.Background(), loc, 1, Options{})
+	require.NoError(t, err)
+	assert.Len(t, forecast, 1)
+
+	hourly, err := client.Hourly(context.Background(), loc, 7, Options{})
+	require.NoError(t, err)
+	require.Len(t, hourly, 3)
+	assert.Equal(t, "2024-06-01T20:00:00+08:00", hourly[0].Time)
+	assert.Equal(t, "2024-06-02T02:00:00+08:00", hourly[2].Time)
+	assert.Equal(t, 60, hourly[2].PrecipitationProbability)
+}
+
+func TestService(t *testing.T) {
+	geoSrv := newGeocodingServer(t)
+	owm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 按地名解析得到的坐标查询，而不是城市名
+		assert.Equal(t, "/weather", r.URL.Path)
+		assert.Empty(t, r.URL.Query().Get("q"))
+		assert.Equal(t, "39.9075", r.URL.Query().Get("lat"))
+		w.Write([]byte(`{"name": "Beijing", "dt": 1717243200, "timezone": 28800, "main": {"temp": 30.5, "humidity": 35}, "weather": [{"description": "晴"}], "wind": {"speed": 2.1}}`))
+	}))
+	defer owm.Close()
+
+	cfg := &config.WeatherConfig{
+		Provider:  config.WeatherProviderOpenWeatherMap,
+		APIKey:    "owm-key",
+		BaseURL:   owm.URL,
+		Timeout:   5,
+		OpenMeteo: config.OpenMeteoConfig{BaseURL: "http://127.0.0.1:0"},
+		Geocoding: config.GeocodingConfig{BaseURL: geoSrv.URL, Language: "zh"},
+	}
+	service, err := NewService(cfg, newTestLogger())
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.WeatherProviderOpenMeteo, config.WeatherProviderOpenWeatherMap}, service.Names())
+
+	data, err := service.Current(context.Background(), "", "北京", Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "北京市, 中国", data.Location)
+	assert.Equal(t, 30.5, data.Temperature)
+	// 观测时间取接口返回的时间，按城市时区表示
+	assert.Equal(t, "2024-06-01T20:00:00+08:00", data.Timestamp)
+
+	// 英制单位在本地换算，可以指定展示时区
+	opts := Options{Units: UnitsImperial, Timezone: "America/New_York"}
+	data, err = service.Current(context.Background(), "", "北京", opts)
+	require.NoError(t, err)
+	assert.InDelta(t, 86.9, data.Temperature, 1e-9)
+	assert.InDelta(t, 4.70, data.WindSpeed, 0.01)
+	assert.Equal(t, "2024-06-01T08:00:00-04:00", data.Timestamp)
+	text := FormatCurrent(data, opts)
+	assert.Contains(t, text, "86.9°F")
+	assert.Contains(t, text, "4.7 mph")
+	assert.Contains(t, text, "2024-06-01 08:00 (UTC-04:00)")
+
+	_, err = service.Current(context.Background(), "unknown", "北京", Options{})
+	assert.Error(t, err)
+
+	// 没有密钥时 OpenWeatherMap 不可作为默认提供方
+	cfg.APIKey = ""
+	_, err = NewService(cfg, newTestLogger())
+	assert.Error(t, err)
+
+	cfg.Provider = config.WeatherProviderOpenMeteo
+	service, err = NewService(cfg, newTestLogger())
+	require.NoError(t, err)
+	assert.Equal(t, []string{config.WeatherProviderOpenMeteo}, service.Names())
+}
+
+func TestOptions(t *testing.T) {
+	assert.NoError(t, Options{}.Validate())
+	assert.NoError(t, Options{Units: UnitsImperial, Lang: LangEn, Timezone: "Asia/Tokyo"}.Validate())
+	assert.NoError(t, Options{Timezone: "auto"}.Validate())
+	assert.Error(t, Options{Units: "kelvin"}.Validate())
+	assert.Error(t, Options{Lang: "fr"}.Validate())
+	assert.Error(t, Options{Timezone: "Mars/Olympus"}.Validate())
+
+	forecasts := []DailyForecast{{
+		Location: "London, United Kingdom", Date: "2024-06-01",
+		TempMin: 10, TempMax: 20, Description: "light rain", Humidity: 80,
+		WindSpeedMax: 10, Precipitation: 25.4, PrecipitationProbability: 70,
+	}}
+	opts := Options{Units: UnitsImperial, Lang: LangEn}
+	opts.convertDaily(forecasts)
+	text := FormatDailyForecast(forecasts, opts)
+	assert.Contains(t, text, "1-day forecast for London, United Kingdom")
+	assert.Contains(t, text, "50.0 ~ 68.0°F")
+	assert.Contains(t, text, "Precipitation: 1.0 in (70% chance)")
+	assert.Contains(t, text, "Max wind: 22.4 mph")
+
+	assert.Equal(t, "UTC+05:30", formatUTCOffset(19800))
+	assert.Equal(t, "UTC-03:00", formatUTCOffset(-10800))
+	assert.Equal(t, "thunderstorm", describeWMOCode(95, LangEn))
+}